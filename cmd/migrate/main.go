@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/migrations"
+)
+
+// Usage: go run ./cmd/migrate [up|down|check]
+func main() {
+	direction := "up"
+	if len(os.Args) > 1 {
+		direction = os.Args[1]
+	}
+
+	database.ConnectDB()
+
+	switch direction {
+	case "up":
+		if err := migrations.Up(database.DB); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		fmt.Println("✅ Migrations applied")
+	case "down":
+		if err := migrations.Down(database.DB); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		fmt.Println("✅ Last migration rolled back")
+	case "check":
+		// Dry run: report rows that would block a unique index, change nothing
+		duplicates, err := migrations.CheckDuplicates(database.DB)
+		if err != nil {
+			log.Fatalf("Duplicate check failed: %v", err)
+		}
+		for _, duplicate := range duplicates {
+			fmt.Printf("%s: %q shared by ids %v (oldest: %d)\n", duplicate.Index, duplicate.Values, duplicate.IDs, duplicate.IDs[0])
+		}
+		if len(duplicates) > 0 {
+			log.Fatalf("%d groups of duplicate rows found", len(duplicates))
+		}
+		fmt.Println("✅ No duplicates found")
+	default:
+		log.Fatalf("Unknown direction %q, expected up, down or check", direction)
+	}
+}
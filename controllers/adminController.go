@@ -0,0 +1,466 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/middleware"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/outbox"
+	"github.com/rohans540/books-backend/redis"
+	"gorm.io/plugin/dbresolver"
+)
+
+// bookCachePattern matches every key books are cached under. The read-only
+// toggle is a setting rather than a cached value and is left alone.
+const bookCachePattern = "book*"
+
+// maxListedCacheKeys caps how many keys GET /admin/cache/keys returns
+const maxListedCacheKeys = 1000
+
+// maxListedDeadLetters caps how many events GET /admin/dead-letters returns
+const maxListedDeadLetters = 100
+
+// deadLetter is an outbox event that was forwarded to the dead-letter topic
+type deadLetter struct {
+	ID             uint            `json:"id"`
+	Topic          string          `json:"topic"`
+	Event          json.RawMessage `json:"event" swaggertype:"object"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeadLetteredAt time.Time       `json:"dead_lettered_at"`
+	Error          string          `json:"error"`
+}
+
+// cacheKeyInfo is one entry of the cache key listing
+type cacheKeyInfo struct {
+	Key string `json:"key"`
+	// TTLSeconds is -1 for keys that don't expire
+	TTLSeconds int64 `json:"ttl_seconds"`
+}
+
+// CacheWarmRequest lists the pages POST /admin/cache/warm loads into the
+// cache, in the same form POST /books/search takes them
+type CacheWarmRequest struct {
+	Queries []BookQuery `json:"queries" binding:"required"`
+}
+
+// CacheRefreshRequest lists the books POST /admin/cache/refresh reloads
+type CacheRefreshRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// cacheRefreshResult is what POST /admin/cache/refresh did for one id:
+// "refreshed" when the book was cached again, "evicted" when it no longer
+// exists and its entries were dropped
+type cacheRefreshResult struct {
+	ID     uint   `json:"id"`
+	Status string `json:"status"`
+}
+
+// ReadOnlyRequest turns read-only mode on or off
+type ReadOnlyRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// GetReadOnly godoc
+// @Summary Get read-only mode
+// @Description Report whether book writes are currently rejected. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} map[string]bool "Whether read-only mode is on"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Router /admin/read-only [get]
+func GetReadOnly(ctx *gin.Context) {
+	respond(ctx, http.StatusOK, gin.H{"enabled": middleware.IsReadOnly(ctx.Request.Context())})
+}
+
+// SetReadOnly godoc
+// @Summary Toggle read-only mode
+// @Description Turn read-only mode on or off for every instance sharing the cache, without a restart.
+// @Description While it is on, book writes fail with 503. READ_ONLY=true in the environment can't be turned off here. Requires the admin token.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body ReadOnlyRequest true "Whether to enable read-only mode"
+// @Success 200 {object} map[string]bool "Whether read-only mode is on"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 409 {object} map[string]string "Read-only mode is forced by READ_ONLY"
+// @Failure 500 {object} map[string]string "Failed to store the toggle"
+// @Router /admin/read-only [put]
+func SetReadOnly(ctx *gin.Context) {
+	var req ReadOnlyRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if !*req.Enabled && os.Getenv("READ_ONLY") == "true" {
+		render(ctx, http.StatusConflict, gin.H{"error": "Read-only mode is forced by READ_ONLY"})
+		return
+	}
+	if err := middleware.SetReadOnly(ctx.Request.Context(), *req.Enabled); err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to update read-only mode"})
+		return
+	}
+	respond(ctx, http.StatusOK, gin.H{"enabled": middleware.IsReadOnly(ctx.Request.Context())})
+}
+
+// featureState is one entry of the feature listing
+type featureState struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Default is the state from FEATURE_<NAME>, used when no runtime toggle is set
+	Default bool `json:"default"`
+}
+
+// FeatureRequest turns a feature on or off
+type FeatureRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// ListFeatures godoc
+// @Summary List feature flags
+// @Description Report whether each optional endpoint is currently enabled, and its FEATURE_<NAME> default. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {array} featureState
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Router /admin/features [get]
+func ListFeatures(ctx *gin.Context) {
+	features := make([]featureState, len(middleware.Features))
+	for i, name := range middleware.Features {
+		features[i] = currentFeature(ctx, name)
+	}
+	respond(ctx, http.StatusOK, features)
+}
+
+// SetFeature godoc
+// @Summary Toggle a feature flag
+// @Description Turn an optional endpoint on or off for every instance sharing the cache, without a restart.
+// @Description Disabled endpoints answer 404. The toggle overrides FEATURE_<NAME> until it is reset. Requires the admin token.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param name path string true "Feature name"
+// @Param request body FeatureRequest true "Whether to enable the feature"
+// @Success 200 {object} featureState
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 404 {object} map[string]string "Unknown feature"
+// @Failure 500 {object} map[string]string "Failed to store the toggle"
+// @Router /admin/features/{name} [put]
+func SetFeature(ctx *gin.Context) {
+	name := ctx.Param("name")
+	if !middleware.IsFeature(name) {
+		render(ctx, http.StatusNotFound, gin.H{"error": "Unknown feature: " + name})
+		return
+	}
+	var req FeatureRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if err := middleware.SetFeature(ctx.Request.Context(), name, *req.Enabled); err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to update the feature"})
+		return
+	}
+	respond(ctx, http.StatusOK, currentFeature(ctx, name))
+}
+
+// ResetFeature godoc
+// @Summary Reset a feature flag
+// @Description Drop the runtime toggle of a feature so its FEATURE_<NAME> default applies again. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param name path string true "Feature name"
+// @Success 200 {object} featureState
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 404 {object} map[string]string "Unknown feature"
+// @Failure 500 {object} map[string]string "Failed to reset the toggle"
+// @Router /admin/features/{name} [delete]
+func ResetFeature(ctx *gin.Context) {
+	name := ctx.Param("name")
+	if !middleware.IsFeature(name) {
+		render(ctx, http.StatusNotFound, gin.H{"error": "Unknown feature: " + name})
+		return
+	}
+	if err := middleware.ResetFeature(ctx.Request.Context(), name); err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to reset the feature"})
+		return
+	}
+	respond(ctx, http.StatusOK, currentFeature(ctx, name))
+}
+
+func currentFeature(ctx *gin.Context, name string) featureState {
+	return featureState{
+		Name:    name,
+		Enabled: middleware.FeatureEnabled(ctx.Request.Context(), name),
+		Default: middleware.FeatureDefault(name),
+	}
+}
+
+// ListCacheKeys godoc
+// @Summary List cached book keys
+// @Description List the book-related cache keys (at most 1000) with their remaining TTL in seconds, -1 when they don't expire.
+// @Description Keys are shown without the CACHE_PREFIX namespace. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} map[string]interface{} "keys, count and whether the listing was truncated"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to list cache keys"
+// @Router /admin/cache/keys [get]
+func ListCacheKeys(ctx *gin.Context) {
+	infos, err := redis.BookCache.Keys(ctx.Request.Context(), bookCachePattern, maxListedCacheKeys)
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to list cache keys"})
+		return
+	}
+
+	keys := make([]cacheKeyInfo, len(infos))
+	for i, info := range infos {
+		keys[i] = cacheKeyInfo{Key: info.Key, TTLSeconds: -1}
+		if info.TTL >= 0 {
+			keys[i].TTLSeconds = int64(info.TTL / time.Second)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+
+	respond(ctx, http.StatusOK, gin.H{"keys": keys, "count": len(keys), "truncated": len(keys) == maxListedCacheKeys})
+}
+
+// FlushCache godoc
+// @Summary Flush the book cache
+// @Description Delete every cached book, list and count of this service. Only keys in the service's
+// @Description CACHE_PREFIX namespace are touched, so other users of a shared Redis are unaffected. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} map[string]string "Cache flushed"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to flush the cache"
+// @Router /admin/cache [delete]
+func FlushCache(ctx *gin.Context) {
+	err := redis.BookCache.DeletePattern(ctx.Request.Context(), bookCachePattern)
+	if err == nil {
+		err = redis.BookCache.Del(ctx.Request.Context(), listCacheTag, statsCacheTag)
+	}
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to flush the cache"})
+		return
+	}
+	respond(ctx, http.StatusOK, gin.H{"message": "Cache flushed"})
+}
+
+// WarmCache godoc
+// @Summary Warm the list cache
+// @Description Load the given pages from the database and cache them under the keys GET /books and POST /books/search
+// @Description read, e.g. ahead of an expected traffic spike. Offset pages also cache their match count. Every query is
+// @Description validated before any is run. Requires the admin token.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body CacheWarmRequest true "Pages to warm"
+// @Success 200 {object} map[string]interface{} "Number of pages warmed and their cache keys"
+// @Failure 400 {object} map[string]string "Invalid JSON or invalid query"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 413 {object} map[string]string "Too many queries"
+// @Failure 500 {object} map[string]string "Failed to load a page"
+// @Router /admin/cache/warm [post]
+func WarmCache(ctx *gin.Context) {
+	var req CacheWarmRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if maxSize := maxBatchSize(); len(req.Queries) > maxSize {
+		render(ctx, http.StatusRequestEntityTooLarge, gin.H{"error": batchTooLargeError{count: int64(len(req.Queries)), max: maxSize}.Error()})
+		return
+	}
+	filters := make([]bookFilters, len(req.Queries))
+	for i := range req.Queries {
+		var err error
+		if filters[i], err = req.Queries[i].validate(); err != nil {
+			render(ctx, http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Query %d: %v", i, err)})
+			return
+		}
+	}
+
+	keys := make([]string, 0, len(req.Queries))
+	for i, query := range req.Queries {
+		limit := clampLimit(ctx, query.Limit)
+		var key string
+		var err error
+		if query.AfterID != nil {
+			key = keysetPageCacheKey(*query.AfterID, limit, filters[i], query.Fields)
+			_, err = loadBooksAfter(*query.AfterID, limit, filters[i], query.Fields, key)
+		} else {
+			key = bookPageCacheKey(query, filters[i], limit)
+			if _, err = loadBookPage(query, filters[i], limit, query.Offset, key); err == nil {
+				_, err = countBooks(ctx, filters[i])
+			}
+		}
+		if err != nil {
+			render(ctx, http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to load query %d", i), "warmed": len(keys)})
+			return
+		}
+		keys = append(keys, key)
+	}
+	respond(ctx, http.StatusOK, gin.H{"warmed": len(keys), "keys": keys})
+}
+
+// ListDeadLetters godoc
+// @Summary List dead-lettered events
+// @Description List the oldest book events (at most 100) that could not be published and were forwarded to the dead-letter topic,
+// @Description with the error of the last attempt. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {array} deadLetter
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to list dead-lettered events"
+// @Router /admin/dead-letters [get]
+func ListDeadLetters(ctx *gin.Context) {
+	events, err := outbox.DeadLetters(database.DB, maxListedDeadLetters)
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to list dead-lettered events"})
+		return
+	}
+
+	letters := make([]deadLetter, len(events))
+	for i, event := range events {
+		letters[i] = deadLetter{
+			ID:        event.ID,
+			Topic:     event.Topic,
+			Event:     json.RawMessage(event.Payload),
+			CreatedAt: event.CreatedAt,
+		}
+		if event.DeadLetteredAt != nil {
+			letters[i].DeadLetteredAt = *event.DeadLetteredAt
+		}
+		if event.LastError != nil {
+			letters[i].Error = *event.LastError
+		}
+	}
+	respond(ctx, http.StatusOK, letters)
+}
+
+// RefreshCache godoc
+// @Summary Refresh cached books
+// @Description Reload the given books from the primary database and rewrite their cache entries by id, slug and ISBN,
+// @Description e.g. after a bulk change made directly in the database. Books that no longer exist are evicted instead.
+// @Description Entries under a slug or ISBN the book no longer has are dropped too. Requires the admin token.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body CacheRefreshRequest true "Book ids to refresh"
+// @Success 200 {object} map[string]interface{} "The outcome per id, refreshed or evicted"
+// @Failure 400 {object} map[string]string "Invalid JSON or invalid id"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 413 {object} map[string]string "Too many ids"
+// @Failure 500 {object} map[string]string "Failed to load the books"
+// @Router /admin/cache/refresh [post]
+func RefreshCache(ctx *gin.Context) {
+	var req CacheRefreshRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if len(req.IDs) == 0 {
+		render(ctx, http.StatusBadRequest, gin.H{"error": "ids must not be empty"})
+		return
+	}
+	if maxSize := maxBatchSize(); len(req.IDs) > maxSize {
+		render(ctx, http.StatusRequestEntityTooLarge, gin.H{"error": batchTooLargeError{count: int64(len(req.IDs)), max: maxSize}.Error()})
+		return
+	}
+	ids := make([]uint, 0, len(req.IDs))
+	seen := make(map[uint]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if id == 0 {
+			render(ctx, http.StatusBadRequest, gin.H{"error": "ids must be positive numbers"})
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	// The primary, since the change being picked up may not have reached
+	// the replicas yet
+	var books []models.Book
+	if err := database.DB.Clauses(dbresolver.Write).Where("id IN ?", ids).Find(&books).Error; err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to load the books"})
+		return
+	}
+	byID := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+
+	results := make([]cacheRefreshResult, len(ids))
+	refreshed := 0
+	for i, id := range ids {
+		// The cached copy names the slug and ISBN keys it is also stored
+		// under, which may no longer match the book
+		key := "book:" + strconv.FormatUint(uint64(id), 10)
+		var cached models.Book
+		if readCachedBook(ctx, key, &cached) {
+			invalidateBookCache(&cached)
+		} else {
+			invalidateBookCache(&models.Book{ID: id})
+		}
+
+		book, ok := byID[id]
+		if !ok {
+			results[i] = cacheRefreshResult{ID: id, Status: "evicted"}
+			continue
+		}
+		refreshBookCache(book)
+		results[i] = cacheRefreshResult{ID: id, Status: "refreshed"}
+		refreshed++
+	}
+	respond(ctx, http.StatusOK, gin.H{"refreshed": refreshed, "evicted": len(ids) - refreshed, "results": results})
+}
+
+// ReplayDeadLetter godoc
+// @Summary Replay a dead-lettered event
+// @Description Queue a dead-lettered event again so the outbox relay publishes it to its original topic. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Event ID"
+// @Success 202 {object} map[string]string "Event queued for publishing"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 404 {object} map[string]string "No dead-lettered event with this id"
+// @Failure 500 {object} map[string]string "Failed to replay the event"
+// @Router /admin/dead-letters/{id}/replay [post]
+func ReplayDeadLetter(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		render(ctx, http.StatusNotFound, gin.H{"error": "Dead-lettered event not found"})
+		return
+	}
+	err = outbox.Replay(database.DB, uint(id))
+	if errors.Is(err, outbox.ErrNotDeadLettered) {
+		render(ctx, http.StatusNotFound, gin.H{"error": "Dead-lettered event not found"})
+		return
+	}
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to replay the event"})
+		return
+	}
+	respond(ctx, http.StatusAccepted, gin.H{"message": "Event queued for publishing"})
+}
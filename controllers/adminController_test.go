@@ -0,0 +1,215 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+const adminToken = "test-admin-token"
+
+func TestWarmCacheCachesEachPage(t *testing.T) {
+	router, db := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	books := seedNumbered(t, db, 5)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/admin/cache/warm",
+		`{"queries": [{"limit": 2, "offset": 2}, {"limit": 2}]}`, "Authorization", "Bearer "+adminToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var warmed struct {
+		Warmed int      `json:"warmed"`
+		Keys   []string `json:"keys"`
+	}
+	decode(t, w, &warmed)
+	if warmed.Warmed != 2 || len(warmed.Keys) != 2 || warmed.Keys[0] == warmed.Keys[1] {
+		t.Fatalf("warmed = %+v, want two pages under their own keys", warmed)
+	}
+
+	// Served from the cache from now on
+	if err := db.Exec("DELETE FROM books").Error; err != nil {
+		t.Fatal(err)
+	}
+	for offset, want := range map[int][]models.Book{0: books[:2], 2: books[2:4]} {
+		w := testutil.Request(router, http.MethodGet, fmt.Sprintf("/v1/books?limit=2&offset=%d", offset), "")
+		var page []models.Book
+		decode(t, w, &page)
+		if fmt.Sprint(ids(page)) != fmt.Sprint(ids(want)) {
+			t.Errorf("offset %d: books = %v, want %v", offset, ids(page), ids(want))
+		}
+	}
+}
+
+func TestWarmCacheRequiresAdminToken(t *testing.T) {
+	router, _ := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/admin/cache/warm", `{"queries": [{"limit": 2}]}`, "Authorization", "Bearer wrong")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", w.Code)
+	}
+}
+
+func TestReadOnlyToggle(t *testing.T) {
+	router, _ := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+	create := `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`
+
+	w := testutil.Request(router, http.MethodPut, "/v1/admin/read-only", `{"enabled": true}`, auth...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("enable: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := testutil.Request(router, http.MethodPost, "/v1/books", create); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("create while read-only: status = %d, want 503", w.Code)
+	}
+	if w := testutil.Request(router, http.MethodGet, "/v1/books", ""); w.Code != http.StatusOK {
+		t.Errorf("list while read-only: status = %d, want 200", w.Code)
+	}
+
+	testutil.Request(router, http.MethodPut, "/v1/admin/read-only", `{"enabled": false}`, auth...)
+	if w := testutil.Request(router, http.MethodPost, "/v1/books", create); w.Code != http.StatusCreated {
+		t.Errorf("create after disabling: status = %d, want 201: %s", w.Code, w.Body)
+	}
+
+	t.Setenv("READ_ONLY", "true")
+	if w := testutil.Request(router, http.MethodPut, "/v1/admin/read-only", `{"enabled": false}`, auth...); w.Code != http.StatusConflict {
+		t.Errorf("disable while READ_ONLY=true: status = %d, want 409", w.Code)
+	}
+}
+
+func TestFeatureToggle(t *testing.T) {
+	router, _ := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+
+	if w := testutil.Request(router, http.MethodGet, "/v1/books/stats", ""); w.Code != http.StatusOK {
+		t.Fatalf("enabled: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	w := testutil.Request(router, http.MethodPut, "/v1/admin/features/stats", `{"enabled": false}`, auth...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("disable: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	// A disabled endpoint looks like one that doesn't exist, on every path
+	for _, path := range []string{"/v1/books/stats", "/books/stats"} {
+		if w := testutil.Request(router, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+			t.Errorf("disabled %s: status = %d, want 404", path, w.Code)
+		}
+	}
+
+	t.Setenv("FEATURE_STATS", "false")
+	testutil.Request(router, http.MethodDelete, "/v1/admin/features/stats", "", auth...)
+	if w := testutil.Request(router, http.MethodGet, "/v1/books/stats", ""); w.Code != http.StatusNotFound {
+		t.Errorf("reset to FEATURE_STATS=false: status = %d, want 404", w.Code)
+	}
+
+	if w := testutil.Request(router, http.MethodPut, "/v1/admin/features/nope", `{"enabled": true}`, auth...); w.Code != http.StatusNotFound {
+		t.Errorf("unknown feature: status = %d, want 404", w.Code)
+	}
+}
+
+func TestFlushCache(t *testing.T) {
+	router, _ := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	shared := redis.NewMemoryCache()
+	testutil.UseCache(t, redis.WithPrefix(shared, "staging"))
+	ctx := context.Background()
+
+	redis.BookCache.Set(ctx, "book:1", "{}", time.Minute)
+	redis.BookCache.Set(ctx, "books", "[]", 0)
+	redis.BookCache.Set(ctx, "session:1", "other data", 0)
+	shared.Set(ctx, "prod:book:1", "{}", 0)
+
+	w := testutil.Request(router, http.MethodGet, "/v1/admin/cache/keys", "", "Authorization", "Bearer "+adminToken)
+	var listed struct {
+		Keys []struct {
+			Key        string `json:"key"`
+			TTLSeconds int64  `json:"ttl_seconds"`
+		} `json:"keys"`
+	}
+	decode(t, w, &listed)
+	if len(listed.Keys) != 2 || listed.Keys[0].Key != "book:1" || listed.Keys[0].TTLSeconds <= 0 || listed.Keys[1].TTLSeconds != -1 {
+		t.Errorf("keys = %+v, want book:1 with its TTL and books without one", listed.Keys)
+	}
+
+	w = testutil.Request(router, http.MethodDelete, "/v1/admin/cache", "", "Authorization", "Bearer "+adminToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	for _, key := range []string{"book:1", "books"} {
+		if _, err := redis.BookCache.Get(ctx, key); err == nil {
+			t.Errorf("%s survived the flush", key)
+		}
+	}
+	if _, err := redis.BookCache.Get(ctx, "session:1"); err != nil {
+		t.Error("flush deleted a key that isn't the book cache's")
+	}
+	if _, err := shared.Get(ctx, "prod:book:1"); err != nil {
+		t.Error("flush deleted a key of another namespace")
+	}
+
+	if w := testutil.Request(router, http.MethodDelete, "/v1/admin/cache", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without the token: status = %d, want 401", w.Code)
+	}
+}
+
+func TestRefreshCache(t *testing.T) {
+	router, db := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	books := seedNumbered(t, db, 2)
+	for _, book := range books {
+		testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(book.ID), "")
+	}
+	// Out-of-band changes the cached copies don't know about
+	db.Model(&models.Book{}).Where("id = ?", books[0].ID).Update("title", "Changed in the database")
+	db.Delete(&models.Book{}, books[1].ID)
+
+	body := fmt.Sprintf(`{"ids": [%d, %d, 999]}`, books[0].ID, books[1].ID)
+	w := testutil.Request(router, http.MethodPost, "/v1/admin/cache/refresh", body, "Authorization", "Bearer "+adminToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got struct {
+		Refreshed int `json:"refreshed"`
+		Evicted   int `json:"evicted"`
+		Results   []struct {
+			ID     uint   `json:"id"`
+			Status string `json:"status"`
+		} `json:"results"`
+	}
+	decode(t, w, &got)
+	if got.Refreshed != 1 || got.Evicted != 2 || len(got.Results) != 3 ||
+		got.Results[0].Status != "refreshed" || got.Results[1].Status != "evicted" || got.Results[2].Status != "evicted" {
+		t.Fatalf("result = %+v, want the first book refreshed, the others evicted", got)
+	}
+
+	ctx := context.Background()
+	cached, err := redis.BookCache.Get(ctx, "book:"+itoa(books[0].ID))
+	if err != nil || !strings.Contains(cached, "Changed in the database") {
+		t.Errorf("book:%d = %q, %v, want the reloaded book", books[0].ID, cached, err)
+	}
+	if _, err := redis.BookCache.Get(ctx, "book:"+itoa(books[1].ID)); err == nil {
+		t.Errorf("book:%d is still cached after the book was deleted", books[1].ID)
+	}
+	if w := testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(books[1].ID), ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET deleted book: status = %d, want 404", w.Code)
+	}
+
+	for name, body := range map[string]string{"empty": `{"ids": []}`, "zero id": `{"ids": [0]}`} {
+		w := testutil.Request(router, http.MethodPost, "/v1/admin/cache/refresh", body, "Authorization", "Bearer "+adminToken)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, w.Code)
+		}
+	}
+	if w := testutil.Request(router, http.MethodPost, "/v1/admin/cache/refresh", body); w.Code != http.StatusUnauthorized {
+		t.Errorf("without the token: status = %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/middleware"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+)
+
+// apiKeyPrefix starts every generated key, so a leaked one is easy to
+// recognize, e.g. by secret scanners
+const apiKeyPrefix = "bk_"
+
+// APIKeyRequest creates an API key
+type APIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	// RateLimit caps the key's requests per minute; 0, the default, means no
+	// limit
+	RateLimit int `json:"rate_limit"`
+}
+
+// CreatedAPIKey is a new API key along with the key itself, which is
+// returned this once
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyWithUsage is an API key with the number of requests made with it
+type APIKeyWithUsage struct {
+	models.APIKey
+	Requests      int64 `json:"requests"`
+	RequestsToday int64 `json:"requests_today"`
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List every API key, revoked ones included, with the number of requests made with it in total and today (UTC).
+// @Description Keys themselves are never listed. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {array} APIKeyWithUsage
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to fetch API keys"
+// @Router /admin/api-keys [get]
+func ListAPIKeys(ctx *gin.Context) {
+	var keys []models.APIKey
+	if err := database.DB.WithContext(ctx.Request.Context()).Order("id").Find(&keys).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+
+	counters := make([]string, 0, 2*len(keys))
+	today := time.Now()
+	for _, key := range keys {
+		counters = append(counters, middleware.APIKeyUsageKey(key.ID), middleware.APIKeyDailyUsageKey(key.ID, today))
+	}
+	counts, _ := redis.BookCache.MGet(ctx.Request.Context(), counters...)
+
+	usage := make([]APIKeyWithUsage, len(keys))
+	for i, key := range keys {
+		usage[i] = APIKeyWithUsage{APIKey: key}
+		if len(counts) == len(counters) {
+			usage[i].Requests, _ = strconv.ParseInt(counts[2*i], 10, 64)
+			usage[i].RequestsToday, _ = strconv.ParseInt(counts[2*i+1], 10, 64)
+		}
+	}
+	respond(ctx, http.StatusOK, usage)
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a key for a machine client, which sends it in the X-API-Key header. The key is only returned here;
+// @Description only its hash is stored. A rate_limit above 0 caps the key's requests per minute. Requires the admin token.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body APIKeyRequest true "Name and optional rate limit"
+// @Success 201 {object} CreatedAPIKey
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to create the API key"
+// @Router /admin/api-keys [post]
+func CreateAPIKey(ctx *gin.Context) {
+	var req APIKeyRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+	if req.RateLimit < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "rate_limit cannot be negative"})
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the API key"})
+		return
+	}
+	raw := apiKeyPrefix + hex.EncodeToString(secret)
+	key := models.APIKey{
+		Name:      req.Name,
+		Prefix:    raw[:len(apiKeyPrefix)+8],
+		KeyHash:   models.HashAPIKey(raw),
+		RateLimit: req.RateLimit,
+	}
+	if err := database.DB.WithContext(ctx.Request.Context()).Create(&key).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the API key"})
+		return
+	}
+	respond(ctx, http.StatusCreated, CreatedAPIKey{APIKey: key, Key: raw})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Reject every further request made with the key. The key stays listed with its usage. Revoking a revoked
+// @Description key does nothing. Requires the admin token.
+// @Tags admin
+// @Security AdminToken
+// @Param id path int true "API key ID"
+// @Success 204 "API key revoked"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 404 {object} map[string]string "API key not found"
+// @Failure 500 {object} map[string]string "Failed to revoke the API key"
+// @Router /admin/api-keys/{id} [delete]
+func RevokeAPIKey(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	db := database.DB.WithContext(ctx.Request.Context())
+	result := db.Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke the API key"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := db.Model(&models.APIKey{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke the API key"})
+			return
+		}
+		if count == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+	}
+	ctx.Status(http.StatusNoContent)
+}
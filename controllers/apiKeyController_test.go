@@ -0,0 +1,109 @@
+package controllers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rohans540/books-backend/controllers"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+// createAPIKey creates an API key through the admin endpoint
+func createAPIKey(t *testing.T, router http.Handler, body string) controllers.CreatedAPIKey {
+	t.Helper()
+	w := testutil.Request(router, http.MethodPost, "/v1/admin/api-keys", body, "Authorization", "Bearer "+adminToken)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create API key: status = %d: %s", w.Code, w.Body)
+	}
+	var created controllers.CreatedAPIKey
+	decode(t, w, &created)
+	return created
+}
+
+func TestAPIKeys(t *testing.T) {
+	router, _ := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+
+	created := createAPIKey(t, router, `{"name": "importer"}`)
+	if !strings.HasPrefix(created.Key, "bk_") || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Fatalf("created key = %+v, want a bk_ key starting with its prefix", created)
+	}
+
+	tests := []struct {
+		name, key string
+		want      int
+	}{
+		{"valid key", created.Key, http.StatusOK},
+		{"unknown key", "bk_not-a-key", http.StatusUnauthorized},
+		{"no key", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := testutil.Request(router, http.MethodGet, "/v1/books", "", "X-API-Key", tt.key); w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+	t.Setenv("REQUIRE_API_KEY", "true")
+	if w := testutil.Request(router, http.MethodGet, "/v1/books", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("no key with REQUIRE_API_KEY=true: status = %d, want 401", w.Code)
+	}
+	if w := testutil.Request(router, http.MethodGet, "/v1/books", "", "X-API-Key", created.Key); w.Code != http.StatusOK {
+		t.Errorf("valid key with REQUIRE_API_KEY=true: status = %d, want 200", w.Code)
+	}
+
+	// Each accepted request is counted, in total and for today
+	var keys []controllers.APIKeyWithUsage
+	w := testutil.Request(router, http.MethodGet, "/v1/admin/api-keys", "", auth...)
+	decode(t, w, &keys)
+	if len(keys) != 1 || keys[0].Requests != 2 || keys[0].RequestsToday != 2 {
+		t.Errorf("keys = %+v, want one key with 2 requests in total and today", keys)
+	}
+	if strings.Contains(w.Body.String(), created.Key) {
+		t.Error("the key itself was listed")
+	}
+
+	if w := testutil.Request(router, http.MethodDelete, "/v1/admin/api-keys/"+itoa(created.ID), "", auth...); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke: status = %d, want 204: %s", w.Code, w.Body)
+	}
+	if w := testutil.Request(router, http.MethodGet, "/v1/books", "", "X-API-Key", created.Key); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: status = %d, want 401", w.Code)
+	}
+	if w := testutil.Request(router, http.MethodDelete, "/v1/admin/api-keys/"+itoa(created.ID), "", auth...); w.Code != http.StatusNoContent {
+		t.Errorf("revoking again: status = %d, want 204", w.Code)
+	}
+	if w := testutil.Request(router, http.MethodDelete, "/v1/admin/api-keys/999", "", auth...); w.Code != http.StatusNotFound {
+		t.Errorf("revoking an unknown key: status = %d, want 404", w.Code)
+	}
+
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/admin/api-keys", "", auth...), &keys)
+	if len(keys) != 1 || keys[0].RevokedAt == nil || keys[0].Requests != 2 {
+		t.Errorf("keys after revoking = %+v, want the revoked key listed with its usage", keys)
+	}
+}
+
+func TestAPIKeyRateLimit(t *testing.T) {
+	router, _ := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	created := createAPIKey(t, router, `{"name": "crawler", "rate_limit": 2}`)
+
+	for i := 1; i <= 3; i++ {
+		w := testutil.Request(router, http.MethodGet, "/v1/books", "", "X-API-Key", created.Key)
+		if i <= 2 && w.Code != http.StatusOK {
+			t.Errorf("request %d: status = %d, want 200", i, w.Code)
+		}
+		if i == 3 && (w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "") {
+			t.Errorf("request %d: status = %d, Retry-After %q, want 429 with Retry-After", i, w.Code, w.Header().Get("Retry-After"))
+		}
+	}
+
+	for _, body := range []string{`{"name": " "}`, `{"name": "x", "rate_limit": -1}`} {
+		w := testutil.Request(router, http.MethodPost, "/v1/admin/api-keys", body, "Authorization", "Bearer "+adminToken)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+	if w := testutil.Request(router, http.MethodPost, "/v1/admin/api-keys", `{"name": "x"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("without the admin token: status = %d, want 401", w.Code)
+	}
+}
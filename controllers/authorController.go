@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"gorm.io/gorm"
+)
+
+// AuthorCount is one entry of GET /books/authors?counts=true
+type AuthorCount struct {
+	Author string `json:"author" xml:"author"`
+	Count  int64  `json:"count" xml:"count"`
+}
+
+// authorsPage is a cached page of GET /books/authors with the number of
+// distinct authors matching the prefix
+type authorsPage struct {
+	Authors []AuthorCount `json:"authors"`
+	Total   int64         `json:"total"`
+}
+
+// GetAuthors godoc
+// @Summary Get distinct authors
+// @Description Retrieve the distinct author names (co-authors included), sorted alphabetically, optionally with the number of books per author.
+// @Description The list is paginated like GET /books; in envelope format meta carries limit, offset and the number of matching authors.
+// @Tags books
+// @Produce json
+// @Param q query string false "Only return authors whose name starts with this prefix (case-insensitive)"
+// @Param counts query bool false "Return {author, count} objects instead of plain names"
+// @Param limit query int false "Number of authors per page (default: DEFAULT_PAGE_SIZE, 10; lowered to MAX_PAGE_SIZE, 100, when larger)"
+// @Param offset query int false "Offset for pagination (default: 0)"
+// @Success 200 {array} AuthorCount
+// @Header 200 {integer} X-Total-Count "Number of distinct authors matching q"
+// @Header 200 {string} Link "URLs of the first, prev, next and last pages"
+// @Router /books/authors [get]
+func GetAuthors(ctx *gin.Context) {
+	prefix := strings.TrimSpace(ctx.Query("q"))
+	withCounts, _ := strconv.ParseBool(ctx.Query("counts"))
+	limit := pageLimit(ctx)
+	offset, _ := strconv.Atoi(ctx.Query("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	params := url.Values{}
+	params.Set("q", prefix)
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("offset", strconv.Itoa(offset))
+	cacheKey := "books:authors:" + params.Encode()
+
+	var page authorsPage
+	cachedAuthors, err := readCache(ctx, cacheKey)
+	if err != nil || json.Unmarshal([]byte(cachedAuthors), &page) != nil {
+		page, err = loadAuthorsPage(prefix, limit, offset)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching authors"})
+			return
+		}
+		data, _ := json.Marshal(page)
+		cacheListResult(cacheKey, data, cacheTTL("authors"))
+	}
+
+	meta := pageMeta(ctx, page.Total, limit, offset)
+	if withCounts {
+		respondWithMeta(ctx, http.StatusOK, page.Authors, meta)
+		return
+	}
+	names := make([]string, len(page.Authors))
+	for i, author := range page.Authors {
+		names[i] = author.Author
+	}
+	respondWithMeta(ctx, http.StatusOK, names, meta)
+}
+
+func loadAuthorsPage(prefix string, limit, offset int) (authorsPage, error) {
+	page := authorsPage{Authors: []AuthorCount{}}
+	// Every author of a book counts, not only the primary one. Other dialects
+	// (SQLite in tests) only list the primary authors, like whereAuthor.
+	source := "books, jsonb_array_elements_text(books.authors) AS a(name)"
+	if !usesFullTextSearch() {
+		source = "(SELECT author AS name FROM books) AS a"
+	}
+	authors := func() *gorm.DB {
+		query := database.DB.Table(source)
+		if prefix != "" {
+			query = query.Where("LOWER(a.name) LIKE ? ESCAPE '\\'", escapeLike(strings.ToLower(prefix))+"%")
+		}
+		return query
+	}
+
+	if err := authors().Select("COUNT(DISTINCT a.name)").Scan(&page.Total).Error; err != nil {
+		return page, err
+	}
+	err := authors().
+		Select("a.name AS author, COUNT(*) AS count").
+		Group("a.name").
+		Order("a.name").
+		Limit(limit).
+		Offset(offset).
+		Scan(&page.Authors).Error
+	if page.Authors == nil {
+		page.Authors = []AuthorCount{}
+	}
+	return page, err
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
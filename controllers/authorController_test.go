@@ -0,0 +1,93 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestGetAuthors(t *testing.T) {
+	router, db := setup(t)
+	testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965},
+		models.Book{Title: "Dune Messiah", Author: "Frank Herbert", Year: 1969},
+		models.Book{Title: "Frankenstein", Author: "Mary Shelley", Year: 1818},
+		models.Book{Title: "Emma", Author: "Jane Austen", Year: 1815},
+		models.Book{Title: "Persuasion", Author: "Jane Austen", Year: 1817},
+		models.Book{Title: "Jane Eyre", Author: "Charlotte Brontë", Year: 1847},
+		models.Book{Title: "Percent", Author: "100% Author", Year: 2000},
+	)
+
+	tests := []struct {
+		name, query string
+		want        []string
+		total       string
+	}{
+		{"all, sorted", "", []string{"100% Author", "Charlotte Brontë", "Frank Herbert", "Jane Austen", "Mary Shelley"}, "5"},
+		{"prefix, case-insensitive", "q=ja", []string{"Jane Austen"}, "1"},
+		{"prefix with a LIKE wildcard", "q=1%25", nil, "0"},
+		{"wildcard matched literally", "q=100%25", []string{"100% Author"}, "1"},
+		{"first page", "limit=2", []string{"100% Author", "Charlotte Brontë"}, "5"},
+		{"second page", "limit=2&offset=2", []string{"Frank Herbert", "Jane Austen"}, "5"},
+		{"past the end", "limit=2&offset=10", nil, "5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Request(router, http.MethodGet, "/v1/books/authors?"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			var names []string
+			decode(t, w, &names)
+			if fmt.Sprint(names) != fmt.Sprint(tt.want) {
+				t.Errorf("authors = %q, want %q", names, tt.want)
+			}
+			if got := w.Header().Get("X-Total-Count"); got != tt.total {
+				t.Errorf("X-Total-Count = %q, want %s", got, tt.total)
+			}
+		})
+	}
+}
+
+func TestGetAuthorsCounts(t *testing.T) {
+	router, db := setup(t)
+	testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965},
+		models.Book{Title: "Dune Messiah", Author: "Frank Herbert", Year: 1969},
+		models.Book{Title: "Emma", Author: "Jane Austen", Year: 1815},
+	)
+
+	var got []struct {
+		Author string `json:"author"`
+		Count  int64  `json:"count"`
+	}
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books/authors?counts=true", ""), &got)
+	if fmt.Sprint(got) != "[{Frank Herbert 2} {Jane Austen 1}]" {
+		t.Errorf("authors = %v, want Frank Herbert with 2 books and Jane Austen with 1", got)
+	}
+}
+
+func TestGetAuthorsCachesPerPrefixAndPage(t *testing.T) {
+	router, db := setup(t)
+	testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965},
+		models.Book{Title: "Emma", Author: "Jane Austen", Year: 1815},
+	)
+	get := func(query string) string {
+		var names []string
+		decode(t, testutil.Request(router, http.MethodGet, "/v1/books/authors?"+query, ""), &names)
+		return fmt.Sprint(names)
+	}
+
+	// Each (q, page) is cached on its own, so the answers don't mix
+	first, prefixed, paged := get("limit=1"), get("q=j&limit=1"), get("limit=1&offset=1")
+	if first != "[Frank Herbert]" || prefixed != "[Jane Austen]" || paged != "[Jane Austen]" {
+		t.Fatalf("pages = %s, %s, %s", first, prefixed, paged)
+	}
+	if again := get("limit=1"); again != first {
+		t.Errorf("cached first page = %s, want %s", again, first)
+	}
+}
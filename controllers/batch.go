@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+)
+
+// maxIDsPerRequest caps how many ids a single ?ids= lookup may ask for
+const maxIDsPerRequest = 100
+
+// parseIDs parses a comma-separated id list, dropping duplicates but keeping
+// the order in which ids were first requested
+func parseIDs(raw string) ([]uint, error) {
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil || id == 0 {
+			return nil, newMessageError(msgIDInvalid, part)
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	if len(ids) > maxIDsPerRequest {
+		return nil, newMessageError(msgTooManyIDs, maxIDsPerRequest)
+	}
+	return ids, nil
+}
+
+// getBooksByIDs serves ?ids=1,2,3. Cached books are fetched with a single
+// MGET, only the misses are loaded from the database in one query and
+// written back to the cache. Books are returned in request order; unknown
+// ids are left out.
+func getBooksByIDs(ctx *gin.Context, ids []uint, fields []string) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = fieldsCacheKey("book:"+strconv.FormatUint(uint64(id), 10), fields)
+	}
+
+	found := make(map[uint]models.Book, len(ids))
+	var cached []string
+	if !bypassCache(ctx) {
+		cached, _ = redis.BookCache.MGet(context.Background(), keys...)
+	}
+	var missing []uint
+	for i, id := range ids {
+		var book models.Book
+		if i < len(cached) && cached[i] != "" && json.Unmarshal([]byte(cached[i]), &book) == nil {
+			found[id] = book
+			continue
+		}
+		missing = append(missing, id)
+	}
+
+	if cached != nil {
+		recordCacheReads(len(keys)-len(missing), len(missing))
+	}
+	if len(missing) > 0 {
+		var books []models.Book
+		query := database.DB.Where("id IN ?", missing)
+		if fields != nil {
+			// The id column is needed to place each book in request order, and
+			// updated_at for the Last-Modified of GetBookByID, which shares
+			// the cached entries
+			query = query.Select(append(selectColumns(fields), bookColumns["id"], bookColumns["updated_at"]))
+		}
+		if err := query.Find(&books).Error; err != nil {
+			render(ctx, http.StatusInternalServerError, gin.H{"error": "Error fetching books"})
+			return
+		}
+		for _, book := range books {
+			found[book.ID] = book
+			data, _ := json.Marshal(book)
+			id := strconv.FormatUint(uint64(book.ID), 10)
+			cacheBook(id, fieldsCacheKey("book:"+id, fields), data)
+		}
+	}
+
+	books := make([]models.Book, 0, len(ids))
+	for _, id := range ids {
+		if book, ok := found[id]; ok {
+			books = append(books, book)
+		}
+	}
+	respondBooks(ctx, books, fields, nil)
+}
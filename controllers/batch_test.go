@@ -0,0 +1,92 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestGetBooksByIDsPartialCacheHit(t *testing.T) {
+	router, db := setup(t)
+	books := seedNumbered(t, db, 3)
+
+	// Only the second book is cached, under a title the database doesn't have
+	cached := books[1]
+	cached.Title = "Cached"
+	data, _ := json.Marshal(cached)
+	redis.BookCache.Set(context.Background(), "book:"+itoa(cached.ID), data, 0)
+
+	path := fmt.Sprintf("/v1/books?ids=%d,%d,%d,999", books[2].ID, books[1].ID, books[0].ID)
+	w := testutil.Request(router, http.MethodGet, path, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got []models.Book
+	decode(t, w, &got)
+	want := []uint{books[2].ID, books[1].ID, books[0].ID}
+	if fmt.Sprint(ids(got)) != fmt.Sprint(want) {
+		t.Fatalf("books = %v, want %v in request order without the unknown id", ids(got), want)
+	}
+	if got[1].Title != "Cached" || got[0].Title != books[2].Title {
+		t.Errorf("titles = %q, %q, want the cached book and the stored one", got[1].Title, got[0].Title)
+	}
+
+	// The misses were backfilled
+	for _, book := range []models.Book{books[0], books[2]} {
+		if _, err := redis.BookCache.Get(context.Background(), "book:"+itoa(book.ID)); err != nil {
+			t.Errorf("book %d not cached after the lookup: %v", book.ID, err)
+		}
+	}
+}
+
+func TestGetBooksByIDsInvalid(t *testing.T) {
+	router, _ := setup(t)
+
+	if w := testutil.Request(router, http.MethodGet, "/v1/books?ids=1,abc", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+}
+
+func TestGetBooksByIDsWithFields(t *testing.T) {
+	router, db := setup(t)
+	books := seedNumbered(t, db, 3)
+
+	path := fmt.Sprintf("/v1/books?ids=%d,404,%d&fields=title", books[2].ID, books[0].ID)
+	w := testutil.Request(router, http.MethodGet, path, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got []map[string]interface{}
+	decode(t, w, &got)
+	if len(got) != 2 || got[0]["title"] != books[2].Title || got[1]["title"] != books[0].Title {
+		t.Fatalf("books = %v, want the titles of books %d and %d in request order", got, books[2].ID, books[0].ID)
+	}
+	for _, book := range got {
+		if len(book) != 1 {
+			t.Errorf("book = %v, want only the title", book)
+		}
+	}
+}
+
+func TestGetBooksByIDsCachesLastModified(t *testing.T) {
+	router, db := setup(t)
+	book := seedNumbered(t, db, 1)[0]
+
+	// The ?ids= lookup fills the cache entry GetBookByID reads for the same fields
+	w := testutil.Request(router, http.MethodGet, fmt.Sprintf("/v1/books?ids=%d&fields=title", book.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("ids: status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	w = testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(book.ID)+"?fields=title", "")
+	want := book.UpdatedAt.UTC().Format(http.TimeFormat)
+	if w.Code != http.StatusOK || w.Header().Get("Last-Modified") != want {
+		t.Errorf("status = %d, Last-Modified = %q, want 200 and %q", w.Code, w.Header().Get("Last-Modified"), want)
+	}
+}
@@ -3,187 +3,526 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"math/rand/v2"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rohans540/books-backend/database"
 	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/middleware"
 	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/outbox"
 	"github.com/rohans540/books-backend/redis"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
 )
 
+const maxRecentBooks = 50
+
+// noOpHeader is set on updates that didn't change anything
+const noOpHeader = "X-No-Op"
+
 // GetBooks godoc
 // @Summary Get all books with pagination
-// @Description Retrieve paginated details of all books
+// @Description Retrieve paginated details of all books.
+// @Description Offset pagination (limit/offset) is the default and returns a plain array,
+// @Description ordered by DEFAULT_SORT (id unless configured) with id as a tie-breaker.
+// @Description Passing after_id switches to keyset pagination ordered by id, which takes
+// @Description precedence over offset and returns {"books": [...], "next_cursor": id}.
+// @Description In envelope format, offset pages carry limit, offset and the total match count in meta.
+// @Description The first offset page also returns a snapshot token (meta and X-Snapshot); passing it as snapshot on the
+// @Description following pages leaves out books added since, so inserts don't shift the pages.
 // @Tags books
 // @Produce json
-// @Param limit query int false "Limit the number of books per page (default: 10)"
-// @Param offset query int false "Offset for pagination (default: 0)"
+// @Param limit query int false "Limit the number of books per page (default: DEFAULT_PAGE_SIZE, 10; lowered to MAX_PAGE_SIZE, 100, when larger)"
+// @Param offset query int false "Offset for pagination (default: 0; at most MAX_OFFSET, 10000, use after_id beyond)"
+// @Param ids query string false "Comma-separated list of book ids to fetch, returned in the same order (other pagination and filters are ignored)"
+// @Param after_id query int false "Keyset cursor: return books with an id greater than this (use next_cursor from the previous page)"
+// @Param snapshot query int false "Snapshot token from the first page: leave out books added after it"
+// @Param fields query string false "Comma-separated list of fields to return (e.g. id,title)"
+// @Param q query string false "Full-text search over title and author"
+// @Param sort query string false "Sort order: relevance (only valid together with q), recent (newest first), or id, title, author, year or created_at, prefixed with - for descending (default: SEARCH_DEFAULT_SORT with q, DEFAULT_SORT otherwise)"
+// @Param author query string false "Only return books by this author"
+// @Param publisher query string false "Only return books from this publisher"
+// @Param year query int false "Only return books published in this year"
+// @Param language query string false "Only return books in this ISO 639-1 language (e.g. en)"
+// @Param available query bool false "Only return books that are (true) or aren't (false) available to check out"
+// @Param If-None-Match header string false "Collection ETag from an earlier response: answer 304 if no book was written since"
 // @Success 200 {array} models.Book
+// @Header 200 {string} ETag "Version of the whole collection, bumped by every write, for If-None-Match"
+// @Header 200 {string} X-Limit-Clamped "Maximum page size, set when the requested limit was lowered to it"
+// @Header 200 {string} Link "URLs of the first, prev, next and last pages (only next for after_id; none past MAX_OFFSET)"
+// @Header 200 {integer} X-Total-Count "Number of books matching the filters (offset pagination only)"
+// @Header 200 {integer} X-Page-Limit "Page size used (offset pagination only)"
+// @Header 200 {integer} X-Page-Offset "Offset used (offset pagination only)"
+// @Header 200 {integer} X-Snapshot "Snapshot token to pass on the following pages (offset pagination only)"
+// @Success 304 "No book was written since the ETag in If-None-Match was handed out"
+// @Failure 400 {object} map[string]string "Unknown field, invalid filter, invalid cursor, invalid snapshot or offset past MAX_OFFSET"
+// @Failure 413 {object} map[string]string "Page larger than MAX_RESPONSE_BYTES; request a smaller limit"
 // @Router /books [get]
 func GetBooks(ctx *gin.Context) {
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))  // Default limit: 10
-	offset, _ := strconv.Atoi(ctx.DefaultQuery("offset", "0")) // Default offset: 0
-
-	cachedBooks, err := redis.RedisClient.Get(context.Background(), "books").Result()
-	if err == nil && cachedBooks != "" {
-		var books []models.Book
-		if json.Unmarshal([]byte(cachedBooks), &books) == nil {
-			// Apply pagination on cached books
-			if offset >= len(books) {
-				ctx.JSON(http.StatusOK, []models.Book{})
-				return
-			}
-			end := offset + limit
-			if end > len(books) {
-				end = len(books)
-			}
-			ctx.JSON(http.StatusOK, books[offset:end])
+	query, err := bookQueryFromURL(ctx)
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	filters, err := query.validate()
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if collectionNotModified(ctx) {
+		return
+	}
+
+	if rawIDs := ctx.Query("ids"); rawIDs != "" {
+		ids, err := parseIDs(rawIDs)
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, err)
 			return
 		}
+		getBooksByIDs(ctx, ids, query.Fields)
+		return
+	}
+	listBooks(ctx, query, filters)
+}
+
+// respondBooks writes a page of books; meta is only shown in envelope format.
+// A page larger than MAX_RESPONSE_BYTES is refused with 413.
+func respondBooks(ctx *gin.Context, books []models.Book, fields []string, meta gin.H) {
+	if books == nil {
+		books = []models.Book{}
+	}
+	var data interface{} = books
+	if fields != nil {
+		data = filterBooks(books, fields)
 	}
+	if responseTooLarge(ctx, data) {
+		return
+	}
+	respondWithMeta(ctx, http.StatusOK, data, meta)
+}
+
+func respondBook(ctx *gin.Context, book models.Book, fields []string) {
+	if fields == nil {
+		respond(ctx, http.StatusOK, book)
+		return
+	}
+	respond(ctx, http.StatusOK, filterFields(book, fields))
+}
+
+// CountBooks godoc
+// @Summary Count books
+// @Description Return the number of books, optionally filtered by author and year
+// @Tags books
+// @Produce json
+// @Param author query string false "Only count books by this author"
+// @Param publisher query string false "Only count books from this publisher"
+// @Param year query int false "Only count books published in this year"
+// @Param language query string false "Only count books in this ISO 639-1 language (e.g. en)"
+// @Param q query string false "Only count books matching this full-text search"
+// @Param available query bool false "Only count books that are (true) or aren't (false) available to check out"
+// @Success 200 {object} map[string]int64
+// @Failure 400 {object} map[string]string "Invalid filter"
+// @Router /books/count [get]
+func CountBooks(ctx *gin.Context) {
+	filters, err := parseBookFilters(ctx)
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	count, err := countBooks(ctx, filters)
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Error counting books"})
+		return
+	}
+	respond(ctx, http.StatusOK, gin.H{"count": count})
+}
+
+// GetRecentBooks godoc
+// @Summary Get recently added books
+// @Description Retrieve the most recently created books, newest first
+// @Tags books
+// @Produce json
+// @Param limit query int false "Number of books to return (default: 5, max: 50)"
+// @Success 200 {array} models.Book
+// @Router /books/recent [get]
+func GetRecentBooks(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "5"))
+	if err != nil || limit <= 0 {
+		limit = 5
+	}
+	if limit > maxRecentBooks {
+		limit = maxRecentBooks
+	}
+	cacheKey := "books:recent:limit=" + strconv.Itoa(limit)
 
 	var books []models.Book
-	result := database.DB.Limit(limit).Offset(offset).Find(&books)
+	cachedBooks, err := readCache(ctx, cacheKey)
+	if err == nil && json.Unmarshal([]byte(cachedBooks), &books) == nil {
+		respond(ctx, http.StatusOK, books)
+		return
+	}
+
+	result := database.DB.Order("created_at desc, id desc").Limit(limit).Find(&books)
 	if result.Error != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching books"})
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Error fetching books"})
 		return
 	}
 
 	booksJSON, _ := json.Marshal(books)
-	redis.RedisClient.Set(context.Background(), "books", booksJSON, 0) // Cache books data
-	ctx.JSON(http.StatusOK, books)
+	cacheListResult(cacheKey, booksJSON, cacheTTL("recent"))
+	respond(ctx, http.StatusOK, books)
+}
+
+// GetRandomBook godoc
+// @Summary Get a random book
+// @Description Return one book picked at random, for a "surprise me" feature. A random id between the lowest and
+// @Description highest is drawn and the first book from there on returned, which costs two index lookups however
+// @Description large the table is, unlike ORDER BY RANDOM() which scans every row. Books that follow a gap in the
+// @Description ids (left by deletes) are proportionally more likely to be picked.
+// @Tags books
+// @Produce json
+// @Success 200 {object} models.Book
+// @Failure 404 {object} map[string]string "There are no books"
+// @Failure 500 {object} map[string]string "Error fetching book"
+// @Router /books/random [get]
+func GetRandomBook(ctx *gin.Context) {
+	var bounds struct {
+		Low  uint
+		High uint
+	}
+	db := database.DB.WithContext(ctx.Request.Context())
+	err := db.Model(&models.Book{}).Select("COALESCE(MIN(id), 0) AS low, COALESCE(MAX(id), 0) AS high").Scan(&bounds).Error
+	if err != nil {
+		respondLookupError(ctx, err)
+		return
+	}
+	if bounds.High == 0 {
+		respondMessage(ctx, http.StatusNotFound, msgBookNotFound)
+		return
+	}
+
+	pick := bounds.Low + uint(rand.N(uint64(bounds.High-bounds.Low)+1))
+	var book models.Book
+	if err := db.Where("id >= ?", pick).Order("id").First(&book).Error; err != nil {
+		// A delete since the bounds were read can leave nothing past pick
+		respondLookupError(ctx, err)
+		return
+	}
+	respond(ctx, http.StatusOK, book)
 }
 
 // GetBookByID godoc
 // @Summary Get book by ID
-// @Description Retrieve details of a book by its ID
+// @Description Retrieve details of a book by its ID. Last-Modified is set from updated_at, and a request
+// @Description with an If-None-Match listing its ETag, or else an If-Modified-Since no older than it, gets 304 without a body.
 // @Tags books
 // @Produce json
 // @Param id path int true "Book ID"
+// @Param fields query string false "Comma-separated list of fields to return (e.g. id,title)"
+// @Param If-Modified-Since header string false "Only return the book if it changed after this HTTP date"
+// @Param If-None-Match header string false "Only return the book if its ETag isn't listed; If-Modified-Since is then ignored"
+// @Param missing query string false "Answer a missing book with 200 and null instead of 404 (default: MISSING_BOOK_RESPONSE)" Enums(null, 404)
 // @Success 200 {object} models.Book
-// @Failure 404 {object} map[string]string "Book not found"
+// @Header 200 {string} Last-Modified "When the book was last updated"
+// @Header 200 {string} ETag "Version of the book, for If-Match on updates and deletes (not set with fields)"
+// @Success 304 "Book not modified (ETag listed in If-None-Match, or not changed since If-Modified-Since)"
+// @Failure 400 {object} map[string]string "Unknown field"
+// @Failure 404 {object} map[string]string "Book not found, unless missing=null"
+// @Failure 500 {object} map[string]string "Error fetching book"
 // @Router /books/{id} [get]
 func GetBookByID(ctx *gin.Context) {
-	ctx.Header("Content-Type", "application/json")
-	id := ctx.Param("id")
+	id, ok := parseBookID(ctx)
+	if !ok {
+		respondGetLookupError(ctx, gorm.ErrRecordNotFound)
+		return
+	}
 	var book models.Book
 
-	cachedBook, err := redis.RedisClient.Get(context.Background(), "book:"+id).Result()
-	if err == nil {
-		json.Unmarshal([]byte(cachedBook), &book)
-		ctx.JSON(http.StatusOK, book)
+	fields, err := parseFields(ctx)
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
+	cacheKey := fieldsCacheKey("book:"+id, fields)
 
-	result := database.DB.First(&book, id)
-	if result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	// A book written moments ago is read from the primary and cached again
+	fresh := recentlyWritten(id)
+	loadKey := cacheKey
+	if fresh {
+		loadKey += ":primary"
+	}
+
+	if !fresh && readCachedBook(ctx, cacheKey, &book) {
+		var etag string
+		if fields == nil {
+			etag = setBookETag(ctx, book)
+		}
+		if !notModified(ctx, etag, book.UpdatedAt) {
+			respondBook(ctx, book, fields)
+		}
+		return
+	}
+
+	book, err = loadOnce(loadKey, func() (models.Book, error) {
+		var book models.Book
+		query := database.DB
+		if fresh {
+			query = query.Clauses(dbresolver.Write)
+		}
+		if fields != nil {
+			// updated_at is always loaded for the Last-Modified header
+			query = query.Select(append(selectColumns(fields), bookColumns["updated_at"]))
+		}
+		if err := query.First(&book, id).Error; err != nil {
+			return book, err
+		}
+		data, _ := json.Marshal(book)
+		cacheBook(id, cacheKey, data)
+		return book, nil
+	})
+	if err != nil {
+		respondGetLookupError(ctx, err)
+		return
+	}
+
+	var etag string
+	if fields == nil {
+		etag = setBookETag(ctx, book)
+	}
+	if !notModified(ctx, etag, book.UpdatedAt) {
+		respondBook(ctx, book, fields)
+	}
+}
+
+// GetBookBySlug godoc
+// @Summary Get book by slug
+// @Description Retrieve details of a book by its URL slug. Slugs are case-insensitive.
+// @Tags books
+// @Produce json
+// @Param slug path string true "Book slug"
+// @Param missing query string false "Answer a missing book with 200 and null instead of 404 (default: MISSING_BOOK_RESPONSE)" Enums(null, 404)
+// @Success 200 {object} models.Book
+// @Header 200 {string} ETag "Version of the book, for If-Match on updates and deletes"
+// @Failure 404 {object} map[string]string "Book not found, unless missing=null"
+// @Failure 500 {object} map[string]string "Error fetching book"
+// @Router /books/slug/{slug} [get]
+func GetBookBySlug(ctx *gin.Context) {
+	slug := models.NormalizeSlug(ctx.Param("slug"))
+	cacheKey := slugCacheKey(slug)
+	var book models.Book
+
+	if readCachedBook(ctx, cacheKey, &book) {
+		setBookETag(ctx, book)
+		respond(ctx, http.StatusOK, book)
 		return
 	}
 
-	data, _ := json.Marshal(book)
-	redis.RedisClient.Set(context.Background(), "book:"+id, data, 0)
-	ctx.JSON(http.StatusOK, book)
+	book, err := loadOnce(cacheKey, func() (models.Book, error) {
+		var book models.Book
+		if err := database.DB.Where("slug = ?", slug).First(&book).Error; err != nil {
+			return book, err
+		}
+		data, _ := json.Marshal(book)
+		cacheBook(strconv.FormatUint(uint64(book.ID), 10), cacheKey, data)
+		return book, nil
+	})
+	if err != nil {
+		respondGetLookupError(ctx, err)
+		return
+	}
+
+	setBookETag(ctx, book)
+	respond(ctx, http.StatusOK, book)
 }
 
 // CreateBook godoc
 // @Summary Create a new book
-// @Description Add a new book to the collection
+// @Description Add a new book to the collection. The slug is generated from the title.
 // @Tags books
 // @Accept json
 // @Produce json
 // @Param book body models.Book true "Book object"
-// @Success 201 {object} models.Book
+// @Param strict query bool false "Reject the book when it has validation warnings instead of returning them"
+// @Param dedupe query bool false "Reject the book if one with the same title and author exists (default: DEDUPE_ON_CREATE)"
+// @Param enrich query bool false "Fill in a missing title, author or year by looking up the book's ISBN"
+// @Success 201 {object} bookWithWarnings
+// @Header 201 {string} Location "URL of the created book"
 // @Failure 400 {object} map[string]string "Invalid request body"
+// @Failure 422 {object} map[string]string "Title or author too long, text with control characters, or an ISBN check digit that doesn't match (with ISBN_CHECKSUM=reject or strict)"
+// @Failure 409 {object} map[string]interface{} "Duplicate book (with the existing book's id) or unique field already in use"
+// @Failure 413 {object} map[string]string "Request body too large"
+// @Failure 415 {object} map[string]string "Content-Type is not application/json"
 // @Router /books [post]
 func CreateBook(ctx *gin.Context) {
 	var book models.Book
-	if err := ctx.ShouldBindJSON(&book); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+	if !bindJSON(ctx, &book) {
 		return
 	}
+	book.Slug = "" // always generated from the title
+	// Availability only changes through checkout and return
+	book.Available = true
 
-	if book.Title == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
-		return
+	if enrichRequested(ctx) {
+		normalizeBook(&book)
+		enrichBook(ctx.Request.Context(), &book)
 	}
-	if book.Author == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Author cannot be empty"})
+	warnings, err := checkBook(&book, strictValidation(ctx), requestLanguage(ctx))
+	if err != nil {
+		respondCheckError(ctx, err)
 		return
 	}
-	if book.Year <= 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Year must be a valid positive number"})
-		return
+	if book.Language == "" {
+		book.Language = models.DefaultLanguage
 	}
 
-	result := database.DB.Create(&book)
-	if result.Error != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book"})
+	if dedupeOnCreate(ctx) {
+		var existing models.Book
+		result := database.DB.Clauses(dbresolver.Write).
+			Where("LOWER(TRIM(title)) = ? AND LOWER(TRIM(author)) = ?", normalizeForDedupe(book.Title), normalizeForDedupe(book.Author)).
+			Limit(1).Find(&existing)
+		if result.Error != nil {
+			render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to create book"})
+			return
+		}
+		if result.RowsAffected > 0 {
+			render(ctx, http.StatusConflict, gin.H{"error": "A book with this title and author already exists", "id": existing.ID})
+			return
+		}
+	}
+
+	// Create fills in the id and timestamps, so a retry starts from the payload
+	draft := book
+	err = database.Transact(ctx.Request.Context(), func(tx *gorm.DB) error {
+		book = draft
+		if err := tx.Create(&book).Error; err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, BookEventsTopic, bookEvent(ctx, "book.created", book))
+	})
+	if err != nil {
+		respondWriteError(ctx, err, "Failed to create book")
 		return
 	}
 
-	redis.RedisClient.Del(context.Background(), "books")
-	kafka.PublishMessage("book_events", "New book added: "+book.Title)
+	cacheCreatedBook(book)
 
-	ctx.JSON(http.StatusCreated, book)
+	ctx.Header("Location", ctx.FullPath()+"/"+strconv.FormatUint(uint64(book.ID), 10))
+	respond(ctx, http.StatusCreated, bookWithWarnings{Book: book, Warnings: warnings})
 }
 
 // UpdateBook godoc
 // @Summary Update an existing book
-// @Description Modify the details of an existing book
+// @Description Modify the details of an existing book. The slug is preserved when the title changes so existing URLs keep working.
+// @Description An update that changes nothing, such as a retry, isn't written and publishes no event; it is answered with the
+// @Description unchanged book and X-No-Op: true.
 // @Tags books
 // @Accept json
 // @Produce json
 // @Param id path int true "Book ID"
 // @Param book body models.Book true "Updated book object"
-// @Success 200 {object} models.Book
+// @Param strict query bool false "Reject the book when it has validation warnings instead of returning them"
+// @Param changes query bool false "Only return the fields that changed, plus id, updated_at and any warnings"
+// @Param If-Match header string false "Only update the book if its current ETag is one of these"
+// @Success 200 {object} bookWithWarnings
+// @Header 200 {string} ETag "Version of the updated book"
+// @Header 200 {string} X-No-Op "true when the update changed nothing and was skipped"
+// @Failure 412 {object} map[string]string "Book has changed since it was fetched"
 // @Failure 400 {object} map[string]string "Invalid request body"
+// @Failure 422 {object} map[string]string "Title or author too long, text with control characters, or an ISBN check digit that doesn't match (with ISBN_CHECKSUM=reject or strict)"
+// @Failure 413 {object} map[string]string "Request body too large"
+// @Failure 415 {object} map[string]string "Content-Type is not application/json"
 // @Failure 404 {object} map[string]string "Book not found"
+// @Failure 500 {object} map[string]string "Error fetching or updating the book"
+// @Failure 409 {object} map[string]string "Unique field already in use"
 // @Router /books/{id} [put]
 func UpdateBook(ctx *gin.Context) {
-	id := ctx.Param("id")
+	id, ok := bookIDParam(ctx)
+	if !ok {
+		return
+	}
 	var book models.Book
-	result := database.DB.First(&book, id)
+	result := database.DB.Clauses(dbresolver.Write).First(&book, id)
 	if result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		respondLookupError(ctx, result.Error)
 		return
 	}
 
 	var updatedBook models.Book
-	if err := ctx.ShouldBindJSON(&updatedBook); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+	if !bindJSON(ctx, &updatedBook) {
 		return
 	}
 
-	if updatedBook.Title == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
+	warnings, err := checkBook(&updatedBook, strictValidation(ctx), requestLanguage(ctx))
+	if err != nil {
+		respondCheckError(ctx, err)
 		return
 	}
-	if updatedBook.Author == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Author cannot be empty"})
+	before := book
+	book.Title = updatedBook.Title
+	book.Author = updatedBook.Author
+	book.Authors = updatedBook.Authors
+	book.Year = updatedBook.Year
+	book.ISBN = updatedBook.ISBN
+	book.Publisher = updatedBook.Publisher
+	if updatedBook.Language != "" {
+		book.Language = updatedBook.Language
+	}
+	noOp := sameFields(before, book)
+	if noOp {
+		// Idempotent retries resend the book as it is: skip the write, the
+		// cache refresh and the event, but still honour If-Match
+		err = checkIfMatch(ctx, database.DB.Clauses(dbresolver.Write), book.ID)
+	} else {
+		err = database.Transact(ctx.Request.Context(), func(tx *gorm.DB) error {
+			if err := checkIfMatch(ctx, tx, book.ID); err != nil {
+				return err
+			}
+			// Save writes every column. Updates(&book) would silently skip
+			// zero values such as an empty publisher or a nil ISBN; use
+			// Select or a map if this ever moves to a partial update.
+			if err := tx.Save(&book).Error; err != nil {
+				return err
+			}
+			return outbox.Enqueue(tx, BookEventsTopic, bookEvent(ctx, "book.updated", book))
+		})
+	}
+	if errors.Is(err, errPreconditionFailed) {
+		respondPreconditionFailed(ctx)
 		return
 	}
-	if updatedBook.Year <= 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Year must be a valid positive number"})
+	if err != nil {
+		respondWriteError(ctx, err, "Failed to update book")
 		return
 	}
-	book.Title = updatedBook.Title
-	book.Author = updatedBook.Author
-	book.Year = updatedBook.Year
-	database.DB.Save(&book)
-
-	deletedKeys := []string{"books", "book:" + id}
-	redis.RedisClient.Del(context.Background(), deletedKeys...)
-
-	val, _ := redis.RedisClient.Get(context.Background(), "books").Result()
-	log.Println("Redis books cache after update:", val)
 
-	kafka.PublishMessage("book_events", "Book updated: "+book.Title)
+	if noOp {
+		ctx.Header(noOpHeader, "true")
+	} else {
+		cacheUpdatedBook(book)
+		if before.ISBN != nil && (book.ISBN == nil || *book.ISBN != *before.ISBN) {
+			// The old ISBN no longer belongs to this book
+			redis.BookCache.Del(context.Background(), isbnCacheKey(*before.ISBN))
+		}
+	}
+	setBookETag(ctx, book)
 
-	ctx.JSON(http.StatusOK, book)
+	if changesOnly, _ := strconv.ParseBool(ctx.Query("changes")); changesOnly {
+		changes := changedFields(before, book)
+		if len(warnings) > 0 {
+			changes["warnings"] = warnings
+		}
+		respond(ctx, http.StatusOK, changes)
+		return
+	}
+	respond(ctx, http.StatusOK, bookWithWarnings{Book: book, Warnings: warnings})
 }
 
 // DeleteBook godoc
@@ -191,27 +530,169 @@ func UpdateBook(ctx *gin.Context) {
 // @Description Remove a book from the collection
 // @Tags books
 // @Param id path int true "Book ID"
+// @Param If-Match header string false "Only delete the book if its current ETag is one of these"
 // @Success 200 {object} map[string]string "Book deleted successfully"
+// @Failure 412 {object} map[string]string "Book has changed since it was fetched"
 // @Failure 404 {object} map[string]string "Book not found"
+// @Failure 500 {object} map[string]string "Error fetching or deleting the book"
 // @Router /books/{id} [delete]
 func DeleteBook(ctx *gin.Context) {
-	ctx.Header("Content-Type", "application/json")
-	id := ctx.Param("id")
+	id, ok := bookIDParam(ctx)
+	if !ok {
+		return
+	}
 	var book models.Book
 
-	result := database.DB.First(&book, id)
+	result := database.DB.Clauses(dbresolver.Write).First(&book, id)
 	if result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		respondLookupError(ctx, result.Error)
 		return
 	}
 
-	database.DB.Unscoped().Delete(&book)
-	deletedKeys := []string{"books", "book:" + id}
-	redis.RedisClient.Del(context.Background(), deletedKeys...)
-	val, _ := redis.RedisClient.Get(context.Background(), "books").Result()
-	log.Println("Redis books cache after delete:", val)
+	err := database.Transact(ctx.Request.Context(), func(tx *gorm.DB) error {
+		if err := checkIfMatch(ctx, tx, book.ID); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&book).Error; err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, BookEventsTopic, bookEvent(ctx, "book.deleted", book))
+	})
+	if errors.Is(err, errPreconditionFailed) {
+		respondPreconditionFailed(ctx)
+		return
+	}
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
+		return
+	}
+
+	// The tombstone goes first so a concurrent read can't re-cache the book
+	markBookDeleted(book)
+	invalidateBookCache(&book)
+
+	respond(ctx, http.StatusOK, gin.H{"message": "Book deleted successfully"})
+}
+
+// dedupeOnCreate reports whether CreateBook should reject duplicates. The
+// ?dedupe= query flag overrides the DEDUPE_ON_CREATE env default.
+func dedupeOnCreate(ctx *gin.Context) bool {
+	if flag, err := strconv.ParseBool(ctx.Query("dedupe")); err == nil {
+		return flag
+	}
+	return os.Getenv("DEDUPE_ON_CREATE") == "true"
+}
+
+func normalizeForDedupe(value string) string {
+	return strings.ToLower(normalizeText(value))
+}
+
+// bookIDParam returns the :id path parameter in canonical form. An id that
+// isn't a positive number can't belong to a book and is answered with 404.
+func bookIDParam(ctx *gin.Context) (string, bool) {
+	id, ok := parseBookID(ctx)
+	if !ok {
+		respondMessage(ctx, http.StatusNotFound, msgBookNotFound)
+	}
+	return id, ok
+}
+
+func parseBookID(ctx *gin.Context) (string, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		return "", false
+	}
+	return strconv.FormatUint(id, 10), true
+}
+
+// missingAsNull reports whether a missing book should be answered with 200
+// and a null body instead of 404: the ?missing= query param wins over the
+// MISSING_BOOK_RESPONSE env default, and only "null" enables it
+func missingAsNull(ctx *gin.Context) bool {
+	missing := ctx.Query("missing")
+	if missing == "" {
+		missing = os.Getenv("MISSING_BOOK_RESPONSE")
+	}
+	return missing == "null"
+}
+
+// respondGetLookupError is respondLookupError for reads, which may answer a
+// missing book with null instead
+func respondGetLookupError(ctx *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) && missingAsNull(ctx) {
+		respond(ctx, http.StatusOK, nil)
+		return
+	}
+	respondLookupError(ctx, err)
+}
+
+// respondLookupError answers 404 when err means the book doesn't exist and
+// 500 for any other failure, so a database outage isn't reported as a
+// missing book
+func respondLookupError(ctx *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondMessage(ctx, http.StatusNotFound, msgBookNotFound)
+		return
+	}
+	log.Println("Error fetching book:", err)
+	render(ctx, http.StatusInternalServerError, gin.H{"error": "Error fetching book"})
+}
+
+// respondWriteError answers 409 naming the field when err is a unique
+// constraint violation, and 500 with message otherwise
+func respondWriteError(ctx *gin.Context, err error, message string) {
+	if field, ok := database.UniqueViolation(err); ok {
+		render(ctx, http.StatusConflict, gin.H{"error": "A book with this " + field + " already exists", "field": field})
+		return
+	}
+	render(ctx, http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// bindJSON decodes the request body into obj, answering 413 when the body
+// exceeds the configured limit, 422 when a book's year isn't numeric and 400
+// when it is empty or not valid JSON
+func bindJSON(ctx *gin.Context, obj interface{}) bool {
+	var err error
+	if strictBinding(ctx) {
+		err = bindStrictJSON(ctx, obj)
+	} else {
+		err = ctx.ShouldBindJSON(obj)
+	}
+	if err == nil {
+		return true
+	}
+
+	var unknown unknownFieldError
+	if errors.As(err, &unknown) {
+		body := errorBody(ctx, msgUnknownField, unknown.field)
+		body["field"] = unknown.field
+		render(ctx, http.StatusBadRequest, body)
+		return false
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		render(ctx, http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+		return false
+	}
+	if errors.Is(err, models.ErrInvalidYear) {
+		respondError(ctx, http.StatusUnprocessableEntity, err)
+		return false
+	}
+	// The decoder hits EOF right away on a body that is empty or only
+	// whitespace, a common mistake worth telling apart from malformed JSON
+	if errors.Is(err, io.EOF) {
+		respondMessage(ctx, http.StatusBadRequest, msgBodyRequired)
+		return false
+	}
+	respondMessage(ctx, http.StatusBadRequest, msgInvalidJSON)
+	return false
+}
 
-	kafka.PublishMessage("book_events", "Book deleted: "+strconv.Itoa(int(book.ID)))
+// BookEventsTopic is the Kafka topic book change events are published to
+const BookEventsTopic = "book_events"
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Book deleted successfully"})
+// bookEvent builds the event for a change to book, tagged with the id of the
+// request that made it
+func bookEvent(ctx *gin.Context, event string, book models.Book) kafka.BookEvent {
+	return kafka.BookEvent{Event: event, ID: book.ID, Title: book.Title, RequestID: middleware.GetRequestID(ctx)}
 }
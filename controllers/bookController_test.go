@@ -0,0 +1,587 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/middleware"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+	"github.com/rohans540/books-backend/routes"
+	"github.com/rohans540/books-backend/testutil"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+func TestCreateBook(t *testing.T) {
+	router, db := setup(t)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created models.Book
+	decode(t, w, &created)
+	if created.ID == 0 || created.Title != "Dune" || created.Slug != "dune" {
+		t.Errorf("created = %+v, want an id, the title and slug dune", created)
+	}
+
+	var stored models.Book
+	if err := db.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("created book not stored: %v", err)
+	}
+	if stored.Author != "Frank Herbert" || stored.Year != 1965 {
+		t.Errorf("stored = %+v", stored)
+	}
+}
+
+func TestCreateBookLocation(t *testing.T) {
+	router, _ := setup(t)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created models.Book
+	decode(t, w, &created)
+	location := w.Header().Get("Location")
+	if want := "/v1/books/" + itoa(created.ID); location != want {
+		t.Fatalf("Location = %q, want %q", location, want)
+	}
+	if w := testutil.Request(router, http.MethodGet, location, ""); w.Code != http.StatusOK {
+		t.Errorf("GET Location: status = %d, want 200", w.Code)
+	}
+}
+
+func TestBookEventsCarryRequestID(t *testing.T) {
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	// Mounted in main.go, in front of every route
+	router.Use(middleware.RequestID())
+	routes.SetupRoutes(router)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`, "X-Request-ID", "req-123")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+	}
+
+	var event models.OutboxEvent
+	if err := db.First(&event).Error; err != nil {
+		t.Fatalf("no event queued: %v", err)
+	}
+	var payload kafka.BookEvent
+	json.Unmarshal([]byte(event.Payload), &payload)
+	if payload.Event != "book.created" || payload.RequestID != "req-123" {
+		t.Errorf("event = %+v, want book.created with request id req-123", payload)
+	}
+}
+
+func TestEmptyBodyIsNotInvalidJSON(t *testing.T) {
+	router, db := setup(t)
+	book := seedNumbered(t, db, 1)[0]
+	paths := map[string]string{http.MethodPost: "/v1/books", http.MethodPut: "/v1/books/" + itoa(book.ID)}
+
+	tests := []struct {
+		name, body, code string
+	}{
+		{"empty", "", "body_required"},
+		{"whitespace", " \n ", "body_required"},
+		{"malformed", `{"title": `, "invalid_json"},
+	}
+	for method, path := range paths {
+		for _, tt := range tests {
+			for _, strict := range []string{"false", "true"} {
+				w := testutil.Request(router, method, path, tt.body, "Content-Type", "application/json", "X-Strict-Binding", strict)
+				var body map[string]string
+				decode(t, w, &body)
+				if w.Code != http.StatusBadRequest || body["code"] != tt.code {
+					t.Errorf("%s %s, %s body, strict %s: status = %d, code %q, want 400 and %s", method, path, tt.name, strict, w.Code, body["code"], tt.code)
+				}
+			}
+		}
+	}
+
+	// The OpenAPI validation mounted in main.go leaves a missing body to
+	// the handlers
+	router = testutil.NewRouter()
+	router.Use(middleware.OpenAPIValidation())
+	routes.SetupRoutes(router)
+	for method, path := range paths {
+		w := testutil.Request(router, method, path, "", "Content-Type", "application/json")
+		var body map[string]string
+		decode(t, w, &body)
+		if w.Code != http.StatusBadRequest || body["code"] != "body_required" {
+			t.Errorf("%s %s with OpenAPI validation: status = %d, code %q, want 400 and body_required", method, path, w.Code, body["code"])
+		}
+	}
+	var body map[string]interface{}
+	decode(t, testutil.Request(router, http.MethodPost, "/v1/books", `{"title": 5}`), &body)
+	if body["error"] != "Request does not match the API schema" {
+		t.Errorf("a wrongly typed body got %v, want it rejected by the OpenAPI validation", body)
+	}
+}
+
+func TestCreateBookValidation(t *testing.T) {
+	router, db := setup(t)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"empty title", `{"title": " ", "author": "Frank Herbert", "year": 1965}`, http.StatusBadRequest},
+		{"missing author", `{"title": "Dune", "year": 1965}`, http.StatusBadRequest},
+		{"year zero", `{"title": "Dune", "author": "Frank Herbert", "year": 0}`, http.StatusBadRequest},
+		{"malformed JSON", `{"title": `, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Request(router, http.MethodPost, "/v1/books", tt.body)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&models.Book{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d books stored, want none", count)
+	}
+}
+
+func TestDuplicateISBNConflict(t *testing.T) {
+	router, db := setup(t)
+	isbn := "9780441013593"
+	testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965, ISBN: &isbn})
+	other := testutil.SeedBooks(t, db, models.Book{Title: "Dune Messiah", Author: "Frank Herbert", Year: 1969})[0]
+
+	requests := []struct{ method, path string }{
+		{http.MethodPost, "/v1/books"},
+		{http.MethodPut, "/v1/books/" + itoa(other.ID)},
+	}
+	for _, r := range requests {
+		w := testutil.Request(router, r.method, r.path, `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969, "isbn": "`+isbn+`"}`)
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s %s: status = %d, want 409: %s", r.method, r.path, w.Code, w.Body)
+			continue
+		}
+		var body map[string]interface{}
+		decode(t, w, &body)
+		if body["field"] != "isbn" {
+			t.Errorf("%s %s: field = %v, want isbn", r.method, r.path, body["field"])
+		}
+	}
+}
+
+func TestGetBookByID(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(book.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got models.Book
+	decode(t, w, &got)
+	if got.ID != book.ID || got.Title != "Dune" {
+		t.Errorf("got %+v, want book %d", got, book.ID)
+	}
+}
+
+func TestGetBookByIDNotFound(t *testing.T) {
+	router, _ := setup(t)
+
+	for _, path := range []string{"/v1/books/42", "/v1/books/not-a-number"} {
+		w := testutil.Request(router, http.MethodGet, path, "")
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status = %d, want 404", path, w.Code)
+		}
+	}
+}
+
+func TestSlugCollisions(t *testing.T) {
+	router, _ := setup(t)
+
+	for i, want := range []string{"dune", "dune-2", "dune-3"} {
+		w := testutil.Request(router, http.MethodPost, "/v1/books", fmt.Sprintf(`{"title": "Dune", "author": "Author %d", "year": 1965}`, i))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+		}
+		var created models.Book
+		decode(t, w, &created)
+		if created.Slug != want {
+			t.Errorf("book %d: slug = %q, want %q", i, created.Slug, want)
+		}
+	}
+}
+
+func TestGetBookBySlug(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "The Left Hand of Darkness", Author: "Ursula K. Le Guin", Year: 1969})[0]
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books/slug/The-Left-Hand-of-Darkness", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got models.Book
+	decode(t, w, &got)
+	if got.ID != book.ID {
+		t.Errorf("got book %d, want %d", got.ID, book.ID)
+	}
+	if _, err := redis.BookCache.Get(context.Background(), "book:slug:the-left-hand-of-darkness"); err != nil {
+		t.Errorf("book not cached under its slug: %v", err)
+	}
+
+	if w := testutil.Request(router, http.MethodGet, "/v1/books/slug/missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown slug: status = %d, want 404", w.Code)
+	}
+}
+
+func TestUpdateBook(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	w := testutil.Request(router, http.MethodPut, "/v1/books/"+itoa(book.ID), `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	var stored models.Book
+	db.First(&stored, book.ID)
+	if stored.Title != "Dune Messiah" || stored.Year != 1969 {
+		t.Errorf("stored = %+v, want the update applied", stored)
+	}
+	if stored.Slug != "dune" {
+		t.Errorf("slug = %q, want it kept as dune", stored.Slug)
+	}
+}
+
+func TestUpdateBookChangesOnly(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	w := testutil.Request(router, http.MethodPut, "/v1/books/"+itoa(book.ID)+"?changes=true", `{"title": "Dune", "author": "Frank Herbert", "year": 1966}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var changes map[string]interface{}
+	decode(t, w, &changes)
+	var keys []string
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[id updated_at year]" || changes["year"] != float64(1966) {
+		t.Errorf("response = %v, want only id, updated_at and the new year", changes)
+	}
+
+	// Without the flag the full book is returned
+	w = testutil.Request(router, http.MethodPut, "/v1/books/"+itoa(book.ID), `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	var full models.Book
+	decode(t, w, &full)
+	if full.Title != "Dune" || full.Author != "Frank Herbert" {
+		t.Errorf("response = %+v, want the full book", full)
+	}
+}
+
+func TestUpdateBookNotFound(t *testing.T) {
+	router, _ := setup(t)
+
+	w := testutil.Request(router, http.MethodPut, "/v1/books/42", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404: %s", w.Code, w.Body)
+	}
+}
+
+func TestUpdateBookValidation(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	w := testutil.Request(router, http.MethodPut, "/v1/books/"+itoa(book.ID), `{"title": "", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+	var stored models.Book
+	db.First(&stored, book.ID)
+	if stored.Title != "Dune" {
+		t.Errorf("title = %q, want it unchanged", stored.Title)
+	}
+}
+
+func TestDeleteBook(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	w := testutil.Request(router, http.MethodDelete, "/v1/books/"+itoa(book.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var count int64
+	db.Model(&models.Book{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d books left, want none", count)
+	}
+
+	w = testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(book.ID), "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET after delete: status = %d, want 404", w.Code)
+	}
+}
+
+func TestDeleteBookNotFound(t *testing.T) {
+	router, _ := setup(t)
+
+	w := testutil.Request(router, http.MethodDelete, "/v1/books/42", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404: %s", w.Code, w.Body)
+	}
+}
+
+func TestCreateBookRollsBackWhenTheEventFails(t *testing.T) {
+	router, db := setup(t)
+	// Queuing the event is the write's last step
+	if err := db.Migrator().DropTable(&models.OutboxEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500: %s", w.Code, w.Body)
+	}
+	var count int64
+	db.Model(&models.Book{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d books stored, want the insert rolled back", count)
+	}
+}
+
+func TestBookResponseKeys(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	var got map[string]interface{}
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(book.ID), ""), &got)
+	keys := make([]string, 0, len(got))
+	for key := range got {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	want := "[author authors available created_at id isbn language publisher slug title updated_at year]"
+	if fmt.Sprint(keys) != want {
+		t.Errorf("keys = %v, want %s", keys, want)
+	}
+}
+
+func TestCorruptCachedBookIsEvicted(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+	ctx := context.Background()
+
+	for _, path := range []string{"/v1/books/" + itoa(book.ID), "/v1/books/slug/dune"} {
+		// Warm the cache, then truncate the entry as a partial write would
+		testutil.Request(router, http.MethodGet, path, "")
+		keys, _ := redis.BookCache.Keys(ctx, "book*", 10)
+		if len(keys) == 0 {
+			t.Fatalf("%s: nothing cached", path)
+		}
+		for _, key := range keys {
+			redis.BookCache.Set(ctx, key.Key, `{"id": 1, "title": "Du`, 0)
+		}
+
+		w := testutil.Request(router, http.MethodGet, path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200: %s", path, w.Code, w.Body)
+		}
+		var got models.Book
+		decode(t, w, &got)
+		if got.ID != book.ID || got.Title != "Dune" || got.Author != "Frank Herbert" {
+			t.Errorf("%s: got %+v, want the stored book", path, got)
+		}
+		// The bad entry is gone: what is cached now decodes
+		for _, key := range keys {
+			cached, err := redis.BookCache.Get(ctx, key.Key)
+			if err == nil && json.Unmarshal([]byte(cached), &models.Book{}) != nil {
+				t.Errorf("%s: %s still holds %q", path, key.Key, cached)
+			}
+		}
+		for _, key := range keys {
+			redis.BookCache.Del(ctx, key.Key)
+		}
+	}
+}
+
+func TestBookDeletedOutOfBandExpiresFromCache(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+	path := "/v1/books/" + itoa(book.ID)
+
+	// By default the cached copy is bounded to ten minutes
+	testutil.Request(router, http.MethodGet, path, "")
+	if ttl := cachedTTL(t, "book:"+itoa(book.ID)); ttl <= 10*time.Minute-time.Second || ttl > 10*time.Minute {
+		t.Errorf("book TTL = %s, want 10m", ttl)
+	}
+	redis.BookCache.Del(context.Background(), "book:"+itoa(book.ID))
+
+	t.Setenv("CACHE_TTL_BOOK", "50ms")
+	testutil.Request(router, http.MethodGet, path, "")
+	// Bypasses the API, so nothing invalidates the cached copy
+	if err := db.Delete(&models.Book{}, book.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if w := testutil.Request(router, http.MethodGet, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("before expiry: status = %d, want the cached 200", w.Code)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if w := testutil.Request(router, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("after expiry: status = %d, want 404", w.Code)
+	}
+}
+
+func TestUpdateBookYear(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1964})[0]
+	path := "/v1/books/" + itoa(book.ID)
+
+	w := testutil.Request(router, http.MethodPut, path, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("valid year: status = %d: %s", w.Code, w.Body)
+	}
+	var stored models.Book
+	db.First(&stored, book.ID)
+	if stored.Year != 1965 {
+		t.Errorf("stored year = %d, want 1965", stored.Year)
+	}
+
+	// PUT replaces the whole book, so a missing year is the zero year and
+	// neither may wipe the stored one
+	for name, body := range map[string]string{
+		"year 0":       `{"title": "Dune", "author": "Frank Herbert", "year": 0}`,
+		"missing year": `{"title": "Dune", "author": "Frank Herbert"}`,
+	} {
+		if w := testutil.Request(router, http.MethodPut, path, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %s", name, w.Code, w.Body)
+		}
+	}
+	db.First(&stored, book.ID)
+	if stored.Year != 1965 {
+		t.Errorf("stored year after the rejected updates = %d, want 1965", stored.Year)
+	}
+}
+
+func TestGetRandomBook(t *testing.T) {
+	router, db := setup(t)
+	if w := testutil.Request(router, http.MethodGet, "/v1/books/random", ""); w.Code != http.StatusNotFound {
+		t.Errorf("empty table: status = %d, want 404", w.Code)
+	}
+
+	books := seedNumbered(t, db, 5)
+	// Gaps in the ids must not make the pick fail
+	db.Delete(&models.Book{}, books[1].ID)
+	db.Delete(&models.Book{}, books[2].ID)
+	stored := map[uint]string{}
+	for _, book := range []models.Book{books[0], books[3], books[4]} {
+		stored[book.ID] = book.Title
+	}
+
+	for i := 0; i < 20; i++ {
+		w := testutil.Request(router, http.MethodGet, "/v1/books/random", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+		}
+		var got models.Book
+		decode(t, w, &got)
+		if title, ok := stored[got.ID]; !ok || got.Title != title {
+			t.Fatalf("random book = %d %q, want one of the stored books", got.ID, got.Title)
+		}
+	}
+}
+
+func TestNoOpUpdate(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+	path := "/v1/books/" + itoa(book.ID)
+	ctx := context.Background()
+
+	// Warm the book and list caches
+	testutil.Request(router, http.MethodGet, path, "")
+	testutil.Request(router, http.MethodGet, "/v1/books", "")
+	cached, _ := redis.BookCache.Keys(ctx, "book*", 10)
+
+	// An idempotent retry sends the book as it is
+	w := testutil.Request(router, http.MethodPut, path, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusOK || w.Header().Get("X-No-Op") != "true" {
+		t.Fatalf("status = %d, X-No-Op = %q, want 200 and true: %s", w.Code, w.Header().Get("X-No-Op"), w.Body)
+	}
+	var got models.Book
+	decode(t, w, &got)
+	if got.ID != book.ID || got.Title != "Dune" {
+		t.Errorf("response = %+v, want the unchanged book", got)
+	}
+
+	var events int64
+	db.Model(&models.OutboxEvent{}).Count(&events)
+	if events != 0 {
+		t.Errorf("%d events queued for a no-op update, want none", events)
+	}
+	if after, _ := redis.BookCache.Keys(ctx, "book*", 10); len(after) != len(cached) || len(cached) < 2 {
+		t.Errorf("cache keys = %v before and %v after, want the book and list entries kept", cached, after)
+	}
+	var stored models.Book
+	db.First(&stored, book.ID)
+	if !stored.UpdatedAt.Equal(book.UpdatedAt) {
+		t.Errorf("updated_at moved from %s to %s", book.UpdatedAt, stored.UpdatedAt)
+	}
+
+	// A real change is written as usual
+	w = testutil.Request(router, http.MethodPut, path, `{"title": "Dune", "author": "Frank Herbert", "year": 1966}`)
+	if w.Code != http.StatusOK || w.Header().Get("X-No-Op") != "" {
+		t.Errorf("change: status = %d, X-No-Op = %q, want 200 without it", w.Code, w.Header().Get("X-No-Op"))
+	}
+	db.Model(&models.OutboxEvent{}).Count(&events)
+	if events != 1 {
+		t.Errorf("%d events queued for a change, want 1", events)
+	}
+}
+
+func TestReadYourWrites(t *testing.T) {
+	for name, window := range map[string]string{"off": "", "on": "5s"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("READ_YOUR_WRITES_WINDOW", window)
+			// Updates drop the cached book, so the next read goes to the
+			// database
+			t.Setenv("CACHE_WRITE_MODE", "cache-aside")
+			replica := testutil.SetupDB(t)
+			router, primary := setup(t)
+			// A replica that never catches up with the primary
+			if err := primary.Use(dbresolver.Register(dbresolver.Config{Replicas: []gorm.Dialector{replica.Dialector}})); err != nil {
+				t.Fatal(err)
+			}
+			book := models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965}
+			testutil.SeedBooks(t, replica, book)
+			testutil.SeedBooks(t, primary, book)
+
+			w := testutil.Request(router, http.MethodPut, "/v1/books/1", `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("update: status = %d: %s", w.Code, w.Body)
+			}
+			var got models.Book
+			decode(t, testutil.Request(router, http.MethodGet, "/v1/books/1", ""), &got)
+			want := "Dune Messiah"
+			if window == "" {
+				want = "Dune"
+			}
+			if got.Title != want {
+				t.Errorf("GET after update = %q, want %q", got.Title, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/outbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultMaxBatchSize = 500
+
+// ValidateBatchSize checks MAX_BATCH_SIZE so a bad value fails at startup
+// instead of being silently replaced by the default
+func ValidateBatchSize() error {
+	_, err := positiveEnvInt("MAX_BATCH_SIZE", defaultMaxBatchSize)
+	return err
+}
+
+// maxBatchSize returns how many books a single bulk request may touch, from
+// MAX_BATCH_SIZE, which defaults to 500. It bounds the request's memory use
+// and the size of its transaction.
+func maxBatchSize() int {
+	size, err := positiveEnvInt("MAX_BATCH_SIZE", defaultMaxBatchSize)
+	if err != nil {
+		return defaultMaxBatchSize
+	}
+	return size
+}
+
+// batchTooLargeError rejects a bulk request touching more than the maximum
+// number of books. It is answered with 413.
+type batchTooLargeError struct {
+	count int64
+	max   int
+}
+
+func (e batchTooLargeError) Error() string {
+	return fmt.Sprintf("Request touches %d books, at most %d can be changed at once", e.count, e.max)
+}
+
+// BulkUpdateFilter selects the books a bulk update applies to. At least one
+// field must be set.
+type BulkUpdateFilter struct {
+	IDs []uint `json:"ids"`
+	// Author matches the primary author
+	Author   *string `json:"author"`
+	Year     *int    `json:"year"`
+	Language *string `json:"language"`
+}
+
+// BulkUpdateRequest sets one field to one value on every matching book
+type BulkUpdateRequest struct {
+	Filter BulkUpdateFilter `json:"filter"`
+	Field  string           `json:"field" example:"author"`
+	Value  interface{}      `json:"value"`
+}
+
+// replacePrimaryAuthor returns the expression that rewrites authors with the
+// current primary author replaced by the new one, keeping co-authors and
+// their order, and its two arguments. SET expressions see the row before the
+// update, so books.author is the old name. Postgres builds a jsonb array;
+// other dialects (SQLite in tests) use json_each, which walks the array in
+// order, and json_group_array, which gives an empty array rather than NULL
+// when there are no authors.
+func replacePrimaryAuthor(author interface{}) clause.Expr {
+	if usesFullTextSearch() {
+		return gorm.Expr(`COALESCE((
+	SELECT jsonb_agg(CASE WHEN a.name = books.author THEN to_jsonb(?::text) ELSE to_jsonb(a.name) END ORDER BY a.ord)
+	FROM jsonb_array_elements_text(books.authors) WITH ORDINALITY AS a(name, ord)
+), jsonb_build_array(?::text))`, author, author)
+	}
+	return gorm.Expr(`COALESCE(NULLIF((
+	SELECT json_group_array(CASE WHEN a.value = books.author THEN ? ELSE a.value END)
+	FROM json_each(books.authors) AS a
+), '[]'), json_array(?))`, author, author)
+}
+
+func (f BulkUpdateFilter) empty() bool {
+	return len(f.IDs) == 0 && f.Author == nil && f.Year == nil && f.Language == nil
+}
+
+func (f BulkUpdateFilter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.IDs) > 0 {
+		query = query.Where("id IN ?", f.IDs)
+	}
+	if f.Author != nil {
+		query = query.Where("author = ?", *f.Author)
+	}
+	if f.Year != nil {
+		query = query.Where("year = ?", *f.Year)
+	}
+	if f.Language != nil {
+		query = query.Where("language = ?", *f.Language)
+	}
+	return query
+}
+
+// bulkUpdateValue validates value for field and returns the column and value
+// to write
+func bulkUpdateValue(field string, value interface{}) (string, interface{}, error) {
+	// A missing value is never taken as the field's zero value: year 0 is
+	// rejected as invalid, no year at all as missing
+	if value == nil {
+		return "", nil, errors.New("A value is required")
+	}
+	switch field {
+	case "author":
+		author, ok := value.(string)
+		if author = normalizeText(author); !ok || author == "" {
+			return "", nil, newMessageError(msgAuthorEmpty)
+		}
+		if escapeHTMLInput() {
+			author = escapeText(author)
+		}
+		_, maxAuthor, err := textLimits()
+		if err != nil {
+			maxAuthor = models.MaxTextLength
+		}
+		if err := checkLength("Author", author, maxAuthor); err != nil {
+			return "", nil, err
+		}
+		if err := checkControlChars("Author", author); err != nil {
+			return "", nil, err
+		}
+		return "author", author, nil
+	case "year":
+		year, ok := value.(float64)
+		if !ok || year <= 0 || year != float64(int(year)) {
+			return "", nil, newMessageError(msgYearInvalid)
+		}
+		return "year", int(year), nil
+	case "language":
+		language, ok := value.(string)
+		if !ok || !models.IsValidLanguage(language) {
+			return "", nil, newMessageError(msgLanguageInvalid)
+		}
+		return "language", language, nil
+	default:
+		return "", nil, errors.New("Field must be one of author, year, language")
+	}
+}
+
+// BulkUpdateBooks godoc
+// @Summary Set one field on many books
+// @Description Set a single field (author, year or language) on every book matching the filter, in one UPDATE statement.
+// @Description The filter is required so the whole table can't be updated by accident. Requires the admin token.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body BulkUpdateRequest true "Filter and field to set"
+// @Success 200 {object} map[string]int64 "Number of updated books"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 413 {object} map[string]string "More books than MAX_BATCH_SIZE"
+// @Failure 422 {object} map[string]string "Author too long or contains control characters"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Router /books [patch]
+func BulkUpdateBooks(ctx *gin.Context) {
+	var req BulkUpdateRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if req.Filter.empty() {
+		render(ctx, http.StatusBadRequest, gin.H{"error": "A filter is required"})
+		return
+	}
+	maxSize := maxBatchSize()
+	if len(req.Filter.IDs) > maxSize {
+		render(ctx, http.StatusRequestEntityTooLarge, gin.H{"error": batchTooLargeError{count: int64(len(req.Filter.IDs)), max: maxSize}.Error()})
+		return
+	}
+	column, value, err := bulkUpdateValue(req.Field, req.Value)
+	if err != nil {
+		respondError(ctx, validationStatus(err), err)
+		return
+	}
+
+	// A map, unlike a struct, writes zero values too, so a validated value
+	// always reaches the column
+	updates := map[string]interface{}{column: value}
+	if column == "author" {
+		updates["authors"] = replacePrimaryAuthor(value)
+	}
+
+	var updated []models.Book
+	err = database.Transact(ctx.Request.Context(), func(tx *gorm.DB) error {
+		updated = nil
+		// Counted before updating so an oversized batch is rejected
+		// without writing or locking anything
+		var matched int64
+		if err := req.Filter.apply(tx.Model(&models.Book{})).Count(&matched).Error; err != nil {
+			return err
+		}
+		if matched > int64(maxSize) {
+			return batchTooLargeError{count: matched, max: maxSize}
+		}
+		result := req.Filter.apply(tx.Model(&updated).Clauses(clause.Returning{})).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		for _, book := range updated {
+			if err := outbox.Enqueue(tx, BookEventsTopic, bookEvent(ctx, "book.updated", book)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var tooLarge batchTooLargeError
+	if errors.As(err, &tooLarge) {
+		render(ctx, http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge.Error()})
+		return
+	}
+	if err != nil {
+		respondWriteError(ctx, err, "Failed to update books")
+		return
+	}
+
+	for i := range updated {
+		invalidateBookCache(&updated[i])
+	}
+	if len(updated) == 0 {
+		invalidateBookCache(nil)
+	}
+
+	respond(ctx, http.StatusOK, gin.H{"updated": len(updated)})
+}
@@ -0,0 +1,151 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestBulkUpdateBatchSize(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	t.Setenv("MAX_BATCH_SIZE", "3")
+	router, db := setup(t)
+	testutil.SeedBooks(t, db,
+		models.Book{Title: "Book 1", Year: 1990},
+		models.Book{Title: "Book 2", Year: 1990},
+		models.Book{Title: "Book 3", Year: 1990},
+		models.Book{Title: "Book 4", Year: 1991},
+		models.Book{Title: "Book 5", Year: 1991},
+		models.Book{Title: "Book 6", Year: 1991},
+		models.Book{Title: "Book 7", Year: 1991},
+	)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+
+	tests := []struct {
+		name, filter string
+		want         int
+	}{
+		{"ids at the limit", `{"ids": [1, 2, 3]}`, http.StatusOK},
+		{"ids beyond the limit", `{"ids": [1, 2, 3, 4]}`, http.StatusRequestEntityTooLarge},
+		{"matches at the limit", `{"year": 1990}`, http.StatusOK},
+		{"matches beyond the limit", `{"year": 1991}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Request(router, http.MethodPatch, "/v1/books", `{"filter": `+tt.filter+`, "field": "language", "value": "fr"}`, auth...)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	// The rejected batches changed nothing
+	var french int64
+	db.Model(&models.Book{}).Where("language = ?", "fr").Count(&french)
+	if french != 3 {
+		t.Errorf("%d books updated, want only the 3 of the accepted batches", french)
+	}
+}
+
+func TestLookupBooksByISBNBatchSize(t *testing.T) {
+	t.Setenv("MAX_BATCH_SIZE", "2")
+	router, _ := setup(t)
+
+	for _, tt := range []struct {
+		isbns []string
+		want  int
+	}{
+		{[]string{"0131103628", "9780131103627"}, http.StatusOK},
+		{[]string{"0131103628", "9780131103627", "0201633612"}, http.StatusRequestEntityTooLarge},
+	} {
+		body := `{"isbns": ["` + strings.Join(tt.isbns, `", "`) + `"]}`
+		if w := testutil.Request(router, http.MethodPost, "/v1/books/lookup", body); w.Code != tt.want {
+			t.Errorf("%d ISBNs: status = %d, want %d: %s", len(tt.isbns), w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestBulkUpdateYear(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	router, db := setup(t)
+	books := seedNumbered(t, db, 2)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+	filter := `{"filter": {"ids": [` + itoa(books[0].ID) + `]}, "field": "year"`
+
+	tests := []struct {
+		name, body, want string
+		status           int
+	}{
+		{"valid year", filter + `, "value": 1965}`, "", http.StatusOK},
+		{"year 0", filter + `, "value": 0}`, "Year must be a valid positive number", http.StatusBadRequest},
+		{"missing year", filter + `}`, "A value is required", http.StatusBadRequest},
+		{"null year", filter + `, "value": null}`, "A value is required", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Request(router, http.MethodPatch, "/v1/books", tt.body, auth...)
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("status = %d, body %s, want %d %q", w.Code, w.Body, tt.status, tt.want)
+			}
+		})
+	}
+
+	// Only the valid year was written, and only to the filtered book
+	var stored []models.Book
+	db.Order("id").Find(&stored)
+	if stored[0].Year != 1965 || stored[1].Year != books[1].Year {
+		t.Errorf("years = %d, %d, want 1965 and %d unchanged", stored[0].Year, stored[1].Year, books[1].Year)
+	}
+}
+
+func TestBulkUpdateAuthor(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	router, db := setup(t)
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "Good Omens", Authors: []string{"Terry Pratchett", "Neil Gaiman"}, Year: 1990},
+		models.Book{Title: "Mort", Author: "Terry Pratchett", Year: 1987},
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965},
+	)
+	// Cache every book so the update has entries to invalidate
+	for _, book := range books {
+		testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(book.ID), "")
+	}
+
+	body := `{"filter": {"author": "Terry Pratchett"}, "field": "author", "value": "Sir Terry Pratchett"}`
+	w := testutil.Request(router, http.MethodPatch, "/v1/books", body, "Authorization", "Bearer "+adminToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	want := []struct{ author, authors string }{
+		{"Sir Terry Pratchett", "[Sir Terry Pratchett Neil Gaiman]"},
+		{"Sir Terry Pratchett", "[Sir Terry Pratchett]"},
+		{"Frank Herbert", "[Frank Herbert]"},
+	}
+	for i, book := range books {
+		var stored models.Book
+		db.First(&stored, book.ID)
+		if stored.Author != want[i].author || fmt.Sprint(stored.Authors) != want[i].authors {
+			t.Errorf("%s: stored author %q, authors %q, want %q, %s", book.Title, stored.Author, stored.Authors, want[i].author, want[i].authors)
+		}
+
+		// The stale cached copy was dropped, so reading the book caches the
+		// new authors
+		testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(book.ID), "")
+		data, err := redis.BookCache.Get(context.Background(), "book:"+itoa(book.ID))
+		var cached models.Book
+		if err != nil || json.Unmarshal([]byte(data), &cached) != nil {
+			t.Fatalf("%s: cached %q, %v, want the book", book.Title, data, err)
+		}
+		if cached.Author != want[i].author || fmt.Sprint(cached.Authors) != want[i].authors {
+			t.Errorf("%s: cached author %q, authors %q, want %q, %s", book.Title, cached.Author, cached.Authors, want[i].author, want[i].authors)
+		}
+	}
+}
@@ -0,0 +1,470 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+	"golang.org/x/sync/singleflight"
+)
+
+// cacheFills collapses concurrent loads of the same cache key
+var cacheFills singleflight.Group
+
+// loadOnce runs load for key unless a load for the same key is already in
+// flight, in which case it waits for that one and shares its result. This
+// keeps a burst of identical requests on a cold key to a single query.
+func loadOnce[T any](key string, load func() (T, error)) (T, error) {
+	value, err, _ := cacheFills.Do(key, func() (interface{}, error) {
+		return load()
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+// bypassCache reports whether the request asked to skip cached data, with
+// Cache-Control: no-cache or ?nocache=true. The fresh result is still cached.
+func bypassCache(ctx *gin.Context) bool {
+	if nocache, _ := strconv.ParseBool(ctx.Query("nocache")); nocache {
+		return true
+	}
+	for _, directive := range strings.Split(ctx.GetHeader("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+	return false
+}
+
+// Cache read metrics, served on /debug/vars and summed up by GET
+// /admin/overview. Requests that bypass the cache and failed reads count as
+// neither.
+var (
+	cacheHits   = expvar.NewInt("cache_hits")
+	cacheMisses = expvar.NewInt("cache_misses")
+)
+
+// recordCacheReads counts the outcome of cache lookups
+func recordCacheReads(hits, misses int) {
+	cacheHits.Add(int64(hits))
+	cacheMisses.Add(int64(misses))
+}
+
+// readCache returns the cached value of key, or a miss when the request
+// bypasses the cache
+func readCache(ctx *gin.Context, key string) (string, error) {
+	if bypassCache(ctx) {
+		return "", redis.ErrCacheMiss
+	}
+	value, err := redis.BookCache.Get(context.Background(), key)
+	switch {
+	case err == nil:
+		recordCacheReads(1, 0)
+	case errors.Is(err, redis.ErrCacheMiss):
+		recordCacheReads(0, 1)
+	}
+	return value, err
+}
+
+// readCachedBook decodes the book cached under key into book. A value that
+// doesn't decode, e.g. after a partial write, is logged and evicted so the
+// caller falls through to the database and caches a good copy.
+func readCachedBook(ctx *gin.Context, key string, book *models.Book) bool {
+	cached, err := readCache(ctx, key)
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(cached), book); err != nil {
+		log.Printf("Evicting corrupt cache entry %s: %v", key, err)
+		redis.BookCache.Del(context.Background(), key)
+		*book = models.Book{}
+		return false
+	}
+	return true
+}
+
+// listInvalidation coalesces list cache invalidations during write bursts
+var listInvalidation = &debouncer{}
+
+// statsInvalidation coalesces stats cache invalidations separately, as
+// statistics are costly to recompute and can stay stale a little longer
+var statsInvalidation = &debouncer{}
+
+// invalidationDebounce returns the window list invalidations are coalesced
+// over, configurable through CACHE_INVALIDATION_DEBOUNCE (e.g. "500ms").
+// Zero, the default, invalidates immediately.
+func invalidationDebounce() time.Duration {
+	window, err := time.ParseDuration(os.Getenv("CACHE_INVALIDATION_DEBOUNCE"))
+	if err != nil || window < 0 {
+		return 0
+	}
+	return window
+}
+
+// statsInvalidationDebounce returns the window stats invalidations are
+// coalesced over, configurable through STATS_INVALIDATION_DEBOUNCE. It
+// defaults to CACHE_INVALIDATION_DEBOUNCE.
+func statsInvalidationDebounce() time.Duration {
+	window, err := time.ParseDuration(os.Getenv("STATS_INVALIDATION_DEBOUNCE"))
+	if err != nil || window < 0 {
+		return invalidationDebounce()
+	}
+	return window
+}
+
+// invalidateDerivedCaches bumps the collection version and schedules dropping
+// the cached lists and stats once their debounce windows pass without
+// further writes
+func invalidateDerivedCaches() {
+	bumpCollectionVersion()
+	listInvalidation.trigger(invalidationDebounce(), invalidateListCache)
+	statsInvalidation.trigger(statsInvalidationDebounce(), invalidateStatsCache)
+}
+
+// invalidateBookCache drops the cached list, every list-derived key (sparse
+// variants, counts), the stats and, when book is set, every cached variant of
+// that book. The single book is dropped right away; the list and stats keys
+// are dropped once their debounce windows pass without further writes.
+func invalidateBookCache(book *models.Book) {
+	if book != nil {
+		id := strconv.FormatUint(uint64(book.ID), 10)
+		redis.BookCache.Del(context.Background(), "book:"+id, slugCacheKey(book.Slug))
+		if book.ISBN != nil {
+			redis.BookCache.Del(context.Background(), isbnCacheKey(*book.ISBN))
+		}
+		redis.BookCache.DeletePattern(context.Background(), "book:"+id+":fields=*")
+		markBookFresh(book.ID)
+	}
+	invalidateDerivedCaches()
+}
+
+// Cache write modes, chosen with CACHE_WRITE_MODE. Write-through stores a
+// written book under its keys right away, so the next read is a hit, at the
+// cost of extra cache writes on every write request. Cache-aside only drops
+// the keys and lets the next read load the book; writes are cheaper and the
+// cache never holds a book nobody read, but the first read after a write
+// always misses.
+const (
+	cacheWriteThrough = "write-through"
+	cacheAside        = "cache-aside"
+)
+
+// ValidateCacheWriteMode checks CACHE_WRITE_MODE so a typo fails at startup
+// instead of silently selecting the default
+func ValidateCacheWriteMode() error {
+	_, err := cacheWriteModeSetting()
+	return err
+}
+
+// writeThrough reports whether written books are cached right away, the
+// default
+func writeThrough() bool {
+	mode, err := cacheWriteModeSetting()
+	return err != nil || mode == cacheWriteThrough
+}
+
+func cacheWriteModeSetting() (string, error) {
+	switch mode := os.Getenv("CACHE_WRITE_MODE"); mode {
+	case "":
+		return cacheWriteThrough, nil
+	case cacheWriteThrough, cacheAside:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("CACHE_WRITE_MODE must be %s or %s, got %q", cacheWriteThrough, cacheAside, mode)
+	}
+}
+
+// cacheCreatedBook updates the cache after book was created: in write-through
+// mode the book is cached, in cache-aside mode only the lists are dropped
+func cacheCreatedBook(book models.Book) {
+	if writeThrough() {
+		refreshBookCache(book)
+		return
+	}
+	markBookFresh(book.ID)
+	invalidateBookCache(nil)
+}
+
+// cacheUpdatedBook updates the cache after book was changed: in write-through
+// mode its keys get the new version, in cache-aside mode they are dropped
+func cacheUpdatedBook(book models.Book) {
+	if writeThrough() {
+		refreshBookCache(book)
+		return
+	}
+	invalidateBookCache(&book)
+}
+
+// refreshBookCache writes a created or updated book through to its id, slug
+// and ISBN keys instead of deleting them, so the next read is a hit with fresh
+// data. Sparse variants, list and stats keys are dropped as in
+// invalidateBookCache.
+func refreshBookCache(book models.Book) {
+	// Stored timestamps only have microsecond precision; match them so the
+	// cached copy equals what a reload would return
+	book.CreatedAt = book.CreatedAt.Truncate(time.Microsecond)
+	book.UpdatedAt = book.UpdatedAt.Truncate(time.Microsecond)
+	data, _ := json.Marshal(book)
+
+	id := strconv.FormatUint(uint64(book.ID), 10)
+	cacheBook(id, "book:"+id, data)
+	cacheBook(id, slugCacheKey(book.Slug), data)
+	if book.ISBN != nil {
+		cacheBook(id, isbnCacheKey(*book.ISBN), data)
+	}
+	redis.BookCache.DeletePattern(context.Background(), "book:"+id+":fields=*")
+	markBookFresh(book.ID)
+	invalidateDerivedCaches()
+}
+
+// freshnessWindow returns how long reads of a written book bypass the cache
+// and the read replica, configurable through READ_YOUR_WRITES_WINDOW (e.g.
+// "5s"). Zero, the default, turns this off.
+func freshnessWindow() time.Duration {
+	window, err := time.ParseDuration(os.Getenv("READ_YOUR_WRITES_WINDOW"))
+	if err != nil || window < 0 {
+		return 0
+	}
+	return window
+}
+
+func freshKey(id string) string {
+	return "book:" + id + ":fresh"
+}
+
+// markBookFresh records that the book was just written. Until the marker
+// expires, reads by id load the book from the primary, so a client reading
+// its own write can't get a copy from a lagging replica or one cached from
+// it before the write.
+func markBookFresh(bookID uint) {
+	if window := freshnessWindow(); window > 0 {
+		id := strconv.FormatUint(uint64(bookID), 10)
+		redis.BookCache.Set(context.Background(), freshKey(id), "1", window)
+	}
+}
+
+// recentlyWritten reports whether the book id was written within the
+// freshness window
+func recentlyWritten(id string) bool {
+	if freshnessWindow() <= 0 {
+		return false
+	}
+	_, err := redis.BookCache.Get(context.Background(), freshKey(id))
+	return err == nil
+}
+
+// bookTombstoneTTL is how long a deleted book is remembered. It only has to
+// outlast the reads that were already in flight when it was deleted.
+const bookTombstoneTTL = time.Minute
+
+func tombstoneKey(id string) string {
+	return "book:" + id + ":deleted"
+}
+
+// markBookDeleted leaves a tombstone for a deleted book. It must be called
+// before the book's cache entries are dropped.
+func markBookDeleted(book models.Book) {
+	id := strconv.FormatUint(uint64(book.ID), 10)
+	redis.BookCache.Set(context.Background(), tombstoneKey(id), "1", bookTombstoneTTL)
+}
+
+// cacheBook caches data, a copy of the book id, under key. A read racing a
+// delete can load the row just before it is deleted and cache it just after
+// the delete dropped the cache. The tombstone is therefore checked after
+// writing: either the write came before the delete's invalidation, which
+// removes it, or the tombstone was already there and the write is undone.
+func cacheBook(id, key string, data []byte) {
+	redis.BookCache.Set(context.Background(), key, data, cacheTTL("book"))
+	if _, err := redis.BookCache.Get(context.Background(), tombstoneKey(id)); err == nil {
+		redis.BookCache.Del(context.Background(), key)
+	}
+}
+
+// hydrateFromLists reports whether list fetches warm the single-book cache.
+// It is on unless CACHE_HYDRATE_BOOKS=false.
+func hydrateFromLists() bool {
+	return os.Getenv("CACHE_HYDRATE_BOOKS") != "false"
+}
+
+// hydrateBookCache caches each book of a freshly loaded list under its
+// book:<id> key, so opening one of the listed books is a cache hit. It runs
+// in the background after the response: one MGET finds the keys that are
+// already cached, which are left alone since a write may have refreshed
+// them since the list was loaded, and one pipelined write fills the rest.
+// Books deleted in the meantime are dropped again as in cacheBook.
+func hydrateBookCache(books []models.Book) {
+	if len(books) == 0 || !hydrateFromLists() {
+		return
+	}
+	// The cache is picked before the request ends, not when the goroutine
+	// gets to run
+	cache := redis.BookCache
+	go func() {
+		keys := make([]string, len(books))
+		for i, book := range books {
+			keys[i] = "book:" + strconv.FormatUint(uint64(book.ID), 10)
+		}
+		cached, err := cache.MGet(context.Background(), keys...)
+		if err != nil {
+			return
+		}
+
+		values := make(map[string]interface{}, len(books))
+		var ids []string
+		for i, book := range books {
+			if cached[i] != "" {
+				continue
+			}
+			data, _ := json.Marshal(book)
+			values[keys[i]] = data
+			ids = append(ids, strconv.FormatUint(uint64(book.ID), 10))
+		}
+		if err := cache.MSet(context.Background(), values, cacheTTL("book")); err != nil {
+			log.Printf("Failed to hydrate %d cached books: %v", len(values), err)
+			return
+		}
+
+		tombstones := make([]string, len(ids))
+		for i, id := range ids {
+			tombstones[i] = tombstoneKey(id)
+		}
+		deleted, _ := cache.MGet(context.Background(), tombstones...)
+		for i, tombstone := range deleted {
+			if tombstone != "" {
+				cache.Del(context.Background(), "book:"+ids[i])
+			}
+		}
+	}()
+}
+
+// slugCacheKey is keyed by the canonical slug, so differently cased lookups
+// share one entry
+func slugCacheKey(slug string) string {
+	return "book:slug:" + models.NormalizeSlug(slug)
+}
+
+// listCacheTag groups every cached list variant (pages, filters, sorts,
+// sparse fields, counts, authors) so a write can drop all of them at once
+const listCacheTag = "tag:books"
+
+// defaultMaxCacheValueBytes keeps single cache values small enough that
+// reading one doesn't stall Redis or the request
+const defaultMaxCacheValueBytes = 1 << 20
+
+// cacheValuesSkipped counts list values too large to cache, served on
+// /debug/vars
+var cacheValuesSkipped = expvar.NewInt("cache_values_skipped")
+
+// ValidateCacheValueSize checks MAX_CACHE_VALUE_BYTES so a bad value fails at
+// startup instead of being silently replaced by the default
+func ValidateCacheValueSize() error {
+	_, err := positiveEnvInt("MAX_CACHE_VALUE_BYTES", defaultMaxCacheValueBytes)
+	return err
+}
+
+// maxCacheValueBytes returns the largest list value that is cached, from
+// MAX_CACHE_VALUE_BYTES, which defaults to 1 MiB
+func maxCacheValueBytes() int {
+	size, err := positiveEnvInt("MAX_CACHE_VALUE_BYTES", defaultMaxCacheValueBytes)
+	if err != nil {
+		return defaultMaxCacheValueBytes
+	}
+	return size
+}
+
+// cacheListResult caches a list-derived value and records its key under
+// listCacheTag. A value larger than MAX_CACHE_VALUE_BYTES is not cached and
+// is served from the database every time instead; a warning is logged so
+// the limit or the page size can be adjusted.
+func cacheListResult(key string, value interface{}, ttl time.Duration) {
+	if data, ok := value.([]byte); ok && len(data) > maxCacheValueBytes() {
+		log.Printf("Not caching %s: %d bytes exceeds MAX_CACHE_VALUE_BYTES (%d)", key, len(data), maxCacheValueBytes())
+		cacheValuesSkipped.Add(1)
+		return
+	}
+	if err := redis.BookCache.Set(context.Background(), key, value, ttl); err != nil {
+		log.Printf("Failed to cache %s: %v", key, err)
+		return
+	}
+	redis.BookCache.Tag(context.Background(), listCacheTag, key)
+}
+
+// invalidateListCache drops every cached list. The collection version is
+// bumped again, since lists served from the cache during the debounce window
+// went out under the version of the write.
+func invalidateListCache() {
+	redis.BookCache.InvalidateTag(context.Background(), listCacheTag)
+	bumpCollectionVersion()
+}
+
+// statsCacheTag groups the cached statistics, which are invalidated on their
+// own debounce schedule
+const statsCacheTag = "tag:stats"
+
+// cacheStatsResult caches computed statistics and records the key under
+// statsCacheTag
+func cacheStatsResult(key string, value interface{}, ttl time.Duration) {
+	redis.BookCache.Set(context.Background(), key, value, ttl)
+	redis.BookCache.Tag(context.Background(), statsCacheTag, key)
+}
+
+func invalidateStatsCache() {
+	redis.BookCache.InvalidateTag(context.Background(), statsCacheTag)
+}
+
+// debouncer runs the latest triggered function once no trigger has happened
+// for the window. A burst never delays it more than maxDebounceWindows
+// windows past the first trigger, so the list can't stay stale indefinitely.
+type debouncer struct {
+	mu           sync.Mutex
+	timer        *time.Timer
+	pendingSince time.Time
+}
+
+const maxDebounceWindows = 10
+
+func (d *debouncer) trigger(window time.Duration, fn func()) {
+	if window <= 0 {
+		fn()
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if d.timer == nil {
+		d.pendingSince = now
+	} else {
+		d.timer.Stop()
+	}
+
+	delay := window
+	if deadline := d.pendingSince.Add(window * maxDebounceWindows); now.Add(delay).After(deadline) {
+		delay = deadline.Sub(now)
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		if d.timer == timer {
+			d.timer = nil
+		}
+		d.mu.Unlock()
+		fn()
+	})
+	d.timer = timer
+}
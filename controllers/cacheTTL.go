@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheTTLDefaults is how long each kind of cached response is kept, by
+// name. Zero keeps it until a write invalidates it. CACHE_TTL_<NAME>
+// overrides a default, e.g. CACHE_TTL_STATS=10m.
+var cacheTTLDefaults = map[string]time.Duration{
+	// Single books are dropped on every write through the API; the TTL bounds
+	// how long a book deleted or edited directly in the database is served
+	"book": 10 * time.Minute,
+	// List pages are dropped on every write
+	"list": 0,
+	// Counts are short-lived; writes also invalidate them
+	"count":      30 * time.Second,
+	"recent":     time.Minute,
+	"authors":    time.Minute,
+	"publishers": time.Minute,
+	// The aggregates scan the whole table, so they are kept for a while
+	"stats": 5 * time.Minute,
+}
+
+// ValidateCacheTTLs checks every CACHE_TTL_<NAME> so a bad value fails at
+// startup instead of being silently replaced by the default
+func ValidateCacheTTLs() error {
+	names := make([]string, 0, len(cacheTTLDefaults))
+	for name := range cacheTTLDefaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := parseCacheTTL(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheTTL returns the TTL for the cached response called name
+func cacheTTL(name string) time.Duration {
+	ttl, err := parseCacheTTL(name)
+	if err != nil {
+		return cacheTTLDefaults[name]
+	}
+	return ttl
+}
+
+func parseCacheTTL(name string) (time.Duration, error) {
+	key := "CACHE_TTL_" + strings.ToUpper(name)
+	raw := os.Getenv(key)
+	if raw == "" {
+		return cacheTTLDefaults[name], nil
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("%s must be a duration of zero or more, got %q", key, raw)
+	}
+	return ttl, nil
+}
@@ -0,0 +1,517 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+	"github.com/rohans540/books-backend/testutil"
+	"gorm.io/gorm"
+)
+
+func TestDebouncerCoalescesRapidWrites(t *testing.T) {
+	var d debouncer
+	var calls atomic.Int32
+	invalidate := func() { calls.Add(1) }
+
+	// Rapid sequential updates, each well inside the window
+	for i := 0; i < 5; i++ {
+		d.trigger(30*time.Millisecond, invalidate)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("invalidated %d times during the burst, want 0", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("invalidated %d times after the burst settled, want 1", n)
+	}
+}
+
+func TestDebouncerWithoutWindowRunsImmediately(t *testing.T) {
+	var d debouncer
+	calls := 0
+	for i := 0; i < 3; i++ {
+		d.trigger(0, func() { calls++ })
+	}
+	if calls != 3 {
+		t.Errorf("invalidated %d times, want every write to invalidate", calls)
+	}
+}
+
+func TestDebouncerFiresDuringEndlessBurst(t *testing.T) {
+	var d debouncer
+	var calls atomic.Int32
+	window := 5 * time.Millisecond
+
+	// Writes keep arriving for longer than maxDebounceWindows windows
+	deadline := time.Now().Add(window * (maxDebounceWindows + 5))
+	for time.Now().Before(deadline) {
+		d.trigger(window, func() { calls.Add(1) })
+		time.Sleep(window / 5)
+	}
+	if calls.Load() == 0 {
+		t.Error("a steady stream of writes postponed the invalidation indefinitely")
+	}
+}
+
+// listKeys returns the cached list variants: every "books..." key but the
+// collection version
+func listKeys(t *testing.T) []string {
+	t.Helper()
+	infos, err := redis.BookCache.Keys(context.Background(), "books*", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, info := range infos {
+		if info.Key != collectionVersionKey {
+			keys = append(keys, info.Key)
+		}
+	}
+	return keys
+}
+
+func TestCreateBookInvalidatesEveryListVariant(t *testing.T) {
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	testutil.SetupPublisher(t)
+	router := testutil.NewRouter()
+	router.GET("/books", GetBooks)
+	router.POST("/books", CreateBook)
+	testutil.SeedBooks(t, db, models.Book{Title: "Dune", Year: 1965}, models.Book{Title: "Neuromancer", Year: 1984})
+
+	for _, path := range []string{"/books", "/books?limit=1&offset=1", "/books?sort=-year", "/books?language=en", "/books?fields=id,title", "/books?after_id=1"} {
+		if w := testutil.Request(router, http.MethodGet, path, ""); w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d: %s", path, w.Code, w.Body)
+		}
+	}
+	if keys := listKeys(t); len(keys) < 6 {
+		t.Fatalf("cached lists = %q, want every variant cached", keys)
+	}
+
+	w := testutil.Request(router, http.MethodPost, "/books", `{"title": "Foundation", "author": "Isaac Asimov", "year": 1951}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+	}
+	if keys := listKeys(t); len(keys) != 0 {
+		t.Errorf("cached lists after create = %q, want none", keys)
+	}
+}
+
+func TestLargeListIsNotCached(t *testing.T) {
+	t.Setenv("MAX_CACHE_VALUE_BYTES", "2000")
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/books", GetBooks)
+	books := make([]models.Book, 50)
+	for i := range books {
+		books[i] = models.Book{Title: "Book " + strconv.Itoa(i+1), Author: "Author", Year: 2000}
+	}
+	testutil.SeedBooks(t, db, books...)
+	skipped := cacheValuesSkipped.Value()
+
+	for _, path := range []string{"/books?limit=2", "/books?limit=50"} {
+		var got []models.Book
+		w := testutil.Request(router, http.MethodGet, path, "")
+		json.Unmarshal(w.Body.Bytes(), &got)
+		if w.Code != http.StatusOK || len(got) == 0 {
+			t.Fatalf("GET %s: status = %d with %d books: %.200s", path, w.Code, len(got), w.Body)
+		}
+	}
+	pages, _ := redis.BookCache.Keys(context.Background(), "books*limit=*", 10)
+	if len(pages) != 1 || !strings.Contains(pages[0].Key, "limit=2&") {
+		t.Errorf("cached pages = %+v, want only the small one", pages)
+	}
+	if n := cacheValuesSkipped.Value() - skipped; n != 1 {
+		t.Errorf("cache_values_skipped grew by %d, want 1", n)
+	}
+
+	// Still served in full, from the database
+	var got []models.Book
+	json.Unmarshal(testutil.Request(router, http.MethodGet, "/books?limit=50", "").Body.Bytes(), &got)
+	if len(got) != 50 {
+		t.Errorf("large page has %d books, want 50", len(got))
+	}
+}
+
+func TestCacheKeysCarryPrefix(t *testing.T) {
+	db := testutil.SetupDB(t)
+	shared := redis.NewMemoryCache()
+	testutil.UseCache(t, redis.WithPrefix(shared, "staging"))
+	testutil.SetupPublisher(t)
+	router := testutil.NewRouter()
+	router.GET("/books", GetBooks)
+	router.GET("/books/:id", GetBookByID)
+	router.POST("/books", CreateBook)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Year: 1965})[0]
+
+	testutil.Request(router, http.MethodGet, "/books", "")
+	testutil.Request(router, http.MethodGet, "/books/"+strconv.FormatUint(uint64(book.ID), 10), "")
+	testutil.Request(router, http.MethodPost, "/books", `{"title": "Foundation", "author": "Isaac Asimov", "year": 1951}`)
+
+	infos, _ := shared.Keys(context.Background(), "*", 100)
+	if len(infos) == 0 {
+		t.Fatal("nothing was cached")
+	}
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Key, "staging:") {
+			t.Errorf("key %q is missing the staging: prefix", info.Key)
+		}
+	}
+}
+
+func TestConcurrentMissesQueryOnce(t *testing.T) {
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/books/:id", GetBookByID)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Year: 1965})[0]
+
+	// Slow book queries down so the requests overlap, and count them
+	var queries atomic.Int32
+	db.Callback().Query().Before("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		if tx.Statement.Table == "books" {
+			queries.Add(1)
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := testutil.Request(router, http.MethodGet, "/books/"+strconv.FormatUint(uint64(book.ID), 10), "")
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d: %s", w.Code, w.Body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("%d database queries for 10 concurrent misses, want 1", n)
+	}
+}
+
+func TestWriteThroughServesUpdatedBookFromCache(t *testing.T) {
+	for _, mode := range []string{cacheWriteThrough, cacheAside} {
+		t.Run(mode, func(t *testing.T) {
+			t.Setenv("CACHE_WRITE_MODE", mode)
+			db := testutil.SetupDB(t)
+			testutil.SetupCache(t)
+			testutil.SetupPublisher(t)
+			router := testutil.NewRouter()
+			router.GET("/books/:id", GetBookByID)
+			router.PUT("/books/:id", UpdateBook)
+			book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+			path := "/books/" + strconv.FormatUint(uint64(book.ID), 10)
+			testutil.Request(router, http.MethodGet, path, "")
+
+			w := testutil.Request(router, http.MethodPut, path, `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("update: status = %d: %s", w.Code, w.Body)
+			}
+			// Only a cached copy still has the updated title after this
+			db.Model(&models.Book{}).Where("id = ?", book.ID).Update("title", "Changed in the database")
+
+			var got models.Book
+			json.Unmarshal(testutil.Request(router, http.MethodGet, path, "").Body.Bytes(), &got)
+			want := "Dune Messiah"
+			if mode == cacheAside {
+				want = "Changed in the database"
+			}
+			if got.Title != want {
+				t.Errorf("GET after update: title = %q, want %q", got.Title, want)
+			}
+		})
+	}
+}
+
+func TestWriteThroughServesCreatedBookFromCache(t *testing.T) {
+	for _, mode := range []string{cacheWriteThrough, cacheAside} {
+		t.Run(mode, func(t *testing.T) {
+			t.Setenv("CACHE_WRITE_MODE", mode)
+			db := testutil.SetupDB(t)
+			testutil.SetupCache(t)
+			testutil.SetupPublisher(t)
+			router := testutil.NewRouter()
+			router.GET("/books/:id", GetBookByID)
+			router.POST("/books", CreateBook)
+
+			w := testutil.Request(router, http.MethodPost, "/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+			}
+			var created models.Book
+			json.Unmarshal(w.Body.Bytes(), &created)
+			// Only a cached copy still has the created title after this
+			db.Model(&models.Book{}).Where("id = ?", created.ID).Update("title", "Changed in the database")
+
+			var got models.Book
+			path := "/books/" + strconv.FormatUint(uint64(created.ID), 10)
+			json.Unmarshal(testutil.Request(router, http.MethodGet, path, "").Body.Bytes(), &got)
+			want := "Dune"
+			if mode == cacheAside {
+				want = "Changed in the database"
+			}
+			if got.Title != want || got.Author != "Frank Herbert" || got.Year != 1965 {
+				t.Errorf("GET after create = %q by %q (%d), want %q by Frank Herbert (1965)", got.Title, got.Author, got.Year, want)
+			}
+		})
+	}
+}
+
+func TestBypassCache(t *testing.T) {
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/books", GetBooks)
+	router.GET("/books/:id", GetBookByID)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+	path := "/books/" + strconv.FormatUint(uint64(book.ID), 10)
+
+	for _, p := range []string{path, "/books"} {
+		t.Run(p, func(t *testing.T) {
+			db.Model(&models.Book{}).Where("id = ?", book.ID).Update("title", "Dune")
+			testutil.Request(router, http.MethodGet, p, "")
+			// Only the database has the new title; the cache still has the old one
+			db.Model(&models.Book{}).Where("id = ?", book.ID).Update("title", "Dune Messiah")
+			if body := testutil.Request(router, http.MethodGet, p, "").Body.String(); !strings.Contains(body, `"Dune"`) {
+				t.Fatalf("cached read = %s, want the cached title", body)
+			}
+
+			bypasses := []struct{ query, header string }{
+				{"?nocache=true", ""},
+				{"", "no-cache"},
+				{"", "max-age=0, No-Cache"},
+			}
+			for _, bypass := range bypasses {
+				var headers []string
+				if bypass.header != "" {
+					headers = []string{"Cache-Control", bypass.header}
+				}
+				if body := testutil.Request(router, http.MethodGet, p+bypass.query, "", headers...).Body.String(); !strings.Contains(body, "Dune Messiah") {
+					t.Errorf("read with %q%q = %s, want the database title", bypass.query, bypass.header, body)
+				}
+			}
+
+			// The bypassing reads refreshed the cache
+			if body := testutil.Request(router, http.MethodGet, p, "").Body.String(); !strings.Contains(body, "Dune Messiah") {
+				t.Errorf("cached read after a bypass = %s, want the refreshed title", body)
+			}
+		})
+	}
+}
+
+func TestDeletedBookIsNotRecached(t *testing.T) {
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	testutil.SetupPublisher(t)
+	router := testutil.NewRouter()
+	router.GET("/books/:id", GetBookByID)
+	router.DELETE("/books/:id", DeleteBook)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+	id := strconv.FormatUint(uint64(book.ID), 10)
+
+	// Hold the read after it loaded the row and before it caches it, so the
+	// delete runs in between
+	loaded, release := make(chan struct{}), make(chan struct{})
+	var paused atomic.Bool
+	db.Callback().Query().After("gorm:query").Register("test:pause", func(tx *gorm.DB) {
+		if tx.Statement.Table == "books" && paused.CompareAndSwap(false, true) {
+			close(loaded)
+			<-release
+		}
+	})
+
+	read := make(chan int)
+	go func() { read <- testutil.Request(router, http.MethodGet, "/books/"+id, "").Code }()
+	<-loaded
+	if w := testutil.Request(router, http.MethodDelete, "/books/"+id, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d: %s", w.Code, w.Body)
+	}
+	close(release)
+	if code := <-read; code != http.StatusOK {
+		t.Fatalf("racing read: status = %d, want the row it loaded", code)
+	}
+
+	if _, err := redis.BookCache.Get(context.Background(), "book:"+id); err == nil {
+		t.Error("the racing read re-cached the deleted book")
+	}
+	if w := testutil.Request(router, http.MethodGet, "/books/"+id, ""); w.Code != http.StatusNotFound {
+		t.Errorf("read after the delete: status = %d, want 404", w.Code)
+	}
+}
+
+// waitForCache polls until key is cached, for writes done in the background
+func waitForCache(t *testing.T, key string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if _, err := redis.BookCache.Get(context.Background(), key); err == nil {
+			return
+		}
+	}
+	t.Fatalf("%s was never cached", key)
+}
+
+func TestListFetchHydratesBookCache(t *testing.T) {
+	db := testutil.SetupDB(t)
+	cache := testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/books", GetBooks)
+	router.GET("/books/:id", GetBookByID)
+	books := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Year: 1965}, models.Book{Title: "Emma", Year: 1815})
+
+	if w := testutil.Request(router, http.MethodGet, "/books", ""); w.Code != http.StatusOK {
+		t.Fatalf("list: status = %d: %s", w.Code, w.Body)
+	}
+	id := strconv.FormatUint(uint64(books[1].ID), 10)
+	waitForCache(t, "book:"+id)
+
+	keys, _ := cache.Keys(context.Background(), "book:"+id, 1)
+	if len(keys) != 1 || keys[0].TTL <= 0 || keys[0].TTL > cacheTTL("book") {
+		t.Errorf("hydrated entry = %+v, want the book TTL", keys)
+	}
+
+	var queries atomic.Int32
+	db.Callback().Query().Before("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		if tx.Statement.Table == "books" {
+			queries.Add(1)
+		}
+	})
+	w := testutil.Request(router, http.MethodGet, "/books/"+id, "")
+	var got models.Book
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || got.Title != "Emma" {
+		t.Fatalf("GET listed book: status = %d, body %s", w.Code, w.Body)
+	}
+	if n := queries.Load(); n != 0 {
+		t.Errorf("%d database queries for a listed book, want it served from cache", n)
+	}
+}
+
+func TestListFetchHydrationCanBeDisabled(t *testing.T) {
+	t.Setenv("CACHE_HYDRATE_BOOKS", "false")
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/books", GetBooks)
+	testutil.SeedBooks(t, db, models.Book{Title: "Dune", Year: 1965})
+
+	testutil.Request(router, http.MethodGet, "/books", "")
+	time.Sleep(20 * time.Millisecond)
+	if keys, _ := redis.BookCache.Keys(context.Background(), "book:*", 10); len(keys) != 0 {
+		t.Errorf("cached %v, want no book entries", keys)
+	}
+}
+
+// errNodeDown is what failingCache returns for the keys on its failing node
+var errNodeDown = errors.New("CLUSTERDOWN node unreachable")
+
+// failingCache fails every operation on the failing keys, like a Redis
+// Cluster with one node down, and passes the rest to Cache
+type failingCache struct {
+	redis.Cache
+	failing map[string]bool
+}
+
+func (c *failingCache) Get(ctx context.Context, key string) (string, error) {
+	if c.failing[key] {
+		return "", errNodeDown
+	}
+	return c.Cache.Get(ctx, key)
+}
+
+func (c *failingCache) MGet(ctx context.Context, keys ...string) ([]string, error) {
+	values, err := c.Cache.MGet(ctx, keys...)
+	if err != nil {
+		return values, err
+	}
+	var errs []error
+	for i, key := range keys {
+		if c.failing[key] {
+			values[i] = ""
+			errs = append(errs, errNodeDown)
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+func (c *failingCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if c.failing[key] {
+		return errNodeDown
+	}
+	return c.Cache.Set(ctx, key, value, ttl)
+}
+
+func (c *failingCache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	healthy := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if !c.failing[key] {
+			healthy[key] = value
+		}
+	}
+	if err := c.Cache.MSet(ctx, healthy, ttl); err != nil {
+		return err
+	}
+	if len(healthy) < len(values) {
+		return errNodeDown
+	}
+	return nil
+}
+
+func TestFailingCacheKeysFallBackToDatabase(t *testing.T) {
+	db := testutil.SetupDB(t)
+	testutil.UseCache(t, &failingCache{Cache: redis.NewMemoryCache(), failing: map[string]bool{"book:2": true}})
+	router := testutil.NewRouter()
+	router.GET("/books", GetBooks)
+	router.GET("/books/:id", GetBookByID)
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965},
+		models.Book{Title: "Neuromancer", Author: "William Gibson", Year: 1984},
+		models.Book{Title: "Hyperion", Author: "Dan Simmons", Year: 1989},
+	)
+	for _, book := range books {
+		path := "/books/" + strconv.FormatUint(uint64(book.ID), 10)
+		if w := testutil.Request(router, http.MethodGet, path, ""); w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d: %s", path, w.Code, w.Body)
+		}
+	}
+	// Only copies cached on the healthy keys keep the old titles
+	db.Model(&models.Book{}).Where("1 = 1").Update("title", "Changed in the database")
+	want := []string{"Dune", "Changed in the database", "Hyperion"}
+
+	for i, book := range books {
+		var got models.Book
+		w := testutil.Request(router, http.MethodGet, "/books/"+strconv.FormatUint(uint64(book.ID), 10), "")
+		json.Unmarshal(w.Body.Bytes(), &got)
+		if w.Code != http.StatusOK || got.Title != want[i] {
+			t.Errorf("GET book %d: status = %d, title %q, want 200 and %q", book.ID, w.Code, got.Title, want[i])
+		}
+	}
+
+	var got []models.Book
+	w := testutil.Request(router, http.MethodGet, "/books?ids=1,2,3", "")
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || len(got) != 3 {
+		t.Fatalf("GET ?ids=1,2,3: status = %d: %s", w.Code, w.Body)
+	}
+	for i, book := range got {
+		if book.Title != want[i] {
+			t.Errorf("GET ?ids=1,2,3: book %d = %q, want %q", book.ID, book.Title, want[i])
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errPreconditionFailed is returned inside a write transaction when the
+// request's If-Match doesn't match the book; it is answered with 412
+var errPreconditionFailed = errors.New("precondition failed")
+
+// bookETag identifies a version of a book. updated_at changes on every write
+// and is stored with microsecond precision, so that is what the tag uses.
+func bookETag(book models.Book) string {
+	return `"` + strconv.FormatInt(book.UpdatedAt.UnixMicro(), 36) + `"`
+}
+
+// setBookETag sets the ETag of a full book representation and returns it, or
+// "" when the book has no timestamp to derive it from
+func setBookETag(ctx *gin.Context, book models.Book) string {
+	if book.UpdatedAt.IsZero() {
+		return ""
+	}
+	etag := bookETag(book)
+	ctx.Header("ETag", etag)
+	return etag
+}
+
+// checkIfMatch enforces the request's If-Match header inside a write
+// transaction: it locks the book's row and returns errPreconditionFailed
+// unless one of the listed tags (or "*") matches its current version.
+// Without the header it does nothing.
+func checkIfMatch(ctx *gin.Context, tx *gorm.DB, id uint) error {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	var current models.Book
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "updated_at").First(&current, id).Error
+	if err != nil {
+		return err
+	}
+	if etagListed(header, bookETag(current)) {
+		return nil
+	}
+	return errPreconditionFailed
+}
+
+// etagListed reports whether the comma-separated tags of an If-Match or
+// If-None-Match header include etag or are "*"
+func etagListed(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// A gzipped response carries the weak form of the same tag
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondPreconditionFailed answers 412, telling the client to fetch the book
+// again before retrying
+func respondPreconditionFailed(ctx *gin.Context) {
+	render(ctx, http.StatusPreconditionFailed, gin.H{"error": "Book has changed since it was fetched"})
+}
+
+// notModified sets Last-Modified to modified and answers 304 when the
+// request's If-None-Match lists etag or, without If-None-Match, when its
+// If-Modified-Since is not older than modified. It returns true when the 304
+// was sent and the body must be skipped. An empty etag (a sparse
+// representation) never matches, and a zero modified time (e.g. a book
+// cached before it had timestamps) disables Last-Modified.
+func notModified(ctx *gin.Context, etag string, modified time.Time) bool {
+	if !modified.IsZero() {
+		// HTTP dates have second precision
+		modified = modified.UTC().Truncate(time.Second)
+		ctx.Header("Last-Modified", modified.Format(http.TimeFormat))
+	}
+
+	// If-None-Match takes precedence when a client sends both, and
+	// If-Modified-Since is then ignored (RFC 9110 13.2.2)
+	if header := ctx.GetHeader("If-None-Match"); header != "" {
+		if etag == "" || !etagListed(header, etag) {
+			return false
+		}
+		ctx.Status(http.StatusNotModified)
+		return true
+	}
+	if modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ctx.GetHeader("If-Modified-Since"))
+	if err != nil || modified.After(since) {
+		return false
+	}
+	ctx.Status(http.StatusNotModified)
+	return true
+}
+
+// collectionVersionKey holds the version of the whole collection of books,
+// shared by every instance. It doesn't start with "book:", so the in-process
+// LRU never serves a stale copy of it.
+const collectionVersionKey = "books:version"
+
+// newCollectionVersion derives versions from the clock, so a version lost to
+// a cache flush or restart is never handed out again
+func newCollectionVersion() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// bumpCollectionVersion gives the collection a new version, so the list
+// ETags handed out before no longer match
+func bumpCollectionVersion() {
+	redis.BookCache.Set(context.Background(), collectionVersionKey, newCollectionVersion(), 0)
+}
+
+// collectionVersion returns the current collection version, starting one
+// when there is none yet. ok is false when the cache can't be used, in
+// which case lists are served without an ETag.
+func collectionVersion() (string, bool) {
+	version, err := redis.BookCache.Get(context.Background(), collectionVersionKey)
+	if err == nil {
+		return version, true
+	}
+	if !errors.Is(err, redis.ErrCacheMiss) {
+		return "", false
+	}
+	version = newCollectionVersion()
+	if err := redis.BookCache.Set(context.Background(), collectionVersionKey, version, 0); err != nil {
+		return "", false
+	}
+	return version, true
+}
+
+// collectionETag identifies the list response to this request at the current
+// collection version. The query and the response format are hashed in, as
+// they select what the body holds and how it is written.
+func collectionETag(ctx *gin.Context, version string) string {
+	variant := fnv.New32a()
+	variant.Write([]byte(ctx.Request.URL.RawQuery + "|" + responseFormat(ctx) + "|" + strconv.FormatBool(wantsXML(ctx))))
+	return `"` + version + "-" + strconv.FormatUint(uint64(variant.Sum32()), 36) + `"`
+}
+
+// collectionNotModified sets the collection ETag and answers 304 when the
+// request's If-None-Match lists it, so a client can check whether anything
+// changed without downloading the list again. It returns true when the 304
+// was sent and the body must be skipped.
+func collectionNotModified(ctx *gin.Context) bool {
+	version, ok := collectionVersion()
+	if !ok {
+		return false
+	}
+	etag := collectionETag(ctx, version)
+	ctx.Header("ETag", etag)
+	if header := ctx.GetHeader("If-None-Match"); header == "" || !etagListed(header, etag) {
+		return false
+	}
+	ctx.Status(http.StatusNotModified)
+	return true
+}
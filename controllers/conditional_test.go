@@ -0,0 +1,176 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestIfModifiedSince(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+	path := "/v1/books/" + itoa(book.ID)
+
+	w := testutil.Request(router, http.MethodGet, path, "")
+	lastModified := w.Header().Get("Last-Modified")
+	if want := book.UpdatedAt.UTC().Format(http.TimeFormat); lastModified != want {
+		t.Fatalf("Last-Modified = %q, want %q", lastModified, want)
+	}
+	older := book.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)
+
+	tests := []struct {
+		name, path, since string
+		want              int
+	}{
+		{"cached, not modified", path, lastModified, http.StatusNotModified},
+		{"loaded, not modified", path + "?nocache=true", lastModified, http.StatusNotModified},
+		{"sparse, not modified", path + "?fields=title", lastModified, http.StatusNotModified},
+		{"modified since", path, older, http.StatusOK},
+		{"invalid date", path, "yesterday", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Request(router, http.MethodGet, tt.path, "", "If-Modified-Since", tt.since)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 with a body: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+	path := "/v1/books/" + itoa(book.ID)
+
+	w := testutil.Request(router, http.MethodGet, path, "")
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	lastModified := w.Header().Get("Last-Modified")
+	older := book.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)
+
+	tests := []struct {
+		name, path, match, since string
+		want                     int
+	}{
+		{"cached, listed", path, etag, "", http.StatusNotModified},
+		{"loaded, listed", path + "?nocache=true", etag, "", http.StatusNotModified},
+		{"listed among others", path, `"other", W/` + etag, "", http.StatusNotModified},
+		{"any", path, "*", "", http.StatusNotModified},
+		{"not listed", path, `"other"`, "", http.StatusOK},
+		{"sparse has no ETag", path + "?fields=title", etag, "", http.StatusOK},
+		// If-Modified-Since only counts without If-None-Match
+		{"listed, modified since", path, etag, older, http.StatusNotModified},
+		{"not listed, not modified since", path, `"other"`, lastModified, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := []string{"If-None-Match", tt.match}
+			if tt.since != "" {
+				headers = append(headers, "If-Modified-Since", tt.since)
+			}
+			w := testutil.Request(router, http.MethodGet, tt.path, "", headers...)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 with a body: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+	path := "/v1/books/" + itoa(book.ID)
+	stale := testutil.Request(router, http.MethodGet, path, "").Header().Get("ETag")
+	update := `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`
+
+	w := testutil.Request(router, http.MethodPut, path, update, "If-Match", `"other"`)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("mismatching PUT: status = %d, want 412: %s", w.Code, w.Body)
+	}
+	var stored models.Book
+	db.First(&stored, book.ID)
+	if stored.Title != "Dune" {
+		t.Errorf("title = %q after a failed precondition, want it unchanged", stored.Title)
+	}
+
+	w = testutil.Request(router, http.MethodPut, path, update, "If-Match", stale)
+	if w.Code != http.StatusOK {
+		t.Fatalf("matching PUT: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	current := w.Header().Get("ETag")
+	if current == "" || current == stale {
+		t.Fatalf("ETag after the update = %q, want a new one", current)
+	}
+
+	// The tag fetched before the update no longer matches
+	if w := testutil.Request(router, http.MethodDelete, path, "", "If-Match", stale); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale DELETE: status = %d, want 412: %s", w.Code, w.Body)
+	}
+	if w := testutil.Request(router, http.MethodDelete, path, "", "If-Match", current); w.Code != http.StatusOK {
+		t.Fatalf("matching DELETE: status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestIfMatchAny(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	if w := testutil.Request(router, http.MethodDelete, "/v1/books/"+itoa(book.ID), "", "If-Match", "*"); w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestCollectionETag(t *testing.T) {
+	router, db := setup(t)
+	book := seedNumbered(t, db, 2)[0]
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag %q, want 200 with an ETag", w.Code, etag)
+	}
+	w = testutil.Request(router, http.MethodGet, "/v1/books", "", "If-None-Match", etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("unchanged collection: status = %d, body %q, want 304 without a body", w.Code, w.Body)
+	}
+	// Another page or format has its own tag
+	for _, path := range []string{"/v1/books?limit=1", "/v1/books?format=envelope"} {
+		if w := testutil.Request(router, http.MethodGet, path, "", "If-None-Match", etag); w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", path, w.Code)
+		}
+	}
+
+	writes := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`},
+		{http.MethodPut, "/v1/books/" + itoa(book.ID), `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`},
+		{http.MethodDelete, "/v1/books/" + itoa(book.ID), ""},
+	}
+	for _, write := range writes {
+		if w := testutil.Request(router, write.method, write.path, write.body); w.Code >= http.StatusBadRequest {
+			t.Fatalf("%s %s: status = %d: %s", write.method, write.path, w.Code, w.Body)
+		}
+		w := testutil.Request(router, http.MethodGet, "/v1/books", "", "If-None-Match", etag)
+		next := w.Header().Get("ETag")
+		if w.Code != http.StatusOK || next == "" || next == etag {
+			t.Fatalf("after %s %s: status = %d, ETag %q, want 200 with a new ETag", write.method, write.path, w.Code, next)
+		}
+		if w := testutil.Request(router, http.MethodGet, "/v1/books", "", "If-None-Match", next); w.Code != http.StatusNotModified {
+			t.Errorf("after %s %s: the new ETag got %d, want 304", write.method, write.path, w.Code)
+		}
+		etag = next
+	}
+}
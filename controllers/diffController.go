@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+)
+
+// diffIgnoredFields are bookkeeping fields that differ between any two books
+var diffIgnoredFields = map[string]bool{"id": true, "created_at": true, "updated_at": true}
+
+// GetBookDiff godoc
+// @Summary Compare two books
+// @Description Compare books a and b field by field, e.g. to spot near-duplicates. Only the fields that differ are
+// @Description returned, each with its value in both books; id and the timestamps are left out. Identical books
+// @Description give an empty object.
+// @Tags books
+// @Produce json
+// @Param a query int true "ID of the first book"
+// @Param b query int true "ID of the second book"
+// @Success 200 {object} map[string]interface{} "{\"title\": {\"a\": \"...\", \"b\": \"...\"}}"
+// @Failure 400 {object} map[string]string "Missing or invalid book id"
+// @Failure 404 {object} map[string]string "Book not found"
+// @Failure 500 {object} map[string]string "Error fetching books"
+// @Router /books/diff [get]
+func GetBookDiff(ctx *gin.Context) {
+	var ids [2]uint
+	for i, name := range []string{"a", "b"} {
+		id, err := strconv.ParseUint(ctx.Query(name), 10, 64)
+		if err != nil || id == 0 {
+			render(ctx, http.StatusBadRequest, gin.H{"error": "Query params a and b must be book ids"})
+			return
+		}
+		ids[i] = uint(id)
+	}
+
+	var books []models.Book
+	if err := database.DB.Where("id IN ?", ids[:]).Find(&books).Error; err != nil {
+		log.Println("Error fetching books to compare:", err)
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Error fetching books"})
+		return
+	}
+	byID := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+	a, okA := byID[ids[0]]
+	b, okB := byID[ids[1]]
+	if !okA || !okB {
+		respondMessage(ctx, http.StatusNotFound, msgBookNotFound)
+		return
+	}
+
+	respond(ctx, http.StatusOK, diffBooks(a, b))
+}
+
+// diffBooks returns the json fields whose value differs between a and b
+func diffBooks(a, b models.Book) gin.H {
+	fieldsA := filterFields(a, nil)
+	fieldsB := filterFields(b, nil)
+
+	diff := gin.H{}
+	for name, value := range fieldsA {
+		if diffIgnoredFields[name] || reflect.DeepEqual(value, fieldsB[name]) {
+			continue
+		}
+		diff[name] = gin.H{"a": value, "b": fieldsB[name]}
+	}
+	return diff
+}
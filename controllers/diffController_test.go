@@ -0,0 +1,61 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestBookDiff(t *testing.T) {
+	router, db := setup(t)
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965, Publisher: "Chilton"},
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1966, Publisher: "Ace"},
+	)
+	path := "/v1/books/diff?a=" + itoa(books[0].ID) + "&b="
+
+	w := testutil.Request(router, http.MethodGet, path+itoa(books[1].ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var diff map[string]map[string]interface{}
+	decode(t, w, &diff)
+	var fields []string
+	for field := range diff {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	// Same title and author, and the id and timestamps are never compared;
+	// the slug differs since both can't be "dune"
+	if got := fmt.Sprint(fields); got != "[publisher slug year]" {
+		t.Errorf("differing fields = %s, want [publisher slug year]: %s", got, w.Body)
+	}
+	if year := diff["year"]; year["a"] != float64(1965) || year["b"] != float64(1966) {
+		t.Errorf("year = %v, want a 1965 and b 1966", year)
+	}
+
+	var same map[string]interface{}
+	decode(t, testutil.Request(router, http.MethodGet, path+itoa(books[0].ID), ""), &same)
+	if same == nil || len(same) != 0 {
+		t.Errorf("diff of a book with itself = %v, want {}", same)
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"?a=" + itoa(books[0].ID) + "&b=999", http.StatusNotFound},
+		{"?a=999&b=" + itoa(books[0].ID), http.StatusNotFound},
+		{"?a=" + itoa(books[0].ID), http.StatusBadRequest},
+		{"?a=x&b=1", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := testutil.Request(router, http.MethodGet, "/v1/books/diff"+tt.query, ""); w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.query, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/metadata"
+	"github.com/rohans540/books-backend/models"
+)
+
+// enrichRequested reports whether ?enrich=true asks CreateBook to look up
+// missing fields by ISBN
+func enrichRequested(ctx *gin.Context) bool {
+	enrich, _ := strconv.ParseBool(ctx.Query("enrich"))
+	return enrich
+}
+
+// enrichBook fills in the title, authors and year of a normalized book from
+// its ISBN when they were left out; fields that were sent are kept. A failed
+// lookup is only logged, so validation then reports whatever is still
+// missing.
+func enrichBook(ctx context.Context, book *models.Book) {
+	if book.ISBN == nil || !models.IsValidISBN(*book.ISBN) {
+		return
+	}
+	if book.Title != "" && book.Author != "" && book.Year != 0 {
+		return
+	}
+
+	found, err := metadata.BookSource.Lookup(ctx, *book.ISBN)
+	if err != nil {
+		log.Printf("ISBN lookup for %s failed: %v", *book.ISBN, err)
+		return
+	}
+	if book.Title == "" {
+		book.Title = found.Title
+	}
+	if book.Author == "" && len(found.Authors) > 0 {
+		book.Authors = models.StringList(found.Authors)
+	}
+	if book.Year == 0 {
+		book.Year = found.Year
+	}
+}
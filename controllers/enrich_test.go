@@ -0,0 +1,82 @@
+package controllers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/metadata"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+// stubSource answers every lookup with book or err and counts the lookups
+type stubSource struct {
+	book    metadata.Book
+	err     error
+	lookups int
+}
+
+func (s *stubSource) Lookup(ctx context.Context, isbn string) (metadata.Book, error) {
+	s.lookups++
+	return s.book, s.err
+}
+
+func useSource(t *testing.T, source metadata.Source) {
+	t.Helper()
+	previous := metadata.BookSource
+	metadata.BookSource = source
+	t.Cleanup(func() { metadata.BookSource = previous })
+}
+
+func TestCreateBookEnrich(t *testing.T) {
+	router, _ := setup(t)
+	source := &stubSource{book: metadata.Book{Title: "The C Programming Language", Authors: []string{"Kernighan", "Ritchie"}, Year: 1988}}
+	useSource(t, source)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books?enrich=true", `{"isbn": "0-13-110362-8"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var created models.Book
+	decode(t, w, &created)
+	if created.Title != "The C Programming Language" || created.Author != "Kernighan" || len(created.Authors) != 2 || created.Year != 1988 {
+		t.Errorf("created %+v, want the looked up title, authors and year", created)
+	}
+
+	// Fields that were sent win over the lookup
+	w = testutil.Request(router, http.MethodPost, "/v1/books?enrich=true", `{"isbn": "9780131103627", "title": "K&R"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	decode(t, w, &created)
+	if created.Title != "K&R" || created.Year != 1988 {
+		t.Errorf("created %+v, want the sent title and the looked up year", created)
+	}
+
+	// Complete books and requests without ?enrich=true aren't looked up
+	source.lookups = 0
+	testutil.Request(router, http.MethodPost, "/v1/books?enrich=true", `{"isbn": "0201633612", "title": "Design Patterns", "author": "Gamma", "year": 1994}`)
+	testutil.Request(router, http.MethodPost, "/v1/books", `{"isbn": "0262033844", "title": "CLRS", "author": "Cormen", "year": 1990}`)
+	if source.lookups != 0 {
+		t.Errorf("%d lookups, want none", source.lookups)
+	}
+}
+
+func TestCreateBookEnrichFallback(t *testing.T) {
+	router, _ := setup(t)
+	useSource(t, &stubSource{err: errors.New("lookup timed out")})
+
+	// A complete book is created even though the lookup fails
+	w := testutil.Request(router, http.MethodPost, "/v1/books?enrich=true", `{"isbn": "0131103628", "title": "K&R", "author": "Kernighan", "year": 1978}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	// Without the lookup the missing fields fail validation as usual
+	w = testutil.Request(router, http.MethodPost, "/v1/books?enrich=true", `{"isbn": "9780131103627"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/outbox"
+)
+
+// historyEventTypes are the event types GET /admin/events can filter on
+var historyEventTypes = map[string]bool{
+	"book.created":     true,
+	"book.updated":     true,
+	"book.deleted":     true,
+	"book.checked_out": true,
+	"book.returned":    true,
+}
+
+// historyEvent is one entry of the event history
+type historyEvent struct {
+	ID        uint            `json:"id"`
+	EventType string          `json:"event_type"`
+	BookID    *uint           `json:"book_id"`
+	Event     json.RawMessage `json:"event" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+	SentAt    *time.Time      `json:"sent_at"`
+}
+
+// ListEvents godoc
+// @Summary List the event history
+// @Description List the book events recorded in the outbox, newest first, whether or not they have been published yet.
+// @Description event_type accepts both the short (created) and the full (book.created) name. since is inclusive and until exclusive. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param event_type query string false "Event type: created, updated, deleted, checked_out or returned"
+// @Param book_id query int false "Only events of this book"
+// @Param since query string false "Only events recorded at or after this RFC 3339 time"
+// @Param until query string false "Only events recorded before this RFC 3339 time"
+// @Param limit query int false "Number of events per page (default: DEFAULT_PAGE_SIZE)"
+// @Param offset query int false "Offset for pagination (default: 0)"
+// @Success 200 {array} historyEvent
+// @Failure 400 {object} map[string]string "Invalid filter"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to list events"
+// @Router /admin/events [get]
+func ListEvents(ctx *gin.Context) {
+	var filter outbox.HistoryFilter
+	if eventType := ctx.Query("event_type"); eventType != "" {
+		if !strings.HasPrefix(eventType, "book.") {
+			eventType = "book." + eventType
+		}
+		if !historyEventTypes[eventType] {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "event_type must be one of created, updated, deleted, checked_out or returned"})
+			return
+		}
+		filter.EventType = eventType
+	}
+	if raw := ctx.Query("book_id"); raw != "" {
+		bookID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || bookID == 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "book_id must be a positive number"})
+			return
+		}
+		filter.BookID = uint(bookID)
+	}
+	for _, bound := range []struct {
+		param  string
+		target *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		raw := ctx.Query(bound.param)
+		if raw == "" {
+			continue
+		}
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": bound.param + " must be an RFC 3339 time, e.g. 2025-02-23T00:00:00Z"})
+			return
+		}
+		*bound.target = value
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "since must be before until"})
+		return
+	}
+
+	limit := pageLimit(ctx)
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	events, err := outbox.History(database.DB, filter, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list events"})
+		return
+	}
+
+	respondWithMeta(ctx, http.StatusOK, toHistoryEvents(events), gin.H{"limit": limit, "offset": offset})
+}
+
+// toHistoryEvents converts outbox events to their event history entries
+func toHistoryEvents(events []models.OutboxEvent) []historyEvent {
+	history := make([]historyEvent, len(events))
+	for i, event := range events {
+		history[i] = historyEvent{
+			ID:        event.ID,
+			EventType: event.EventType,
+			BookID:    event.BookID,
+			Event:     json.RawMessage(event.Payload),
+			CreatedAt: event.CreatedAt,
+			SentAt:    event.SentAt,
+		}
+	}
+	return history
+}
@@ -0,0 +1,89 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestListEvents(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	router, db := setup(t)
+	day := 24 * time.Hour
+	start := time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)
+	first, second := uint(1), uint(2)
+	for _, event := range []models.OutboxEvent{
+		{EventType: "book.created", BookID: &first, CreatedAt: start},
+		{EventType: "book.updated", BookID: &first, CreatedAt: start.Add(day)},
+		{EventType: "book.deleted", BookID: &first, CreatedAt: start.Add(2 * day)},
+		{EventType: "book.created", BookID: &second, CreatedAt: start.Add(3 * day)},
+		{EventType: "book.deleted", BookID: &second, CreatedAt: start.Add(9 * day)},
+	} {
+		event.Topic, event.Payload = "book_events", "{}"
+		if err := db.Create(&event).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	at := func(days int) string { return start.Add(time.Duration(days) * day).Format(time.RFC3339) }
+
+	tests := []struct {
+		query string
+		want  []uint // event ids, newest first
+	}{
+		{"", []uint{5, 4, 3, 2, 1}},
+		{"event_type=deleted", []uint{5, 3}},
+		{"event_type=book.created", []uint{4, 1}},
+		{"book_id=1", []uint{3, 2, 1}},
+		{"since=" + at(2), []uint{5, 4, 3}},
+		{"since=" + at(1) + "&until=" + at(3), []uint{3, 2}},
+		{"event_type=deleted&since=" + at(3), []uint{5}},
+		{"event_type=created&book_id=2", []uint{4}},
+		{"event_type=updated&book_id=2", []uint{}},
+		{"limit=2&offset=1", []uint{4, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := testutil.Request(router, http.MethodGet, "/v1/admin/events?"+tt.query, "", "Authorization", "Bearer "+adminToken)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			var events []struct {
+				ID uint `json:"id"`
+			}
+			decode(t, w, &events)
+			got := make([]uint, len(events))
+			for i, event := range events {
+				got[i] = event.ID
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListEventsInvalidFilters(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	router, _ := setup(t)
+
+	for _, query := range []string{
+		"event_type=renamed",
+		"book_id=0",
+		"book_id=abc",
+		"since=yesterday",
+		"since=2025-02-12T00:00:00Z&until=2025-02-12T00:00:00Z",
+	} {
+		w := testutil.Request(router, http.MethodGet, "/v1/admin/events?"+query, "", "Authorization", "Bearer "+adminToken)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+
+	if w := testutil.Request(router, http.MethodGet, "/v1/admin/events", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without the admin token: status = %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+)
+
+const defaultMaxExportChunks = 1000
+
+// ValidateExportChunks checks MAX_EXPORT_CHUNKS so a bad value fails at
+// startup instead of being silently replaced by the default
+func ValidateExportChunks() error {
+	_, err := positiveEnvInt("MAX_EXPORT_CHUNKS", defaultMaxExportChunks)
+	return err
+}
+
+// maxExportChunks returns how many chunks a manifest may list, from
+// MAX_EXPORT_CHUNKS, which defaults to 1000, so a tiny chunk_size can't make
+// the manifest as large as the export itself
+func maxExportChunks() int {
+	chunks, err := positiveEnvInt("MAX_EXPORT_CHUNKS", defaultMaxExportChunks)
+	if err != nil {
+		return defaultMaxExportChunks
+	}
+	return chunks
+}
+
+// ExportManifest lists the chunks a chunked export is split into
+type ExportManifest struct {
+	Total     int64         `json:"total"`
+	ChunkSize int           `json:"chunk_size"`
+	Chunks    []ExportChunk `json:"chunks"`
+}
+
+// ExportChunk is one file of a chunked export: the range of ids it covers
+// and the URL to download it from
+type ExportChunk struct {
+	Chunk  int    `json:"chunk"`
+	Count  int64  `json:"count"`
+	FromID uint   `json:"from_id"`
+	ToID   uint   `json:"to_id"`
+	URL    string `json:"url"`
+}
+
+// ExportBooks godoc
+// @Summary Export books as JSON
+// @Description Download every matching book as a single JSON array in DEFAULT_SORT order, for backups. Like
+// @Description /books/stream the rows are streamed as they are read and never held in memory.
+// @Description With chunk_size the export is split into files of at most that many books, in id order: without chunk
+// @Description the response is a manifest with the id range and URL of every chunk, and with chunk (starting at 1),
+// @Description from_id and to_id it is the JSON array of the books in that range. Ranges don't shift when books are
+// @Description added or deleted after the manifest was made, and books added since aren't in any chunk.
+// @Tags books
+// @Produce json
+// @Param q query string false "Full-text search over title and author"
+// @Param author query string false "Only export books by this author"
+// @Param publisher query string false "Only export books from this publisher"
+// @Param year query int false "Only export books published in this year"
+// @Param language query string false "Only export books in this ISO 639-1 language (e.g. en)"
+// @Param available query bool false "Only export books that are (true) or aren't (false) available to check out"
+// @Param chunk_size query int false "Split the export into chunks of this many books"
+// @Param chunk query int false "Download this chunk (starting at 1); requires chunk_size, from_id and to_id"
+// @Param from_id query int false "First id of the chunk, from the manifest"
+// @Param to_id query int false "Last id of the chunk, from the manifest"
+// @Success 200 {array} models.Book "The books, or an ExportManifest when chunk_size is set without chunk"
+// @Failure 400 {object} map[string]string "Invalid filter or chunk, or more chunks than MAX_EXPORT_CHUNKS"
+// @Router /books/export.json [get]
+func ExportBooks(ctx *gin.Context) {
+	filters, err := parseBookFilters(ctx)
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	chunkSize, chunk := 0, 0
+	var chunkRange [2]uint64
+	if raw := ctx.Query("chunk_size"); raw != "" {
+		if chunkSize, err = strconv.Atoi(raw); err != nil || chunkSize <= 0 {
+			render(ctx, http.StatusBadRequest, gin.H{"error": "chunk_size must be a positive number"})
+			return
+		}
+	}
+	if raw := ctx.Query("chunk"); raw != "" {
+		if chunk, err = strconv.Atoi(raw); err != nil || chunk <= 0 {
+			render(ctx, http.StatusBadRequest, gin.H{"error": "chunk must be a positive number"})
+			return
+		}
+		if chunkSize == 0 {
+			render(ctx, http.StatusBadRequest, gin.H{"error": "chunk requires chunk_size"})
+			return
+		}
+		fromID, fromErr := strconv.ParseUint(ctx.Query("from_id"), 10, 64)
+		toID, toErr := strconv.ParseUint(ctx.Query("to_id"), 10, 64)
+		if fromErr != nil || toErr != nil || fromID == 0 || toID < fromID {
+			render(ctx, http.StatusBadRequest, gin.H{"error": "chunk requires the from_id and to_id of the manifest"})
+			return
+		}
+		chunkRange = [2]uint64{fromID, toID}
+	}
+
+	if chunkSize > 0 && chunk == 0 {
+		exportManifest(ctx, filters, chunkSize)
+		return
+	}
+
+	db := database.DB.WithContext(ctx.Request.Context())
+	query := orderByDefault(filters.apply(db.Model(&models.Book{})))
+	filename := "books.json"
+	if chunk > 0 {
+		// A chunk is an id range rather than an offset, so it holds the same
+		// books however many were added or deleted before it
+		query = filters.apply(db.Model(&models.Book{})).
+			Where("id BETWEEN ? AND ?", chunkRange[0], chunkRange[1]).
+			Order("id")
+		filename = fmt.Sprintf("books-%d.json", chunk)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Error exporting books"})
+		return
+	}
+	defer rows.Close()
+
+	// Exporting the whole catalog can outlast the server's write timeout;
+	// a client that stops reading is caught by the request context instead
+	http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+
+	ctx.Header("Content-Type", "application/json; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Status(http.StatusOK)
+	if _, err := ctx.Writer.WriteString("["); err != nil {
+		return
+	}
+	encoder := json.NewEncoder(ctx.Writer)
+	for count := 1; rows.Next(); count++ {
+		var book models.Book
+		if err := db.ScanRows(rows, &book); err != nil {
+			// Leave the array unterminated so the truncated export is invalid
+			log.Println("Failed to read book while exporting:", err)
+			return
+		}
+		if count > 1 {
+			if _, err := ctx.Writer.WriteString(","); err != nil {
+				return
+			}
+		}
+		if err := encoder.Encode(book); err != nil {
+			return // client went away
+		}
+		if count%streamFlushEvery == 0 {
+			ctx.Writer.Flush()
+		}
+	}
+	// Headers are already sent, so a failure can only be logged; the array
+	// is left unterminated so the client can't mistake it for a full export
+	if err := rows.Err(); err != nil {
+		log.Println("Failed to export books:", err)
+		return
+	}
+	ctx.Writer.WriteString("]\n")
+}
+
+// exportChunkBound is a book that starts or ends a chunk, numbered by its
+// position among the matching books in id order
+type exportChunkBound struct {
+	ID    uint
+	N     int64
+	Total int64
+}
+
+// exportManifest responds with the chunks of an export split into chunkSize
+// books, each linking to the same request with its chunk number and id
+// range added. Only the first and last book of each chunk are read.
+func exportManifest(ctx *gin.Context, filters bookFilters, chunkSize int) {
+	total, err := countBooks(ctx, filters)
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Error counting books"})
+		return
+	}
+	if chunks := (total + int64(chunkSize) - 1) / int64(chunkSize); chunks > int64(maxExportChunks()) {
+		render(ctx, http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%d books in chunks of %d make %d chunks, more than the %d allowed; raise chunk_size", total, chunkSize, chunks, maxExportChunks())})
+		return
+	}
+
+	db := database.DB.WithContext(ctx.Request.Context())
+	numbered := filters.apply(db.Model(&models.Book{})).
+		Select("id, ROW_NUMBER() OVER (ORDER BY id) AS n, COUNT(*) OVER () AS total")
+	var bounds []exportChunkBound
+	err = db.Table("(?) AS numbered", numbered).
+		Where("(n - 1) % ? = 0 OR n % ? = 0 OR n = total", chunkSize, chunkSize).
+		Order("n").
+		Scan(&bounds).Error
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Error exporting books"})
+		return
+	}
+
+	// The count may have been served from the cache; the bounds were read
+	// together with theirs
+	if len(bounds) > 0 {
+		total = bounds[0].Total
+	}
+	manifest := ExportManifest{Total: total, ChunkSize: chunkSize, Chunks: []ExportChunk{}}
+	var first exportChunkBound
+	for _, bound := range bounds {
+		if (bound.N-1)%int64(chunkSize) == 0 {
+			first = bound
+		}
+		if bound.N%int64(chunkSize) != 0 && bound.N != bound.Total {
+			continue
+		}
+		chunk := len(manifest.Chunks) + 1
+		query := ctx.Request.URL.Query()
+		query.Set("chunk", strconv.Itoa(chunk))
+		query.Set("from_id", strconv.FormatUint(uint64(first.ID), 10))
+		query.Set("to_id", strconv.FormatUint(uint64(bound.ID), 10))
+		manifest.Chunks = append(manifest.Chunks, ExportChunk{
+			Chunk:  chunk,
+			Count:  bound.N - first.N + 1,
+			FromID: first.ID,
+			ToID:   bound.ID,
+			URL:    ctx.Request.URL.Path + "?" + query.Encode(),
+		})
+	}
+	render(ctx, http.StatusOK, manifest)
+}
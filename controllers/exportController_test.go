@@ -0,0 +1,114 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/controllers"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+// exportIDs downloads path and returns the ids of the exported JSON array
+func exportIDs(t *testing.T, router http.Handler, path string) []uint {
+	t.Helper()
+	w := testutil.Request(router, http.MethodGet, path, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: status = %d: %s", path, w.Code, w.Body)
+	}
+	var books []models.Book
+	if err := json.Unmarshal(w.Body.Bytes(), &books); err != nil {
+		t.Fatalf("%s: invalid JSON: %v", path, err)
+	}
+	return ids(books)
+}
+
+func TestExportBooks(t *testing.T) {
+	router, db := setup(t)
+	if got := exportIDs(t, router, "/v1/books/export.json"); len(got) != 0 {
+		t.Errorf("empty catalog: exported %v", got)
+	}
+
+	// More books than are written between flushes
+	books := seedNumbered(t, db, 120)
+	if got := exportIDs(t, router, "/v1/books/export.json"); fmt.Sprint(got) != fmt.Sprint(ids(books)) {
+		t.Errorf("exported %d books, want all %d in order", len(got), len(books))
+	}
+	if got := exportIDs(t, router, "/v1/books/export.json?year=2003"); fmt.Sprint(got) != fmt.Sprint([]uint{books[3].ID}) {
+		t.Errorf("filtered export = %v, want only book %d", got, books[3].ID)
+	}
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books/export.json", "")
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="books.json"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+}
+
+func TestExportBooksInChunks(t *testing.T) {
+	router, db := setup(t)
+	books := seedNumbered(t, db, 25)
+
+	var manifest controllers.ExportManifest
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books/export.json?chunk_size=10", ""), &manifest)
+	if manifest.Total != 25 || len(manifest.Chunks) != 3 {
+		t.Fatalf("manifest = %+v, want 25 books in 3 chunks", manifest)
+	}
+
+	// The chunks add up to the full export
+	var all []uint
+	for i, chunk := range manifest.Chunks {
+		got := exportIDs(t, router, chunk.URL)
+		if int64(len(got)) != chunk.Count || chunk.Chunk != i+1 {
+			t.Errorf("chunk %+v: downloaded %d books", chunk, len(got))
+		}
+		all = append(all, got...)
+	}
+	if fmt.Sprint(all) != fmt.Sprint(ids(books)) {
+		t.Errorf("chunks = %v, want every book once in order", all)
+	}
+
+	// Chunks are id ranges: deleting a book of the first chunk doesn't
+	// shift the second, and books added since aren't in any chunk
+	second := exportIDs(t, router, manifest.Chunks[1].URL)
+	db.Delete(&models.Book{}, books[0].ID)
+	seedNumbered(t, db, 5)
+	if got := exportIDs(t, router, manifest.Chunks[1].URL); fmt.Sprint(got) != fmt.Sprint(second) {
+		t.Errorf("second chunk after changes = %v, want %v", got, second)
+	}
+	if got := exportIDs(t, router, manifest.Chunks[2].URL); fmt.Sprint(got) != fmt.Sprint(ids(books[20:])) {
+		t.Errorf("last chunk after inserts = %v, want %v", got, ids(books[20:]))
+	}
+
+	for _, query := range []string{"chunk_size=0", "chunk_size=x", "chunk=1", "chunk_size=10&chunk=0", "chunk_size=10&chunk=1", "chunk_size=10&chunk=1&from_id=5&to_id=4"} {
+		if w := testutil.Request(router, http.MethodGet, "/v1/books/export.json?"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestExportManifestChunkLimit(t *testing.T) {
+	t.Setenv("MAX_EXPORT_CHUNKS", "3")
+	router, db := setup(t)
+	books := seedNumbered(t, db, 25)
+
+	for query, want := range map[string]int{"chunk_size=9": http.StatusOK, "chunk_size=8": http.StatusBadRequest} {
+		if w := testutil.Request(router, http.MethodGet, "/v1/books/export.json?"+query, ""); w.Code != want {
+			t.Errorf("%s: status = %d, want %d: %s", query, w.Code, want, w.Body)
+		}
+	}
+
+	// Filters, a search included, apply to the chunk ranges too: Book 2 and
+	// Book 20 to Book 25 match
+	var manifest controllers.ExportManifest
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books/export.json?chunk_size=3&q=book%202", ""), &manifest)
+	var all []uint
+	for _, chunk := range manifest.Chunks {
+		all = append(all, exportIDs(t, router, chunk.URL)...)
+	}
+	want := append([]uint{books[1].ID}, ids(books[19:])...)
+	if manifest.Total != 7 || len(manifest.Chunks) != 3 || fmt.Sprint(all) != fmt.Sprint(want) {
+		t.Errorf("manifest = %+v, chunks = %v, want %v in 3 chunks", manifest, all, want)
+	}
+}
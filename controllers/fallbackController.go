@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteNotFound answers requests for paths no route matches
+func RouteNotFound(ctx *gin.Context) {
+	body := errorBody(ctx, msgRouteNotFound)
+	body["path"] = ctx.Request.URL.Path
+	ctx.JSON(http.StatusNotFound, body)
+}
+
+// MethodNotAllowed answers requests for a known path with an unsupported
+// method. gin has already set the Allow header to the supported methods.
+func MethodNotAllowed(ctx *gin.Context) {
+	ctx.JSON(http.StatusMethodNotAllowed, gin.H{
+		"error": "Method " + ctx.Request.Method + " not allowed",
+		"path":  ctx.Request.URL.Path,
+		"allow": ctx.Writer.Header().Get("Allow"),
+	})
+}
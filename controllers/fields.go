@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/models"
+	"gorm.io/gorm/schema"
+)
+
+// bookColumns maps the json name of every Book field to its database column
+var bookColumns = jsonColumns(reflect.TypeOf(models.Book{}))
+
+func jsonColumns(t reflect.Type) map[string]string {
+	naming := schema.NamingStrategy{}
+	columns := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		columns[name] = naming.ColumnName("", field.Name)
+	}
+	return columns
+}
+
+// parseFields reads the ?fields= query parameter. It returns the requested
+// json names sorted and de-duplicated, or nil when the parameter is absent.
+func parseFields(ctx *gin.Context) ([]string, error) {
+	raw := ctx.Query("fields")
+	if raw == "" {
+		return nil, nil
+	}
+	return normalizeFields(strings.Split(raw, ","))
+}
+
+// normalizeFields checks that every name is a Book json field and returns
+// them sorted and de-duplicated
+func normalizeFields(names []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var fields []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := bookColumns[name]; !ok {
+			return nil, newMessageError(msgUnknownField, name)
+		}
+		seen[name] = true
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// selectColumns returns the database columns for the requested json fields
+func selectColumns(fields []string) []string {
+	columns := make([]string, len(fields))
+	for i, name := range fields {
+		columns[i] = bookColumns[name]
+	}
+	return columns
+}
+
+// fieldsCacheKey scopes a cache key to the requested field set
+func fieldsCacheKey(key string, fields []string) string {
+	if fields == nil {
+		return key
+	}
+	return key + ":fields=" + strings.Join(fields, ",")
+}
+
+// filterFields keeps only the requested json fields of v, or all of them
+// when fields is nil
+func filterFields(v interface{}, fields []string) map[string]interface{} {
+	data, _ := json.Marshal(v)
+	var full map[string]interface{}
+	json.Unmarshal(data, &full)
+	if fields == nil {
+		return full
+	}
+
+	filtered := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		filtered[name] = full[name]
+	}
+	return filtered
+}
+
+func filterBooks(books []models.Book, fields []string) []map[string]interface{} {
+	filtered := make([]map[string]interface{}, len(books))
+	for i, book := range books {
+		filtered[i] = filterFields(book, fields)
+	}
+	return filtered
+}
+
+// sameFields reports whether before and after have the same value for every
+// json field
+func sameFields(before, after interface{}) bool {
+	return reflect.DeepEqual(filterFields(before, nil), filterFields(after, nil))
+}
+
+// changedFields returns the json fields whose value differs between before
+// and after, always including id and updated_at so the client can tell which
+// version of which book it is looking at
+func changedFields(before, after interface{}) map[string]interface{} {
+	old := filterFields(before, nil)
+	current := filterFields(after, nil)
+
+	changes := map[string]interface{}{"id": current["id"], "updated_at": current["updated_at"]}
+	for name, value := range current {
+		if !reflect.DeepEqual(old[name], value) {
+			changes[name] = value
+		}
+	}
+	return changes
+}
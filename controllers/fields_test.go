@@ -0,0 +1,43 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestSparseFieldsOmitOtherFields(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books?fields=id,title", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: status = %d: %s", w.Code, w.Body)
+	}
+	var list []map[string]interface{}
+	decode(t, w, &list)
+	if len(list) != 1 || len(list[0]) != 2 || list[0]["title"] != "Dune" || list[0]["id"] == nil {
+		t.Errorf("list = %v, want only id and title", list)
+	}
+
+	w = testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(book.ID)+"?fields=author", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: status = %d: %s", w.Code, w.Body)
+	}
+	var single map[string]interface{}
+	decode(t, w, &single)
+	if len(single) != 1 || single["author"] != "Frank Herbert" {
+		t.Errorf("book = %v, want only author", single)
+	}
+}
+
+func TestSparseFieldsRejectUnknownField(t *testing.T) {
+	router, _ := setup(t)
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books?fields=id,password", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/models"
+	"gorm.io/gorm"
+)
+
+// bookFilters holds the optional filters shared by the list endpoints
+type bookFilters struct {
+	Query     string
+	Author    string
+	Publisher string
+	Year      int
+	Language  string
+	Available *bool
+	// Snapshot, when set, leaves out books added after the snapshot was
+	// taken: those with a higher id
+	Snapshot uint
+}
+
+func parseBookFilters(ctx *gin.Context) (bookFilters, error) {
+	filters := bookFilters{
+		Query:     strings.TrimSpace(ctx.Query("q")),
+		Author:    ctx.Query("author"),
+		Publisher: ctx.Query("publisher"),
+	}
+	if raw := ctx.Query("year"); raw != "" {
+		year, err := strconv.Atoi(raw)
+		if err != nil {
+			return filters, newMessageError(msgYearNotNumber)
+		}
+		filters.Year = year
+	}
+	if language := ctx.Query("language"); language != "" {
+		if !models.IsValidLanguage(language) {
+			return filters, newMessageError(msgLanguageUnknown, language)
+		}
+		filters.Language = language
+	}
+	if raw := ctx.Query("available"); raw != "" {
+		available, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filters, newMessageError(msgAvailableInvalid)
+		}
+		filters.Available = &available
+	}
+	return filters, nil
+}
+
+func (f bookFilters) apply(query *gorm.DB) *gorm.DB {
+	if f.Query != "" {
+		query = applySearch(query, f.Query)
+	}
+	if f.Author != "" {
+		query = whereAuthor(query, f.Author)
+	}
+	if f.Publisher != "" {
+		query = query.Where(filterColumn("publisher")+" = ?", f.Publisher)
+	}
+	if f.Year != 0 {
+		query = query.Where(filterColumn("year")+" = ?", f.Year)
+	}
+	if f.Language != "" {
+		query = query.Where(filterColumn("language")+" = ?", f.Language)
+	}
+	if f.Available != nil {
+		query = query.Where(filterColumn("available")+" = ?", *f.Available)
+	}
+	if f.Snapshot != 0 {
+		query = query.Where("id <= ?", f.Snapshot)
+	}
+	return query
+}
+
+// cacheKey derives a stable cache key suffix from the filters
+func (f bookFilters) cacheKey() string {
+	values := url.Values{}
+	if f.Query != "" {
+		values.Set("q", f.Query)
+	}
+	if f.Author != "" {
+		values.Set("author", f.Author)
+	}
+	if f.Publisher != "" {
+		values.Set("publisher", f.Publisher)
+	}
+	if f.Year != 0 {
+		values.Set("year", strconv.Itoa(f.Year))
+	}
+	if f.Language != "" {
+		values.Set("language", f.Language)
+	}
+	if f.Available != nil {
+		values.Set("available", strconv.FormatBool(*f.Available))
+	}
+	if f.Snapshot != 0 {
+		values.Set("snapshot", strconv.FormatUint(uint64(f.Snapshot), 10))
+	}
+	return values.Encode()
+}
+
+// listCacheKey scopes the list cache key to the filters in use
+func (f bookFilters) listCacheKey() string {
+	if key := f.cacheKey(); key != "" {
+		return "books:filter=" + key
+	}
+	return "books"
+}
+
+// whereAuthor matches books with author among their authors. Postgres uses
+// jsonb containment, backed by the GIN index on authors; other dialects
+// (SQLite in tests) look through the list with json_each.
+func whereAuthor(query *gorm.DB, author string) *gorm.DB {
+	if !usesFullTextSearch() {
+		return query.Where("EXISTS (SELECT 1 FROM json_each(books.authors) WHERE value = ?)", author)
+	}
+	contained, _ := json.Marshal([]string{author})
+	return query.Where("authors @> ?", string(contained))
+}
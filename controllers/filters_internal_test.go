@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// usePostgresDryRun points database.DB at a Postgres dialect that only
+// builds statements, for checking SQL SQLite can't run
+func usePostgresDryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+	return db
+}
+
+func TestAuthorFilterMatchesAnyAuthorOnPostgres(t *testing.T) {
+	db := usePostgresDryRun(t)
+
+	stmt := bookFilters{Author: "Neil Gaiman"}.apply(db.Model(&models.Book{})).Find(&[]models.Book{}).Statement
+	sql := stmt.SQL.String()
+	if !strings.Contains(sql, "authors @> $1") {
+		t.Fatalf("SQL = %s, want a jsonb containment match on authors", sql)
+	}
+	if len(stmt.Vars) != 1 || stmt.Vars[0] != `["Neil Gaiman"]` {
+		t.Errorf("vars = %v, want the author as a JSON array", stmt.Vars)
+	}
+}
@@ -0,0 +1,199 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rohans540/books-backend/controllers"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestCreateBookLanguage(t *testing.T) {
+	router, _ := setup(t)
+
+	tests := []struct {
+		name     string
+		language string
+		want     int
+		stored   string
+	}{
+		{"valid code", `"fr"`, http.StatusCreated, "fr"},
+		{"missing defaults to en", "", http.StatusCreated, models.DefaultLanguage},
+		{"unknown code", `"xx"`, http.StatusBadRequest, ""},
+		{"not ISO 639-1", `"eng"`, http.StatusBadRequest, ""},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"title": "Book %d", "author": "Author", "year": 2000`, i)
+			if tt.language != "" {
+				body += `, "language": ` + tt.language
+			}
+			w := testutil.Request(router, http.MethodPost, "/v1/books", body+"}")
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.stored == "" {
+				return
+			}
+			var created models.Book
+			decode(t, w, &created)
+			if created.Language != tt.stored {
+				t.Errorf("language = %q, want %q", created.Language, tt.stored)
+			}
+		})
+	}
+}
+
+func TestListBooksByLanguage(t *testing.T) {
+	router, db := setup(t)
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Year: 1965, Language: "en"},
+		models.Book{Title: "L'Étranger", Year: 1942, Language: "fr"},
+		models.Book{Title: "Der Process", Year: 1925, Language: "de"},
+	)
+
+	// Each language is cached under its own key
+	for i, language := range []string{"en", "fr", "de"} {
+		w := testutil.Request(router, http.MethodGet, "/v1/books?language="+language, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("language %s: status = %d: %s", language, w.Code, w.Body)
+		}
+		var page []models.Book
+		decode(t, w, &page)
+		if len(page) != 1 || page[0].ID != books[i].ID {
+			t.Errorf("language %s: books = %v, want [%d]", language, ids(page), books[i].ID)
+		}
+	}
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books?language=xx", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown language: status = %d, want 400", w.Code)
+	}
+}
+
+func TestCreateBookWithAuthors(t *testing.T) {
+	router, db := setup(t)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Good Omens", "authors": ["Terry Pratchett", "Neil Gaiman"], "year": 1990}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created models.Book
+	decode(t, w, &created)
+	if created.Author != "Terry Pratchett" || fmt.Sprint(created.Authors) != "[Terry Pratchett Neil Gaiman]" {
+		t.Errorf("author %q, authors %q, want both with the first as primary", created.Author, created.Authors)
+	}
+
+	var stored models.Book
+	db.First(&stored, created.ID)
+	if len(stored.Authors) != 2 {
+		t.Errorf("stored authors = %q, want both", stored.Authors)
+	}
+
+	// Both the primary author and a co-author match
+	for _, author := range []string{"Terry%20Pratchett", "Neil%20Gaiman"} {
+		w = testutil.Request(router, http.MethodGet, "/v1/books?author="+author, "")
+		var page []models.Book
+		decode(t, w, &page)
+		if len(page) != 1 || page[0].ID != created.ID {
+			t.Errorf("author=%s: books = %v, want [%d]", author, ids(page), created.ID)
+		}
+	}
+	w = testutil.Request(router, http.MethodGet, "/v1/books?author=Neil", "")
+	var page []models.Book
+	decode(t, w, &page)
+	if len(page) != 0 {
+		t.Errorf("author=Neil: books = %v, want only whole names to match", ids(page))
+	}
+}
+
+func TestListBooksByPublisher(t *testing.T) {
+	router, db := setup(t)
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Year: 1965, Publisher: "Chilton Books"},
+		models.Book{Title: "Neuromancer", Year: 1984, Publisher: "Ace"},
+		models.Book{Title: "Count Zero", Year: 1986, Publisher: "Ace"},
+		models.Book{Title: "Untitled", Year: 2000},
+	)
+
+	tests := []struct {
+		publisher string
+		want      []uint
+	}{
+		{"Ace", []uint{books[1].ID, books[2].ID}},
+		{"Chilton%20Books", []uint{books[0].ID}},
+		{"Chilton", nil},
+	}
+	for _, tt := range tests {
+		w := testutil.Request(router, http.MethodGet, "/v1/books?sort=id&publisher="+tt.publisher, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("publisher %s: status = %d: %s", tt.publisher, w.Code, w.Body)
+		}
+		var page []models.Book
+		decode(t, w, &page)
+		if got := ids(page); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("publisher %s: books = %v, want %v", tt.publisher, got, tt.want)
+		}
+	}
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books/count?publisher=Ace", "")
+	var count struct {
+		Count int64 `json:"count"`
+	}
+	decode(t, w, &count)
+	if count.Count != 2 {
+		t.Errorf("count = %d, want 2", count.Count)
+	}
+}
+
+func TestCreateBookPublisher(t *testing.T) {
+	router, _ := setup(t)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965, "publisher": "  Chilton Books "}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created models.Book
+	decode(t, w, &created)
+	if created.Publisher != "Chilton Books" {
+		t.Errorf("publisher = %q, want it trimmed", created.Publisher)
+	}
+
+	long := strings.Repeat("p", models.MaxTextLength+1)
+	w = testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Children of Dune", "author": "Frank Herbert", "year": 1976, "publisher": "`+long+`"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("long publisher: status = %d, want 422: %s", w.Code, w.Body)
+	}
+}
+
+func TestGetPublishers(t *testing.T) {
+	router, db := setup(t)
+	testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Year: 1965, Publisher: "Chilton Books"},
+		models.Book{Title: "Neuromancer", Year: 1984, Publisher: "Ace"},
+		models.Book{Title: "Count Zero", Year: 1986, Publisher: "Ace"},
+		models.Book{Title: "Untitled", Year: 2000},
+	)
+
+	var names []string
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books/publishers", ""), &names)
+	if fmt.Sprint(names) != "[Ace Chilton Books]" {
+		t.Errorf("publishers = %q, want the distinct non-empty names in order", names)
+	}
+
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books/publishers?q=chil", ""), &names)
+	if fmt.Sprint(names) != "[Chilton Books]" {
+		t.Errorf("publishers starting with chil = %q", names)
+	}
+
+	var counts []controllers.PublisherCount
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books/publishers?counts=true", ""), &counts)
+	want := []controllers.PublisherCount{{Publisher: "Ace", Count: 2}, {Publisher: "Chilton Books", Count: 1}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("publisher counts = %v, want %v", counts, want)
+	}
+}
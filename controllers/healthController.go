@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/redis"
+)
+
+const readinessTimeout = 2 * time.Second
+
+var shuttingDown atomic.Bool
+
+// MarkShuttingDown makes /readyz fail so load balancers stop routing new
+// traffic here before the server closes
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// Livez is the liveness probe. It returns 200 while the process is able to
+// serve requests; a failure means the pod should be restarted.
+//
+// Probes live outside /v1 and are therefore not part of the Swagger spec.
+func Livez(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz is the readiness probe, also served as /healthz. It returns 200 when
+// the database is reachable and the server is not shutting down, 503
+// otherwise. Cache and Kafka producer health are reported but don't affect
+// readiness: the API works without the cache and events wait in the outbox.
+func Readyz(ctx *gin.Context) {
+	if shuttingDown.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+
+	status, ready := checkDependencies(ctx)
+	if !ready {
+		status["status"] = "unavailable"
+		ctx.JSON(http.StatusServiceUnavailable, status)
+		return
+	}
+	ctx.JSON(http.StatusOK, status)
+}
+
+// checkDependencies reports the state of the database, cache and Kafka
+// producer, "ok" or the error, and whether the database, the only one the
+// service can't work without, is reachable
+func checkDependencies(ctx *gin.Context) (gin.H, bool) {
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	status := gin.H{"status": "ok", "database": "ok", "cache": "ok", "kafka": "ok", "database_breaker": database.DBBreaker.State()}
+	ready := true
+
+	sqlDB, err := database.DB.DB()
+	if err == nil {
+		err = sqlDB.PingContext(checkCtx)
+	}
+	if err != nil {
+		status["database"] = err.Error()
+		ready = false
+	}
+	if err := redis.BookCache.Ping(checkCtx); err != nil {
+		status["cache"] = err.Error()
+	}
+	if !kafka.Enabled() {
+		status["kafka"] = "disabled"
+	} else if reporter, ok := kafka.EventPublisher.(kafka.HealthReporter); ok {
+		if err := reporter.Health(); err != nil {
+			status["kafka"] = err.Error()
+		}
+	}
+	return status, ready
+}
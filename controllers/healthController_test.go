@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestReadyzFailsDuringShutdown(t *testing.T) {
+	testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/livez", Livez)
+	router.GET("/readyz", Readyz)
+	t.Cleanup(func() { shuttingDown.Store(false) })
+
+	if w := testutil.Request(router, http.MethodGet, "/readyz", ""); w.Code != http.StatusOK {
+		t.Fatalf("readyz before shutdown: status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	MarkShuttingDown()
+	if w := testutil.Request(router, http.MethodGet, "/readyz", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz during shutdown: status = %d, want 503", w.Code)
+	}
+	// The process is still alive and must not be restarted
+	if w := testutil.Request(router, http.MethodGet, "/livez", ""); w.Code != http.StatusOK {
+		t.Errorf("livez during shutdown: status = %d, want 200", w.Code)
+	}
+}
+
+func TestReadyzFailsWithoutDatabase(t *testing.T) {
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/readyz", Readyz)
+
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	if w := testutil.Request(router, http.MethodGet, "/readyz", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503: %s", w.Code, w.Body)
+	}
+}
+
+// unhealthyPublisher reports a broker error
+type unhealthyPublisher struct {
+	kafka.NoopPublisher
+}
+
+func (unhealthyPublisher) Health() error {
+	return errors.New("broker down")
+}
+
+func TestReadyzReportsProducerHealth(t *testing.T) {
+	testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/healthz", Readyz)
+	t.Setenv("KAFKA_BROKER", "localhost:9092")
+	previous := kafka.EventPublisher
+	kafka.EventPublisher = unhealthyPublisher{}
+	defer func() { kafka.EventPublisher = previous }()
+
+	// Events wait in the outbox, so a broken producer doesn't fail readiness
+	w := testutil.Request(router, http.MethodGet, "/healthz", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var status map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if status["kafka"] != "broker down" {
+		t.Errorf("kafka = %v, want the producer error", status["kafka"])
+	}
+}
+
+func TestHealthzReportsBreakerState(t *testing.T) {
+	testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/healthz", Readyz)
+
+	state := func() interface{} {
+		var status map[string]interface{}
+		json.Unmarshal(testutil.Request(router, http.MethodGet, "/healthz", "").Body.Bytes(), &status)
+		return status["database_breaker"]
+	}
+	if got := state(); got != database.BreakerClosed {
+		t.Errorf("database_breaker = %v, want closed", got)
+	}
+
+	// SetupDB restores the disabled breaker when the test ends
+	database.DBBreaker = database.NewBreaker(1, time.Minute)
+	database.DBBreaker.Record(context.DeadlineExceeded)
+	if got := state(); got != database.BreakerOpen {
+		t.Errorf("tripped: database_breaker = %v, want open", got)
+	}
+}
@@ -0,0 +1,158 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+	"github.com/rohans540/books-backend/testutil"
+	"gorm.io/gorm"
+)
+
+func TestMixedCaseISBNResolvesToSameBook(t *testing.T) {
+	router, db := setup(t)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Neuromancer", "author": "William Gibson", "year": 1984, "isbn": "080442957x"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+	}
+	var created models.Book
+	decode(t, w, &created)
+	if created.ISBN == nil || *created.ISBN != "080442957X" {
+		t.Fatalf("isbn = %v, want it stored as 080442957X", created.ISBN)
+	}
+
+	// Another case or formatting of the same ISBN is a duplicate
+	w = testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Count Zero", "author": "William Gibson", "year": 1986, "isbn": "0-8044-2957-X"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate isbn: status = %d, want 409: %s", w.Code, w.Body)
+	}
+
+	var result struct {
+		Books    []models.Book `json:"books"`
+		NotFound []string      `json:"not_found"`
+	}
+	for _, isbn := range []string{"080442957x", "080442957X"} {
+		decode(t, testutil.Request(router, http.MethodPost, "/v1/books/lookup", `{"isbns": ["`+isbn+`"]}`), &result)
+		if len(result.Books) != 1 || result.Books[0].ID != created.ID || len(result.NotFound) != 0 {
+			t.Errorf("lookup %s = %v, not found %v, want book %d", isbn, ids(result.Books), result.NotFound, created.ID)
+		}
+	}
+	if _, err := redis.BookCache.Get(context.Background(), "book:isbn:080442957X"); err != nil {
+		t.Errorf("book not cached under its canonical isbn: %v", err)
+	}
+
+	w = testutil.Request(router, http.MethodPut, "/v1/books/isbn/080442957x", `{"title": "Neuromancer", "author": "William Gibson", "year": 1985}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("upsert: status = %d, want 200 for the existing book: %s", w.Code, w.Body)
+	}
+	var count int64
+	db.Model(&models.Book{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d books stored, want 1", count)
+	}
+}
+
+func TestMixedCaseSlugResolvesToSameBook(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	for _, slug := range []string{"DUNE", "dune", "Dune"} {
+		w := testutil.Request(router, http.MethodGet, "/v1/books/slug/"+slug, "")
+		var got models.Book
+		decode(t, w, &got)
+		if got.ID != book.ID {
+			t.Errorf("slug %s: book %d, want %d", slug, got.ID, book.ID)
+		}
+	}
+	keys, _ := redis.BookCache.Keys(context.Background(), "book:slug:*", 10)
+	if len(keys) != 1 || keys[0].Key != "book:slug:dune" {
+		t.Errorf("slug cache keys = %v, want only book:slug:dune", keys)
+	}
+}
+
+func TestLookupBooksByISBN(t *testing.T) {
+	router, db := setup(t)
+	kr, dune := "9780131103627", "9780441013593"
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "The C Programming Language", Year: 1988, ISBN: &kr},
+		models.Book{Title: "Dune", Year: 1965, ISBN: &dune},
+	)
+	var queries atomic.Int32
+	db.Callback().Query().Before("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		queries.Add(1)
+	})
+
+	var result struct {
+		Books    []models.Book `json:"books"`
+		NotFound []string      `json:"not_found"`
+	}
+	body := `{"isbns": ["978-0-441-01359-3", "0201633612", "not an isbn", "978 0131103627", "9780441013593"]}`
+	decode(t, testutil.Request(router, http.MethodPost, "/v1/books/lookup", body), &result)
+	// Books in request order without duplicates; not-found ISBNs as sent
+	if got, want := fmt.Sprint(ids(result.Books)), fmt.Sprint([]uint{books[1].ID, books[0].ID}); got != want {
+		t.Errorf("books = %s, want %s", got, want)
+	}
+	if got := fmt.Sprint(result.NotFound); got != "[not an isbn 0201633612]" {
+		t.Errorf("not found = %s, want the invalid and the unknown ISBN", got)
+	}
+	if n := queries.Swap(0); n != 1 {
+		t.Errorf("%d queries, want a single IN query", n)
+	}
+
+	// Found books are now served from the cache
+	decode(t, testutil.Request(router, http.MethodPost, "/v1/books/lookup", `{"isbns": ["9780131103627", "9780441013593"]}`), &result)
+	if len(result.Books) != 2 || len(result.NotFound) != 0 {
+		t.Errorf("cached lookup = %v, not found %v, want both books", ids(result.Books), result.NotFound)
+	}
+	if n := queries.Load(); n != 0 {
+		t.Errorf("%d queries for cached books, want none", n)
+	}
+}
+
+func TestISBNChecksum(t *testing.T) {
+	const (
+		valid       = "9780306406157"
+		badChecksum = "9780306406158"
+		wrongLength = "97803064061"
+	)
+	tests := []struct {
+		mode, query, isbn string
+		want              int
+		warned            bool
+	}{
+		{"warn", "", valid, http.StatusCreated, false},
+		{"warn", "", badChecksum, http.StatusCreated, true},
+		{"warn", "", wrongLength, http.StatusBadRequest, false},
+		{"warn", "?strict=true", badChecksum, http.StatusUnprocessableEntity, false},
+		{"reject", "", valid, http.StatusCreated, false},
+		{"reject", "", badChecksum, http.StatusUnprocessableEntity, false},
+		{"reject", "", wrongLength, http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+" "+tt.isbn+tt.query, func(t *testing.T) {
+			t.Setenv("ISBN_CHECKSUM", tt.mode)
+			router, _ := setup(t)
+			body := fmt.Sprintf(`{"title": "Dune", "author": "Frank Herbert", "year": 1965, "isbn": %q}`, tt.isbn)
+			w := testutil.Request(router, http.MethodPost, "/v1/books"+tt.query, body)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			var got struct {
+				Code     string   `json:"code"`
+				Warnings []string `json:"warnings"`
+			}
+			decode(t, w, &got)
+			if warned := len(got.Warnings) == 1 && got.Warnings[0] == "ISBN check digit doesn't match"; warned != tt.warned {
+				t.Errorf("warnings = %q, want the check digit warning: %v", got.Warnings, tt.warned)
+			}
+			if tt.want == http.StatusUnprocessableEntity && got.Code != "isbn_checksum" {
+				t.Errorf("code = %q, want isbn_checksum", got.Code)
+			}
+		})
+	}
+}
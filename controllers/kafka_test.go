@@ -0,0 +1,57 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/routes"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestWritesSucceedWithKafkaDisabled(t *testing.T) {
+	t.Setenv("KAFKA_BROKER", "")
+	t.Setenv("BROKER", "")
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	previous := kafka.EventPublisher
+	kafka.EventPublisher = kafka.NoopPublisher{}
+	t.Cleanup(func() { kafka.EventPublisher = previous })
+	kafka.InitProducer()
+	router := testutil.NewRouter()
+	routes.SetupRoutes(router)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var book models.Book
+	decode(t, w, &book)
+	path := "/v1/books/" + itoa(book.ID)
+
+	for _, r := range []struct{ method, path, body string }{
+		{http.MethodPut, path, `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`},
+		{http.MethodPost, path + "/checkout", ""},
+		{http.MethodPost, path + "/return", ""},
+		{http.MethodDelete, path, ""},
+	} {
+		if w := testutil.Request(router, r.method, r.path, r.body); w.Code != http.StatusOK {
+			t.Errorf("%s %s: status = %d, want 200: %s", r.method, r.path, w.Code, w.Body)
+		}
+	}
+
+	// Events stay queued for when a broker is configured
+	var events int64
+	db.Model(&models.OutboxEvent{}).Where("sent_at IS NULL").Count(&events)
+	if events != 5 {
+		t.Errorf("%d events queued, want 5", events)
+	}
+
+	var status map[string]interface{}
+	w = testutil.Request(router, http.MethodGet, "/readyz", "")
+	decode(t, w, &status)
+	if w.Code != http.StatusOK || status["kafka"] != "disabled" {
+		t.Errorf("readyz = %d %s, want ready with kafka disabled", w.Code, w.Body)
+	}
+}
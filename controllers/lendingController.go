@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/outbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+var errAvailabilityUnchanged = errors.New("availability unchanged")
+
+// CheckoutBook godoc
+// @Summary Check out a book
+// @Description Mark an available book as checked out.
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Success 200 {object} models.Book
+// @Failure 404 {object} map[string]string "Book not found"
+// @Failure 409 {object} map[string]string "Book is already checked out"
+// @Router /books/{id}/checkout [post]
+func CheckoutBook(ctx *gin.Context) {
+	setAvailability(ctx, false, "book.checked_out", "Book is already checked out")
+}
+
+// ReturnBook godoc
+// @Summary Return a book
+// @Description Mark a checked out book as available again.
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Success 200 {object} models.Book
+// @Failure 404 {object} map[string]string "Book not found"
+// @Failure 409 {object} map[string]string "Book is not checked out"
+// @Router /books/{id}/return [post]
+func ReturnBook(ctx *gin.Context) {
+	setAvailability(ctx, true, "book.returned", "Book is not checked out")
+}
+
+// setAvailability flips the book's availability with a single guarded UPDATE,
+// so of two concurrent checkouts exactly one succeeds and the other gets
+// conflict as its error
+func setAvailability(ctx *gin.Context, available bool, event, conflict string) {
+	id, ok := bookIDParam(ctx)
+	if !ok {
+		return
+	}
+	var book models.Book
+	err := database.Transact(ctx.Request.Context(), func(tx *gorm.DB) error {
+		book = models.Book{}
+		result := tx.Model(&book).Clauses(clause.Returning{}).
+			Where("id = ? AND available = ?", id, !available).
+			Update("available", available)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAvailabilityUnchanged
+		}
+		return outbox.Enqueue(tx, BookEventsTopic, bookEvent(ctx, event, book))
+	})
+	if errors.Is(err, errAvailabilityUnchanged) {
+		// Either the book doesn't exist or it is already in the target state
+		var count int64
+		if err := database.DB.Clauses(dbresolver.Write).Model(&models.Book{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+			return
+		}
+		if count == 0 {
+			respondMessage(ctx, http.StatusNotFound, msgBookNotFound)
+			return
+		}
+		render(ctx, http.StatusConflict, gin.H{"error": conflict})
+		return
+	}
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+		return
+	}
+
+	cacheUpdatedBook(book)
+	setBookETag(ctx, book)
+	respond(ctx, http.StatusOK, book)
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+)
+
+// Pagination headers of offset-paginated lists, mirroring the envelope meta
+const (
+	totalCountHeader = "X-Total-Count"
+	pageLimitHeader  = "X-Page-Limit"
+	pageOffsetHeader = "X-Page-Offset"
+	snapshotHeader   = "X-Snapshot"
+)
+
+// countBooks returns the number of books matching filters, cached for
+// the "count" cache TTL under the key CountBooks uses
+func countBooks(ctx *gin.Context, filters bookFilters) (int64, error) {
+	cacheKey := "books:count:" + filters.cacheKey()
+	cachedCount, err := readCache(ctx, cacheKey)
+	if err == nil {
+		if count, err := strconv.ParseInt(cachedCount, 10, 64); err == nil {
+			return count, nil
+		}
+	}
+
+	var count int64
+	if err := filters.apply(database.DB.Model(&models.Book{})).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	cacheListResult(cacheKey, count, cacheTTL("count"))
+	return count, nil
+}
+
+// setPageLinks sets an RFC 8288 Link header with the first, prev, next and
+// last pages of an offset-paginated list and returns the list's meta. The
+// links keep every other query parameter of the request. prev and next are
+// left out at the boundaries, and next and last when they are past
+// MAX_OFFSET; the header and the total are skipped when the total can't be
+// counted. The meta is also sent as X-Page-Limit, X-Page-Offset and
+// X-Total-Count headers for clients that don't read the envelope.
+func setPageLinks(ctx *gin.Context, filters bookFilters, limit, offset int) gin.H {
+	total, err := countBooks(ctx, filters)
+	if err != nil {
+		ctx.Header(pageLimitHeader, strconv.Itoa(limit))
+		ctx.Header(pageOffsetHeader, strconv.Itoa(offset))
+		return gin.H{"limit": limit, "offset": offset}
+	}
+	return pageMeta(ctx, total, limit, offset)
+}
+
+// pageMeta is setPageLinks for a list whose total is already known
+func pageMeta(ctx *gin.Context, total int64, limit, offset int) gin.H {
+	meta := gin.H{"limit": limit, "offset": offset, "total": total}
+	ctx.Header(pageLimitHeader, strconv.Itoa(limit))
+	ctx.Header(pageOffsetHeader, strconv.Itoa(offset))
+	ctx.Header(totalCountHeader, strconv.FormatInt(total, 10))
+	// A search body can't be carried by a link, so POST searches only get meta
+	if ctx.Request.Method != http.MethodGet {
+		return meta
+	}
+
+	lastOffset := 0
+	if total > 0 {
+		lastOffset = int((total - 1) / int64(limit) * int64(limit))
+	}
+	links := []string{pageLink(ctx, "first", "offset", "0", limit)}
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, pageLink(ctx, "prev", "offset", strconv.Itoa(prev), limit))
+	}
+	if int64(offset+limit) < total && offset+limit <= maxOffset() {
+		links = append(links, pageLink(ctx, "next", "offset", strconv.Itoa(offset+limit), limit))
+	}
+	if lastOffset <= maxOffset() {
+		links = append(links, pageLink(ctx, "last", "offset", strconv.Itoa(lastOffset), limit))
+	}
+	// Add keeps the successor-version link of the deprecated routes
+	ctx.Writer.Header().Add("Link", strings.Join(links, ", "))
+	return meta
+}
+
+// setCursorLink sets a Link header pointing at the next keyset page
+func setCursorLink(ctx *gin.Context, nextCursor uint, limit int) {
+	if ctx.Request.Method != http.MethodGet {
+		return
+	}
+	ctx.Writer.Header().Add("Link", pageLink(ctx, "next", "after_id", strconv.FormatUint(uint64(nextCursor), 10), limit))
+}
+
+// latestBookID returns the highest book id, the snapshot token of a first
+// page, or zero when there are no books. It is cached with the lists, which
+// every write drops, so a first page served from the cache doesn't query the
+// database for its snapshot.
+func latestBookID(ctx *gin.Context) (uint, error) {
+	cacheKey := "books:snapshot"
+	cachedID, err := readCache(ctx, cacheKey)
+	if err == nil {
+		if id, err := strconv.ParseUint(cachedID, 10, 64); err == nil {
+			return uint(id), nil
+		}
+	}
+
+	var id uint
+	if err := database.DB.Model(&models.Book{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error; err != nil {
+		return 0, err
+	}
+	cacheListResult(cacheKey, id, cacheTTL("list"))
+	return id, nil
+}
+
+// addLinkParam adds a query parameter to the request URL, so every page link
+// set afterwards carries it
+func addLinkParam(ctx *gin.Context, param, value string) {
+	query := ctx.Request.URL.Query()
+	query.Set(param, value)
+	ctx.Request.URL.RawQuery = query.Encode()
+}
+
+func pageLink(ctx *gin.Context, rel, param, value string, limit int) string {
+	query := ctx.Request.URL.Query()
+	query.Set(param, value)
+	query.Set("limit", strconv.Itoa(limit))
+	return fmt.Sprintf("<%s?%s>; rel=%q", ctx.Request.URL.Path, query.Encode(), rel)
+}
@@ -0,0 +1,92 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rohans540/books-backend/testutil"
+)
+
+var linkPattern = regexp.MustCompile(`<([^>]*)>; rel="([^"]*)"`)
+
+// links maps the rel of every link in the Link headers of w to its URL
+func links(t *testing.T, w http.ResponseWriter) map[string]*url.URL {
+	t.Helper()
+	out := map[string]*url.URL{}
+	for _, header := range w.Header().Values("Link") {
+		for _, match := range linkPattern.FindAllStringSubmatch(header, -1) {
+			target, err := url.Parse(match[1])
+			if err != nil {
+				t.Fatalf("parse link %q: %v", match[1], err)
+			}
+			out[match[2]] = target
+		}
+	}
+	return out
+}
+
+func TestPageLinks(t *testing.T) {
+	router, db := setup(t)
+	seedNumbered(t, db, 5)
+
+	tests := []struct {
+		offset string
+		want   map[string]string // rel to offset
+	}{
+		{"0", map[string]string{"first": "0", "next": "2", "last": "4"}},
+		{"2", map[string]string{"first": "0", "prev": "0", "next": "4", "last": "4"}},
+		{"3", map[string]string{"first": "0", "prev": "1", "last": "4"}},
+		{"4", map[string]string{"first": "0", "prev": "2", "last": "4"}},
+	}
+	for _, tt := range tests {
+		t.Run("offset "+tt.offset, func(t *testing.T) {
+			w := testutil.Request(router, http.MethodGet, "/v1/books?limit=2&sort=title&offset="+tt.offset, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			got := map[string]string{}
+			for rel, link := range links(t, w) {
+				if link.Path != "/v1/books" {
+					t.Errorf("%s link path = %q, want /v1/books", rel, link.Path)
+				}
+				query := link.Query()
+				// Other query parameters are kept
+				if query.Get("limit") != "2" || query.Get("sort") != "title" {
+					t.Errorf("%s link %s doesn't keep limit and sort", rel, link)
+				}
+				got[rel] = query.Get("offset")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("links = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageLinksOfEmptyList(t *testing.T) {
+	router, _ := setup(t)
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books?limit=2", "")
+	got := links(t, w)
+	if len(got) != 2 || got["first"] == nil || got["last"] == nil || got["last"].Query().Get("offset") != "0" {
+		t.Errorf("links = %v, want first and last at offset 0", w.Header().Values("Link"))
+	}
+}
+
+func TestPageLinksOnDeprecatedRoute(t *testing.T) {
+	router, db := setup(t)
+	seedNumbered(t, db, 3)
+
+	w := testutil.Request(router, http.MethodGet, "/books?limit=2", "")
+	got := links(t, w)
+	if got["successor-version"] == nil || got["successor-version"].Path != "/v1/books" {
+		t.Errorf("Link = %q, want a successor-version link to /v1/books", w.Header().Values("Link"))
+	}
+	if got["next"] == nil || !strings.HasPrefix(got["next"].Path, "/books") {
+		t.Errorf("Link = %q, want a next page link", w.Header().Values("Link"))
+	}
+}
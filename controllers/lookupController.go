@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+)
+
+// LookupRequest lists the ISBNs to look up
+type LookupRequest struct {
+	ISBNs []string `json:"isbns" binding:"required"`
+}
+
+// lookupResult is the response of POST /books/lookup
+type lookupResult struct {
+	Books []models.Book `json:"books" xml:"books>book"`
+	// NotFound echoes the requested ISBNs, as sent, that match no book
+	// or aren't valid ISBNs
+	NotFound []string `json:"not_found" xml:"not_found>isbn"`
+}
+
+func isbnCacheKey(isbn string) string {
+	return "book:isbn:" + models.NormalizeISBN(isbn)
+}
+
+// LookupBooksByISBN godoc
+// @Summary Look up books by ISBN
+// @Description Return the books matching a list of ISBNs, in request order, and the ISBNs that match no book, so a
+// @Description partner can reconcile their catalog against ours. ISBNs may contain hyphens and spaces. Cached books are
+// @Description served from the cache and the rest are loaded in a single query. At most MAX_BATCH_SIZE ISBNs per request.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param request body LookupRequest true "ISBNs to look up"
+// @Success 200 {object} lookupResult
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 413 {object} map[string]string "More ISBNs than MAX_BATCH_SIZE"
+// @Failure 500 {object} map[string]string "Error fetching books"
+// @Router /books/lookup [post]
+func LookupBooksByISBN(ctx *gin.Context) {
+	var req LookupRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	if maxSize := maxBatchSize(); len(req.ISBNs) > maxSize {
+		render(ctx, http.StatusRequestEntityTooLarge, gin.H{"error": batchTooLargeError{count: int64(len(req.ISBNs)), max: maxSize}.Error()})
+		return
+	}
+
+	// Requested ISBNs in canonical form, without duplicates, keeping the
+	// first spelling of each for the not-found list
+	result := lookupResult{Books: []models.Book{}, NotFound: []string{}}
+	var isbns []string
+	sent := make(map[string]string)
+	for _, raw := range req.ISBNs {
+		isbn := models.NormalizeISBN(raw)
+		if !models.IsValidISBN(isbn) {
+			result.NotFound = append(result.NotFound, raw)
+			continue
+		}
+		if _, ok := sent[isbn]; !ok {
+			sent[isbn] = raw
+			isbns = append(isbns, isbn)
+		}
+	}
+	if len(isbns) == 0 {
+		respond(ctx, http.StatusOK, result)
+		return
+	}
+
+	found := make(map[string]models.Book, len(isbns))
+	keys := make([]string, len(isbns))
+	for i, isbn := range isbns {
+		keys[i] = isbnCacheKey(isbn)
+	}
+	var cached []string
+	if !bypassCache(ctx) {
+		cached, _ = redis.BookCache.MGet(context.Background(), keys...)
+	}
+	var missing []string
+	for i, isbn := range isbns {
+		var book models.Book
+		if i < len(cached) && cached[i] != "" && json.Unmarshal([]byte(cached[i]), &book) == nil {
+			found[isbn] = book
+			continue
+		}
+		missing = append(missing, isbn)
+	}
+
+	if cached != nil {
+		recordCacheReads(len(keys)-len(missing), len(missing))
+	}
+	if len(missing) > 0 {
+		var books []models.Book
+		if err := database.DB.Where("isbn IN ?", missing).Find(&books).Error; err != nil {
+			render(ctx, http.StatusInternalServerError, gin.H{"error": "Error fetching books"})
+			return
+		}
+		for _, book := range books {
+			found[*book.ISBN] = book
+			data, _ := json.Marshal(book)
+			cacheBook(strconv.FormatUint(uint64(book.ID), 10), isbnCacheKey(*book.ISBN), data)
+		}
+	}
+
+	for _, isbn := range isbns {
+		if book, ok := found[isbn]; ok {
+			result.Books = append(result.Books, book)
+		} else {
+			result.NotFound = append(result.NotFound, sent[isbn])
+		}
+	}
+	respond(ctx, http.StatusOK, result)
+}
@@ -0,0 +1,49 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestBookLookupErrors(t *testing.T) {
+	requests := []struct{ method, path, body string }{
+		{http.MethodGet, "/v1/books/1", ""},
+		{http.MethodGet, "/v1/books/slug/dune", ""},
+		{http.MethodPut, "/v1/books/1", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`},
+		{http.MethodDelete, "/v1/books/1", ""},
+		{http.MethodPost, "/v1/books/1/checkout", ""},
+	}
+
+	t.Run("missing book", func(t *testing.T) {
+		router, _ := setup(t)
+		for _, r := range requests {
+			if w := testutil.Request(router, r.method, r.path, r.body); w.Code != http.StatusNotFound {
+				t.Errorf("%s %s: status = %d, want 404: %s", r.method, r.path, w.Code, w.Body)
+			}
+		}
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		router, db := setup(t)
+		if err := db.Migrator().DropTable(&models.Book{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range requests {
+			if w := testutil.Request(router, r.method, r.path, r.body); w.Code != http.StatusInternalServerError {
+				t.Errorf("%s %s: status = %d, want 500: %s", r.method, r.path, w.Code, w.Body)
+			}
+		}
+	})
+}
+
+func TestInvalidBookIDIsNotFound(t *testing.T) {
+	router, _ := setup(t)
+	for _, id := range []string{"0", "-1", "abc", "1.5"} {
+		if w := testutil.Request(router, http.MethodGet, "/v1/books/"+id, ""); w.Code != http.StatusNotFound {
+			t.Errorf("id %q: status = %d, want 404", id, w.Code)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/models"
+	"golang.org/x/text/language"
+)
+
+// messageCode identifies an error message in the catalog. It is returned
+// next to the message so clients can also translate errors themselves.
+type messageCode string
+
+const (
+	msgBookNotFound      messageCode = "book_not_found"
+	msgRouteNotFound     messageCode = "route_not_found"
+	msgInvalidJSON       messageCode = "invalid_json"
+	msgBodyRequired      messageCode = "body_required"
+	msgUnknownField      messageCode = "unknown_field"
+	msgTitleEmpty        messageCode = "title_empty"
+	msgAuthorEmpty       messageCode = "author_empty"
+	msgAuthorsEmptyName  messageCode = "authors_empty_name"
+	msgAuthorsDuplicate  messageCode = "authors_duplicate"
+	msgTooLong           messageCode = "too_long"
+	msgControlChars      messageCode = "control_characters"
+	msgYearInvalid       messageCode = "year_invalid"
+	msgYearNotWhole      messageCode = "year_not_whole"
+	msgYearZero          messageCode = "year_zero"
+	msgYearTooEarly      messageCode = "year_too_early"
+	msgISBNInvalid       messageCode = "isbn_invalid"
+	msgISBNChecksum      messageCode = "isbn_checksum"
+	msgLanguageInvalid   messageCode = "language_invalid"
+	msgValidationWarning messageCode = "validation_warnings"
+	msgResponseTooLarge  messageCode = "response_too_large"
+	msgIDInvalid         messageCode = "id_invalid"
+	msgTooManyIDs        messageCode = "too_many_ids"
+	msgYearNotNumber     messageCode = "year_not_number"
+	msgLanguageUnknown   messageCode = "language_unknown"
+	msgAvailableInvalid  messageCode = "available_invalid"
+	msgAfterIDInvalid    messageCode = "after_id_invalid"
+	msgSnapshotInvalid   messageCode = "snapshot_invalid"
+	msgSortUnknown       messageCode = "sort_unknown"
+	msgSortNeedsQuery    messageCode = "sort_requires_q"
+	msgSortWithAfterID   messageCode = "sort_with_after_id"
+	msgOffsetTooLarge    messageCode = "offset_too_large"
+	msgWarnYearOld       messageCode = "warning_year_old"
+	msgWarnYearFuture    messageCode = "warning_year_future"
+	msgWarnISBNChecksum  messageCode = "warning_isbn_checksum"
+	msgWarnTitleCaps     messageCode = "warning_title_caps"
+	msgWarnAuthorCaps    messageCode = "warning_author_caps"
+)
+
+// supportedLanguages are the languages of the catalog; the first one is the
+// default for requests without a matching Accept-Language
+var supportedLanguages = []language.Tag{language.English, language.Spanish}
+
+var languageMatcher = language.NewMatcher(supportedLanguages)
+
+// messages holds the text of every message code per language. Messages with
+// arguments are fmt format strings.
+var messages = map[language.Tag]map[messageCode]string{
+	language.English: {
+		msgBookNotFound:      "Book not found",
+		msgRouteNotFound:     "Route not found",
+		msgInvalidJSON:       "Invalid JSON data",
+		msgBodyRequired:      "Request body is required",
+		msgUnknownField:      "Unknown field %s",
+		msgTitleEmpty:        "Title cannot be empty",
+		msgAuthorEmpty:       "Author cannot be empty",
+		msgAuthorsEmptyName:  "Authors cannot contain empty names",
+		msgAuthorsDuplicate:  "Authors cannot contain duplicates",
+		msgTooLong:           "%s must be at most %d characters",
+		msgControlChars:      "%s cannot contain control characters",
+		msgYearInvalid:       "Year must be a valid positive number",
+		msgYearNotWhole:      "Year must be a whole number",
+		msgYearZero:          "There is no year 0; use -1 for 1 BCE",
+		msgYearTooEarly:      "Year must be %d or later",
+		msgISBNInvalid:       "ISBN must be 10 digits (the last may be X) or 13 digits",
+		msgISBNChecksum:      "ISBN check digit is invalid",
+		msgLanguageInvalid:   "Language must be a valid ISO 639-1 code",
+		msgValidationWarning: "Book has validation warnings",
+		msgResponseTooLarge:  "Response of %d bytes exceeds the limit of %d bytes; request fewer books with limit and paginate",
+		msgIDInvalid:         "Invalid id: %s",
+		msgTooManyIDs:        "At most %d ids can be requested at once",
+		msgYearNotNumber:     "Year must be a number",
+		msgLanguageUnknown:   "Unknown language: %s",
+		msgAvailableInvalid:  "Available must be true or false",
+		msgAfterIDInvalid:    "after_id must be a positive number",
+		msgSnapshotInvalid:   "snapshot must be a positive number",
+		msgSortUnknown:       "Unknown sort: %s",
+		msgSortNeedsQuery:    "sort=relevance requires q",
+		msgSortWithAfterID:   "sort=%s cannot be combined with after_id",
+		msgOffsetTooLarge:    "offset must not exceed %d; page further with after_id (keyset pagination) instead",
+		msgWarnYearOld:       "Year %d is unusually old",
+		msgWarnYearFuture:    "Year %d is in the future",
+		msgWarnISBNChecksum:  "ISBN check digit doesn't match",
+		msgWarnTitleCaps:     "Title is in all caps",
+		msgWarnAuthorCaps:    "Author %s is in all caps",
+	},
+	language.Spanish: {
+		msgBookNotFound:      "Libro no encontrado",
+		msgRouteNotFound:     "Ruta no encontrada",
+		msgInvalidJSON:       "Datos JSON no válidos",
+		msgBodyRequired:      "El cuerpo de la solicitud es obligatorio",
+		msgUnknownField:      "Campo desconocido %s",
+		msgTitleEmpty:        "El título no puede estar vacío",
+		msgAuthorEmpty:       "El autor no puede estar vacío",
+		msgAuthorsEmptyName:  "La lista de autores no puede contener nombres vacíos",
+		msgAuthorsDuplicate:  "La lista de autores no puede contener duplicados",
+		msgTooLong:           "%s debe tener como máximo %d caracteres",
+		msgControlChars:      "%s no puede contener caracteres de control",
+		msgYearInvalid:       "El año debe ser un número positivo válido",
+		msgYearNotWhole:      "El año debe ser un número entero",
+		msgYearZero:          "No existe el año 0; use -1 para el 1 a. C.",
+		msgYearTooEarly:      "El año debe ser %d o posterior",
+		msgISBNInvalid:       "El ISBN debe tener 10 dígitos (el último puede ser X) o 13 dígitos",
+		msgISBNChecksum:      "El dígito de control del ISBN no es válido",
+		msgLanguageInvalid:   "El idioma debe ser un código ISO 639-1 válido",
+		msgValidationWarning: "El libro tiene advertencias de validación",
+		msgResponseTooLarge:  "La respuesta de %d bytes supera el límite de %d bytes; solicite menos libros con limit y pagine",
+		msgIDInvalid:         "Id no válido: %s",
+		msgTooManyIDs:        "Se pueden solicitar como máximo %d ids a la vez",
+		msgYearNotNumber:     "El año debe ser un número",
+		msgLanguageUnknown:   "Idioma desconocido: %s",
+		msgAvailableInvalid:  "available debe ser true o false",
+		msgAfterIDInvalid:    "after_id debe ser un número positivo",
+		msgSnapshotInvalid:   "snapshot debe ser un número positivo",
+		msgSortUnknown:       "Orden desconocido: %s",
+		msgSortNeedsQuery:    "sort=relevance requiere q",
+		msgSortWithAfterID:   "sort=%s no se puede combinar con after_id",
+		msgOffsetTooLarge:    "offset no puede superar %d; siga paginando con after_id (paginación por clave)",
+		msgWarnYearOld:       "El año %d es inusualmente antiguo",
+		msgWarnYearFuture:    "El año %d está en el futuro",
+		msgWarnISBNChecksum:  "El dígito de control del ISBN no coincide",
+		msgWarnTitleCaps:     "El título está todo en mayúsculas",
+		msgWarnAuthorCaps:    "El autor %s está todo en mayúsculas",
+	},
+}
+
+// message returns the text of code in lang, falling back to English for
+// codes a language doesn't translate
+func message(lang language.Tag, code messageCode, args ...interface{}) string {
+	format, ok := messages[lang][code]
+	if !ok {
+		format = messages[language.English][code]
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// requestLanguage picks the catalog language that best matches the
+// Accept-Language header, English when nothing matches
+func requestLanguage(ctx *gin.Context) language.Tag {
+	tags, _, _ := language.ParseAcceptLanguage(ctx.GetHeader("Accept-Language"))
+	_, index, confidence := languageMatcher.Match(tags...)
+	if confidence == language.No {
+		return supportedLanguages[0]
+	}
+	return supportedLanguages[index]
+}
+
+// localizedError is an error whose message is in the catalog
+type localizedError interface {
+	error
+	messageCode() messageCode
+	localize(lang language.Tag) string
+}
+
+// messageError is an error with a catalog message. Error returns the
+// English text.
+type messageError struct {
+	code messageCode
+	args []interface{}
+}
+
+func newMessageError(code messageCode, args ...interface{}) messageError {
+	return messageError{code: code, args: args}
+}
+
+func (e messageError) Error() string {
+	return e.localize(language.English)
+}
+
+func (e messageError) messageCode() messageCode {
+	return e.code
+}
+
+func (e messageError) localize(lang language.Tag) string {
+	return message(lang, e.code, e.args...)
+}
+
+// errorBody is the body of a localized error response
+func errorBody(ctx *gin.Context, code messageCode, args ...interface{}) gin.H {
+	lang := requestLanguage(ctx)
+	ctx.Header("Content-Language", lang.String())
+	return gin.H{"error": message(lang, code, args...), "code": code}
+}
+
+// respondMessage answers status with the catalog message code in the
+// language the client asked for
+func respondMessage(ctx *gin.Context, status int, code messageCode, args ...interface{}) {
+	render(ctx, status, errorBody(ctx, code, args...))
+}
+
+// errorMessage returns the message of err in the client's language, adding
+// its code to body. Errors outside the catalog keep their own text.
+func errorMessage(ctx *gin.Context, err error, body gin.H) string {
+	if errors.Is(err, models.ErrInvalidYear) {
+		err = newMessageError(msgYearNotWhole)
+	}
+	var localized localizedError
+	if !errors.As(err, &localized) {
+		return err.Error()
+	}
+	lang := requestLanguage(ctx)
+	ctx.Header("Content-Language", lang.String())
+	body["code"] = localized.messageCode()
+	return localized.localize(lang)
+}
+
+// respondError answers status with err's message in the client's language
+func respondError(ctx *gin.Context, status int, err error) {
+	body := gin.H{}
+	body["error"] = errorMessage(ctx, err, body)
+	render(ctx, status, body)
+}
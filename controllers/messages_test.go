@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/testutil"
+	"golang.org/x/text/language"
+)
+
+func TestRequestLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   language.Tag
+	}{
+		{"", language.English},
+		{"es", language.Spanish},
+		{"es-MX", language.Spanish},
+		{"fr, es;q=0.5", language.Spanish},
+		{"es;q=0.3, en;q=0.8", language.English},
+		{"fr", language.English},
+		{"not a language tag", language.English},
+	}
+	for _, tt := range tests {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.Header.Set("Accept-Language", tt.header)
+		if got := requestLanguage(ctx); got != tt.want {
+			t.Errorf("Accept-Language %q: language = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMessageCatalogIsComplete(t *testing.T) {
+	for _, lang := range supportedLanguages {
+		for code, english := range messages[language.English] {
+			text, ok := messages[lang][code]
+			if !ok {
+				t.Errorf("%s: no message for %s", lang, code)
+				continue
+			}
+			if strings.Count(text, "%") != strings.Count(english, "%") {
+				t.Errorf("%s: message for %s takes other arguments than the English one", lang, code)
+			}
+		}
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/books", GetBooks)
+	router.GET("/books/:id", GetBookByID)
+	router.POST("/books", CreateBook)
+	router.POST("/books/search", SearchBooks)
+
+	tests := []struct {
+		name, method, path, body, acceptLanguage string
+		code                                     messageCode
+		language, message                        string
+	}{
+		{"not found, default", http.MethodGet, "/books/1", "", "", msgBookNotFound, "en", "Book not found"},
+		{"not found, Spanish", http.MethodGet, "/books/1", "", "es-ES,es;q=0.9", msgBookNotFound, "es", "Libro no encontrado"},
+		{"not found, unsupported", http.MethodGet, "/books/1", "", "de", msgBookNotFound, "en", "Book not found"},
+		{"validation, Spanish", http.MethodPost, "/books", `{"author": "Frank Herbert", "year": 1965}`, "es", msgTitleEmpty, "es", "El título no puede estar vacío"},
+		{"validation with arguments, Spanish", http.MethodPost, "/books", `{"title": "` + strings.Repeat("t", 300) + `", "author": "Frank Herbert", "year": 1965}`, "es", msgTooLong, "es", "Title debe tener como máximo 255 caracteres"},
+		{"filter, Spanish", http.MethodGet, "/books?year=soon", "", "es", msgYearNotNumber, "es", "El año debe ser un número"},
+		{"sort, Spanish", http.MethodGet, "/books?sort=pages", "", "es", msgSortUnknown, "es", "Orden desconocido: pages"},
+		{"sort, default", http.MethodGet, "/books?sort=pages", "", "", msgSortUnknown, "en", "Unknown sort: pages"},
+		{"fields, Spanish", http.MethodGet, "/books/1?fields=pages", "", "es", msgUnknownField, "es", "Campo desconocido pages"},
+		{"search, Spanish", http.MethodPost, "/books/search", `{"sort": "relevance"}`, "es", msgSortNeedsQuery, "es", "sort=relevance requiere q"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Request(router, tt.method, tt.path, tt.body, "Accept-Language", tt.acceptLanguage)
+			var body struct {
+				Error string      `json:"error"`
+				Code  messageCode `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode %s: %v", w.Body, err)
+			}
+			if body.Code != tt.code || body.Error != tt.message {
+				t.Errorf("error = %q (%s), want %q (%s)", body.Error, body.Code, tt.message, tt.code)
+			}
+			if got := w.Header().Get("Content-Language"); got != tt.language {
+				t.Errorf("Content-Language = %q, want %q", got, tt.language)
+			}
+		})
+	}
+}
+
+func TestLocalizedWarnings(t *testing.T) {
+	testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.POST("/books", CreateBook)
+
+	w := testutil.Request(router, http.MethodPost, "/books", `{"title": "DUNE", "author": "Frank Herbert", "year": 1965}`, "Accept-Language", "es")
+	var body struct {
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	if len(body.Warnings) != 1 || body.Warnings[0] != "El título está todo en mayúsculas" {
+		t.Errorf("warnings = %q, want the all caps title in Spanish", body.Warnings)
+	}
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/outbox"
+)
+
+const (
+	// overviewTTL is how long an overview is reused, so a dashboard polling
+	// it doesn't add load while the service is already struggling
+	overviewTTL = 5 * time.Second
+	// overviewEvents is how many of the latest events an overview lists
+	overviewEvents = 10
+)
+
+// AdminOverview is the response of GET /admin/overview
+type AdminOverview struct {
+	GeneratedAt  time.Time              `json:"generated_at"`
+	Books        overviewBooks          `json:"books"`
+	Cache        overviewCache          `json:"cache"`
+	RecentEvents []historyEvent         `json:"recent_events"`
+	DatabasePool overviewPool           `json:"database_pool"`
+	Health       map[string]interface{} `json:"health" swaggertype:"object"`
+}
+
+type overviewBooks struct {
+	Total int64 `json:"total"`
+}
+
+// overviewCache counts this instance's cache reads since it started.
+// HitRatio is null before the first read.
+type overviewCache struct {
+	Hits     int64    `json:"hits"`
+	Misses   int64    `json:"misses"`
+	HitRatio *float64 `json:"hit_ratio"`
+}
+
+// overviewPool is the state of this instance's connection pool to the
+// primary database
+type overviewPool struct {
+	MaxOpen        int   `json:"max_open"`
+	Open           int   `json:"open"`
+	InUse          int   `json:"in_use"`
+	Idle           int   `json:"idle"`
+	WaitCount      int64 `json:"wait_count"`
+	WaitDurationMs int64 `json:"wait_duration_ms"`
+}
+
+// lastOverview is the overview served until it is overviewTTL old
+var lastOverview struct {
+	sync.Mutex
+	overview AdminOverview
+}
+
+// GetOverview godoc
+// @Summary Get an operational overview
+// @Description Return, in one call, the number of books, this instance's cache hit ratio and database pool state, the
+// @Description latest events and the health of the database, cache and Kafka. The overview is reused for five seconds,
+// @Description so polling it is cheap. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} AdminOverview
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to build the overview"
+// @Router /admin/overview [get]
+func GetOverview(ctx *gin.Context) {
+	lastOverview.Lock()
+	overview := lastOverview.overview
+	lastOverview.Unlock()
+	if time.Since(overview.GeneratedAt) < overviewTTL {
+		respond(ctx, http.StatusOK, overview)
+		return
+	}
+
+	overview, err := loadOnce("admin:overview", func() (AdminOverview, error) {
+		return buildOverview(ctx)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build the overview"})
+		return
+	}
+	lastOverview.Lock()
+	lastOverview.overview = overview
+	lastOverview.Unlock()
+	respond(ctx, http.StatusOK, overview)
+}
+
+func buildOverview(ctx *gin.Context) (AdminOverview, error) {
+	overview := AdminOverview{GeneratedAt: time.Now().UTC()}
+
+	db := database.DB.WithContext(ctx.Request.Context())
+	if err := db.Model(&models.Book{}).Count(&overview.Books.Total).Error; err != nil {
+		return overview, err
+	}
+	events, err := outbox.History(db, outbox.HistoryFilter{}, overviewEvents, 0)
+	if err != nil {
+		return overview, err
+	}
+	overview.RecentEvents = toHistoryEvents(events)
+
+	overview.Cache.Hits, overview.Cache.Misses = cacheHits.Value(), cacheMisses.Value()
+	if reads := overview.Cache.Hits + overview.Cache.Misses; reads > 0 {
+		ratio := float64(overview.Cache.Hits) / float64(reads)
+		overview.Cache.HitRatio = &ratio
+	}
+
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return overview, err
+	}
+	stats := sqlDB.Stats()
+	overview.DatabasePool = overviewPool{
+		MaxOpen:        stats.MaxOpenConnections,
+		Open:           stats.OpenConnections,
+		InUse:          stats.InUse,
+		Idle:           stats.Idle,
+		WaitCount:      stats.WaitCount,
+		WaitDurationMs: stats.WaitDuration.Milliseconds(),
+	}
+
+	overview.Health, _ = checkDependencies(ctx)
+	return overview, nil
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestOverviewIncludesEachSection(t *testing.T) {
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	testutil.SetupPublisher(t)
+	resetOverview := func() {
+		lastOverview.Lock()
+		lastOverview.overview = AdminOverview{}
+		lastOverview.Unlock()
+	}
+	resetOverview()
+	t.Cleanup(resetOverview)
+	router := testutil.NewRouter()
+	router.POST("/books", CreateBook)
+	router.GET("/books/:id", GetBookByID)
+	router.GET("/admin/overview", GetOverview)
+
+	testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})
+	w := testutil.Request(router, http.MethodPost, "/books", `{"title": "Neuromancer", "author": "William Gibson", "year": 1984}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+	}
+	testutil.Request(router, http.MethodGet, "/books/1", "")
+
+	w = testutil.Request(router, http.MethodGet, "/admin/overview", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var sections map[string]json.RawMessage
+	json.Unmarshal(w.Body.Bytes(), &sections)
+	for _, section := range []string{"generated_at", "books", "cache", "recent_events", "database_pool", "health"} {
+		if _, ok := sections[section]; !ok {
+			t.Errorf("overview lacks %s: %s", section, w.Body)
+		}
+	}
+
+	var overview AdminOverview
+	json.Unmarshal(w.Body.Bytes(), &overview)
+	if overview.Books.Total != 2 {
+		t.Errorf("books total = %d, want 2", overview.Books.Total)
+	}
+	if overview.Cache.Hits+overview.Cache.Misses == 0 || overview.Cache.HitRatio == nil {
+		t.Errorf("cache = %+v, want the reads counted with a hit ratio", overview.Cache)
+	}
+	if len(overview.RecentEvents) != 1 || overview.RecentEvents[0].EventType != "book.created" {
+		t.Errorf("recent events = %+v, want the book.created event", overview.RecentEvents)
+	}
+	if overview.DatabasePool.Open == 0 {
+		t.Errorf("database pool = %+v, want the open connections", overview.DatabasePool)
+	}
+	if overview.Health["database"] != "ok" || overview.Health["kafka"] != "disabled" {
+		t.Errorf("health = %v, want the database ok and Kafka disabled", overview.Health)
+	}
+
+	// Reused for a few seconds instead of being rebuilt
+	testutil.SeedBooks(t, db, models.Book{Title: "Hyperion", Author: "Dan Simmons", Year: 1989})
+	var again AdminOverview
+	json.Unmarshal(testutil.Request(router, http.MethodGet, "/admin/overview", "").Body.Bytes(), &again)
+	if again.Books.Total != 2 || !again.GeneratedAt.Equal(overview.GeneratedAt) {
+		t.Errorf("second overview counts %d books, generated at %s, want the first one reused", again.Books.Total, again.GeneratedAt)
+	}
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+)
+
+const (
+	defaultPageSize    = 10
+	defaultMaxPageSize = 100
+	// defaultMaxOffset keeps offset pages from making the database skip over
+	// more rows than this; deeper pages are reached with after_id
+	defaultMaxOffset = 10000
+)
+
+// limitClampedHeader is set to the maximum page size when a requested limit
+// was lowered to it
+const limitClampedHeader = "X-Limit-Clamped"
+
+// ValidatePageSizes checks DEFAULT_PAGE_SIZE, MAX_PAGE_SIZE and MAX_OFFSET so
+// a bad value fails at startup instead of being silently replaced by the
+// defaults
+func ValidatePageSizes() error {
+	if _, _, err := pageSizes(); err != nil {
+		return err
+	}
+	_, err := positiveEnvInt("MAX_OFFSET", defaultMaxOffset)
+	return err
+}
+
+// maxOffset returns the deepest offset book lists accept, from MAX_OFFSET
+func maxOffset() int {
+	offset, err := positiveEnvInt("MAX_OFFSET", defaultMaxOffset)
+	if err != nil {
+		return defaultMaxOffset
+	}
+	return offset
+}
+
+// pageSizes returns the default and maximum list page size from
+// DEFAULT_PAGE_SIZE and MAX_PAGE_SIZE, which default to 10 and 100
+func pageSizes() (int, int, error) {
+	defaultSize, err := positiveEnvInt("DEFAULT_PAGE_SIZE", defaultPageSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxSize, err := positiveEnvInt("MAX_PAGE_SIZE", defaultMaxPageSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	if defaultSize > maxSize {
+		return 0, 0, fmt.Errorf("DEFAULT_PAGE_SIZE (%d) is larger than MAX_PAGE_SIZE (%d)", defaultSize, maxSize)
+	}
+	return defaultSize, maxSize, nil
+}
+
+func positiveEnvInt(key string, fallback int) (int, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number, got %q", key, raw)
+	}
+	return value, nil
+}
+
+// pageLimit reads ?limit=, using the default page size when it is missing or
+// not positive. A limit above the maximum is lowered to it and flagged with
+// the X-Limit-Clamped header.
+func pageLimit(ctx *gin.Context) int {
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+	return clampLimit(ctx, limit)
+}
+
+// clampLimit applies the page size rules of pageLimit to a limit that was
+// already parsed, e.g. from a request body
+func clampLimit(ctx *gin.Context, limit int) int {
+	defaultSize, maxSize, err := pageSizes()
+	if err != nil {
+		defaultSize, maxSize = defaultPageSize, defaultMaxPageSize
+	}
+
+	if limit <= 0 {
+		return defaultSize
+	}
+	if limit > maxSize {
+		ctx.Header(limitClampedHeader, strconv.Itoa(maxSize))
+		return maxSize
+	}
+	return limit
+}
+
+// keysetPage is the response body for keyset (cursor) pagination
+type keysetPage struct {
+	Books      interface{} `json:"books"`
+	NextCursor *uint       `json:"next_cursor"`
+}
+
+// keysetPageCacheKey returns the key getBooksAfter caches a page under
+func keysetPageCacheKey(afterID uint, limit int, filters bookFilters, fields []string) string {
+	return fieldsCacheKey(fmt.Sprintf("%s:after_id=%d&limit=%d", filters.listCacheKey(), afterID, limit), fields)
+}
+
+// loadBooksAfter reads a keyset page from the database and caches it under
+// cacheKey
+func loadBooksAfter(afterID uint, limit int, filters bookFilters, fields []string, cacheKey string) ([]models.Book, error) {
+	var books []models.Book
+	query := filters.apply(database.DB).Where("id > ?", afterID).Order("id").Limit(limit)
+	if fields != nil {
+		// The id column is always needed to build the next cursor
+		query = query.Select(append(selectColumns(fields), bookColumns["id"]))
+	}
+	if err := query.Find(&books).Error; err != nil {
+		return nil, err
+	}
+
+	booksJSON, _ := json.Marshal(books)
+	cacheListResult(cacheKey, booksJSON, cacheTTL("list"))
+	if fields == nil {
+		hydrateBookCache(books)
+	}
+	return books, nil
+}
+
+// getBooksAfter serves one page of books with an id greater than afterID,
+// ordered by id. next_cursor is null once the last page has been reached.
+func getBooksAfter(ctx *gin.Context, afterID uint, limit int, filters bookFilters, fields []string) {
+	cacheKey := keysetPageCacheKey(afterID, limit, filters, fields)
+
+	var books []models.Book
+	cachedBooks, err := readCache(ctx, cacheKey)
+	if err != nil || json.Unmarshal([]byte(cachedBooks), &books) != nil {
+		if books, err = loadBooksAfter(afterID, limit, filters, fields, cacheKey); err != nil {
+			render(ctx, http.StatusInternalServerError, gin.H{"error": "Error fetching books"})
+			return
+		}
+	}
+
+	page := keysetPage{Books: books}
+	if fields != nil {
+		page.Books = filterBooks(books, fields)
+	}
+	if responseTooLarge(ctx, page.Books) {
+		return
+	}
+	if limit > 0 && len(books) == limit {
+		next := books[len(books)-1].ID
+		page.NextCursor = &next
+		setCursorLink(ctx, next, limit)
+	}
+	if wantsEnvelope(ctx) {
+		respondWithMeta(ctx, http.StatusOK, page.Books, gin.H{"next_cursor": page.NextCursor})
+		return
+	}
+	render(ctx, http.StatusOK, page)
+}
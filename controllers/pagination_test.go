@@ -0,0 +1,269 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/controllers"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestKeysetPaginationMatchesOffset(t *testing.T) {
+	router, db := setup(t)
+	seedNumbered(t, db, 5)
+
+	var offsetIDs, keysetIDs []uint
+	for offset := 0; offset < 5; offset += 2 {
+		w := testutil.Request(router, http.MethodGet, fmt.Sprintf("/v1/books?limit=2&offset=%d", offset), "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("offset %d: status = %d: %s", offset, w.Code, w.Body)
+		}
+		var page []models.Book
+		decode(t, w, &page)
+		offsetIDs = append(offsetIDs, ids(page)...)
+	}
+
+	path := "/v1/books?limit=2&after_id=0"
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("keyset pagination did not end")
+		}
+		w := testutil.Request(router, http.MethodGet, path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", path, w.Code, w.Body)
+		}
+		var page struct {
+			Books      []models.Book `json:"books"`
+			NextCursor *uint         `json:"next_cursor"`
+		}
+		decode(t, w, &page)
+		keysetIDs = append(keysetIDs, ids(page.Books)...)
+		if page.NextCursor == nil {
+			break
+		}
+		path = "/v1/books?limit=2&after_id=" + itoa(*page.NextCursor)
+	}
+
+	if fmt.Sprint(keysetIDs) != fmt.Sprint(offsetIDs) || len(offsetIDs) != 5 {
+		t.Errorf("keyset pages = %v, offset pages = %v, want the same 5 books", keysetIDs, offsetIDs)
+	}
+}
+
+func TestAfterIDTakesPrecedenceOverOffset(t *testing.T) {
+	router, db := setup(t)
+	books := seedNumbered(t, db, 4)
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books?limit=2&offset=3&after_id="+itoa(books[0].ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var page struct {
+		Books []models.Book `json:"books"`
+	}
+	decode(t, w, &page)
+	if got := ids(page.Books); fmt.Sprint(got) != fmt.Sprint(ids(books[1:3])) {
+		t.Errorf("books = %v, want %v", got, ids(books[1:3]))
+	}
+}
+
+func TestLimitClamping(t *testing.T) {
+	t.Setenv("DEFAULT_PAGE_SIZE", "2")
+	t.Setenv("MAX_PAGE_SIZE", "3")
+	router, db := setup(t)
+	seedNumbered(t, db, 5)
+
+	tests := []struct {
+		query   string
+		books   int
+		clamped string
+	}{
+		{"", 2, ""},
+		{"?limit=0", 2, ""},
+		{"?limit=3", 3, ""},
+		{"?limit=4", 3, "3"},
+		{"?limit=1000", 3, "3"},
+	}
+	for _, tt := range tests {
+		w := testutil.Request(router, http.MethodGet, "/v1/books"+tt.query, "")
+		var page []models.Book
+		decode(t, w, &page)
+		if len(page) != tt.books {
+			t.Errorf("%q: %d books, want %d", tt.query, len(page), tt.books)
+		}
+		if got := w.Header().Get("X-Limit-Clamped"); got != tt.clamped {
+			t.Errorf("%q: X-Limit-Clamped = %q, want %q", tt.query, got, tt.clamped)
+		}
+	}
+}
+
+func TestValidatePageSizes(t *testing.T) {
+	tests := []struct {
+		defaultSize, maxSize string
+		valid                bool
+	}{
+		{"", "", true},
+		{"20", "50", true},
+		{"50", "50", true},
+		{"51", "50", false},
+		{"0", "", false},
+		{"", "lots", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("DEFAULT_PAGE_SIZE", tt.defaultSize)
+		t.Setenv("MAX_PAGE_SIZE", tt.maxSize)
+		if err := controllers.ValidatePageSizes(); (err == nil) != tt.valid {
+			t.Errorf("DEFAULT_PAGE_SIZE=%q MAX_PAGE_SIZE=%q: err = %v, want valid %t", tt.defaultSize, tt.maxSize, err, tt.valid)
+		}
+	}
+}
+
+func TestPaginationHeaders(t *testing.T) {
+	router, db := setup(t)
+	seedNumbered(t, db, 5)
+
+	tests := []struct {
+		path, total, limit, offset string
+	}{
+		{"/v1/books?limit=2&offset=2", "5", "2", "2"},
+		{"/v1/books?limit=2&offset=2&year=2001", "1", "2", "2"},
+		// Keyset pages aren't counted
+		{"/v1/books?limit=2&after_id=0", "", "", ""},
+	}
+	for _, tt := range tests {
+		w := testutil.Request(router, http.MethodGet, tt.path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.path, w.Code, w.Body)
+		}
+		got := [3]string{w.Header().Get("X-Total-Count"), w.Header().Get("X-Page-Limit"), w.Header().Get("X-Page-Offset")}
+		if want := [3]string{tt.total, tt.limit, tt.offset}; got != want {
+			t.Errorf("%s: total, limit, offset headers = %q, want %q", tt.path, got, want)
+		}
+	}
+}
+
+func TestMaxOffset(t *testing.T) {
+	t.Setenv("MAX_OFFSET", "50")
+	router, db := setup(t)
+	seedNumbered(t, db, 3)
+	const hint = "offset must not exceed 50; page further with after_id (keyset pagination) instead"
+
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodGet, "/v1/books?offset=50", "", http.StatusOK},
+		{http.MethodGet, "/v1/books?offset=51", "", http.StatusBadRequest},
+		{http.MethodPost, "/v1/books/search", `{"offset": 50}`, http.StatusOK},
+		{http.MethodPost, "/v1/books/search", `{"offset": 51}`, http.StatusBadRequest},
+		// Keyset pages ignore the offset, so they can go any depth
+		{http.MethodGet, "/v1/books?offset=51&after_id=0", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := testutil.Request(router, tt.method, tt.path, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s %s %s: status = %d, want %d: %s", tt.method, tt.path, tt.body, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status == http.StatusBadRequest {
+			var got map[string]string
+			decode(t, w, &got)
+			if got["error"] != hint {
+				t.Errorf("%s %s %s: error = %q, want %q", tt.method, tt.path, tt.body, got["error"], hint)
+			}
+		}
+	}
+}
+
+func TestSnapshotKeepsPagesStable(t *testing.T) {
+	router, db := setup(t)
+	books := seedNumbered(t, db, 4)
+
+	first := testutil.Request(router, http.MethodGet, "/v1/books?limit=2&sort=recent", "")
+	snapshot := first.Header().Get("X-Snapshot")
+	if snapshot != itoa(books[3].ID) {
+		t.Fatalf("X-Snapshot = %q, want the latest id %d", snapshot, books[3].ID)
+	}
+
+	// Books added mid-pagination would push the first page's books onto the
+	// second one
+	for _, title := range []string{"Late 1", "Late 2"} {
+		body := fmt.Sprintf(`{"title": %q, "author": "Someone", "year": 2020}`, title)
+		if w := testutil.Request(router, http.MethodPost, "/v1/books", body); w.Code != http.StatusCreated {
+			t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+		}
+	}
+
+	var page []models.Book
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books?limit=2&offset=2&sort=recent&snapshot="+snapshot, ""), &page)
+	if got, want := fmt.Sprint(ids(page)), fmt.Sprint([]uint{books[1].ID, books[0].ID}); got != want {
+		t.Errorf("second page with the snapshot = %s, want %s", got, want)
+	}
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books?limit=2&offset=2&sort=recent", ""), &page)
+	if got, want := fmt.Sprint(ids(page)), fmt.Sprint([]uint{books[3].ID, books[2].ID}); got != want {
+		t.Errorf("second page without the snapshot = %s, want %s", got, want)
+	}
+}
+
+func TestSnapshotOfCachedFirstPageIsCached(t *testing.T) {
+	router, db := setup(t)
+	books := seedNumbered(t, db, 2)
+	if got := testutil.Request(router, http.MethodGet, "/v1/books?limit=1", "").Header().Get("X-Snapshot"); got != itoa(books[1].ID) {
+		t.Fatalf("X-Snapshot = %q, want %d", got, books[1].ID)
+	}
+
+	// A book the cache doesn't know about, so only a database query would
+	// see the new latest id
+	testutil.SeedBooks(t, db, models.Book{Title: "Uncached", Year: 2020})
+	if got := testutil.Request(router, http.MethodGet, "/v1/books?limit=1", "").Header().Get("X-Snapshot"); got != itoa(books[1].ID) {
+		t.Errorf("cached first page: X-Snapshot = %q, want the cached %d", got, books[1].ID)
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	t.Setenv("MAX_RESPONSE_BYTES", "5000")
+	router, db := setup(t)
+	seedNumbered(t, db, 100)
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/v1/books?limit=5", "", http.StatusOK},
+		{http.MethodGet, "/v1/books?limit=100", "", http.StatusRequestEntityTooLarge},
+		{http.MethodGet, "/v1/books?limit=100&after_id=0", "", http.StatusRequestEntityTooLarge},
+		{http.MethodPost, "/v1/books/search", `{"limit": 100}`, http.StatusRequestEntityTooLarge},
+		// Sparse fields keep a large page small
+		{http.MethodGet, "/v1/books?limit=100&fields=id", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := testutil.Request(router, tt.method, tt.path, tt.body)
+		if w.Code != tt.want {
+			t.Errorf("%s %s %s: status = %d, want %d: %.200s", tt.method, tt.path, tt.body, w.Code, tt.want, w.Body)
+			continue
+		}
+		if tt.want == http.StatusRequestEntityTooLarge {
+			var got map[string]string
+			decode(t, w, &got)
+			if got["code"] != "response_too_large" {
+				t.Errorf("%s %s %s: body = %v, want the response_too_large code", tt.method, tt.path, tt.body, got)
+			}
+		}
+	}
+}
+
+func TestMaxResponseBytesBoundary(t *testing.T) {
+	router, db := setup(t)
+	seedNumbered(t, db, 5)
+	size := testutil.Request(router, http.MethodGet, "/v1/books?limit=5", "", "Cache-Control", "no-cache").Body.Len()
+
+	// A page exactly MAX_RESPONSE_BYTES long is still written
+	for limit, want := range map[int]int{size: http.StatusOK, size - 1: http.StatusRequestEntityTooLarge} {
+		t.Setenv("MAX_RESPONSE_BYTES", itoa(uint(limit)))
+		w := testutil.Request(router, http.MethodGet, "/v1/books?limit=5", "", "Cache-Control", "no-cache")
+		if w.Code != want {
+			t.Errorf("MAX_RESPONSE_BYTES=%d: status = %d, want %d", limit, w.Code, want)
+		}
+	}
+}
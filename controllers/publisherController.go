@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+)
+
+// PublisherCount is one entry of GET /books/publishers?counts=true
+type PublisherCount struct {
+	Publisher string `json:"publisher"`
+	Count     int64  `json:"count"`
+}
+
+// GetPublishers godoc
+// @Summary Get distinct publishers
+// @Description Retrieve the distinct publisher names, sorted alphabetically, optionally with the number of books per publisher. Books without a publisher are left out.
+// @Tags books
+// @Produce json
+// @Param q query string false "Only return publishers whose name starts with this prefix (case-insensitive)"
+// @Param counts query bool false "Return {publisher, count} objects instead of plain names"
+// @Success 200 {array} PublisherCount
+// @Router /books/publishers [get]
+func GetPublishers(ctx *gin.Context) {
+	prefix := strings.TrimSpace(ctx.Query("q"))
+	withCounts, _ := strconv.ParseBool(ctx.Query("counts"))
+
+	params := url.Values{}
+	params.Set("q", prefix)
+	params.Set("counts", strconv.FormatBool(withCounts))
+	cacheKey := "books:publishers:" + params.Encode()
+
+	var publishers []PublisherCount
+	cachedPublishers, err := readCache(ctx, cacheKey)
+	if err != nil || json.Unmarshal([]byte(cachedPublishers), &publishers) != nil {
+		query := database.DB.Model(&models.Book{}).
+			Select("publisher, COUNT(*) AS count").
+			Where("publisher <> ''").
+			Group("publisher").
+			Order("publisher")
+		if prefix != "" {
+			query = query.Where("LOWER(publisher) LIKE ? ESCAPE '\\'", escapeLike(strings.ToLower(prefix))+"%")
+		}
+		if err := query.Scan(&publishers).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching publishers"})
+			return
+		}
+
+		data, _ := json.Marshal(publishers)
+		cacheListResult(cacheKey, data, cacheTTL("publishers"))
+	}
+
+	if withCounts {
+		respond(ctx, http.StatusOK, publishers)
+		return
+	}
+	names := make([]string, len(publishers))
+	for i, publisher := range publishers {
+		names[i] = publisher.Publisher
+	}
+	respond(ctx, http.StatusOK, names)
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+)
+
+// maxReconciledKeys bounds how many single-book keys one cycle checks, so a
+// cycle stays a handful of cache round trips and one indexed query
+const maxReconciledKeys = 500
+
+// reconcileInterval returns how often the cache is reconciled with the
+// database, configurable through CACHE_RECONCILE_INTERVAL (e.g. "5m").
+// Zero, the default, disables reconciliation.
+func reconcileInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("CACHE_RECONCILE_INTERVAL"))
+	if err != nil || interval < 0 {
+		return 0
+	}
+	return interval
+}
+
+// StartCacheReconciler periodically refreshes the cached first page of books
+// and drops cached books that no longer match the database, until ctx is
+// cancelled. It catches drift from missed invalidations and does nothing
+// unless CACHE_RECONCILE_INTERVAL is set.
+func StartCacheReconciler(ctx context.Context) {
+	interval := reconcileInterval()
+	if interval == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reconcileCache(ctx)
+			}
+		}
+	}()
+}
+
+func reconcileCache(ctx context.Context) {
+	refreshed := refreshFirstPage()
+	checked, dropped, err := dropStaleBooks(ctx)
+	if err != nil {
+		log.Println("Cache reconcile:", err)
+	}
+	log.Printf("Cache reconcile: first page refreshed=%t, %d cached books checked, %d stale dropped", refreshed, checked, dropped)
+}
+
+// refreshFirstPage recomputes the unfiltered first page at the default page
+// size
+func refreshFirstPage() bool {
+	limit, _, err := pageSizes()
+	if err != nil {
+		limit = defaultPageSize
+	}
+
+	var books []models.Book
+	if err := orderByDefault(database.DB).Limit(limit).Find(&books).Error; err != nil {
+		log.Println("Cache reconcile: load first page:", err)
+		return false
+	}
+	booksJSON, _ := json.Marshal(books)
+	cacheListResult(bookPageCacheKey(BookQuery{}, bookFilters{}, limit), booksJSON, cacheTTL("list"))
+	return true
+}
+
+// dropStaleBooks compares cached books (by id, slug and sparse variants) with
+// the database and deletes those whose book is gone or has been updated since
+func dropStaleBooks(ctx context.Context) (int, int, error) {
+	infos, err := redis.BookCache.Keys(ctx, "book:*", maxReconciledKeys)
+	if err != nil || len(infos) == 0 {
+		return 0, 0, err
+	}
+	keys := make([]string, len(infos))
+	for i, info := range infos {
+		keys[i] = info.Key
+	}
+	values, err := redis.BookCache.MGet(ctx, keys...)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cached := make(map[string]models.Book, len(keys))
+	var ids []uint
+	for i, key := range keys {
+		var book models.Book
+		if values[i] == "" || json.Unmarshal([]byte(values[i]), &book) != nil {
+			continue
+		}
+		// Sparse variants may not contain the id, but their key does
+		if id, err := strconv.ParseUint(strings.SplitN(strings.TrimPrefix(key, "book:"), ":", 2)[0], 10, 64); err == nil {
+			book.ID = uint(id)
+		}
+		if book.ID == 0 {
+			continue
+		}
+		cached[key] = book
+		ids = append(ids, book.ID)
+	}
+	if len(ids) == 0 {
+		return 0, 0, nil
+	}
+
+	var current []models.Book
+	if err := database.DB.Select("id", "updated_at").Where("id IN ?", ids).Find(&current).Error; err != nil {
+		return len(cached), 0, err
+	}
+	updatedAt := make(map[uint]time.Time, len(current))
+	for _, book := range current {
+		updatedAt[book.ID] = book.UpdatedAt
+	}
+
+	var stale []string
+	for key, book := range cached {
+		latest, ok := updatedAt[book.ID]
+		if !ok || (!book.UpdatedAt.IsZero() && !book.UpdatedAt.Equal(latest)) {
+			stale = append(stale, key)
+		}
+	}
+	if len(stale) == 0 {
+		return len(cached), 0, nil
+	}
+	return len(cached), len(stale), redis.BookCache.Del(ctx, stale...)
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+	"gorm.io/gorm"
+)
+
+func registryRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	router.GET("/books", GetBooks)
+	router.POST("/books/search", SearchBooks)
+	return router, db
+}
+
+func TestSortAndFilterInjectionIsRejected(t *testing.T) {
+	router, db := registryRouter(t)
+	testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})
+
+	for _, sort := range []string{"title; DROP TABLE books", "title,(SELECT 1)", "-id--", "year DESC", "books.title", "password"} {
+		if w := testutil.Request(router, http.MethodGet, "/books?sort="+url.QueryEscape(sort), ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET sort=%q: status = %d, want 400", sort, w.Code)
+		}
+		body, _ := json.Marshal(gin.H{"sort": sort})
+		if w := testutil.Request(router, http.MethodPost, "/books/search", string(body)); w.Code != http.StatusBadRequest {
+			t.Errorf("POST sort=%q: status = %d, want 400", sort, w.Code)
+		}
+	}
+	if w := testutil.Request(router, http.MethodGet, "/books?year="+url.QueryEscape("1965 OR 1=1"), ""); w.Code != http.StatusBadRequest {
+		t.Errorf("year filter with SQL: status = %d, want 400", w.Code)
+	}
+
+	// Filter values are only ever bound, so SQL in them matches nothing
+	w := testutil.Request(router, http.MethodGet, "/books?author="+url.QueryEscape("x' OR '1'='1"), "")
+	var books []models.Book
+	json.Unmarshal(w.Body.Bytes(), &books)
+	if w.Code != http.StatusOK || len(books) != 0 {
+		t.Errorf("author filter with SQL: status = %d, %d books, want 200 and none", w.Code, len(books))
+	}
+	var count int64
+	if err := db.Model(&models.Book{}).Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("books table after the attempts: %d rows, %v", count, err)
+	}
+}
+
+func TestRegistryFieldIsSortableEverywhere(t *testing.T) {
+	router, db := registryRouter(t)
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Publisher: "Chilton", Year: 1965},
+		models.Book{Title: "Emma", Publisher: "Bentley", Year: 1815},
+	)
+	want := fmt.Sprint([]uint{books[1].ID, books[0].ID})
+	// sorted returns the status and the ids of the listed books
+	sorted := func(method, path, body string) string {
+		w := testutil.Request(router, method, path, body)
+		var page []models.Book
+		json.Unmarshal(w.Body.Bytes(), &page)
+		ids := make([]uint, len(page))
+		for i, book := range page {
+			ids[i] = book.ID
+		}
+		return fmt.Sprintf("%d %v", w.Code, ids)
+	}
+	if got := sorted(http.MethodGet, "/books?sort=publisher", ""); !strings.HasPrefix(got, "400 ") {
+		t.Fatalf("publisher isn't sortable yet, but sort=publisher answered %s", got)
+	}
+
+	field := listFields["publisher"]
+	field.Sortable = true
+	listFields["publisher"] = field
+	t.Cleanup(func() {
+		field.Sortable = false
+		listFields["publisher"] = field
+	})
+
+	if got := sorted(http.MethodGet, "/books?sort=publisher", ""); got != "200 "+want {
+		t.Errorf("GET sort=publisher = %s, want 200 %s", got, want)
+	}
+	if got := sorted(http.MethodPost, "/books/search", `{"sort": "publisher"}`); got != "200 "+want {
+		t.Errorf("POST sort=publisher = %s, want 200 %s", got, want)
+	}
+	t.Setenv("DEFAULT_SORT", "publisher")
+	testutil.SetupCache(t)
+	if got := sorted(http.MethodGet, "/books", ""); got != "200 "+want {
+		t.Errorf("DEFAULT_SORT=publisher = %s, want 200 %s", got, want)
+	}
+}
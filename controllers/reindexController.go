@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"gorm.io/plugin/dbresolver"
+)
+
+// searchIndex is the full-text index searches use, created by the
+// add_book_authors migration
+const searchIndex = "idx_books_search"
+
+// ReindexStatus reports a search index rebuild. Progress comes from Postgres
+// and covers a rebuild started by any instance; the start, end and error are
+// those of the last rebuild started by the instance that answers.
+type ReindexStatus struct {
+	Running     bool       `json:"running"`
+	Phase       string     `json:"phase,omitempty"`
+	BlocksDone  int64      `json:"blocks_done"`
+	BlocksTotal int64      `json:"blocks_total"`
+	TuplesDone  int64      `json:"tuples_done"`
+	TuplesTotal int64      `json:"tuples_total"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// lastReindex is the last rebuild started by this instance
+var lastReindex struct {
+	sync.Mutex
+	running    bool
+	startedAt  *time.Time
+	finishedAt *time.Time
+	err        string
+}
+
+// StartReindex godoc
+// @Summary Rebuild the search index
+// @Description Rebuild the full-text search index in the background with REINDEX CONCURRENTLY, e.g. after a bulk import
+// @Description bloated it. Searches keep working on the old index until the new one is ready. Poll GET /admin/reindex for
+// @Description progress. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 202 {object} ReindexStatus "Rebuild started"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 409 {object} map[string]string "A rebuild is already running"
+// @Failure 500 {object} map[string]string "Failed to check for a running rebuild"
+// @Failure 501 {object} map[string]string "The database has no full-text index"
+// @Router /admin/reindex [post]
+func StartReindex(ctx *gin.Context) {
+	if !usesFullTextSearch() {
+		ctx.JSON(http.StatusNotImplemented, gin.H{"error": "Search index rebuilds need Postgres"})
+		return
+	}
+	status, err := reindexProgress()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check for a running rebuild"})
+		return
+	}
+
+	lastReindex.Lock()
+	if status.Running || lastReindex.running {
+		lastReindex.Unlock()
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A search index rebuild is already running"})
+		return
+	}
+	now := time.Now()
+	lastReindex.running, lastReindex.startedAt, lastReindex.finishedAt, lastReindex.err = true, &now, nil, ""
+	lastReindex.Unlock()
+
+	// The rebuild outlives the request, so it must not use its context
+	go rebuildSearchIndex()
+
+	status.Running = true
+	status.StartedAt = &now
+	respond(ctx, http.StatusAccepted, status)
+}
+
+// GetReindexStatus godoc
+// @Summary Get search index rebuild progress
+// @Description Report whether a search index rebuild is running and how far it got, and how the last rebuild started
+// @Description through this instance ended. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} ReindexStatus
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to read the rebuild progress"
+// @Router /admin/reindex [get]
+func GetReindexStatus(ctx *gin.Context) {
+	var status ReindexStatus
+	if usesFullTextSearch() {
+		var err error
+		if status, err = reindexProgress(); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read the rebuild progress"})
+			return
+		}
+	}
+
+	lastReindex.Lock()
+	status.Running = status.Running || lastReindex.running
+	status.StartedAt, status.FinishedAt, status.Error = lastReindex.startedAt, lastReindex.finishedAt, lastReindex.err
+	lastReindex.Unlock()
+	respond(ctx, http.StatusOK, status)
+}
+
+// reindexProgress reads the progress of a running rebuild of the books
+// indexes from the primary, where REINDEX runs
+func reindexProgress() (ReindexStatus, error) {
+	var rows []ReindexStatus
+	err := database.DB.Clauses(dbresolver.Write).Raw(`SELECT phase, blocks_done, blocks_total, tuples_done, tuples_total
+		FROM pg_stat_progress_create_index
+		WHERE relid = 'books'::regclass AND command LIKE 'REINDEX%'`).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return ReindexStatus{}, err
+	}
+	status := rows[0]
+	status.Running = true
+	return status, nil
+}
+
+// rebuildSearchIndex runs the rebuild and records how it ended
+func rebuildSearchIndex() {
+	log.Printf("Rebuilding search index %s", searchIndex)
+	err := database.DB.Exec("REINDEX INDEX CONCURRENTLY " + searchIndex).Error
+
+	now := time.Now()
+	lastReindex.Lock()
+	defer lastReindex.Unlock()
+	lastReindex.running, lastReindex.finishedAt = false, &now
+	if err != nil {
+		log.Printf("Failed to rebuild search index %s: %v", searchIndex, err)
+		lastReindex.err = err.Error()
+		return
+	}
+	log.Printf("Rebuilt search index %s in %s", searchIndex, now.Sub(*lastReindex.startedAt).Round(time.Millisecond))
+}
@@ -0,0 +1,45 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/controllers"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+// The rebuild itself runs REINDEX CONCURRENTLY, which needs Postgres; these
+// cover what the SQLite test database can: access and the fallback answers
+func TestReindexRequiresAdmin(t *testing.T) {
+	router, _ := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+
+	for _, method := range []string{http.MethodPost, http.MethodGet} {
+		if w := testutil.Request(router, method, "/v1/admin/reindex", ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s without token: status = %d, want 401", method, w.Code)
+		}
+		if w := testutil.Request(router, method, "/v1/admin/reindex", "", "Authorization", "Bearer wrong"); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s with a wrong token: status = %d, want 401", method, w.Code)
+		}
+	}
+}
+
+func TestReindexWithoutFullTextIndex(t *testing.T) {
+	router, _ := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+
+	if w := testutil.Request(router, http.MethodPost, "/v1/admin/reindex", "", auth...); w.Code != http.StatusNotImplemented {
+		t.Errorf("start: status = %d, want 501: %s", w.Code, w.Body)
+	}
+
+	w := testutil.Request(router, http.MethodGet, "/v1/admin/reindex", "", auth...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var status controllers.ReindexStatus
+	decode(t, w, &status)
+	if status.Running || status.StartedAt != nil {
+		t.Errorf("status = %+v, want nothing started", status)
+	}
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	formatPlain    = "plain"
+	formatEnvelope = "envelope"
+)
+
+// responseFormat picks the response shape for this request: the ?format=
+// query param wins over the X-Response-Format header, which wins over the
+// RESPONSE_FORMAT env default. Anything but "envelope" means plain JSON.
+func responseFormat(ctx *gin.Context) string {
+	format := ctx.Query("format")
+	if format == "" {
+		format = ctx.GetHeader("X-Response-Format")
+	}
+	if format == "" {
+		format = os.Getenv("RESPONSE_FORMAT")
+	}
+	if format == formatEnvelope {
+		return formatEnvelope
+	}
+	return formatPlain
+}
+
+func wantsEnvelope(ctx *gin.Context) bool {
+	return responseFormat(ctx) == formatEnvelope
+}
+
+// wantsPrettyJSON reports whether JSON should be indented for reading by hand,
+// asked for with ?pretty=true or made the default with PRETTY_JSON=true, which
+// ?pretty=false overrides. Compact JSON stays the default. ETags derive from
+// the book's version, never from the body, so they don't change with it.
+func wantsPrettyJSON(ctx *gin.Context) bool {
+	if pretty, err := strconv.ParseBool(ctx.Query("pretty")); err == nil {
+		return pretty
+	}
+	return os.Getenv("PRETTY_JSON") == "true"
+}
+
+// respond writes a successful response. Plain format writes data as is;
+// envelope format wraps it as {"data": ..., "meta": ...}.
+func respond(ctx *gin.Context, status int, data interface{}) {
+	respondWithMeta(ctx, status, data, nil)
+}
+
+func respondWithMeta(ctx *gin.Context, status int, data interface{}, meta gin.H) {
+	if !wantsEnvelope(ctx) {
+		render(ctx, status, data)
+		return
+	}
+	if meta == nil {
+		meta = gin.H{}
+	}
+	render(ctx, status, gin.H{"data": data, "meta": meta})
+}
+
+// defaultMaxResponseBytes is far above a full page at MAX_PAGE_SIZE, so only a
+// page that somehow slipped past the clamp reaches it
+const defaultMaxResponseBytes = 10 << 20
+
+// ValidateResponseSize checks MAX_RESPONSE_BYTES so a bad value fails at
+// startup instead of being silently replaced by the default
+func ValidateResponseSize() error {
+	_, err := positiveEnvInt("MAX_RESPONSE_BYTES", defaultMaxResponseBytes)
+	return err
+}
+
+// maxResponseBytes returns the largest list response that is written, from
+// MAX_RESPONSE_BYTES, which defaults to 10 MiB
+func maxResponseBytes() int {
+	size, err := positiveEnvInt("MAX_RESPONSE_BYTES", defaultMaxResponseBytes)
+	if err != nil {
+		return defaultMaxResponseBytes
+	}
+	return size
+}
+
+// byteCounter is an io.Writer that only counts what is written to it
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// responseTooLarge answers 413 and reports true when the JSON encoding of
+// books exceeds MAX_RESPONSE_BYTES. It is the last check before a list is
+// written, a backstop for the page size clamp. The encoding is only counted,
+// never held in memory, so measuring a page doesn't double its footprint.
+func responseTooLarge(ctx *gin.Context, books interface{}) bool {
+	var size byteCounter
+	if err := json.NewEncoder(&size).Encode(books); err != nil {
+		return false
+	}
+	// Encode ends the document with a newline the response doesn't have
+	encoded := int(size) - 1
+	if encoded <= maxResponseBytes() {
+		return false
+	}
+	log.Printf("Refusing %s %s: %d byte response exceeds MAX_RESPONSE_BYTES (%d)", ctx.Request.Method, ctx.Request.URL.Path, encoded, maxResponseBytes())
+	respondMessage(ctx, http.StatusRequestEntityTooLarge, msgResponseTooLarge, encoded, maxResponseBytes())
+	return true
+}
@@ -0,0 +1,100 @@
+package controllers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestEmptyFilteredList(t *testing.T) {
+	router, db := setup(t)
+	testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})
+
+	for _, path := range []string{"/v1/books?author=Nobody", "/v1/books?year=1800", "/v1/books?q=nothing"} {
+		// The second request is served from the cache
+		for attempt := 1; attempt <= 2; attempt++ {
+			w := testutil.Request(router, http.MethodGet, path+"&format=envelope", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d: %s", path, w.Code, w.Body)
+			}
+			var envelope struct {
+				Data []models.Book          `json:"data"`
+				Meta map[string]interface{} `json:"meta"`
+			}
+			decode(t, w, &envelope)
+			if envelope.Data == nil || len(envelope.Data) != 0 || envelope.Meta["total"] != float64(0) || envelope.Meta["offset"] != float64(0) {
+				t.Errorf("%s (attempt %d): body = %s, want an empty envelope with total 0", path, attempt, w.Body)
+			}
+
+			w = testutil.Request(router, http.MethodGet, path, "")
+			if body := strings.TrimSpace(w.Body.String()); body != "[]" || w.Header().Get("X-Total-Count") != "0" {
+				t.Errorf("%s (attempt %d): plain body = %s, X-Total-Count %q, want [] and 0", path, attempt, body, w.Header().Get("X-Total-Count"))
+			}
+		}
+	}
+}
+
+func TestMissingBookResponse(t *testing.T) {
+	router, _ := setup(t)
+
+	tests := []struct {
+		name, env, path string
+		want            int
+		body            string
+	}{
+		{"404 by default", "", "/v1/books/1", http.StatusNotFound, ""},
+		{"null by query", "", "/v1/books/1?missing=null", http.StatusOK, "null"},
+		{"null by config", "null", "/v1/books/1", http.StatusOK, "null"},
+		{"query overrides config", "null", "/v1/books/1?missing=404", http.StatusNotFound, ""},
+		{"slug, null by query", "", "/v1/books/slug/dune?missing=null", http.StatusOK, "null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MISSING_BOOK_RESPONSE", tt.env)
+			w := testutil.Request(router, http.MethodGet, tt.path, "")
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.body != "" && strings.TrimSpace(w.Body.String()) != tt.body {
+				t.Errorf("body = %s, want %s", w.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	router, db := setup(t)
+	book := seedNumbered(t, db, 1)[0]
+	path := "/v1/books/" + itoa(book.ID)
+	compact := testutil.Request(router, http.MethodGet, path, "")
+
+	tests := []struct {
+		name, env, query string
+		indented         bool
+	}{
+		{"compact by default", "", "", false},
+		{"indented by query", "", "?pretty=true", true},
+		{"indented by config", "true", "", true},
+		{"query overrides config", "true", "?pretty=false", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PRETTY_JSON", tt.env)
+			w := testutil.Request(router, http.MethodGet, path+tt.query, "")
+			if indented := strings.Contains(w.Body.String(), "\n    \""); indented != tt.indented {
+				t.Errorf("indented = %v, want %v: %s", indented, tt.indented, w.Body)
+			}
+			var got models.Book
+			decode(t, w, &got)
+			if got.ID != book.ID || got.Title != book.Title {
+				t.Errorf("book = %+v, want %+v", got, book)
+			}
+			if etag := w.Header().Get("ETag"); etag == "" || etag != compact.Header().Get("ETag") {
+				t.Errorf("ETag = %q, want the compact response's %q", etag, compact.Header().Get("ETag"))
+			}
+		})
+	}
+}
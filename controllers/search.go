@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"os"
+	"strings"
+
+	"github.com/rohans540/books-backend/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchDocument must match the expression indexed by the add_book_authors
+// migration, otherwise Postgres can't use the index
+const searchDocument = "to_tsvector('english', title || ' ' || authors::text)"
+
+func usesFullTextSearch() bool {
+	return database.DB.Dialector.Name() == "postgres"
+}
+
+// applySearch restricts query to books matching q. Postgres uses full-text
+// search; other dialects (SQLite in tests) fall back to a LIKE match.
+func applySearch(query *gorm.DB, q string) *gorm.DB {
+	if usesFullTextSearch() {
+		return query.Where(searchDocument+" @@ plainto_tsquery('english', ?)", q)
+	}
+	pattern := "%" + strings.ToLower(q) + "%"
+	return query.Where("LOWER(title) LIKE ? OR LOWER(authors) LIKE ?", pattern, pattern)
+}
+
+// orderByRelevance sorts matches for q best first, falling back to id order
+// where ranking isn't available
+func orderByRelevance(query *gorm.DB, q string) *gorm.DB {
+	if !usesFullTextSearch() {
+		return query.Order("id")
+	}
+	return query.Clauses(clause.OrderBy{
+		Expression: clause.Expr{
+			SQL:                "ts_rank(" + searchDocument + ", plainto_tsquery('english', ?)) DESC, id",
+			Vars:               []interface{}{q},
+			WithoutParentheses: true,
+		},
+	})
+}
+
+// Sorts a list request can ask for instead of DEFAULT_SORT
+const (
+	sortRelevance = "relevance"
+	sortRecent    = "recent"
+)
+
+// searchDefaultSort is the order of search results (q set) when the request
+// doesn't pick one: relevance, the default, or recent through
+// SEARCH_DEFAULT_SORT=recent
+func searchDefaultSort() string {
+	if os.Getenv("SEARCH_DEFAULT_SORT") == sortRecent {
+		return sortRecent
+	}
+	return sortRelevance
+}
+
+// orderByRecency sorts the most recently added books first
+func orderByRecency(query *gorm.DB) *gorm.DB {
+	return query.Order("created_at DESC, id DESC")
+}
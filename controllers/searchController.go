@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+)
+
+// BookQuery selects a page of books: filters, sort, sparse fields and
+// pagination. GET /books reads it from the query string and POST
+// /books/search from the request body; both are served by listBooks.
+type BookQuery struct {
+	Q         string `json:"q"`
+	Author    string `json:"author"`
+	Publisher string `json:"publisher"`
+	Year      int    `json:"year"`
+	Language  string `json:"language"`
+	Available *bool  `json:"available"`
+	// Sort is relevance (only valid together with q), recent, or a sortable
+	// field such as title or -year (descending). Left empty, searches use
+	// SEARCH_DEFAULT_SORT and other lists DEFAULT_SORT.
+	Sort   string   `json:"sort"`
+	Fields []string `json:"fields"`
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+	// AfterID switches to keyset pagination and takes precedence over Offset
+	AfterID *uint `json:"after_id"`
+	// Snapshot is the token from the first page's meta. Passing it on later
+	// pages leaves out books added since, so pages don't shift under inserts.
+	Snapshot *uint `json:"snapshot"`
+}
+
+// bookQueryFromURL reads a BookQuery from the query parameters of GET /books
+func bookQueryFromURL(ctx *gin.Context) (BookQuery, error) {
+	fields, err := parseFields(ctx)
+	if err != nil {
+		return BookQuery{}, err
+	}
+	filters, err := parseBookFilters(ctx)
+	if err != nil {
+		return BookQuery{}, err
+	}
+	query := BookQuery{
+		Q:         filters.Query,
+		Author:    filters.Author,
+		Publisher: filters.Publisher,
+		Year:      filters.Year,
+		Language:  filters.Language,
+		Available: filters.Available,
+		Sort:      ctx.Query("sort"),
+		Fields:    fields,
+	}
+	query.Limit, _ = strconv.Atoi(ctx.Query("limit"))
+	query.Offset, _ = strconv.Atoi(ctx.Query("offset"))
+	if raw := ctx.Query("after_id"); raw != "" {
+		afterID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return query, newMessageError(msgAfterIDInvalid)
+		}
+		id := uint(afterID)
+		query.AfterID = &id
+	}
+	if raw := ctx.Query("snapshot"); raw != "" {
+		snapshot, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || snapshot == 0 {
+			return query, newMessageError(msgSnapshotInvalid)
+		}
+		id := uint(snapshot)
+		query.Snapshot = &id
+	}
+	return query, nil
+}
+
+// validate checks q and normalizes it in place, so equal searches share a
+// cache key whichever way they were sent, and returns its filters
+func (q *BookQuery) validate() (bookFilters, error) {
+	q.Q = strings.TrimSpace(q.Q)
+	if q.Language != "" && !models.IsValidLanguage(q.Language) {
+		return bookFilters{}, newMessageError(msgLanguageUnknown, q.Language)
+	}
+	fields, err := normalizeFields(q.Fields)
+	if err != nil {
+		return bookFilters{}, err
+	}
+	q.Fields = fields
+	if q.Sort != "" && q.Sort != sortRelevance && q.Sort != sortRecent {
+		if _, _, err := sortColumn(q.Sort); err != nil {
+			return bookFilters{}, err
+		}
+	}
+	if q.Sort == sortRelevance && q.Q == "" {
+		return bookFilters{}, newMessageError(msgSortNeedsQuery)
+	}
+	if q.Sort != "" && q.AfterID != nil {
+		return bookFilters{}, newMessageError(msgSortWithAfterID, q.Sort)
+	}
+	// Resolved here so the cache key names the order actually used;
+	// keyset pages are always in id order
+	if q.Sort == "" && q.Q != "" && q.AfterID == nil {
+		q.Sort = searchDefaultSort()
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.AfterID == nil && q.Offset > maxOffset() {
+		return bookFilters{}, newMessageError(msgOffsetTooLarge, maxOffset())
+	}
+	filters := bookFilters{
+		Query:     q.Q,
+		Author:    q.Author,
+		Publisher: q.Publisher,
+		Year:      q.Year,
+		Language:  q.Language,
+		Available: q.Available,
+	}
+	if q.Snapshot != nil {
+		if *q.Snapshot == 0 {
+			return bookFilters{}, newMessageError(msgSnapshotInvalid)
+		}
+		filters.Snapshot = *q.Snapshot
+	}
+	return filters, nil
+}
+
+// SearchBooks godoc
+// @Summary Search books
+// @Description Same as GET /books, for searches too long or structured for a query string: the filters, sort,
+// @Description fields and pagination are sent as a JSON body. Results, caching and response formats match the
+// @Description equivalent GET request, except that no Link header is set since the next page needs the same body.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param request body BookQuery true "Filters, sort, fields and pagination"
+// @Success 200 {array} models.Book
+// @Header 200 {string} X-Limit-Clamped "Maximum page size, set when the requested limit was lowered to it"
+// @Header 200 {integer} X-Total-Count "Number of books matching the filters (offset pagination only)"
+// @Header 200 {integer} X-Page-Limit "Page size used (offset pagination only)"
+// @Header 200 {integer} X-Page-Offset "Offset used (offset pagination only)"
+// @Header 200 {integer} X-Snapshot "Snapshot token to send on the following pages (offset pagination only)"
+// @Failure 400 {object} map[string]string "Invalid JSON, unknown field, invalid filter, invalid sort or offset past MAX_OFFSET"
+// @Failure 413 {object} map[string]string "Page larger than MAX_RESPONSE_BYTES; request a smaller limit"
+// @Router /books/search [post]
+func SearchBooks(ctx *gin.Context) {
+	var query BookQuery
+	if !bindJSON(ctx, &query) {
+		return
+	}
+	filters, err := query.validate()
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	listBooks(ctx, query, filters)
+}
+
+// listBooks serves a validated BookQuery with offset or, when AfterID is
+// set, keyset pagination
+func listBooks(ctx *gin.Context, query BookQuery, filters bookFilters) {
+	limit := clampLimit(ctx, query.Limit)
+	fields := query.Fields
+
+	if query.AfterID != nil {
+		getBooksAfter(ctx, *query.AfterID, limit, filters, fields)
+		return
+	}
+
+	offset := query.Offset
+	// The first page hands out a snapshot for the following ones. It isn't
+	// applied to the first page itself, which keeps sharing its cache key
+	// with requests that don't paginate any further.
+	snapshot := filters.Snapshot
+	if snapshot == 0 && offset == 0 {
+		if latest, err := latestBookID(ctx); err == nil && latest != 0 {
+			snapshot = latest
+			addLinkParam(ctx, "snapshot", strconv.FormatUint(uint64(snapshot), 10))
+		}
+	}
+	meta := setPageLinks(ctx, filters, limit, offset)
+	if snapshot != 0 {
+		meta["snapshot"] = snapshot
+		ctx.Header(snapshotHeader, strconv.FormatUint(uint64(snapshot), 10))
+	}
+
+	cacheKey := bookPageCacheKey(query, filters, limit)
+
+	cachedBooks, err := readCache(ctx, cacheKey)
+	if err == nil && cachedBooks != "" {
+		var books []models.Book
+		if json.Unmarshal([]byte(cachedBooks), &books) == nil {
+			respondBooks(ctx, books, fields, meta)
+			return
+		}
+	}
+
+	books, err := loadBookPage(query, filters, limit, offset, cacheKey)
+	if err != nil {
+		render(ctx, http.StatusInternalServerError, gin.H{"error": "Error fetching books"})
+		return
+	}
+	respondBooks(ctx, books, fields, meta)
+}
+
+// bookPageCacheKey returns the key listBooks caches an offset page of query
+// under. Every page has its own key, so a cached page is served as is.
+func bookPageCacheKey(query BookQuery, filters bookFilters, limit int) string {
+	listKey := filters.listCacheKey()
+	if query.Sort != "" {
+		listKey += ":sort=" + query.Sort
+	}
+	listKey += fmt.Sprintf(":limit=%d&offset=%d", limit, query.Offset)
+	return fieldsCacheKey(listKey, query.Fields)
+}
+
+// loadBookPage reads an offset page of query from the database and caches it
+// under cacheKey
+func loadBookPage(query BookQuery, filters bookFilters, limit, offset int, cacheKey string) ([]models.Book, error) {
+	var books []models.Book
+	db := filters.apply(database.DB).Limit(limit).Offset(offset)
+	switch query.Sort {
+	case sortRelevance:
+		db = orderByRelevance(db, filters.Query)
+	case sortRecent:
+		db = orderByRecency(db)
+	case "":
+		db = orderByDefault(db)
+	default:
+		db = orderByField(db, query.Sort)
+	}
+	if query.Fields != nil {
+		db = db.Select(selectColumns(query.Fields))
+	}
+	if err := db.Find(&books).Error; err != nil {
+		return nil, err
+	}
+
+	booksJSON, _ := json.Marshal(books)
+	cacheListResult(cacheKey, booksJSON, cacheTTL("list")) // Cache books data
+	if query.Fields == nil {
+		hydrateBookCache(books)
+	}
+	return books, nil
+}
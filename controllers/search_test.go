@@ -0,0 +1,156 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+// These run the LIKE fallback search uses on SQLite; the Postgres full-text
+// query is not exercised here
+func TestSearchLikeFallback(t *testing.T) {
+	router, db := setup(t)
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965},
+		models.Book{Title: "Children of Dune", Author: "Frank Herbert", Year: 1976},
+		models.Book{Title: "Neuromancer", Author: "William Gibson", Year: 1984},
+	)
+
+	tests := []struct {
+		q    string
+		want []models.Book
+	}{
+		{"dune", books[:2]},
+		{"DUNE", books[:2]},
+		{"gibson", books[2:]},
+		{"asimov", nil},
+	}
+	for _, tt := range tests {
+		w := testutil.Request(router, http.MethodGet, "/v1/books?q="+tt.q, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("q=%s: status = %d: %s", tt.q, w.Code, w.Body)
+		}
+		var page []models.Book
+		decode(t, w, &page)
+		if fmt.Sprint(ids(page)) != fmt.Sprint(ids(tt.want)) {
+			t.Errorf("q=%s: books = %v, want %v", tt.q, ids(page), ids(tt.want))
+		}
+	}
+}
+
+func TestSortRelevanceRequiresQuery(t *testing.T) {
+	router, db := setup(t)
+	testutil.SeedBooks(t, db, models.Book{Title: "Dune", Year: 1965})
+
+	if w := testutil.Request(router, http.MethodGet, "/v1/books?sort=relevance", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("sort=relevance without q: status = %d, want 400", w.Code)
+	}
+	if w := testutil.Request(router, http.MethodGet, "/v1/books?q=dune&sort=relevance", ""); w.Code != http.StatusOK {
+		t.Errorf("sort=relevance with q: status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestSearchMatchesGet(t *testing.T) {
+	router, db := setup(t)
+	testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965, Publisher: "Chilton Books"},
+		models.Book{Title: "Children of Dune", Author: "Frank Herbert", Year: 1976},
+		models.Book{Title: "Neuromancer", Author: "William Gibson", Year: 1984, Language: "en"},
+		models.Book{Title: "Der Process", Author: "Franz Kafka", Year: 1925, Language: "de"},
+	)
+
+	tests := []struct{ query, body string }{
+		{"", `{}`},
+		{"q=dune&sort=-year", `{"q": "dune", "sort": "-year"}`},
+		{"author=Frank%20Herbert&limit=1&offset=1", `{"author": "Frank Herbert", "limit": 1, "offset": 1}`},
+		{"language=de", `{"language": "de"}`},
+		{"publisher=Chilton%20Books&fields=id,title", `{"publisher": "Chilton Books", "fields": ["id", "title"]}`},
+		{"year=1984", `{"year": 1984}`},
+		{"after_id=1&limit=2", `{"after_id": 1, "limit": 2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			get := testutil.Request(router, http.MethodGet, "/v1/books?"+tt.query, "")
+			post := testutil.Request(router, http.MethodPost, "/v1/books/search", tt.body)
+			if get.Code != http.StatusOK || post.Code != http.StatusOK {
+				t.Fatalf("status GET %d, POST %d: %s", get.Code, post.Code, post.Body)
+			}
+			if get.Body.String() != post.Body.String() {
+				t.Errorf("GET = %s\nPOST = %s", get.Body, post.Body)
+			}
+			if get.Header().Get("X-Total-Count") != post.Header().Get("X-Total-Count") {
+				t.Errorf("X-Total-Count GET %q, POST %q", get.Header().Get("X-Total-Count"), post.Header().Get("X-Total-Count"))
+			}
+		})
+	}
+}
+
+func TestSearchSharesTheListCache(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+
+	testutil.Request(router, http.MethodGet, "/v1/books?author=Frank%20Herbert", "")
+	// Only the cached page still has the old title
+	db.Model(&models.Book{}).Where("id = ?", book.ID).Update("title", "Changed in the database")
+
+	var page []models.Book
+	decode(t, testutil.Request(router, http.MethodPost, "/v1/books/search", `{"author": "Frank Herbert"}`), &page)
+	if len(page) != 1 || page[0].Title != "Dune" {
+		t.Errorf("search = %+v, want the page GET cached", page)
+	}
+}
+
+func TestSearchRejectsInvalidBodies(t *testing.T) {
+	router, _ := setup(t)
+	for _, body := range []string{`{"sort": "relevance"}`, `{"sort": "colour"}`, `{"limit": `} {
+		if w := testutil.Request(router, http.MethodPost, "/v1/books/search", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestSearchDefaultSort(t *testing.T) {
+	tests := []struct {
+		env, query string
+		want       []int // indexes into the seeded books
+	}{
+		// SQLite ranks every match equally, so relevance falls back to id order
+		{"", "", []int{0, 1, 2}},
+		{"relevance", "", []int{0, 1, 2}},
+		{"recent", "", []int{2, 0, 1}},
+		// An explicit sort wins over the default
+		{"recent", "&sort=relevance", []int{0, 1, 2}},
+		{"", "&sort=recent", []int{2, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run("SEARCH_DEFAULT_SORT="+tt.env+tt.query, func(t *testing.T) {
+			t.Setenv("SEARCH_DEFAULT_SORT", tt.env)
+			router, db := setup(t)
+			// Added in an order that matches neither id order nor its reverse
+			added := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			books := testutil.SeedBooks(t, db,
+				models.Book{Title: "Dune", Year: 1965, CreatedAt: added.Add(time.Hour)},
+				models.Book{Title: "Dune Messiah", Year: 1969, CreatedAt: added},
+				models.Book{Title: "Children of Dune", Year: 1976, CreatedAt: added.Add(2 * time.Hour)},
+			)
+
+			w := testutil.Request(router, http.MethodGet, "/v1/books?q=dune"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			var page []models.Book
+			decode(t, w, &page)
+			want := make([]uint, len(tt.want))
+			for i, index := range tt.want {
+				want[i] = books[index].ID
+			}
+			if got := ids(page); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("order = %v, want %v", got, want)
+			}
+		})
+	}
+}
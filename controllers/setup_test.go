@@ -0,0 +1,57 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/routes"
+	"github.com/rohans540/books-backend/testutil"
+	"gorm.io/gorm"
+)
+
+// setup mounts every route on a test router backed by an empty SQLite
+// database, an empty in-memory cache and a recording event publisher
+func setup(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	db := testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	testutil.SetupPublisher(t)
+	router := testutil.NewRouter()
+	routes.SetupRoutes(router)
+	return router, db
+}
+
+// decode unmarshals the JSON body of w into v
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// seedNumbered inserts n books titled "Book 1" to "Book n"
+func seedNumbered(t *testing.T, db *gorm.DB, n int) []models.Book {
+	t.Helper()
+	books := make([]models.Book, n)
+	for i := range books {
+		books[i] = models.Book{Title: fmt.Sprintf("Book %d", i+1), Year: 2000 + i}
+	}
+	return testutil.SeedBooks(t, db, books...)
+}
+
+func ids(books []models.Book) []uint {
+	out := make([]uint, len(books))
+	for i, book := range books {
+		out[i] = book.ID
+	}
+	return out
+}
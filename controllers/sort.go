@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// defaultOrder returns the ORDER BY clause for list queries from DEFAULT_SORT,
+// a sortable field optionally prefixed with "-" for descending order (e.g.
+// "-created_at"). Unknown values fall back to id.
+func defaultOrder() string {
+	sort := strings.TrimSpace(os.Getenv("DEFAULT_SORT"))
+	if order, err := fieldOrder(sort); err == nil {
+		return order
+	}
+	if strings.HasPrefix(sort, "-") {
+		return "id DESC"
+	}
+	return "id ASC"
+}
+
+// fieldOrder returns the ORDER BY clause for a field sort such as "-year".
+// id is always appended as a tie-breaker: without a total order, offset
+// pagination can return the same row on two pages or skip rows entirely.
+func fieldOrder(sort string) (string, error) {
+	column, direction, err := sortColumn(sort)
+	if err != nil {
+		return "", err
+	}
+	if column == "id" {
+		return "id " + direction, nil
+	}
+	return column + " " + direction + ", id " + direction, nil
+}
+
+func orderByDefault(query *gorm.DB) *gorm.DB {
+	return query.Order(defaultOrder())
+}
+
+// orderByField sorts by a field sort that was already validated
+func orderByField(query *gorm.DB, sort string) *gorm.DB {
+	order, _ := fieldOrder(sort)
+	return query.Order(order)
+}
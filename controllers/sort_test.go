@@ -0,0 +1,68 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+// pageThrough collects the ids of every page of path, limit books at a time
+func pageThrough(t *testing.T, router http.Handler, path string, limit, total int) []uint {
+	t.Helper()
+	var all []uint
+	for offset := 0; offset < total; offset += limit {
+		w := testutil.Request(router, http.MethodGet, fmt.Sprintf("%slimit=%d&offset=%d", path, limit, offset), "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("offset %d: status = %d: %s", offset, w.Code, w.Body)
+		}
+		var page []models.Book
+		decode(t, w, &page)
+		all = append(all, ids(page)...)
+	}
+	return all
+}
+
+func TestPagingIsStable(t *testing.T) {
+	for _, sort := range []string{"", "year", "-year", "title"} {
+		t.Run("DEFAULT_SORT="+sort, func(t *testing.T) {
+			t.Setenv("DEFAULT_SORT", sort)
+			router, db := setup(t)
+			// Every book ties on year and title, only id tells them apart
+			books := make([]models.Book, 7)
+			for i := range books {
+				books[i] = models.Book{Title: "Dune", Author: fmt.Sprintf("Author %d", i), Year: 1965}
+			}
+			testutil.SeedBooks(t, db, books...)
+
+			seen := map[uint]bool{}
+			for _, id := range pageThrough(t, router, "/v1/books?", 2, len(books)) {
+				if seen[id] {
+					t.Fatalf("book %d returned on two pages", id)
+				}
+				seen[id] = true
+			}
+			if len(seen) != len(books) {
+				t.Errorf("%d books returned across pages, want %d", len(seen), len(books))
+			}
+		})
+	}
+}
+
+func TestDefaultSort(t *testing.T) {
+	t.Setenv("DEFAULT_SORT", "-year")
+	router, db := setup(t)
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Year: 1965},
+		models.Book{Title: "Neuromancer", Year: 1984},
+		models.Book{Title: "Foundation", Year: 1951},
+	)
+
+	got := pageThrough(t, router, "/v1/books?", 10, len(books))
+	want := []uint{books[1].ID, books[0].ID, books[2].ID}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("books = %v, want %v (newest year first)", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+)
+
+const (
+	defaultTopAuthors = 10
+	maxTopAuthors     = 100
+)
+
+// BookStats is the response of GET /books/stats
+type BookStats struct {
+	Total   int64 `json:"total" xml:"total"`
+	MinYear *int  `json:"min_year" xml:"min_year,omitempty"`
+	MaxYear *int  `json:"max_year" xml:"max_year,omitempty"`
+	// ByDecade is ordered by decade, e.g. 1990 for 1990-1999 and -10 for
+	// 10-1 BCE
+	ByDecade   []DecadeCount `json:"by_decade" xml:"by_decade>item"`
+	TopAuthors []AuthorCount `json:"top_authors" xml:"top_authors>item"`
+}
+
+// DecadeCount is the number of books published in the decade starting at Decade
+type DecadeCount struct {
+	Decade int   `json:"decade" xml:"decade"`
+	Count  int64 `json:"count" xml:"count"`
+}
+
+// GetBookStats godoc
+// @Summary Get catalog statistics
+// @Description Return the number of books, the earliest and latest publication year, the number of books per decade
+// @Description and the authors (co-authors included) with the most books. Results are cached for CACHE_TTL_STATS (five minutes by default)
+// @Description and dropped after writes, once STATS_INVALIDATION_DEBOUNCE passes without another write.
+// @Tags books
+// @Produce json
+// @Param top query int false "Number of authors to return (default: 10, max: 100)"
+// @Success 200 {object} BookStats
+// @Router /books/stats [get]
+func GetBookStats(ctx *gin.Context) {
+	top, err := strconv.Atoi(ctx.DefaultQuery("top", strconv.Itoa(defaultTopAuthors)))
+	if err != nil || top <= 0 {
+		top = defaultTopAuthors
+	}
+	if top > maxTopAuthors {
+		top = maxTopAuthors
+	}
+	cacheKey := "books:stats:top=" + strconv.Itoa(top)
+
+	var stats BookStats
+	cachedStats, err := readCache(ctx, cacheKey)
+	if err == nil && json.Unmarshal([]byte(cachedStats), &stats) == nil {
+		respond(ctx, http.StatusOK, stats)
+		return
+	}
+
+	stats, err = loadBookStats(top)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing statistics"})
+		return
+	}
+
+	data, _ := json.Marshal(stats)
+	cacheStatsResult(cacheKey, data, cacheTTL("stats"))
+	respond(ctx, http.StatusOK, stats)
+}
+
+func loadBookStats(top int) (BookStats, error) {
+	stats := BookStats{ByDecade: []DecadeCount{}, TopAuthors: []AuthorCount{}}
+
+	var totals struct {
+		Total   int64
+		MinYear *int
+		MaxYear *int
+	}
+	err := database.DB.Model(&models.Book{}).
+		Select("COUNT(*) AS total, MIN(year) AS min_year, MAX(year) AS max_year").
+		Scan(&totals).Error
+	if err != nil {
+		return stats, err
+	}
+	stats.Total, stats.MinYear, stats.MaxYear = totals.Total, totals.MinYear, totals.MaxYear
+
+	err = database.DB.Model(&models.Book{}).
+		// Rounds down for BCE years too, where year / 10 * 10 would round
+		// towards zero and lump -9..9 together
+		Select("year - ((year % 10) + 10) % 10 AS decade, COUNT(*) AS count").
+		Group("decade").
+		Order("decade").
+		Scan(&stats.ByDecade).Error
+	if err != nil {
+		return stats, err
+	}
+
+	// Ties are broken by name so the top list is stable
+	table, name := authorRows()
+	err = database.DB.Table(table).
+		Select(name + " AS author, COUNT(*) AS count").
+		Group(name).
+		Order("count DESC, " + name).
+		Limit(top).
+		Scan(&stats.TopAuthors).Error
+	return stats, err
+}
+
+// authorRows returns a table expression with one row per author of every
+// book, and the column holding the author. Postgres expands the jsonb array;
+// other dialects (SQLite in tests) use json_each.
+func authorRows() (string, string) {
+	if usesFullTextSearch() {
+		return "books, jsonb_array_elements_text(books.authors) AS a(name)", "a.name"
+	}
+	return "books, json_each(books.authors) AS a", "a.value"
+}
@@ -0,0 +1,139 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/controllers"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/redis"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestBookStats(t *testing.T) {
+	router, db := setup(t)
+	testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965},
+		models.Book{Title: "Dune Messiah", Author: "Frank Herbert", Year: 1969},
+		models.Book{Title: "Good Omens", Authors: models.StringList{"Terry Pratchett", "Neil Gaiman"}, Year: 1990},
+		models.Book{Title: "Neverwhere", Author: "Neil Gaiman", Year: 1996},
+		models.Book{Title: "The Gallic War", Author: "Julius Caesar", Year: -50},
+		models.Book{Title: "Some Old Text", Author: "Anonymous", Year: -5},
+	)
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books/stats?top=2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var stats controllers.BookStats
+	decode(t, w, &stats)
+
+	if stats.Total != 6 || *stats.MinYear != -50 || *stats.MaxYear != 1996 {
+		t.Errorf("total %d, years %d to %d, want 6 from -50 to 1996", stats.Total, *stats.MinYear, *stats.MaxYear)
+	}
+	wantDecades := []controllers.DecadeCount{{Decade: -50, Count: 1}, {Decade: -10, Count: 1}, {Decade: 1960, Count: 2}, {Decade: 1990, Count: 2}}
+	if len(stats.ByDecade) != len(wantDecades) {
+		t.Fatalf("by decade = %+v, want %+v", stats.ByDecade, wantDecades)
+	}
+	for i, want := range wantDecades {
+		if stats.ByDecade[i] != want {
+			t.Errorf("by decade = %+v, want %+v", stats.ByDecade, wantDecades)
+			break
+		}
+	}
+	// Co-authors count, and ties are broken by name
+	wantAuthors := []controllers.AuthorCount{{Author: "Frank Herbert", Count: 2}, {Author: "Neil Gaiman", Count: 2}}
+	if len(stats.TopAuthors) != 2 || stats.TopAuthors[0] != wantAuthors[0] || stats.TopAuthors[1] != wantAuthors[1] {
+		t.Errorf("top authors = %+v, want %+v", stats.TopAuthors, wantAuthors)
+	}
+}
+
+// cachedTTL returns the remaining TTL of the only cache key matching pattern
+func cachedTTL(t *testing.T, pattern string) time.Duration {
+	t.Helper()
+	keys, err := redis.BookCache.Keys(context.Background(), pattern, 10)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("keys matching %s = %v, %v, want one", pattern, keys, err)
+	}
+	return keys[0].TTL
+}
+
+func TestStatsCacheTTL(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", 5 * time.Minute},
+		{"1h", time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run("CACHE_TTL_STATS="+tt.env, func(t *testing.T) {
+			t.Setenv("CACHE_TTL_STATS", tt.env)
+			router, db := setup(t)
+			seedNumbered(t, db, 2)
+
+			testutil.Request(router, http.MethodGet, "/v1/books/stats", "")
+			if ttl := cachedTTL(t, "books:stats:*"); ttl <= tt.want-time.Second || ttl > tt.want {
+				t.Errorf("stats TTL = %s, want %s", ttl, tt.want)
+			}
+
+			// List pages keep their own TTL: they don't expire
+			testutil.Request(router, http.MethodGet, "/v1/books", "")
+			if ttl := cachedTTL(t, "books*limit=*"); ttl != -1 {
+				t.Errorf("list page TTL = %s, want none", ttl)
+			}
+		})
+	}
+}
+
+func TestValidateCacheTTLs(t *testing.T) {
+	for env, valid := range map[string]bool{"": true, "0": true, "90s": true, "-1m": false, "soon": false} {
+		t.Setenv("CACHE_TTL_STATS", env)
+		if err := controllers.ValidateCacheTTLs(); (err == nil) != valid {
+			t.Errorf("CACHE_TTL_STATS=%q: ValidateCacheTTLs = %v", env, err)
+		}
+	}
+}
+
+func TestWritesInvalidateStats(t *testing.T) {
+	stats := func(router http.Handler) int64 {
+		var stats controllers.BookStats
+		decode(t, testutil.Request(router, http.MethodGet, "/v1/books/stats", ""), &stats)
+		return stats.Total
+	}
+	create := `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`
+
+	t.Run("immediately", func(t *testing.T) {
+		router, db := setup(t)
+		seedNumbered(t, db, 2)
+		stats(router)
+		if w := testutil.Request(router, http.MethodPost, "/v1/books", create); w.Code != http.StatusCreated {
+			t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+		}
+		if got := stats(router); got != 3 {
+			t.Errorf("total after a create = %d, want 3", got)
+		}
+	})
+
+	t.Run("debounced", func(t *testing.T) {
+		t.Setenv("STATS_INVALIDATION_DEBOUNCE", "50ms")
+		router, db := setup(t)
+		seedNumbered(t, db, 2)
+		stats(router)
+		for i := 0; i < 3; i++ {
+			if w := testutil.Request(router, http.MethodPost, "/v1/books", create); w.Code != http.StatusCreated {
+				t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+			}
+		}
+		// Not recomputed during the burst
+		if got := stats(router); got != 2 {
+			t.Errorf("total inside the debounce window = %d, want the cached 2", got)
+		}
+		time.Sleep(150 * time.Millisecond)
+		if got := stats(router); got != 5 {
+			t.Errorf("total after the debounce window = %d, want 5", got)
+		}
+	})
+}
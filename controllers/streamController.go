@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/models"
+)
+
+// streamFlushEvery is how many books are written between flushes
+const streamFlushEvery = 100
+
+const defaultHeartbeatInterval = 15 * time.Second
+
+// liveEventsBuffer is how many events a live client may fall behind before
+// it is disconnected; the browser's EventSource then reconnects by itself
+const liveEventsBuffer = 64
+
+// heartbeatInterval returns how often idle live event connections get a
+// comment line, configurable through SSE_HEARTBEAT_INTERVAL. It keeps
+// proxies from closing the connection and detects clients that went away.
+func heartbeatInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("SSE_HEARTBEAT_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return defaultHeartbeatInterval
+	}
+	return interval
+}
+
+// StartLiveEvents consumes the book events topic so GET /books/events can
+// forward changes to connected clients, until ctx is cancelled
+func StartLiveEvents(ctx context.Context) {
+	kafka.StartConsumer(ctx, BookEventsTopic)
+}
+
+// StreamBooks godoc
+// @Summary Stream books as NDJSON
+// @Description Stream every matching book as newline-delimited JSON, one book per line, in DEFAULT_SORT order.
+// @Description Rows are read from the database one at a time and flushed every 100 books, so the whole
+// @Description collection is never held in memory and consumers can start processing right away.
+// @Tags books
+// @Produce application/x-ndjson
+// @Param q query string false "Full-text search over title and author"
+// @Param author query string false "Only stream books by this author"
+// @Param publisher query string false "Only stream books from this publisher"
+// @Param year query int false "Only stream books published in this year"
+// @Param language query string false "Only stream books in this ISO 639-1 language (e.g. en)"
+// @Param available query bool false "Only stream books that are (true) or aren't (false) available to check out"
+// @Success 200 {object} models.Book "One book per line"
+// @Failure 400 {object} map[string]string "Invalid filter"
+// @Router /books/stream [get]
+func StreamBooks(ctx *gin.Context) {
+	filters, err := parseBookFilters(ctx)
+	if err != nil {
+		body := gin.H{}
+		body["error"] = errorMessage(ctx, err, body)
+		ctx.JSON(http.StatusBadRequest, body)
+		return
+	}
+
+	// Tie the query to the request so a disconnected client stops the scan
+	db := database.DB.WithContext(ctx.Request.Context())
+	rows, err := orderByDefault(filters.apply(db.Model(&models.Book{}))).Rows()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching books"})
+		return
+	}
+	defer rows.Close()
+
+	// Streaming the whole collection can outlast the server's write timeout;
+	// a client that stops reading is caught by the request context instead
+	http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Status(http.StatusOK)
+	encoder := json.NewEncoder(ctx.Writer)
+	for count := 1; rows.Next(); count++ {
+		var book models.Book
+		if err := db.ScanRows(rows, &book); err != nil {
+			log.Println("Failed to read book while streaming:", err)
+			return
+		}
+		if err := encoder.Encode(book); err != nil {
+			return // client went away
+		}
+		if count%streamFlushEvery == 0 {
+			ctx.Writer.Flush()
+		}
+	}
+	// Headers are already sent, so a failure can only be logged
+	if err := rows.Err(); err != nil {
+		log.Println("Failed to stream books:", err)
+	}
+}
+
+// StreamBookEvents godoc
+// @Summary Stream live book changes
+// @Description Keep the connection open and push a server-sent event for every book change on any instance, read from
+// @Description the book_events Kafka topic. The event name is the event type (book.created, book.updated, book.deleted,
+// @Description book.checked_out or book.returned) and the data the event payload. A comment line is sent every
+// @Description SSE_HEARTBEAT_INTERVAL while idle. Clients that fall too far behind are disconnected and should reconnect.
+// @Tags books
+// @Produce text/event-stream
+// @Success 200 {object} kafka.BookEvent "One event per change"
+// @Router /books/events [get]
+func StreamBookEvents(ctx *gin.Context) {
+	events, unsubscribe := kafka.BookEvents.Subscribe(liveEventsBuffer)
+	defer unsubscribe()
+
+	// The connection is meant to stay open; a client that went away is
+	// caught by the request context or a failed heartbeat instead
+	http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	// Keep nginx and similar proxies from buffering the stream
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval())
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case evt, ok := <-events:
+			if !ok {
+				return // too far behind, or the server is shutting down
+			}
+			data, _ := json.Marshal(evt)
+			if _, err := fmt.Fprintf(ctx.Writer, "event: %s\ndata: %s\n\n", evt.Event, data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		ctx.Writer.Flush()
+	}
+}
@@ -0,0 +1,112 @@
+package controllers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestStreamBooks(t *testing.T) {
+	router, db := setup(t)
+	// More than one flush worth of books
+	books := seedNumbered(t, db, 250)
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books/stream", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", contentType)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var book models.Book
+		if err := json.Unmarshal(scanner.Bytes(), &book); err != nil {
+			t.Fatalf("line %d is not a book: %v", lines+1, err)
+		}
+		if book.ID != books[lines].ID {
+			t.Fatalf("line %d is book %d, want %d", lines+1, book.ID, books[lines].ID)
+		}
+		lines++
+	}
+	if lines != len(books) {
+		t.Errorf("%d lines streamed, want %d", lines, len(books))
+	}
+}
+
+func TestStreamBooksFiltered(t *testing.T) {
+	router, db := setup(t)
+	seedNumbered(t, db, 5)
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books/stream?year=2001", "")
+	scanner := bufio.NewScanner(w.Body)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+	}
+	if lines != 1 {
+		t.Errorf("%d lines streamed, want the one book from 2001", lines)
+	}
+
+	if w := testutil.Request(router, http.MethodGet, "/v1/books/stream?language=xx", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid filter: status = %d, want 400", w.Code)
+	}
+}
+
+func TestStreamBookEvents(t *testing.T) {
+	t.Setenv("SSE_HEARTBEAT_INTERVAL", "20ms")
+	router, _ := setup(t)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/books/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", contentType)
+	}
+
+	// The client is subscribed once the headers are sent
+	kafka.BookEvents.Broadcast(kafka.BookEvent{Event: "book.created", ID: 7, Title: "Dune", RequestID: "req-1"})
+
+	var event string
+	var got kafka.BookEvent
+	pinged := false
+	reader := bufio.NewReader(resp.Body)
+	for got.ID == 0 || !pinged {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended before the event and a heartbeat arrived: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == ": ping":
+			pinged = true
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &got); err != nil {
+				t.Fatalf("data %q: %v", line, err)
+			}
+		}
+	}
+	if event != "book.created" || got.ID != 7 || got.Title != "Dune" || got.RequestID != "req-1" {
+		t.Errorf("received %s %+v, want the broadcast book.created event", event, got)
+	}
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// strictBinding reports whether unknown body fields are rejected for this
+// request: the X-Strict-Binding header wins over the STRICT_BINDING env
+// default. Lenient binding, which ignores them, is the default.
+func strictBinding(ctx *gin.Context) bool {
+	if header := ctx.GetHeader("X-Strict-Binding"); header != "" {
+		strict, _ := strconv.ParseBool(header)
+		return strict
+	}
+	return os.Getenv("STRICT_BINDING") == "true"
+}
+
+// unknownFieldError rejects a body with a field the target type doesn't have
+type unknownFieldError struct {
+	field string
+}
+
+func (e unknownFieldError) Error() string {
+	return "unknown field " + e.field
+}
+
+// bindStrictJSON binds the body like ShouldBindJSON after checking it for
+// unknown fields
+func bindStrictJSON(ctx *gin.Context, obj interface{}) error {
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return err
+	}
+	if field := unknownBodyField(data, obj); field != "" {
+		return unknownFieldError{field: field}
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(data))
+	return ctx.ShouldBindJSON(obj)
+}
+
+// unknownBodyField returns the path of the first field of the JSON body data
+// that obj has no field for, e.g. "titlee" or "filter.idz", or "" when every
+// field is known or data isn't valid JSON. json.Decoder's
+// DisallowUnknownFields doesn't reach into custom unmarshalers such as
+// Book's, so the body is checked against obj's type instead.
+func unknownBodyField(data []byte, obj interface{}) string {
+	var value interface{}
+	if json.Unmarshal(data, &value) != nil {
+		return ""
+	}
+	return unknownField(value, reflect.TypeOf(obj), "")
+}
+
+func unknownField(value interface{}, t reflect.Type, path string) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if t.Kind() == reflect.Map {
+			for _, key := range sortedKeys(value) {
+				if found := unknownField(value[key], t.Elem(), fieldPath(path, key)); found != "" {
+					return found
+				}
+			}
+			return ""
+		}
+		if t.Kind() != reflect.Struct {
+			return ""
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(value) {
+			fieldType, ok := lookupJSONField(fields, key)
+			if !ok {
+				return fieldPath(path, key)
+			}
+			if found := unknownField(value[key], fieldType, fieldPath(path, key)); found != "" {
+				return found
+			}
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return ""
+		}
+		for i, item := range value {
+			if found := unknownField(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); found != "" {
+				return found
+			}
+		}
+	}
+	return ""
+}
+
+// jsonFields maps the JSON names of t's fields to their types, flattening
+// embedded structs the way encoding/json does
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for embeddedName, embeddedType := range jsonFields(embedded) {
+					if _, ok := fields[embeddedName]; !ok {
+						fields[embeddedName] = embeddedType
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// lookupJSONField finds key among fields, falling back to the
+// case-insensitive match encoding/json also accepts
+func lookupJSONField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if fieldType, ok := fields[key]; ok {
+		return fieldType, true
+	}
+	for name, fieldType := range fields {
+		if strings.EqualFold(name, key) {
+			return fieldType, true
+		}
+	}
+	return nil, false
+}
+
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sortedKeys returns the keys of m in order, so the reported field doesn't
+// depend on map iteration order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
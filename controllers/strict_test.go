@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestUnknownBodyField(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		obj  interface{}
+		want string
+	}{
+		{"known fields", `{"title": "Dune", "authors": ["Frank Herbert"], "year": 1965}`, &models.Book{}, ""},
+		{"case-insensitive match", `{"Title": "Dune"}`, &models.Book{}, ""},
+		{"typo", `{"titlee": "Dune", "year": 1965}`, &models.Book{}, "titlee"},
+		{"first in order", `{"zzz": 1, "aaa": 2}`, &models.Book{}, "aaa"},
+		{"nested", `{"filter": {"idz": [1]}, "field": "year", "value": 2000}`, &BulkUpdateRequest{}, "filter.idz"},
+		{"in a list", `{"queries": [{"limit": 2}, {"limt": 2}]}`, &CacheWarmRequest{}, "queries[1].limt"},
+		{"any value", `{"filter": {"ids": [1]}, "field": "year", "value": {"anything": true}}`, &BulkUpdateRequest{}, ""},
+		{"invalid JSON", `{"titlee": `, &models.Book{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unknownBodyField([]byte(tt.body), tt.obj); got != tt.want {
+				t.Errorf("unknown field = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrictBinding(t *testing.T) {
+	testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	testutil.SetupPublisher(t)
+	router := testutil.NewRouter()
+	router.POST("/books", CreateBook)
+
+	tests := []struct {
+		name, env, header string
+		want              int
+	}{
+		{"lenient by default", "", "", http.StatusCreated},
+		{"strict by header", "", "true", http.StatusBadRequest},
+		{"strict by config", "true", "", http.StatusBadRequest},
+		{"header overrides config", "true", "false", http.StatusCreated},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STRICT_BINDING", tt.env)
+			var headers []string
+			if tt.header != "" {
+				headers = []string{"X-Strict-Binding", tt.header}
+			}
+			body := fmt.Sprintf(`{"title": "Book %d", "author": "Author", "year": 2000, "titlee": "typo"}`, i)
+			w := testutil.Request(router, http.MethodPost, "/books", body, headers...)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusBadRequest {
+				return
+			}
+			var result struct {
+				Field string      `json:"field"`
+				Code  messageCode `json:"code"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &result)
+			if result.Field != "titlee" || result.Code != msgUnknownField {
+				t.Errorf("error = %s, want unknown_field naming titlee", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/outbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// upsertColumns are overwritten when an upsert hits an existing book. The
+// slug is kept so existing URLs keep working, and availability only changes
+// through checkout and return.
+var upsertColumns = []string{"title", "author", "authors", "year", "publisher", "language", "updated_at"}
+
+// UpsertBookByISBN godoc
+// @Summary Create or update a book by ISBN
+// @Description Store the book under the ISBN in the path: the book with that ISBN is updated if there is one,
+// @Description otherwise a new book is created. Lets catalog integrations sync without tracking our ids.
+// @Description The ISBN may contain hyphens and spaces; an ISBN in the body must be the same one.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param isbn path string true "ISBN-10 or ISBN-13"
+// @Param book body models.Book true "Book object"
+// @Param strict query bool false "Reject the book when it has validation warnings instead of returning them"
+// @Success 200 {object} bookWithWarnings "Existing book updated"
+// @Success 201 {object} bookWithWarnings "Book created"
+// @Header 201 {string} Location "URL of the created book"
+// @Header 200 {string} Content-Location "URL of the updated book"
+// @Failure 400 {object} map[string]string "Invalid ISBN or request body"
+// @Failure 422 {object} map[string]string "Title or author too long, text with control characters, or an ISBN check digit that doesn't match (with ISBN_CHECKSUM=reject or strict)"
+// @Failure 409 {object} map[string]string "Unique field already in use"
+// @Failure 413 {object} map[string]string "Request body too large"
+// @Failure 415 {object} map[string]string "Content-Type is not application/json"
+// @Router /books/isbn/{isbn} [put]
+func UpsertBookByISBN(ctx *gin.Context) {
+	isbn := models.NormalizeISBN(ctx.Param("isbn"))
+	if !models.IsValidISBN(isbn) {
+		respondMessage(ctx, http.StatusBadRequest, msgISBNInvalid)
+		return
+	}
+
+	var book models.Book
+	if !bindJSON(ctx, &book) {
+		return
+	}
+	if book.ISBN != nil && models.NormalizeISBN(*book.ISBN) != isbn {
+		render(ctx, http.StatusBadRequest, gin.H{"error": "The ISBN in the body does not match the one in the URL"})
+		return
+	}
+	book.ISBN = &isbn
+	book.Slug = "" // generated from the title when the book is created
+	book.Available = true
+
+	warnings, err := checkBook(&book, strictValidation(ctx), requestLanguage(ctx))
+	if err != nil {
+		respondCheckError(ctx, err)
+		return
+	}
+	if book.Language == "" {
+		book.Language = models.DefaultLanguage
+	}
+
+	// Create fills in the id and timestamps, so a retry starts from the payload
+	draft := book
+	var created bool
+	err = database.Transact(ctx.Request.Context(), func(tx *gorm.DB) error {
+		book = draft
+		// Only decides the status and event; a concurrent insert of the same
+		// ISBN is still turned into an update by ON CONFLICT
+		var existing models.Book
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("isbn = ?", isbn).First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		created = errors.Is(err, gorm.ErrRecordNotFound)
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "isbn"}},
+			DoUpdates: clause.AssignmentColumns(upsertColumns),
+		}).Create(&book).Error
+		if err != nil {
+			return err
+		}
+		// Reload the stored row, whose slug, availability and creation time
+		// win over the payload's on update
+		if err := tx.First(&book, book.ID).Error; err != nil {
+			return err
+		}
+		event := "book.updated"
+		if created {
+			event = "book.created"
+		}
+		return outbox.Enqueue(tx, BookEventsTopic, bookEvent(ctx, event, book))
+	})
+	if err != nil {
+		respondWriteError(ctx, err, "Failed to store book")
+		return
+	}
+
+	location := strings.TrimSuffix(ctx.FullPath(), "/isbn/:isbn") + "/" + strconv.FormatUint(uint64(book.ID), 10)
+	if created {
+		cacheCreatedBook(book)
+		ctx.Header("Location", location)
+		respond(ctx, http.StatusCreated, bookWithWarnings{Book: book, Warnings: warnings})
+		return
+	}
+	cacheUpdatedBook(book)
+	setBookETag(ctx, book)
+	ctx.Header("Content-Location", location)
+	respond(ctx, http.StatusOK, bookWithWarnings{Book: book, Warnings: warnings})
+}
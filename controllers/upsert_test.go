@@ -0,0 +1,82 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestUpsertBookByISBN(t *testing.T) {
+	router, db := setup(t)
+	const path = "/v1/books/isbn/978-0-441-17271-9"
+
+	// No book has the ISBN yet: it is created
+	w := testutil.Request(router, http.MethodPut, path, `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("insert: status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created models.Book
+	decode(t, w, &created)
+	if created.ID == 0 || created.ISBN == nil || *created.ISBN != "9780441172719" {
+		t.Fatalf("insert: created = %+v, want an id and the normalized ISBN", created)
+	}
+	if got, want := w.Header().Get("Location"), "/v1/books/"+itoa(created.ID); got != want {
+		t.Errorf("insert: Location = %q, want %q", got, want)
+	}
+
+	// The same ISBN again updates that book in place
+	w = testutil.Request(router, http.MethodPut, path, `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var updated models.Book
+	decode(t, w, &updated)
+	if updated.ID != created.ID || updated.Title != "Dune Messiah" || updated.Year != 1969 {
+		t.Errorf("update: updated = %+v, want book %d retitled", updated, created.ID)
+	}
+	// The slug is kept so existing URLs keep working
+	if updated.Slug != created.Slug {
+		t.Errorf("update: slug = %q, want %q", updated.Slug, created.Slug)
+	}
+	if got, want := w.Header().Get("Content-Location"), "/v1/books/"+itoa(created.ID); got != want {
+		t.Errorf("update: Content-Location = %q, want %q", got, want)
+	}
+	if w.Header().Get("Location") != "" {
+		t.Errorf("update: Location = %q, want none", w.Header().Get("Location"))
+	}
+
+	var count int64
+	db.Model(&models.Book{}).Count(&count)
+	if count != 1 {
+		t.Errorf("books stored = %d, want 1", count)
+	}
+	var events []string
+	db.Model(&models.OutboxEvent{}).Order("id").Pluck("event_type", &events)
+	if len(events) != 2 || events[0] != "book.created" || events[1] != "book.updated" {
+		t.Errorf("events = %v, want book.created then book.updated", events)
+	}
+	// The cached copy is replaced too
+	var got models.Book
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books/"+itoa(created.ID), ""), &got)
+	if got.Title != "Dune Messiah" {
+		t.Errorf("GET after update: title = %q, want the updated one", got.Title)
+	}
+}
+
+func TestUpsertBookByISBNErrors(t *testing.T) {
+	router, _ := setup(t)
+
+	tests := []struct {
+		name, path, body string
+	}{
+		{"invalid ISBN", "/v1/books/isbn/123", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`},
+		{"mismatched body ISBN", "/v1/books/isbn/9780441172719", `{"title": "Dune", "author": "Frank Herbert", "year": 1965, "isbn": "9780553293357"}`},
+	}
+	for _, tt := range tests {
+		if w := testutil.Request(router, http.MethodPut, tt.path, tt.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %s", tt.name, w.Code, w.Body)
+		}
+	}
+}
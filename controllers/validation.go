@@ -0,0 +1,480 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/models"
+	"golang.org/x/text/language"
+)
+
+// earliestPrintedYear is when movable type printing took off in Europe;
+// anything older is probably a typo
+const earliestPrintedYear = 1450
+
+// defaultMinYear only allows years of the common era. Negative years are BCE:
+// -1 is 1 BCE, the year before 1, and there is no year 0.
+const defaultMinYear = 1
+
+// ValidateMinYear checks MIN_YEAR so a bad value fails at startup instead of
+// being silently replaced by the default
+func ValidateMinYear() error {
+	_, err := minYearSetting()
+	return err
+}
+
+// minYear returns the earliest year a book may have, from MIN_YEAR
+func minYear() int {
+	year, err := minYearSetting()
+	if err != nil {
+		return defaultMinYear
+	}
+	return year
+}
+
+// minYearSetting reads MIN_YEAR: any year but 0, negative for BCE
+func minYearSetting() (int, error) {
+	raw := os.Getenv("MIN_YEAR")
+	if raw == "" {
+		return defaultMinYear, nil
+	}
+	year, err := strconv.Atoi(raw)
+	if err != nil || year == 0 {
+		return 0, fmt.Errorf("MIN_YEAR must be a non-zero year (negative for BCE), got %q", raw)
+	}
+	return year, nil
+}
+
+// checkYear enforces MIN_YEAR. While it is positive, the default, a
+// non-positive year keeps its original error.
+func checkYear(year int) error {
+	earliest := minYear()
+	switch {
+	case year <= 0 && earliest > 0:
+		return newMessageError(msgYearInvalid)
+	case year == 0:
+		return newMessageError(msgYearZero)
+	case year < earliest:
+		return newMessageError(msgYearTooEarly, earliest)
+	}
+	return nil
+}
+
+// normalizeText trims s and collapses inner runs of whitespace to a single space
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// escapeHTMLInput reports whether HTML special characters in text fields are
+// escaped before storing, enabled with ESCAPE_HTML_INPUT=true
+func escapeHTMLInput() bool {
+	return os.Getenv("ESCAPE_HTML_INPUT") == "true"
+}
+
+// escapeText HTML-escapes s. Escaped text is unescaped first, so sending back
+// a stored value unchanged doesn't escape it twice.
+func escapeText(s string) string {
+	return html.EscapeString(html.UnescapeString(s))
+}
+
+// normalizeBook normalizes the free-text fields and the ISBN of book in place
+// and fills in Author from Authors or the other way round. It runs before
+// validation so whitespace-only values are rejected as empty.
+func normalizeBook(book *models.Book) {
+	if book.ISBN != nil {
+		if isbn := models.NormalizeISBN(*book.ISBN); isbn != "" {
+			book.ISBN = &isbn
+		} else {
+			book.ISBN = nil
+		}
+	}
+	clean := normalizeText
+	if escapeHTMLInput() {
+		clean = func(s string) string { return escapeText(normalizeText(s)) }
+	}
+	book.Title = clean(book.Title)
+	book.Publisher = clean(book.Publisher)
+	book.Author = clean(book.Author)
+	for i, author := range book.Authors {
+		book.Authors[i] = clean(author)
+	}
+	book.SyncAuthors()
+}
+
+// tooLongError reports a text field longer than its configured limit. It is
+// answered with 422 rather than 400.
+type tooLongError struct {
+	field string
+	max   int
+}
+
+func (e tooLongError) Error() string {
+	return e.localize(language.English)
+}
+
+func (e tooLongError) messageCode() messageCode {
+	return msgTooLong
+}
+
+func (e tooLongError) localize(lang language.Tag) string {
+	return message(lang, msgTooLong, e.field, e.max)
+}
+
+// controlCharsError reports a text field containing control characters such
+// as null bytes. Like tooLongError it is answered with 422.
+type controlCharsError struct {
+	field string
+}
+
+func (e controlCharsError) Error() string {
+	return e.localize(language.English)
+}
+
+func (e controlCharsError) messageCode() messageCode {
+	return msgControlChars
+}
+
+func (e controlCharsError) localize(lang language.Tag) string {
+	return message(lang, msgControlChars, e.field)
+}
+
+// checkControlChars returns a controlCharsError when value contains a control
+// character. Tabs and line breaks are already collapsed by normalizeText.
+func checkControlChars(field, value string) error {
+	if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return controlCharsError{field: field}
+	}
+	return nil
+}
+
+// ISBN_CHECKSUM modes for an ISBN whose check digit doesn't match
+const (
+	isbnChecksumWarn   = "warn"
+	isbnChecksumReject = "reject"
+)
+
+// ValidateISBNChecksumMode checks ISBN_CHECKSUM so a typo fails at startup
+// instead of silently selecting the default
+func ValidateISBNChecksumMode() error {
+	_, err := isbnChecksumSetting()
+	return err
+}
+
+func isbnChecksumMode() string {
+	mode, err := isbnChecksumSetting()
+	if err != nil {
+		return isbnChecksumWarn
+	}
+	return mode
+}
+
+// isbnChecksumSetting reads ISBN_CHECKSUM: "warn", the default, accepts a bad
+// check digit with a warning and "reject" refuses it
+func isbnChecksumSetting() (string, error) {
+	switch mode := os.Getenv("ISBN_CHECKSUM"); mode {
+	case "":
+		return isbnChecksumWarn, nil
+	case isbnChecksumWarn, isbnChecksumReject:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("ISBN_CHECKSUM must be %s or %s, got %q", isbnChecksumWarn, isbnChecksumReject, mode)
+	}
+}
+
+// isbnChecksumError reports an ISBN with a wrong check digit that isn't
+// accepted, in reject or strict mode. Like tooLongError it is answered with
+// 422.
+type isbnChecksumError struct{}
+
+func (e isbnChecksumError) Error() string {
+	return e.localize(language.English)
+}
+
+func (e isbnChecksumError) messageCode() messageCode {
+	return msgISBNChecksum
+}
+
+func (e isbnChecksumError) localize(lang language.Tag) string {
+	return message(lang, msgISBNChecksum)
+}
+
+// badISBNChecksum reports whether book has an ISBN of the right shape whose
+// check digit doesn't match
+func badISBNChecksum(book models.Book) bool {
+	return book.ISBN != nil && models.IsValidISBN(*book.ISBN) && !models.HasValidISBNChecksum(*book.ISBN)
+}
+
+// validationStatus is the status code to answer a validateBook error with
+func validationStatus(err error) int {
+	var tooLong tooLongError
+	var controlChars controlCharsError
+	var checksum isbnChecksumError
+	if errors.As(err, &tooLong) || errors.As(err, &controlChars) || errors.As(err, &checksum) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// ValidateTextLimits checks MAX_TITLE_LENGTH and MAX_AUTHOR_LENGTH so a bad
+// value fails at startup instead of being silently replaced by the default
+func ValidateTextLimits() error {
+	_, _, err := textLimits()
+	return err
+}
+
+// textLimits returns the maximum title and author length from
+// MAX_TITLE_LENGTH and MAX_AUTHOR_LENGTH. Both default to, and may not
+// exceed, the column size so the database never has to reject a value the
+// application accepted.
+func textLimits() (int, int, error) {
+	maxTitle, err := positiveEnvInt("MAX_TITLE_LENGTH", models.MaxTextLength)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxAuthor, err := positiveEnvInt("MAX_AUTHOR_LENGTH", models.MaxTextLength)
+	if err != nil {
+		return 0, 0, err
+	}
+	if maxTitle > models.MaxTextLength || maxAuthor > models.MaxTextLength {
+		return 0, 0, fmt.Errorf("MAX_TITLE_LENGTH and MAX_AUTHOR_LENGTH can't exceed the column size of %d", models.MaxTextLength)
+	}
+	return maxTitle, maxAuthor, nil
+}
+
+// checkLength returns a tooLongError when value has more than max characters
+func checkLength(field, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return tooLongError{field: field, max: max}
+	}
+	return nil
+}
+
+// validateBook checks the rules a book must satisfy to be stored. An empty
+// language is allowed; callers fill in the default or keep the current one.
+func validateBook(book models.Book) error {
+	maxTitle, maxAuthor, err := textLimits()
+	if err != nil {
+		maxTitle, maxAuthor = models.MaxTextLength, models.MaxTextLength
+	}
+
+	if book.Title == "" {
+		return newMessageError(msgTitleEmpty)
+	}
+	if err := checkLength("Title", book.Title, maxTitle); err != nil {
+		return err
+	}
+	if err := checkControlChars("Title", book.Title); err != nil {
+		return err
+	}
+	if book.Author == "" {
+		return newMessageError(msgAuthorEmpty)
+	}
+	seen := make(map[string]bool, len(book.Authors))
+	for _, author := range book.Authors {
+		if author == "" {
+			return newMessageError(msgAuthorsEmptyName)
+		}
+		if seen[author] {
+			return newMessageError(msgAuthorsDuplicate)
+		}
+		seen[author] = true
+		if err := checkLength("Author", author, maxAuthor); err != nil {
+			return err
+		}
+		if err := checkControlChars("Author", author); err != nil {
+			return err
+		}
+	}
+	if err := checkLength("Publisher", book.Publisher, models.MaxTextLength); err != nil {
+		return err
+	}
+	if err := checkControlChars("Publisher", book.Publisher); err != nil {
+		return err
+	}
+	if err := checkYear(book.Year); err != nil {
+		return err
+	}
+	if book.ISBN != nil && !models.IsValidISBN(*book.ISBN) {
+		return newMessageError(msgISBNInvalid)
+	}
+	if book.Language != "" && !models.IsValidLanguage(book.Language) {
+		return newMessageError(msgLanguageInvalid)
+	}
+	return nil
+}
+
+// warningRule inspects a valid book and returns a catalog message when
+// something looks off, or nil when it doesn't
+type warningRule func(book models.Book) localizedError
+
+var warningRules = []warningRule{
+	func(book models.Book) localizedError {
+		if book.Year < earliestPrintedYear {
+			return newMessageError(msgWarnYearOld, book.Year)
+		}
+		return nil
+	},
+	func(book models.Book) localizedError {
+		if book.Year > time.Now().Year() {
+			return newMessageError(msgWarnYearFuture, book.Year)
+		}
+		return nil
+	},
+	func(book models.Book) localizedError {
+		if badISBNChecksum(book) {
+			return newMessageError(msgWarnISBNChecksum)
+		}
+		return nil
+	},
+	func(book models.Book) localizedError {
+		if isAllCaps(book.Title) {
+			return newMessageError(msgWarnTitleCaps)
+		}
+		return nil
+	},
+	func(book models.Book) localizedError {
+		for _, author := range book.Authors {
+			if isAllCaps(author) {
+				return newMessageError(msgWarnAuthorCaps, author)
+			}
+		}
+		return nil
+	},
+}
+
+// bookWarnings runs every warning rule against book and returns the
+// warnings in lang
+func bookWarnings(book models.Book, lang language.Tag) []string {
+	var warnings []string
+	for _, rule := range warningRules {
+		if warning := rule(book); warning != nil {
+			warnings = append(warnings, warning.localize(lang))
+		}
+	}
+	return warnings
+}
+
+// strictValidation reports whether the client asked for warnings to be
+// treated as errors with ?strict=true
+func strictValidation(ctx *gin.Context) bool {
+	strict, _ := strconv.ParseBool(ctx.Query("strict"))
+	return strict
+}
+
+// isAllCaps reports whether s has more than one letter and all of them are
+// upper case, so acronyms like "IT" aren't flagged
+func isAllCaps(s string) bool {
+	letters := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			if !unicode.IsUpper(r) {
+				return false
+			}
+			letters++
+		}
+	}
+	return letters > 3 && strings.ToUpper(s) == s
+}
+
+// warningsError rejects a valid book that has warnings in strict mode
+type warningsError struct {
+	warnings []string
+}
+
+func (e warningsError) Error() string {
+	return e.localize(language.English)
+}
+
+func (e warningsError) messageCode() messageCode {
+	return msgValidationWarning
+}
+
+func (e warningsError) localize(lang language.Tag) string {
+	return message(lang, msgValidationWarning)
+}
+
+// checkBook normalizes book and runs every check a book must pass before it
+// is stored: the validation rules and, in strict mode, the warning rules. It
+// returns the warnings of a book that passes, in lang. Create, update and
+// the validate endpoint all go through it so their rules can't drift apart.
+func checkBook(book *models.Book, strict bool, lang language.Tag) ([]string, error) {
+	normalizeBook(book)
+	if err := validateBook(*book); err != nil {
+		return nil, err
+	}
+	// A bad ISBN check digit is a warning unless ISBN_CHECKSUM=reject, and
+	// is rejected like an invalid value rather than as a warning in strict
+	// mode
+	if badISBNChecksum(*book) && (strict || isbnChecksumMode() == isbnChecksumReject) {
+		return nil, isbnChecksumError{}
+	}
+	warnings := bookWarnings(*book, lang)
+	if strict && len(warnings) > 0 {
+		return warnings, warningsError{warnings: warnings}
+	}
+	return warnings, nil
+}
+
+// respondCheckError answers a checkBook error with the status create and
+// update use: 422 for values that are too long, 400 otherwise
+func respondCheckError(ctx *gin.Context, err error) {
+	var withWarnings warningsError
+	if errors.As(err, &withWarnings) {
+		body := gin.H{"warnings": withWarnings.warnings}
+		body["error"] = errorMessage(ctx, err, body)
+		render(ctx, http.StatusBadRequest, body)
+		return
+	}
+	respondError(ctx, validationStatus(err), err)
+}
+
+// bookWithWarnings is the create/update response: the stored book plus any
+// non-fatal observations about it
+type bookWithWarnings struct {
+	models.Book
+	Warnings []string `json:"warnings,omitempty" xml:"warnings>warning,omitempty"`
+}
+
+// ValidateBookPayload godoc
+// @Summary Validate a book without creating it
+// @Description Run the checks of POST /books on a book without storing it or publishing an event, e.g. for
+// @Description live form validation. The response reports the warnings create would return.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param book body models.Book true "Book object"
+// @Param strict query bool false "Treat validation warnings as errors"
+// @Success 200 {object} map[string]interface{} "{\"valid\": true, \"warnings\": [...]}"
+// @Failure 400 {object} map[string]string "Invalid JSON"
+// @Failure 422 {object} map[string]interface{} "{\"valid\": false, \"error\": \"...\"}"
+// @Router /books/validate [post]
+func ValidateBookPayload(ctx *gin.Context) {
+	var book models.Book
+	if !bindJSON(ctx, &book) {
+		return
+	}
+
+	warnings, err := checkBook(&book, strictValidation(ctx), requestLanguage(ctx))
+	if err != nil {
+		body := gin.H{"valid": false}
+		body["error"] = errorMessage(ctx, err, body)
+		if len(warnings) > 0 {
+			body["warnings"] = warnings
+		}
+		render(ctx, http.StatusUnprocessableEntity, body)
+		return
+	}
+	if warnings == nil {
+		warnings = []string{}
+	}
+	respond(ctx, http.StatusOK, gin.H{"valid": true, "warnings": warnings})
+}
@@ -0,0 +1,342 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rohans540/books-backend/controllers"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestCreateBookWarnings(t *testing.T) {
+	router, db := setup(t)
+	body := `{"title": "THE HOBBIT", "author": "J. R. R. Tolkien", "year": 1200}`
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+	}
+	// Not embedding models.Book, whose UnmarshalJSON would take over
+	var created struct {
+		Warnings []string `json:"warnings"`
+	}
+	decode(t, w, &created)
+	got := strings.Join(created.Warnings, "; ")
+	if len(created.Warnings) != 2 || !strings.Contains(got, "unusually old") || !strings.Contains(got, "all caps") {
+		t.Errorf("warnings = %q, want the old year and the all caps title", created.Warnings)
+	}
+
+	w = testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if strings.Contains(w.Body.String(), "warnings") {
+		t.Errorf("book without warnings: body = %s, want no warnings field", w.Body)
+	}
+
+	var count int64
+	db.Model(&models.Book{}).Count(&count)
+	if count != 2 {
+		t.Errorf("%d books stored, want 2", count)
+	}
+}
+
+func TestStrictModeRejectsWarnings(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "The Hobbit", Author: "J. R. R. Tolkien", Year: 1937})[0]
+	body := `{"title": "THE HOBBIT", "author": "J. R. R. Tolkien", "year": 1937}`
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books?strict=true", body)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("create: status = %d, want 400: %s", w.Code, w.Body)
+	}
+	w = testutil.Request(router, http.MethodPut, "/v1/books/"+itoa(book.ID)+"?strict=true", body)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("update: status = %d, want 400: %s", w.Code, w.Body)
+	}
+
+	var stored []models.Book
+	db.Find(&stored)
+	if len(stored) != 1 || stored[0].Title != "The Hobbit" {
+		t.Errorf("stored = %+v, want only the seeded book unchanged", stored)
+	}
+}
+
+func TestWhitespaceIsNormalized(t *testing.T) {
+	router, db := setup(t)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "  The   Hobbit\t", "author": " J. R. R.  Tolkien ", "year": 1937}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created models.Book
+	decode(t, w, &created)
+	var stored models.Book
+	db.First(&stored, created.ID)
+	if stored.Title != "The Hobbit" || stored.Author != "J. R. R. Tolkien" {
+		t.Errorf("stored title %q and author %q, want them trimmed and collapsed", stored.Title, stored.Author)
+	}
+
+	// The dedup check compares normalized values
+	w = testutil.Request(router, http.MethodPost, "/v1/books?dedupe=true", `{"title": "The Hobbit ", "author": "J. R. R. Tolkien", "year": 1937}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate with extra whitespace: status = %d, want 409: %s", w.Code, w.Body)
+	}
+
+	w = testutil.Request(router, http.MethodPut, "/v1/books/"+itoa(created.ID), `{"title": " \t ", "author": "J. R. R. Tolkien", "year": 1937}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("update to a blank title: status = %d, want 400: %s", w.Code, w.Body)
+	}
+}
+
+func TestTitleAndAuthorLength(t *testing.T) {
+	router, db := setup(t)
+	atLimit := strings.Repeat("é", models.MaxTextLength)
+	overLimit := atLimit + "x"
+
+	tests := []struct {
+		name, title, author string
+		want                int
+	}{
+		{"title at the limit", atLimit, "Author", http.StatusCreated},
+		{"title over the limit", overLimit, "Author", http.StatusUnprocessableEntity},
+		{"author at the limit", "Book", atLimit, http.StatusCreated},
+		{"author over the limit", "Book", overLimit, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"title": "` + tt.title + `", "author": "` + tt.author + `", "year": 2000}`
+			if w := testutil.Request(router, http.MethodPost, "/v1/books", body); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %.200s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	var stored models.Book
+	db.Where("author = ?", "Author").First(&stored)
+	if stored.Title != atLimit {
+		t.Errorf("title stored with %d characters, want it unchanged", len([]rune(stored.Title)))
+	}
+}
+
+func TestConfiguredTitleLength(t *testing.T) {
+	t.Setenv("MAX_TITLE_LENGTH", "4")
+	router, _ := setup(t)
+
+	if w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`); w.Code != http.StatusCreated {
+		t.Errorf("title at the limit: status = %d, want 201", w.Code)
+	}
+	if w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dunes", "author": "Frank Herbert", "year": 1965}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("title over the limit: status = %d, want 422", w.Code)
+	}
+
+	t.Setenv("MAX_TITLE_LENGTH", strconv.Itoa(models.MaxTextLength+1))
+	if err := controllers.ValidateTextLimits(); err == nil {
+		t.Error("a limit above the column size was accepted")
+	}
+}
+
+func TestYearAsString(t *testing.T) {
+	router, _ := setup(t)
+
+	w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": "1965"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("numeric string: status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created models.Book
+	decode(t, w, &created)
+	if created.Year != 1965 {
+		t.Errorf("year = %d, want 1965", created.Year)
+	}
+
+	w = testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": "sixties"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("garbage year: status = %d, want 422: %s", w.Code, w.Body)
+	}
+}
+
+func TestValidateBookPayload(t *testing.T) {
+	router, db := setup(t)
+
+	tests := []struct {
+		name, path, body string
+		want             int
+		valid            bool
+		warnings         int
+	}{
+		{"valid", "/v1/books/validate", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`, http.StatusOK, true, 0},
+		{"with warnings", "/v1/books/validate", `{"title": "THE HOBBIT", "author": "J. R. R. Tolkien", "year": 1937}`, http.StatusOK, true, 1},
+		{"warnings in strict mode", "/v1/books/validate?strict=true", `{"title": "THE HOBBIT", "author": "J. R. R. Tolkien", "year": 1937}`, http.StatusUnprocessableEntity, false, 1},
+		{"missing title", "/v1/books/validate", `{"author": "Frank Herbert", "year": 1965}`, http.StatusUnprocessableEntity, false, 0},
+		{"title too long", "/v1/books/validate", `{"title": "` + strings.Repeat("t", models.MaxTextLength+1) + `", "author": "Frank Herbert", "year": 1965}`, http.StatusUnprocessableEntity, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Request(router, http.MethodPost, tt.path, tt.body)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			var result struct {
+				Valid    bool     `json:"valid"`
+				Error    string   `json:"error"`
+				Warnings []string `json:"warnings"`
+			}
+			decode(t, w, &result)
+			if result.Valid != tt.valid || len(result.Warnings) != tt.warnings || (result.Error == "") == !tt.valid {
+				t.Errorf("result = %+v, want valid %t with %d warnings", result, tt.valid, tt.warnings)
+			}
+		})
+	}
+
+	if w := testutil.Request(router, http.MethodPost, "/v1/books/validate", `{"title": `); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed JSON: status = %d, want 400", w.Code)
+	}
+
+	// Nothing was stored or queued for publishing
+	var books, events int64
+	db.Model(&models.Book{}).Count(&books)
+	db.Model(&models.OutboxEvent{}).Count(&events)
+	if books != 0 || events != 0 {
+		t.Errorf("%d books and %d events stored, want none", books, events)
+	}
+}
+
+func TestBCEYears(t *testing.T) {
+	tests := []struct {
+		minYear string
+		year    int
+		code    string // empty when the book is accepted
+	}{
+		{"", -429, "year_invalid"},
+		{"", 0, "year_invalid"},
+		{"-3000", -429, ""},
+		{"-3000", -3000, ""},
+		{"-3000", -3001, "year_too_early"},
+		{"-3000", 0, "year_zero"},
+		{"1450", 1449, "year_too_early"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("MIN_YEAR=%s year=%d", tt.minYear, tt.year), func(t *testing.T) {
+			t.Setenv("MIN_YEAR", tt.minYear)
+			router, _ := setup(t)
+
+			w := testutil.Request(router, http.MethodPost, "/v1/books", fmt.Sprintf(`{"title": "The Histories", "author": "Herodotus", "year": %d}`, tt.year))
+			if tt.code == "" {
+				var created models.Book
+				decode(t, w, &created)
+				if w.Code != http.StatusCreated || created.Year != tt.year {
+					t.Errorf("status = %d, year %d, want 201 and %d: %s", w.Code, created.Year, tt.year, w.Body)
+				}
+				return
+			}
+			var body map[string]interface{}
+			decode(t, w, &body)
+			if w.Code != http.StatusBadRequest || body["code"] != tt.code {
+				t.Errorf("status = %d, code %v, want 400 and %s", w.Code, body["code"], tt.code)
+			}
+		})
+	}
+}
+
+func TestBCEYearsSortAndGroup(t *testing.T) {
+	t.Setenv("MIN_YEAR", "-3000")
+	router, db := setup(t)
+	books := testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Year: 1965},
+		models.Book{Title: "The Histories", Year: -429},
+		models.Book{Title: "The Odyssey", Year: -8},
+		models.Book{Title: "Metamorphoses", Year: 8},
+	)
+
+	var sorted []models.Book
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books?sort=year", ""), &sorted)
+	if got, want := ids(sorted), []uint{books[1].ID, books[2].ID, books[3].ID, books[0].ID}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("sort=year = %v, want %v", got, want)
+	}
+
+	var stats controllers.BookStats
+	decode(t, testutil.Request(router, http.MethodGet, "/v1/books/stats", ""), &stats)
+	var decades []string
+	for _, decade := range stats.ByDecade {
+		decades = append(decades, fmt.Sprintf("%d:%d", decade.Decade, decade.Count))
+	}
+	// -8 is in the decade 10-1 BCE, not lumped with 8 CE
+	if got := fmt.Sprint(decades); got != "[-430:1 -10:1 0:1 1960:1]" {
+		t.Errorf("decades = %s, want -430, -10, 0 and 1960", got)
+	}
+	if stats.MinYear == nil || *stats.MinYear != -429 {
+		t.Errorf("min_year = %v, want -429", stats.MinYear)
+	}
+}
+
+func TestControlCharacters(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	router, db := setup(t)
+
+	tests := []struct {
+		name, title, author, publisher string
+		want                           int
+	}{
+		{"null byte in the title", `Du\u0000ne`, "Frank Herbert", "", http.StatusUnprocessableEntity},
+		{"escape sequence in the author", "Dune", `\u001b[31mFrank Herbert`, "", http.StatusUnprocessableEntity},
+		{"delete in the publisher", "Dune", "Frank Herbert", `Chilton\u007f`, http.StatusUnprocessableEntity},
+		{"tabs and line breaks", `Dune\tMessiah\r\n`, "Frank Herbert", "", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"title": "%s", "author": "%s", "publisher": "%s", "year": 1965}`, tt.title, tt.author, tt.publisher)
+			w := testutil.Request(router, http.MethodPost, "/v1/books", body)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusUnprocessableEntity && !strings.Contains(w.Body.String(), `"code":"control_characters"`) {
+				t.Errorf("body = %s, want the control_characters code", w.Body)
+			}
+		})
+	}
+
+	var stored models.Book
+	db.First(&stored)
+	if stored.Title != "Dune Messiah" {
+		t.Errorf("title = %q, want tabs and line breaks collapsed", stored.Title)
+	}
+	w := testutil.Request(router, http.MethodPatch, "/v1/books", `{"filter": {"ids": [`+itoa(stored.ID)+`]}, "field": "author", "value": "Frank\u0000Herbert"}`,
+		"Authorization", "Bearer "+adminToken)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("bulk update with a null byte: status = %d, want 422: %s", w.Code, w.Body)
+	}
+}
+
+func TestHTMLInput(t *testing.T) {
+	const script = "<script>alert('x')</script>"
+	const escaped = "&lt;script&gt;alert(&#39;x&#39;)&lt;/script&gt;"
+	for _, tt := range []struct {
+		name, env, want string
+	}{
+		{"verbatim by default", "", script},
+		{"escaped", "true", escaped},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ESCAPE_HTML_INPUT", tt.env)
+			router, _ := setup(t)
+			w := testutil.Request(router, http.MethodPost, "/v1/books", `{"title": "`+script+`", "author": "Frank Herbert", "year": 1965}`)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+			}
+			var created models.Book
+			decode(t, w, &created)
+			if created.Title != tt.want {
+				t.Errorf("title = %q, want %q", created.Title, tt.want)
+			}
+
+			// Sending the stored title back doesn't escape it again
+			w = testutil.Request(router, http.MethodPut, "/v1/books/"+itoa(created.ID), fmt.Sprintf(`{"title": %q, "author": "Frank Herbert", "year": 1965}`, created.Title))
+			var updated models.Book
+			decode(t, w, &updated)
+			if updated.Title != tt.want {
+				t.Errorf("title after sending it back = %q, want %q", updated.Title, tt.want)
+			}
+		})
+	}
+}
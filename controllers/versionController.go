@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build information, set at build time with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/rohans540/books-backend/controllers.Version=v1.4.0
+//	  -X github.com/rohans540/books-backend/controllers.Commit=$(git rev-parse HEAD)
+//	  -X github.com/rohans540/books-backend/controllers.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// GetVersion reports which build is running, to confirm what is deployed.
+// Like the probes it lives outside /v1 and isn't part of the Swagger spec.
+func GetVersion(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"version": Version, "commit": Commit, "build_time": BuildTime})
+}
+
+// defaultServiceName is what GET / calls the service unless SERVICE_NAME is set
+const defaultServiceName = "books-backend"
+
+// RootInfo returns the handler for GET /, which names the service and links
+// to the docs and health check, for smoke checks and for anyone opening the
+// base URL. Links are under base, the path the API is mounted on.
+func RootInfo(base string) gin.HandlerFunc {
+	name := os.Getenv("SERVICE_NAME")
+	if name == "" {
+		name = defaultServiceName
+	}
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"service": name,
+			"version": Version,
+			"links": gin.H{
+				"docs":   base + "/swagger/index.html",
+				"health": base + "/healthz",
+			},
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rohans540/books-backend/controllers"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestGetVersion(t *testing.T) {
+	router, _ := setup(t)
+
+	var got map[string]string
+	w := testutil.Request(router, http.MethodGet, "/version", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	decode(t, w, &got)
+	want := map[string]string{"version": "dev", "commit": "unknown", "build_time": "unknown"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("default %s = %q, want %q", key, got[key], value)
+		}
+	}
+
+	// Values injected with -ldflags replace the defaults
+	version, commit, buildTime := controllers.Version, controllers.Commit, controllers.BuildTime
+	t.Cleanup(func() { controllers.Version, controllers.Commit, controllers.BuildTime = version, commit, buildTime })
+	controllers.Version, controllers.Commit, controllers.BuildTime = "v1.4.0", "abc123", "2026-01-02T03:04:05Z"
+
+	got = nil
+	decode(t, testutil.Request(router, http.MethodGet, "/version", ""), &got)
+	want = map[string]string{"version": "v1.4.0", "commit": "abc123", "build_time": "2026-01-02T03:04:05Z"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("injected %s = %q, want %q", key, got[key], value)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("response = %v, want only %v", got, want)
+	}
+}
+
+func TestRootInfo(t *testing.T) {
+	tests := []struct {
+		serviceName, want string
+	}{
+		{"", "books-backend"},
+		{"catalog", "catalog"},
+	}
+	for _, tt := range tests {
+		t.Setenv("SERVICE_NAME", tt.serviceName)
+		router, _ := setup(t)
+
+		w := testutil.Request(router, http.MethodGet, "/", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+		}
+		var got struct {
+			Service string            `json:"service"`
+			Version string            `json:"version"`
+			Links   map[string]string `json:"links"`
+		}
+		decode(t, w, &got)
+		if got.Service != tt.want || got.Version != controllers.Version {
+			t.Errorf("SERVICE_NAME=%q: service %q, version %q, want %q and %q", tt.serviceName, got.Service, got.Version, tt.want, controllers.Version)
+		}
+		if got.Links["docs"] != "/swagger/index.html" || got.Links["health"] != "/healthz" {
+			t.Errorf("links = %v, want the docs and health check", got.Links)
+		}
+		// The links lead somewhere
+		if w := testutil.Request(router, http.MethodGet, got.Links["health"], ""); w.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d", got.Links["health"], w.Code)
+		}
+	}
+}
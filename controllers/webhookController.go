@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// WebhookRequest registers or changes a webhook. Without a secret, creating
+// generates one and updating keeps the current one.
+type WebhookRequest struct {
+	URL    string `json:"url" binding:"required"`
+	Secret string `json:"secret"`
+}
+
+// CreatedWebhook is a new webhook along with its secret, which is returned
+// this once
+type CreatedWebhook struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description List every registered webhook with its delivery state. Secrets are never listed. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {array} models.Webhook
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to fetch webhooks"
+// @Router /admin/webhooks [get]
+func ListWebhooks(ctx *gin.Context) {
+	var hooks []models.Webhook
+	if err := database.DB.WithContext(ctx.Request.Context()).Order("id").Find(&hooks).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+	respond(ctx, http.StatusOK, hooks)
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Description Get a webhook with its delivery state: the last event it accepted, failures in a row, the last error and
+// @Description when it is retried or was disabled. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.Webhook
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Failure 500 {object} map[string]string "Failed to fetch the webhook"
+// @Router /admin/webhooks/{id} [get]
+func GetWebhook(ctx *gin.Context) {
+	hook, ok := findWebhook(ctx, database.DB)
+	if !ok {
+		return
+	}
+	respond(ctx, http.StatusOK, hook)
+}
+
+// CreateWebhook godoc
+// @Summary Register a webhook
+// @Description Register a URL that receives every book event from now on as a POST of the event JSON. Each delivery
+// @Description carries X-Webhook-Event, X-Webhook-Delivery (the event id) and X-Webhook-Signature, "sha256=" followed by
+// @Description the hex HMAC-SHA256 of the body keyed with the secret. The secret is only returned here. Requires the admin token.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param request body WebhookRequest true "Webhook URL and optional secret"
+// @Success 201 {object} CreatedWebhook
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 500 {object} map[string]string "Failed to create the webhook"
+// @Router /admin/webhooks [post]
+func CreateWebhook(ctx *gin.Context) {
+	var req WebhookRequest
+	if !bindWebhookRequest(ctx, &req) {
+		return
+	}
+	if req.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the webhook"})
+			return
+		}
+		req.Secret = secret
+	}
+
+	hook := models.Webhook{URL: req.URL, Secret: req.Secret}
+	err := database.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// Start after the newest event so the webhook doesn't get the backlog
+		err := tx.Model(&models.OutboxEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&hook.LastEventID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&hook).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the webhook"})
+		return
+	}
+	respond(ctx, http.StatusCreated, CreatedWebhook{Webhook: hook, Secret: hook.Secret})
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Change a webhook's URL and, if given, its secret. This also clears its failures and enables it again;
+// @Description delivery resumes with the first event it hasn't accepted. Requires the admin token.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Webhook ID"
+// @Param request body WebhookRequest true "Webhook URL and optional new secret"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Failure 500 {object} map[string]string "Failed to update the webhook"
+// @Router /admin/webhooks/{id} [put]
+func UpdateWebhook(ctx *gin.Context) {
+	var req WebhookRequest
+	if !bindWebhookRequest(ctx, &req) {
+		return
+	}
+	hook, ok := findWebhook(ctx, database.DB.Clauses(dbresolver.Write))
+	if !ok {
+		return
+	}
+
+	hook.URL = req.URL
+	if req.Secret != "" {
+		hook.Secret = req.Secret
+	}
+	hook.Failures, hook.LastError, hook.RetryAt, hook.DisabledAt = 0, nil, nil, nil
+	err := database.DB.WithContext(ctx.Request.Context()).Model(&hook).
+		Select("url", "secret", "failures", "last_error", "retry_at", "disabled_at").
+		Updates(&hook).Error
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the webhook"})
+		return
+	}
+	respond(ctx, http.StatusOK, hook)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Stop delivering events to a webhook and remove it. Requires the admin token.
+// @Tags admin
+// @Security AdminToken
+// @Param id path int true "Webhook ID"
+// @Success 204 "Webhook deleted"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Failure 500 {object} map[string]string "Failed to delete the webhook"
+// @Router /admin/webhooks/{id} [delete]
+func DeleteWebhook(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	result := database.DB.WithContext(ctx.Request.Context()).Delete(&models.Webhook{}, id)
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete the webhook"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary List a webhook's delivery attempts
+// @Description List the attempts to deliver events to a webhook, newest first, with the receiver's status code and
+// @Description the error of failed ones, to debug a failing receiver. Requires the admin token.
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Webhook ID"
+// @Param status query string false "Only attempts with this outcome" Enums(delivered, failed)
+// @Param limit query int false "Number of attempts per page (default: DEFAULT_PAGE_SIZE)"
+// @Param offset query int false "Offset for pagination (default: 0)"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} map[string]string "Invalid filter"
+// @Failure 401 {object} map[string]string "Missing or wrong admin token"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Failure 500 {object} map[string]string "Failed to list deliveries"
+// @Router /admin/webhooks/{id}/deliveries [get]
+func ListWebhookDeliveries(ctx *gin.Context) {
+	status := ctx.Query("status")
+	if status != "" && status != models.DeliveryDelivered && status != models.DeliveryFailed {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "status must be delivered or failed"})
+		return
+	}
+	hook, ok := findWebhook(ctx, database.DB)
+	if !ok {
+		return
+	}
+
+	limit := pageLimit(ctx)
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := database.DB.WithContext(ctx.Request.Context()).Where("webhook_id = ?", hook.ID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list deliveries"})
+		return
+	}
+	respondWithMeta(ctx, http.StatusOK, deliveries, gin.H{"limit": limit, "offset": offset})
+}
+
+// findWebhook loads the webhook named by the id parameter, answering 404 or
+// 500 when it can't
+func findWebhook(ctx *gin.Context, db *gorm.DB) (models.Webhook, bool) {
+	var hook models.Webhook
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return hook, false
+	}
+	err = db.WithContext(ctx.Request.Context()).First(&hook, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return hook, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the webhook"})
+		return hook, false
+	}
+	return hook, true
+}
+
+// bindWebhookRequest binds req and checks that its URL is absolute http(s)
+func bindWebhookRequest(ctx *gin.Context, req *WebhookRequest) bool {
+	if !bindJSON(ctx, req) {
+		return false
+	}
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http or https URL"})
+		return false
+	}
+	return true
+}
+
+// newWebhookSecret returns 32 random bytes as hex
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
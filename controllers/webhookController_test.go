@@ -0,0 +1,135 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestWebhookCRUD(t *testing.T) {
+	router, db := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+
+	if w := testutil.Request(router, http.MethodPost, "/v1/admin/webhooks", `{"url": "ftp://example.com"}`, auth...); w.Code != http.StatusBadRequest {
+		t.Errorf("create with a non-http URL: status = %d, want 400", w.Code)
+	}
+
+	// Registered after this event, so it isn't delivered the backlog
+	db.Create(&models.OutboxEvent{Topic: "book_events", Payload: "{}"})
+	w := testutil.Request(router, http.MethodPost, "/v1/admin/webhooks", `{"url": "https://example.com/hook"}`, auth...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", w.Code, w.Body)
+	}
+	var created struct {
+		ID          uint   `json:"id"`
+		Secret      string `json:"secret"`
+		LastEventID uint   `json:"last_event_id"`
+	}
+	decode(t, w, &created)
+	if created.ID == 0 || len(created.Secret) != 64 || created.LastEventID != 1 {
+		t.Fatalf("created = %+v, want an id, a generated secret and the backlog skipped", created)
+	}
+	path := "/v1/admin/webhooks/" + itoa(created.ID)
+
+	// The secret is only returned once
+	for _, get := range []string{"/v1/admin/webhooks", path} {
+		w := testutil.Request(router, http.MethodGet, get, "", auth...)
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), created.Secret) {
+			t.Errorf("GET %s: status = %d, secret exposed: %v", get, w.Code, strings.Contains(w.Body.String(), created.Secret))
+		}
+	}
+
+	// Updating re-enables a disabled webhook
+	db.Model(&models.Webhook{}).Where("id = ?", created.ID).Updates(map[string]interface{}{"failures": 10, "disabled_at": db.NowFunc()})
+	w = testutil.Request(router, http.MethodPut, path, `{"url": "https://example.com/new", "secret": "rotated"}`, auth...)
+	var updated models.Webhook
+	decode(t, w, &updated)
+	if w.Code != http.StatusOK || updated.URL != "https://example.com/new" || updated.Failures != 0 || updated.DisabledAt != nil {
+		t.Errorf("update: status = %d, webhook %+v, want the new URL and the failures cleared", w.Code, updated)
+	}
+	var stored models.Webhook
+	db.First(&stored, created.ID)
+	if stored.Secret != "rotated" {
+		t.Errorf("stored secret = %q, want the rotated one", stored.Secret)
+	}
+
+	if w := testutil.Request(router, http.MethodDelete, path, "", auth...); w.Code != http.StatusNoContent {
+		t.Errorf("delete: status = %d, want 204", w.Code)
+	}
+	if w := testutil.Request(router, http.MethodGet, path, "", auth...); w.Code != http.StatusNotFound {
+		t.Errorf("GET after delete: status = %d, want 404", w.Code)
+	}
+	if w := testutil.Request(router, http.MethodGet, "/v1/admin/webhooks", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want 401", w.Code)
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	router, db := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	auth := []string{"Authorization", "Bearer " + adminToken}
+
+	hook, other := models.Webhook{URL: "https://example.com/a"}, models.Webhook{URL: "https://example.com/b"}
+	db.Create(&hook)
+	db.Create(&other)
+	// Event 1 fails twice before it is delivered, then event 2 fails
+	base := time.Now().Add(-time.Hour)
+	serverError, ok := 500, 200
+	message := "receiver answered 500"
+	attempts := []models.WebhookDelivery{
+		{WebhookID: hook.ID, EventID: 1, Attempt: 1, Status: models.DeliveryFailed, ResponseCode: &serverError, Error: &message},
+		{WebhookID: hook.ID, EventID: 1, Attempt: 2, Status: models.DeliveryFailed, ResponseCode: &serverError, Error: &message},
+		{WebhookID: hook.ID, EventID: 1, Attempt: 3, Status: models.DeliveryDelivered, ResponseCode: &ok},
+		{WebhookID: hook.ID, EventID: 2, Attempt: 1, Status: models.DeliveryFailed},
+		{WebhookID: other.ID, EventID: 1, Attempt: 1, Status: models.DeliveryFailed},
+	}
+	for i := range attempts {
+		attempts[i].CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		db.Create(&attempts[i])
+	}
+	path := "/v1/admin/webhooks/" + itoa(hook.ID) + "/deliveries"
+
+	tests := []struct {
+		query string
+		want  []uint // attempt ids, newest first
+	}{
+		{"", []uint{attempts[3].ID, attempts[2].ID, attempts[1].ID, attempts[0].ID}},
+		{"?status=failed", []uint{attempts[3].ID, attempts[1].ID, attempts[0].ID}},
+		{"?status=delivered", []uint{attempts[2].ID}},
+		{"?status=failed&limit=2", []uint{attempts[3].ID, attempts[1].ID}},
+		{"?status=failed&limit=2&offset=2", []uint{attempts[0].ID}},
+	}
+	for _, tt := range tests {
+		w := testutil.Request(router, http.MethodGet, path+tt.query, "", auth...)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.query, w.Code, w.Body)
+		}
+		var got []models.WebhookDelivery
+		decode(t, w, &got)
+		ids := make([]uint, len(got))
+		for i, delivery := range got {
+			ids[i] = delivery.ID
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: deliveries = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+
+	var first []models.WebhookDelivery
+	decode(t, testutil.Request(router, http.MethodGet, path+"?status=failed&limit=1&offset=1", "", auth...), &first)
+	if len(first) != 1 || first[0].Attempt != 2 || *first[0].ResponseCode != 500 || *first[0].Error != message {
+		t.Errorf("failed attempt = %+v, want attempt 2 with its code and error", first)
+	}
+	if w := testutil.Request(router, http.MethodGet, path+"?status=pending", "", auth...); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown status: status = %d, want 400", w.Code)
+	}
+	if w := testutil.Request(router, http.MethodGet, "/v1/admin/webhooks/999/deliveries", "", auth...); w.Code != http.StatusNotFound {
+		t.Errorf("unknown webhook: status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"encoding/xml"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+)
+
+// wantsXML reports whether the client's Accept header weighs XML higher
+// than JSON. JSON stays the default, including on a tie or without Accept.
+func wantsXML(ctx *gin.Context) bool {
+	var jsonQ, xmlQ float64
+	for _, part := range strings.Split(ctx.GetHeader("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				q, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case binding.MIMEXML, binding.MIMEXML2:
+			xmlQ = math.Max(xmlQ, q)
+		case binding.MIMEJSON, "application/*", "*/*":
+			jsonQ = math.Max(jsonQ, q)
+		}
+	}
+	return xmlQ > jsonQ
+}
+
+// render writes body as XML when the client asked for it and as JSON
+// otherwise. Use it instead of ctx.JSON in the book endpoints, for errors
+// too, so a client gets a single format back. A server error while the
+// database circuit breaker is open becomes 503 with Retry-After, since the
+// request failed because the database was skipped.
+func render(ctx *gin.Context, status int, body interface{}) {
+	ctx.Writer.Header().Add("Vary", "Accept")
+	if status == http.StatusInternalServerError && database.DBBreaker.State() != database.BreakerClosed {
+		status = http.StatusServiceUnavailable
+		retryAfter := int(math.Ceil(database.DBBreaker.RetryAfter().Seconds()))
+		ctx.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		body = gin.H{"error": "Database unavailable, try again later"}
+	}
+	if !wantsXML(ctx) {
+		if wantsPrettyJSON(ctx) {
+			ctx.IndentedJSON(status, body)
+			return
+		}
+		ctx.JSON(status, body)
+		return
+	}
+	ctx.Render(status, xmlDocument{body: body})
+}
+
+// xmlDocument renders a response body as an XML document. Books are
+// <book> elements and lists of them <books>; everything else, errors
+// included, is wrapped in <response>.
+type xmlDocument struct {
+	body interface{}
+}
+
+func (d xmlDocument) Render(w http.ResponseWriter) error {
+	d.WriteContentType(w)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	body := d.body
+	if body == nil {
+		// A document needs a root, so a null body (?missing=null) is empty
+		body = gin.H{}
+	}
+	encoder := xml.NewEncoder(w)
+	if err := encodeXML(encoder, xmlRootName(body), body); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func (d xmlDocument) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+}
+
+func xmlRootName(body interface{}) string {
+	switch body.(type) {
+	// Sparse fieldsets of a book are plain maps; errors use gin.H
+	case models.Book, bookWithWarnings, map[string]interface{}:
+		return "book"
+	case []models.Book, []map[string]interface{}, keysetPage:
+		return "books"
+	}
+	return "response"
+}
+
+// encodeXML writes v as an element called name. Structs use their xml tags;
+// the maps and slices built for sparse fields, meta and errors have no
+// tags, so their keys become element names and slice entries are named
+// after their parent: <books><book>, <authors><author>, otherwise <item>.
+func encodeXML(e *xml.Encoder, name string, v interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case gin.H:
+		return encodeXMLMap(e, start, v)
+	case map[string]interface{}:
+		return encodeXMLMap(e, start, v)
+	case []map[string]interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return encodeXMLList(e, start, items)
+	case []interface{}:
+		return encodeXMLList(e, start, v)
+	case []models.Book:
+		items := make([]interface{}, len(v))
+		for i, book := range v {
+			items[i] = book
+		}
+		return encodeXMLList(e, start, items)
+	case keysetPage:
+		return encodeXMLMap(e, start, gin.H{"books": v.Books, "next_cursor": v.NextCursor})
+	case *uint:
+		if v == nil {
+			return nil
+		}
+	case float64:
+		// Numbers decoded from JSON are floats; keep whole ones out of
+		// exponent notation
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return e.EncodeElement(strconv.FormatInt(int64(v), 10), start)
+		}
+	default:
+		// Any other slice, e.g. []string, is also one element with an entry
+		// per item: encoding/xml would repeat the element for each item and
+		// write nothing for an empty slice, leaving the document without a root
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+			items := make([]interface{}, rv.Len())
+			for i := range items {
+				items[i] = rv.Index(i).Interface()
+			}
+			return encodeXMLList(e, start, items)
+		}
+	}
+	return e.EncodeElement(v, start)
+}
+
+func encodeXMLMap(e *xml.Encoder, start xml.StartElement, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := encodeXML(e, key, m[key]); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func encodeXMLList(e *xml.Encoder, start xml.StartElement, items []interface{}) error {
+	itemName := "item"
+	if singular := strings.TrimSuffix(start.Name.Local, "s"); singular != start.Name.Local {
+		itemName = singular
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := encodeXML(e, itemName, item); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,128 @@
+package controllers_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestBookAsJSONAndXML(t *testing.T) {
+	router, db := setup(t)
+	book := testutil.SeedBooks(t, db, models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})[0]
+	path := "/v1/books/" + itoa(book.ID)
+
+	for _, accept := range []string{"", "application/json", "application/xml;q=0.5, application/json"} {
+		w := testutil.Request(router, http.MethodGet, path, "", "Accept", accept)
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("Accept %q: Content-Type = %q, want JSON", accept, w.Header().Get("Content-Type"))
+		}
+		var got models.Book
+		decode(t, w, &got)
+		if got.ID != book.ID || got.Title != "Dune" {
+			t.Errorf("Accept %q: book = %+v", accept, got)
+		}
+	}
+
+	w := testutil.Request(router, http.MethodGet, path, "", "Accept", "application/xml")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/xml") {
+		t.Fatalf("XML: status = %d, Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var got struct {
+		XMLName xml.Name
+		models.Book
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("XML: %v: %s", err, w.Body)
+	}
+	if got.XMLName.Local != "book" || got.ID != book.ID || got.Title != "Dune" || len(got.Authors) != 1 {
+		t.Errorf("XML: <%s> %+v, want <book> with the stored book", got.XMLName.Local, got.Book)
+	}
+}
+
+func TestXMLErrors(t *testing.T) {
+	router, _ := setup(t)
+
+	w := testutil.Request(router, http.MethodGet, "/v1/books/999", "", "Accept", "application/xml")
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/xml") {
+		t.Fatalf("status = %d, Content-Type = %q, want an XML 404", w.Code, w.Header().Get("Content-Type"))
+	}
+	var got struct {
+		XMLName xml.Name
+		Error   string `xml:"error"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Error == "" {
+		t.Errorf("body %s: %+v, %v, want an <error>", w.Body, got, err)
+	}
+}
+
+func TestXMLListsHaveOneRoot(t *testing.T) {
+	router, db := setup(t)
+
+	// Empty lists still have their root element
+	for path, root := range map[string]string{"/v1/books": "books", "/v1/books/authors": "response"} {
+		w := testutil.Request(router, http.MethodGet, path, "", "Accept", "application/xml")
+		if !strings.HasSuffix(strings.TrimSpace(w.Body.String()), "<"+root+"></"+root+">") {
+			t.Errorf("empty %s: body = %s, want an empty <%s>", path, w.Body, root)
+		}
+	}
+
+	// Seeded behind the API's back, so start from an empty cache
+	testutil.SetupCache(t)
+	testutil.SeedBooks(t, db,
+		models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965},
+		models.Book{Title: "Emma", Author: "Jane Austen", Year: 1815},
+	)
+	w := testutil.Request(router, http.MethodGet, "/v1/books", "", "Accept", "application/xml")
+	var books struct {
+		XMLName xml.Name
+		Books   []models.Book `xml:"book"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &books); err != nil || books.XMLName.Local != "books" || len(books.Books) != 2 {
+		t.Errorf("books: <%s> with %d books, %v: %s", books.XMLName.Local, len(books.Books), err, w.Body)
+	}
+
+	// Plain string lists are wrapped like every other slice
+	w = testutil.Request(router, http.MethodGet, "/v1/books/authors", "", "Accept", "application/xml")
+	var authors struct {
+		XMLName xml.Name
+		Items   []string `xml:"item"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &authors); err != nil || authors.XMLName.Local != "response" ||
+		strings.Join(authors.Items, ",") != "Frank Herbert,Jane Austen" {
+		t.Errorf("authors: <%s> %q, %v: %s", authors.XMLName.Local, authors.Items, err, w.Body)
+	}
+}
+
+func TestXMLErrorsFromEveryController(t *testing.T) {
+	router, _ := setup(t)
+	t.Setenv("ADMIN_TOKEN", adminToken)
+	auth := "Bearer " + adminToken
+
+	tests := []struct {
+		name, method, path, body string
+		status                   int
+	}{
+		{"delete a missing book", http.MethodDelete, "/v1/books/999", "", http.StatusNotFound},
+		{"bulk update without a filter", http.MethodPatch, "/v1/books", `{"set":{"year":2000}}`, http.StatusBadRequest},
+		{"export with a bad chunk size", http.MethodGet, "/v1/books/export.json?chunk_size=0", "", http.StatusBadRequest},
+		{"unknown feature", http.MethodPut, "/v1/admin/features/nope", `{"enabled":true}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Request(router, tt.method, tt.path, tt.body, "Accept", "application/xml", "Authorization", auth)
+			if w.Code != tt.status || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/xml") {
+				t.Fatalf("status = %d, Content-Type = %q, want an XML %d: %s", w.Code, w.Header().Get("Content-Type"), tt.status, w.Body)
+			}
+			var got struct {
+				Error string `xml:"error"`
+			}
+			if err := xml.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Error == "" {
+				t.Errorf("body %s: %+v, %v, want an <error>", w.Body, got, err)
+			}
+		})
+	}
+}
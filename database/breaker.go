@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCoolDown  = 10 * time.Second
+)
+
+// ErrCircuitOpen is returned instead of running a statement while the
+// breaker is open
+var ErrCircuitOpen = errors.New("database circuit breaker is open")
+
+// Breaker states, as reported on /healthz
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// Breaker stops sending statements to an overloaded database. After
+// threshold consecutive failures that point at the database itself
+// (connection errors, timeouts, resource exhaustion) it opens and every
+// statement fails with ErrCircuitOpen for the cool-down. Then a single
+// statement is let through as a probe: it closes the breaker if it succeeds
+// and reopens it if it fails. A threshold of zero disables the breaker.
+type Breaker struct {
+	threshold int
+	coolDown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	probing  bool
+}
+
+// DBBreaker guards DB. ConnectDB installs it with BREAKER_THRESHOLD and
+// BREAKER_COOLDOWN.
+var DBBreaker = NewBreaker(0, 0)
+
+func NewBreaker(threshold int, coolDown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, coolDown: coolDown}
+}
+
+// breakerConfig reads BREAKER_THRESHOLD (default 5, 0 disables) and
+// BREAKER_COOLDOWN (default 10s)
+func breakerConfig() (int, time.Duration) {
+	threshold, err := strconv.Atoi(os.Getenv("BREAKER_THRESHOLD"))
+	if err != nil || threshold < 0 {
+		threshold = defaultBreakerThreshold
+	}
+	coolDown, err := time.ParseDuration(os.Getenv("BREAKER_COOLDOWN"))
+	if err != nil || coolDown <= 0 {
+		coolDown = defaultBreakerCoolDown
+	}
+	return threshold, coolDown
+}
+
+// Allow reports whether a statement may run, or ErrCircuitOpen
+func (b *Breaker) Allow() error {
+	if b.threshold == 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return nil
+	}
+	if b.probing || time.Since(b.openedAt) < b.coolDown {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// Record counts the outcome of a statement that Allow let through
+func (b *Breaker) Record(err error) {
+	if b.threshold == 0 || errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !isOverloaded(err) {
+		b.failures = 0
+		b.open, b.probing = false, false
+		return
+	}
+	b.failures++
+	if b.probing || b.failures >= b.threshold {
+		b.open, b.probing = true, false
+		b.openedAt = time.Now()
+	}
+}
+
+// State returns BreakerClosed, BreakerOpen or BreakerHalfOpen, the latter
+// once the cool-down is over and the next statement will be a probe
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case !b.open:
+		return BreakerClosed
+	case b.probing || time.Since(b.openedAt) >= b.coolDown:
+		return BreakerHalfOpen
+	}
+	return BreakerOpen
+}
+
+// RetryAfter returns how long until the breaker lets a probe through, or
+// zero when it is closed
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return 0
+	}
+	if remaining := b.coolDown - time.Since(b.openedAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// isOverloaded reports whether err says the database is unreachable or
+// struggling, as opposed to rejecting this particular statement
+func isOverloaded(err error) bool {
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code[:2] {
+		// connection exception, insufficient resources, operator
+		// intervention (including statement timeouts), system error
+		case "08", "53", "57", "58":
+			return true
+		}
+		return false
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// breakerPlugin runs every statement made through DB past DBBreaker
+type breakerPlugin struct {
+	breaker *Breaker
+}
+
+func (breakerPlugin) Name() string {
+	return "circuit_breaker"
+}
+
+func (p breakerPlugin) Initialize(db *gorm.DB) error {
+	allow := func(tx *gorm.DB) {
+		if err := p.breaker.Allow(); err != nil {
+			tx.AddError(err)
+		}
+	}
+	record := func(tx *gorm.DB) {
+		p.breaker.Record(tx.Error)
+	}
+
+	callbacks := db.Callback()
+	// Writes are checked before their implicit transaction is opened
+	errs := []error{
+		callbacks.Create().Before("gorm:begin_transaction").Register("breaker:allow", allow),
+		callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("breaker:record", record),
+		callbacks.Update().Before("gorm:begin_transaction").Register("breaker:allow", allow),
+		callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("breaker:record", record),
+		callbacks.Delete().Before("gorm:begin_transaction").Register("breaker:allow", allow),
+		callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("breaker:record", record),
+		callbacks.Query().Before("gorm:query").Register("breaker:allow", allow),
+		callbacks.Query().After("gorm:query").Register("breaker:record", record),
+		callbacks.Row().Before("gorm:row").Register("breaker:allow", allow),
+		callbacks.Row().After("gorm:row").Register("breaker:record", record),
+		callbacks.Raw().Before("gorm:raw").Register("breaker:allow", allow),
+		callbacks.Raw().After("gorm:raw").Register("breaker:record", record),
+	}
+	return errors.Join(errs...)
+}
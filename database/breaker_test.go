@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBreakerTripsAndResets(t *testing.T) {
+	breaker := NewBreaker(3, 50*time.Millisecond)
+	overloaded := &pgconn.PgError{Code: "53300"} // too many connections
+
+	for i := 0; i < 2; i++ {
+		breaker.Record(overloaded)
+	}
+	if err := breaker.Allow(); err != nil || breaker.State() != BreakerClosed {
+		t.Fatalf("below the threshold: Allow = %v, state %s, want closed", err, breaker.State())
+	}
+
+	breaker.Record(overloaded)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) || breaker.State() != BreakerOpen {
+		t.Fatalf("at the threshold: Allow = %v, state %s, want open", err, breaker.State())
+	}
+	if breaker.RetryAfter() <= 0 {
+		t.Error("open breaker has no RetryAfter")
+	}
+
+	// After the cool-down a single probe is let through; a failed one reopens it
+	time.Sleep(60 * time.Millisecond)
+	if state := breaker.State(); state != BreakerHalfOpen {
+		t.Fatalf("after the cool-down: state = %s, want half_open", state)
+	}
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("probe: Allow = %v", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("during the probe: Allow = %v, want ErrCircuitOpen", err)
+	}
+	breaker.Record(overloaded)
+	if state := breaker.State(); state != BreakerOpen {
+		t.Fatalf("failed probe: state = %s, want open", state)
+	}
+
+	// A successful probe closes it
+	time.Sleep(60 * time.Millisecond)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("second probe: Allow = %v", err)
+	}
+	breaker.Record(nil)
+	if err := breaker.Allow(); err != nil || breaker.State() != BreakerClosed || breaker.RetryAfter() != 0 {
+		t.Errorf("after a successful probe: Allow = %v, state %s, want closed", err, breaker.State())
+	}
+}
+
+func TestBreakerIgnoresStatementErrors(t *testing.T) {
+	breaker := NewBreaker(1, time.Minute)
+
+	// The database answered: these say nothing about its health
+	for _, err := range []error{gorm.ErrRecordNotFound, &pgconn.PgError{Code: "23505"}, errors.New("syntax error")} {
+		breaker.Record(err)
+		if state := breaker.State(); state != BreakerClosed {
+			t.Errorf("after %v: state = %s, want closed", err, state)
+		}
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	breaker := NewBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		breaker.Record(context.DeadlineExceeded)
+	}
+	if err := breaker.Allow(); err != nil || breaker.State() != BreakerClosed {
+		t.Errorf("Allow = %v, state %s, want a disabled breaker to stay closed", err, breaker.State())
+	}
+}
+
+func TestBreakerPluginShortCircuitsStatements(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:breaker?mode=memory"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	breaker := NewBreaker(2, 50*time.Millisecond)
+	if err := db.Use(breakerPlugin{breaker: breaker}); err != nil {
+		t.Fatal(err)
+	}
+	query := func(ctx context.Context) error {
+		var n int64
+		return db.WithContext(ctx).Raw("SELECT 1").Scan(&n).Error
+	}
+
+	// Statements that time out trip the breaker
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		if err := query(expired); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expired statement: %v, want a deadline error", err)
+		}
+	}
+	if err := query(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("while open: %v, want ErrCircuitOpen without running it", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := query(context.Background()); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if state := breaker.State(); state != BreakerClosed {
+		t.Errorf("after the probe: state = %s, want closed", state)
+	}
+}
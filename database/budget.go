@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// RetryBudget returns how much time the retries of one request may take in
+// total, waits and repeated attempts included, configurable through
+// REQUEST_RETRY_BUDGET (e.g. "2s"). Zero, the default, leaves retries bounded
+// only by the request's deadline.
+func RetryBudget() time.Duration {
+	budget, err := time.ParseDuration(os.Getenv("REQUEST_RETRY_BUDGET"))
+	if err != nil || budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+type retryBudgetKey struct{}
+
+// retryBudget is the retry time a request has left, shared by every retry
+// made with its context
+type retryBudget struct {
+	mu        sync.Mutex
+	remaining time.Duration
+}
+
+// WithRetryBudget returns a copy of ctx whose retries share budget. A budget
+// of zero returns ctx unchanged.
+func WithRetryBudget(ctx context.Context, budget time.Duration) context.Context {
+	if budget <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, retryBudgetKey{}, &retryBudget{remaining: budget})
+}
+
+// reserveRetry reports whether waiting delay before another attempt still
+// fits: the context must not be done, its deadline must be further away than
+// the wait, and its retry budget, if any, must cover the wait, which is then
+// taken from it
+func reserveRetry(ctx context.Context, delay time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return false
+	}
+	budget, ok := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	if !ok {
+		return true
+	}
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	if budget.remaining < delay {
+		return false
+	}
+	budget.remaining -= delay
+	return true
+}
+
+// spendRetry takes the time a repeated attempt ran from ctx's retry budget
+func spendRetry(ctx context.Context, elapsed time.Duration) {
+	if budget, ok := ctx.Value(retryBudgetKey{}).(*retryBudget); ok {
+		budget.mu.Lock()
+		budget.remaining -= elapsed
+		budget.mu.Unlock()
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"os"
 
 	"github.com/joho/godotenv"
+	"github.com/rohans540/books-backend/startup"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 var DB *gorm.DB
@@ -38,10 +41,43 @@ func ConnectDB() {
 		dbHost, dbUser, dbPassword, dbName, dbPort,
 	)
 
-	// Open database connection
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// Open database connection, waiting for Postgres to come up
+	err = startup.WaitFor("Postgres", func() error {
+		var openErr error
+		DB, openErr = gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: newLogger(slowQueryThreshold())})
+		return openErr
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	fmt.Println("✅ Database connected successfully!")
+
+	// Fail fast instead of piling queries onto a database that is down or
+	// overloaded
+	DBBreaker = NewBreaker(breakerConfig())
+	if err := DB.Use(breakerPlugin{breaker: DBBreaker}); err != nil {
+		log.Fatalf("Failed to install database circuit breaker: %v", err)
+	}
+
+	// Route reads to a read-replica when one is configured
+	if replicaDSN := os.Getenv("DB_REPLICA_DSN"); replicaDSN != "" {
+		useReplica(replicaDSN)
+	}
+}
+
+// useReplica sends queries to the replica and writes to the primary. With
+// DB_DEBUG=true every statement is logged with a [source] or [replica] prefix.
+func useReplica(replicaDSN string) {
+	debug := os.Getenv("DB_DEBUG") == "true"
+	err := DB.Use(dbresolver.Register(dbresolver.Config{
+		Replicas:          []gorm.Dialector{postgres.Open(replicaDSN)},
+		TraceResolverMode: debug,
+	}))
+	if err != nil {
+		log.Fatalf("Failed to configure read-replica: %v", err)
+	}
+	if debug {
+		DB.Logger = DB.Logger.LogMode(logger.Info)
+	}
+	fmt.Println("✅ Read-replica configured")
 }
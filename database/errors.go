@@ -0,0 +1,60 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const pgUniqueViolation = "23505"
+
+var (
+	// Postgres reports the offending columns as: Key (isbn)=(123) already
+	// exists. Expression indexes nest parentheses: Key (lower(slug))=(dune).
+	pgUniqueDetail = regexp.MustCompile(`^Key \((.+?)\)=\(`)
+	// SQLite reports them as: UNIQUE constraint failed: books.isbn
+	sqliteUnique = regexp.MustCompile(`UNIQUE constraint failed: ([\w.]+(?:, [\w.]+)*)`)
+)
+
+// UniqueViolation reports whether err is a unique-constraint violation and,
+// if so, which field caused it. Both Postgres and SQLite errors are recognized.
+func UniqueViolation(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code != pgUniqueViolation {
+			return "", false
+		}
+		if match := pgUniqueDetail.FindStringSubmatch(pgErr.Detail); match != nil {
+			return cleanColumn(match[1]), true
+		}
+		return pgErr.ConstraintName, true
+	}
+
+	if match := sqliteUnique.FindStringSubmatch(err.Error()); match != nil {
+		column := strings.Split(match[1], ", ")[0]
+		if i := strings.LastIndex(column, "."); i >= 0 {
+			column = column[i+1:]
+		}
+		return column, true
+	}
+	return "", false
+}
+
+// cleanColumn turns an indexed expression such as lower((isbn)::text) or
+// upper(isbn) into the plain column name
+func cleanColumn(column string) string {
+	for _, fn := range []string{"lower(", "upper("} {
+		column = strings.TrimPrefix(column, fn)
+	}
+	column = strings.Trim(column, "()")
+	if i := strings.Index(column, "::"); i >= 0 {
+		column = column[:i]
+	}
+	return strings.Trim(column, "()")
+}
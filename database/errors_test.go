@@ -0,0 +1,38 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		field string
+		ok    bool
+	}{
+		{"postgres", &pgconn.PgError{Code: pgUniqueViolation, Detail: "Key (isbn)=(9780441013593) already exists."}, "isbn", true},
+		{"postgres lower index", &pgconn.PgError{Code: pgUniqueViolation, Detail: "Key (lower(slug))=(dune) already exists."}, "slug", true},
+		{"postgres upper index", &pgconn.PgError{Code: pgUniqueViolation, Detail: "Key (upper(isbn::text))=(9780441013593) already exists."}, "isbn", true},
+		{"postgres cast", &pgconn.PgError{Code: pgUniqueViolation, Detail: "Key (lower((isbn)::text))=(9780441013593) already exists."}, "isbn", true},
+		{"postgres without detail", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "idx_books_isbn"}, "idx_books_isbn", true},
+		{"postgres wrapped", fmt.Errorf("create: %w", &pgconn.PgError{Code: pgUniqueViolation, Detail: "Key (slug)=(dune) already exists."}), "slug", true},
+		{"postgres other error", &pgconn.PgError{Code: "23502"}, "", false},
+		{"sqlite", errors.New("UNIQUE constraint failed: books.isbn"), "isbn", true},
+		{"sqlite composite", errors.New("UNIQUE constraint failed: books.title, books.author"), "title", true},
+		{"other error", errors.New("connection refused"), "", false},
+		{"nil", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, ok := UniqueViolation(tt.err)
+			if field != tt.field || ok != tt.ok {
+				t.Errorf("UniqueViolation = %q, %t, want %q, %t", field, ok, tt.field, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+const defaultSlowQueryThreshold = 200 * time.Millisecond
+
+// slowQueryThreshold returns how long a query may take before it is logged
+// as slow, configurable through DB_SLOW_QUERY_THRESHOLD (e.g. "100ms"). Zero
+// turns slow query logging off.
+func slowQueryThreshold() time.Duration {
+	raw := os.Getenv("DB_SLOW_QUERY_THRESHOLD")
+	if raw == "" {
+		return defaultSlowQueryThreshold
+	}
+	threshold, err := time.ParseDuration(raw)
+	if err != nil || threshold < 0 {
+		log.Printf("Invalid DB_SLOW_QUERY_THRESHOLD %q, using %s", raw, defaultSlowQueryThreshold)
+		return defaultSlowQueryThreshold
+	}
+	return threshold
+}
+
+// slowQueryLogger reports queries slower than threshold as structured warn
+// entries with their SQL, duration and row count, and leaves everything else
+// (errors, and every statement in Info mode) to GORM's default logger
+type slowQueryLogger struct {
+	logger.Interface
+	threshold time.Duration
+	slow      *slog.Logger
+}
+
+// newLogger builds the GORM logger. Slow queries are written as JSON when
+// LOG_FORMAT=json, like the access log.
+func newLogger(threshold time.Duration) logger.Interface {
+	base := logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		// Slow queries are reported by slowQueryLogger instead
+		SlowThreshold: 0,
+		LogLevel:      logger.Warn,
+		Colorful:      true,
+	})
+	slow := slog.Default()
+	if os.Getenv("LOG_FORMAT") == "json" {
+		slow = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+	return slowQueryLogger{Interface: base, threshold: threshold, slow: slow}
+}
+
+func (l slowQueryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	l.Interface = l.Interface.LogMode(level)
+	return l
+}
+
+func (l slowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	if l.threshold == 0 || elapsed <= l.threshold {
+		l.Interface.Trace(ctx, begin, fc, err)
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []any{
+		slog.String("sql", sql),
+		slog.Duration("duration", elapsed),
+		slog.Int64("rows", rows),
+		slog.Duration("threshold", l.threshold),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.slow.WarnContext(ctx, "slow query", attrs...)
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+func TestSlowQueryThreshold(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", defaultSlowQueryThreshold},
+		{"50ms", 50 * time.Millisecond},
+		{"0", 0},
+		{"-1s", defaultSlowQueryThreshold},
+		{"fast", defaultSlowQueryThreshold},
+	}
+	for _, tt := range tests {
+		t.Setenv("DB_SLOW_QUERY_THRESHOLD", tt.env)
+		if got := slowQueryThreshold(); got != tt.want {
+			t.Errorf("DB_SLOW_QUERY_THRESHOLD=%q: threshold = %s, want %s", tt.env, got, tt.want)
+		}
+	}
+}
+
+// tracedLogger counts the queries left to GORM's logger
+type tracedLogger struct {
+	logger.Interface
+	traced int
+}
+
+func (l *tracedLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.traced++
+}
+
+func TestSlowQueryLogger(t *testing.T) {
+	base := &tracedLogger{Interface: logger.Discard}
+	var out bytes.Buffer
+	l := slowQueryLogger{Interface: base, threshold: 100 * time.Millisecond, slow: slog.New(slog.NewJSONHandler(&out, nil))}
+	query := func() (string, int64) { return "SELECT * FROM books", 3 }
+
+	l.Trace(context.Background(), time.Now().Add(-10*time.Millisecond), query, nil)
+	if out.Len() != 0 || base.traced != 1 {
+		t.Fatalf("fast query: logged %q, %d traced, want nothing logged and 1 traced", out.String(), base.traced)
+	}
+
+	l.Trace(context.Background(), time.Now().Add(-time.Second), query, nil)
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("slow query: log entry %q: %v", out.String(), err)
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "slow query" || entry["sql"] != "SELECT * FROM books" || entry["rows"] != float64(3) {
+		t.Errorf("slow query logged as %v", entry)
+	}
+	if duration, _ := entry["duration"].(float64); time.Duration(duration) < time.Second {
+		t.Errorf("duration = %v, want at least 1s", entry["duration"])
+	}
+	if base.traced != 1 {
+		t.Errorf("slow query was also traced by the default logger")
+	}
+
+	// A zero threshold turns slow query logging off
+	out.Reset()
+	l.threshold = 0
+	l.Trace(context.Background(), time.Now().Add(-time.Second), query, nil)
+	if out.Len() != 0 || base.traced != 2 {
+		t.Errorf("disabled: logged %q, %d traced", out.String(), base.traced)
+	}
+}
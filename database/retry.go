@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	defaultRetryAttempts = 3
+	retryBaseDelay       = 50 * time.Millisecond
+)
+
+// transientCodes lists the PostgreSQL SQLSTATE codes that are safe to retry
+var transientCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"57P01": true, // admin_shutdown
+	"08000": true, // connection_exception
+	"08003": true, // connection_does_not_exist
+	"08006": true, // connection_failure
+}
+
+// RetryAttempts returns the number of attempts made for write operations,
+// configurable through DB_RETRY_ATTEMPTS
+func RetryAttempts() int {
+	attempts, err := strconv.Atoi(os.Getenv("DB_RETRY_ATTEMPTS"))
+	if err != nil || attempts <= 0 {
+		return defaultRetryAttempts
+	}
+	return attempts
+}
+
+// IsTransient reports whether err is worth retrying
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return transientCodes[pgErr.Code]
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// WithRetry runs fn and retries it with exponential backoff while it keeps
+// failing with a transient error. It gives up early, returning the last
+// error, once another wait would pass ctx's deadline or exceed the retry
+// budget the request's retries share, so retries can't outlast the client.
+func WithRetry(ctx context.Context, fn func() error) error {
+	attempts := RetryAttempts()
+	delay := retryBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		started := time.Now()
+		err := fn()
+		if attempt > 1 {
+			spendRetry(ctx, time.Since(started))
+		}
+		if !IsTransient(err) || attempt == attempts {
+			return err
+		}
+		if !reserveRetry(ctx, delay) {
+			log.Printf("Transient database error, no time left to retry (attempt %d/%d): %v", attempt, attempts, err)
+			return err
+		}
+		log.Printf("Transient database error (attempt %d/%d): %v", attempt, attempts, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+	}
+}
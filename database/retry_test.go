@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestWithRetryRecoversFromTransientErrors(t *testing.T) {
+	calls := 0
+	err := WithRetry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithRetry = %v, want success after the transient errors", err)
+	}
+	if calls != 3 {
+		t.Errorf("fn called %d times, want 3", calls)
+	}
+}
+
+func TestWithRetryGivesUpAfterAttempts(t *testing.T) {
+	t.Setenv("DB_RETRY_ATTEMPTS", "2")
+	calls := 0
+	err := WithRetry(context.Background(), func() error {
+		calls++
+		return &pgconn.PgError{Code: "40P01"}
+	})
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		t.Fatalf("WithRetry = %v, want the last transient error", err)
+	}
+	if calls != 2 {
+		t.Errorf("fn called %d times, want 2", calls)
+	}
+}
+
+func TestWithRetryDoesNotRetryPermanentErrors(t *testing.T) {
+	permanent := &pgconn.PgError{Code: pgUniqueViolation}
+	calls := 0
+	err := WithRetry(context.Background(), func() error {
+		calls++
+		return permanent
+	})
+	if err != permanent || calls != 1 {
+		t.Errorf("WithRetry = %v after %d calls, want the error after 1 call", err, calls)
+	}
+}
+
+func TestWithRetryStopsWhenBudgetIsSpent(t *testing.T) {
+	t.Setenv("DB_RETRY_ATTEMPTS", "10")
+	// Waits of 50ms and then 100ms: the first fits, the second doesn't
+	ctx := WithRetryBudget(context.Background(), 120*time.Millisecond)
+	failing := func(calls *int) func() error {
+		return func() error {
+			*calls++
+			return &pgconn.PgError{Code: "40001"}
+		}
+	}
+
+	// Every retry of the request draws from the same budget
+	for i, want := range []int{2, 2, 1} {
+		calls := 0
+		if err := WithRetry(ctx, failing(&calls)); err == nil {
+			t.Fatalf("WithRetry %d succeeded, want the transient error", i+1)
+		}
+		if calls != want {
+			t.Errorf("WithRetry %d called fn %d times, want %d", i+1, calls, want)
+		}
+	}
+
+	// Without a budget all attempts are made
+	t.Setenv("DB_RETRY_ATTEMPTS", "3")
+	calls := 0
+	WithRetry(WithRetryBudget(context.Background(), 0), failing(&calls))
+	if calls != 3 {
+		t.Errorf("without a budget: fn called %d times, want 3", calls)
+	}
+}
+
+func TestWithRetryStopsBeforeDeadline(t *testing.T) {
+	t.Setenv("DB_RETRY_ATTEMPTS", "10")
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	calls := 0
+	err := WithRetry(ctx, func() error {
+		calls++
+		return &pgconn.PgError{Code: "40001"}
+	})
+	if err == nil || calls != 2 {
+		t.Errorf("WithRetry = %v after %d calls, want the transient error after 2", err, calls)
+	}
+	if elapsed := time.Since(started); elapsed >= 80*time.Millisecond {
+		t.Errorf("WithRetry took %s, want it to give up before the deadline", elapsed)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	for env, want := range map[string]time.Duration{"": 0, "2s": 2 * time.Second, "-1s": 0, "soon": 0} {
+		t.Setenv("REQUEST_RETRY_BUDGET", env)
+		if got := RetryBudget(); got != want {
+			t.Errorf("REQUEST_RETRY_BUDGET=%q: RetryBudget() = %s, want %s", env, got, want)
+		}
+	}
+}
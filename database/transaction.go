@@ -0,0 +1,19 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Transact runs fn in a transaction on DB that is committed when fn returns
+// nil and rolled back when it returns an error or panics, so a multi-step
+// write is never left half done. A transaction failing with a transient
+// error is retried from the start, so fn must reset any state it builds
+// outside tx. The transaction is bound to ctx, whose deadline and retry
+// budget also limit the retries.
+func Transact(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return WithRetry(ctx, func() error {
+		return DB.WithContext(ctx).Transaction(fn)
+	})
+}
@@ -0,0 +1,71 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+	"gorm.io/gorm"
+)
+
+func TestTransactCommits(t *testing.T) {
+	db := testutil.SetupDB(t)
+
+	err := database.Transact(context.Background(), func(tx *gorm.DB) error {
+		if err := tx.Create(&models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OutboxEvent{Topic: "book_events", Payload: "{}"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRows(t, db, 1)
+}
+
+func TestTransactRollsBackOnError(t *testing.T) {
+	db := testutil.SetupDB(t)
+	failure := errors.New("second step failed")
+
+	err := database.Transact(context.Background(), func(tx *gorm.DB) error {
+		if err := tx.Create(&models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965}).Error; err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Transact = %v, want the step's error", err)
+	}
+	assertRows(t, db, 0)
+}
+
+func TestTransactRollsBackOnPanic(t *testing.T) {
+	db := testutil.SetupDB(t)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the panic was swallowed")
+			}
+		}()
+		database.Transact(context.Background(), func(tx *gorm.DB) error {
+			tx.Create(&models.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965})
+			panic("second step panicked")
+		})
+	}()
+	assertRows(t, db, 0)
+}
+
+// assertRows checks that both the books and the outbox table hold n rows
+func assertRows(t *testing.T, db *gorm.DB, n int64) {
+	t.Helper()
+	var books, events int64
+	db.Model(&models.Book{}).Count(&books)
+	db.Model(&models.OutboxEvent{}).Count(&events)
+	if books != n || events != n {
+		t.Errorf("%d books and %d events stored, want %d", books, events, n)
+	}
+}
@@ -15,44 +15,58 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/books": {
+        "/admin/api-keys": {
             "get": {
-                "description": "Retrieve paginated details of all books",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "List every API key, revoked ones included, with the number of requests made with it in total and today (UTC).\nKeys themselves are never listed. Requires the admin token.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "books"
-                ],
-                "summary": "Get all books with pagination",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Limit the number of books per page (default: 10)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Offset for pagination (default: 0)",
-                        "name": "offset",
-                        "in": "query"
-                    }
+                    "admin"
                 ],
+                "summary": "List API keys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Book"
+                                "$ref": "#/definitions/controllers.APIKeyWithUsage"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to fetch API keys",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     }
                 }
             },
             "post": {
-                "description": "Add a new book to the collection",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Create a key for a machine client, which sends it in the X-API-Key header. The key is only returned here;\nonly its hash is stored. A rate_limit above 0 caps the key's requests per minute. Requires the admin token.",
                 "consumes": [
                     "application/json"
                 ],
@@ -60,17 +74,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Create a new book",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "description": "Book object",
-                        "name": "book",
+                        "description": "Name and optional rate limit",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Book"
+                            "$ref": "#/definitions/controllers.APIKeyRequest"
                         }
                     }
                 ],
@@ -78,11 +92,29 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Book"
+                            "$ref": "#/definitions/controllers.CreatedAPIKey"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create the API key",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -93,34 +125,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/{id}": {
-            "get": {
-                "description": "Retrieve details of a book by its ID",
-                "produces": [
-                    "application/json"
+        "/admin/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Reject every further request made with the key. The key stays listed with its usage. Revoking a revoked\nkey does nothing. Requires the admin token.",
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Get book by ID",
+                "summary": "Revoke an API key",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Book ID",
+                        "description": "API key ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "API key revoked"
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
                         "schema": {
-                            "$ref": "#/definitions/models.Book"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
-                        "description": "Book not found",
+                        "description": "API key not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to revoke the API key",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -129,46 +178,123 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Modify the details of an existing book",
-                "consumes": [
-                    "application/json"
+            }
+        },
+        "/admin/backup": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Download every row of the books, outbox, webhooks, webhook_deliveries and api_keys tables as one JSON document,\n{\"created_at\", \"schema_version\", \"tables\": {\"\u003ctable\u003e\": [rows]}}, for disaster recovery drills. Rows keep\nevery column as stored, including webhook secrets, so keep backups private. All tables are read in one\nsnapshot and streamed as they are read, never held in memory. A backup that fails part way is left\nunterminated so it can't be mistaken for a complete one. Requires the admin token.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Update an existing book",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Book ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                "summary": "Download a backup",
+                "responses": {
+                    "200": {
+                        "description": "The backup",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     },
+                    "500": {
+                        "description": "Failed to start the backup",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cache": {
+            "delete": {
+                "security": [
                     {
-                        "description": "Updated book object",
-                        "name": "book",
-                        "in": "body",
-                        "required": true,
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Delete every cached book, list and count of this service. Only keys in the service's\nCACHE_PREFIX namespace are touched, so other users of a shared Redis are unaffected. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Flush the book cache",
+                "responses": {
+                    "200": {
+                        "description": "Cache flushed",
                         "schema": {
-                            "$ref": "#/definitions/models.Book"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to flush the cache",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
+                }
+            }
+        },
+        "/admin/cache/keys": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "List the book-related cache keys (at most 1000) with their remaining TTL in seconds, -1 when they don't expire.\nKeys are shown without the CACHE_PREFIX namespace. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
                 ],
+                "summary": "List cached book keys",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "keys, count and whether the listing was truncated",
                         "schema": {
-                            "$ref": "#/definitions/models.Book"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Invalid request body",
+                    "401": {
+                        "description": "Missing or wrong admin token",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -176,8 +302,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Book not found",
+                    "500": {
+                        "description": "Failed to list cache keys",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -186,25 +312,47 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Remove a book from the collection",
+            }
+        },
+        "/admin/cache/refresh": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Reload the given books from the primary database and rewrite their cache entries by id, slug and ISBN,\ne.g. after a bulk change made directly in the database. Books that no longer exist are evicted instead.\nEntries under a slug or ISBN the book no longer has are dropped too. Requires the admin token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Delete a book",
+                "summary": "Refresh cached books",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Book ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Book ids to refresh",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.CacheRefreshRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Book deleted successfully",
+                        "description": "The outcome per id, refreshed or evicted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON or invalid id",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -212,8 +360,26 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Book not found",
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Too many ids",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to load the books",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -223,25 +389,3444 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Book": {
-            "type": "object",
-            "properties": {
-                "author": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "title": {
-                    "type": "string"
-                },
-                "year": {
-                    "type": "integer"
-                }
-            }
+        },
+        "/admin/cache/warm": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Load the given pages from the database and cache them under the keys GET /books and POST /books/search\nread, e.g. ahead of an expected traffic spike. Offset pages also cache their match count. Every query is\nvalidated before any is run. Requires the admin token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Warm the list cache",
+                "parameters": [
+                    {
+                        "description": "Pages to warm",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.CacheWarmRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of pages warmed and their cache keys",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON or invalid query",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Too many queries",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to load a page",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "List the oldest book events (at most 100) that could not be published and were forwarded to the dead-letter topic,\nwith the error of the last attempt. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List dead-lettered events",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/controllers.deadLetter"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list dead-lettered events",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/{id}/replay": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Queue a dead-lettered event again so the outbox relay publishes it to its original topic. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replay a dead-lettered event",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Event queued for publishing",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No dead-lettered event with this id",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to replay the event",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/events": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "List the book events recorded in the outbox, newest first, whether or not they have been published yet.\nevent_type accepts both the short (created) and the full (book.created) name. since is inclusive and until exclusive. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List the event history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event type: created, updated, deleted, checked_out or returned",
+                        "name": "event_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only events of this book",
+                        "name": "book_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events recorded at or after this RFC 3339 time",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events recorded before this RFC 3339 time",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of events per page (default: DEFAULT_PAGE_SIZE)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default: 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/controllers.historyEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list events",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/features": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Report whether each optional endpoint is currently enabled, and its FEATURE_\u003cNAME\u003e default. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List feature flags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/controllers.featureState"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/features/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Turn an optional endpoint on or off for every instance sharing the cache, without a restart.\nDisabled endpoints answer 404. The toggle overrides FEATURE_\u003cNAME\u003e until it is reset. Requires the admin token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Toggle a feature flag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Feature name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Whether to enable the feature",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.FeatureRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.featureState"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown feature",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to store the toggle",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Drop the runtime toggle of a feature so its FEATURE_\u003cNAME\u003e default applies again. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset a feature flag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Feature name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.featureState"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown feature",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to reset the toggle",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/overview": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Return, in one call, the number of books, this instance's cache hit ratio and database pool state, the\nlatest events and the health of the database, cache and Kafka. The overview is reused for five seconds,\nso polling it is cheap. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get an operational overview",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.AdminOverview"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to build the overview",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/read-only": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Report whether book writes are currently rejected. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get read-only mode",
+                "responses": {
+                    "200": {
+                        "description": "Whether read-only mode is on",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Turn read-only mode on or off for every instance sharing the cache, without a restart.\nWhile it is on, book writes fail with 503. READ_ONLY=true in the environment can't be turned off here. Requires the admin token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Toggle read-only mode",
+                "parameters": [
+                    {
+                        "description": "Whether to enable read-only mode",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ReadOnlyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Whether read-only mode is on",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Read-only mode is forced by READ_ONLY",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to store the toggle",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reindex": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Rebuild the full-text search index in the background with REINDEX CONCURRENTLY, e.g. after a bulk import\nbloated it. Searches keep working on the old index until the new one is ready. Poll GET /admin/reindex for\nprogress. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Rebuild the search index",
+                "responses": {
+                    "202": {
+                        "description": "Rebuild started",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ReindexStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "A rebuild is already running",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to check for a running rebuild",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "501": {
+                        "description": "The database has no full-text index",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Report whether a search index rebuild is running and how far it got, and how the last rebuild started\nthrough this instance ended. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get search index rebuild progress",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ReindexStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to read the rebuild progress",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "List every registered webhook with its delivery state. Secrets are never listed. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Webhook"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to fetch webhooks",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Register a URL that receives every book event from now on as a POST of the event JSON. Each delivery\ncarries X-Webhook-Event, X-Webhook-Delivery (the event id) and X-Webhook-Signature, \"sha256=\" followed by\nthe hex HMAC-SHA256 of the body keyed with the secret. The secret is only returned here. Requires the admin token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Register a webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook URL and optional secret",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.CreatedWebhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create the webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Get a webhook with its delivery state: the last event it accepted, failures in a row, the last error and\nwhen it is retried or was disabled. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to fetch the webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Change a webhook's URL and, if given, its secret. This also clears its failures and enables it again;\ndelivery resumes with the first event it hasn't accepted. Requires the admin token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook URL and optional new secret",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update the webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Stop delivering events to a webhook and remove it. Requires the admin token.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Webhook deleted"
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete the webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "List the attempts to deliver events to a webhook, newest first, with the receiver's status code and\nthe error of failed ones, to debug a failing receiver. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List a webhook's delivery attempts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "delivered",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Only attempts with this outcome",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of attempts per page (default: DEFAULT_PAGE_SIZE)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default: 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list deliveries",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books": {
+            "get": {
+                "description": "Retrieve paginated details of all books.\nOffset pagination (limit/offset) is the default and returns a plain array,\nordered by DEFAULT_SORT (id unless configured) with id as a tie-breaker.\nPassing after_id switches to keyset pagination ordered by id, which takes\nprecedence over offset and returns {\"books\": [...], \"next_cursor\": id}.\nIn envelope format, offset pages carry limit, offset and the total match count in meta.\nThe first offset page also returns a snapshot token (meta and X-Snapshot); passing it as snapshot on the\nfollowing pages leaves out books added since, so inserts don't shift the pages.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get all books with pagination",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit the number of books per page (default: DEFAULT_PAGE_SIZE, 10; lowered to MAX_PAGE_SIZE, 100, when larger)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default: 0; at most MAX_OFFSET, 10000, use after_id beyond)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated list of book ids to fetch, returned in the same order (other pagination and filters are ignored)",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Keyset cursor: return books with an id greater than this (use next_cursor from the previous page)",
+                        "name": "after_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Snapshot token from the first page: leave out books added after it",
+                        "name": "snapshot",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated list of fields to return (e.g. id,title)",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Full-text search over title and author",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: relevance (only valid together with q), recent (newest first), or id, title, author, year or created_at, prefixed with - for descending (default: SEARCH_DEFAULT_SORT with q, DEFAULT_SORT otherwise)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return books by this author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return books from this publisher",
+                        "name": "publisher",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only return books published in this year",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return books in this ISO 639-1 language (e.g. en)",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only return books that are (true) or aren't (false) available to check out",
+                        "name": "available",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Collection ETag from an earlier response: answer 304 if no book was written since",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Book"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the whole collection, bumped by every write, for If-None-Match"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "URLs of the first, prev, next and last pages (only next for after_id; none past MAX_OFFSET)"
+                            },
+                            "X-Limit-Clamped": {
+                                "type": "string",
+                                "description": "Maximum page size, set when the requested limit was lowered to it"
+                            },
+                            "X-Page-Limit": {
+                                "type": "integer",
+                                "description": "Page size used (offset pagination only)"
+                            },
+                            "X-Page-Offset": {
+                                "type": "integer",
+                                "description": "Offset used (offset pagination only)"
+                            },
+                            "X-Snapshot": {
+                                "type": "integer",
+                                "description": "Snapshot token to pass on the following pages (offset pagination only)"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Number of books matching the filters (offset pagination only)"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "No book was written since the ETag in If-None-Match was handed out"
+                    },
+                    "400": {
+                        "description": "Unknown field, invalid filter, invalid cursor, invalid snapshot or offset past MAX_OFFSET",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Page larger than MAX_RESPONSE_BYTES; request a smaller limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add a new book to the collection. The slug is generated from the title.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Create a new book",
+                "parameters": [
+                    {
+                        "description": "Book object",
+                        "name": "book",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Reject the book when it has validation warnings instead of returning them",
+                        "name": "strict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Reject the book if one with the same title and author exists (default: DEDUPE_ON_CREATE)",
+                        "name": "dedupe",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Fill in a missing title, author or year by looking up the book's ISBN",
+                        "name": "enrich",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.bookWithWarnings"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created book"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Duplicate book (with the existing book's id) or unique field already in use",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type is not application/json",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Title or author too long, text with control characters, or an ISBN check digit that doesn't match (with ISBN_CHECKSUM=reject or strict)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Set a single field (author, year or language) on every book matching the filter, in one UPDATE statement.\nThe filter is required so the whole table can't be updated by accident. Requires the admin token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Set one field on many books",
+                "parameters": [
+                    {
+                        "description": "Filter and field to set",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.BulkUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of updated books",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or wrong admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "More books than MAX_BATCH_SIZE",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Author too long or contains control characters",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/authors": {
+            "get": {
+                "description": "Retrieve the distinct author names (co-authors included), sorted alphabetically, optionally with the number of books per author.\nThe list is paginated like GET /books; in envelope format meta carries limit, offset and the number of matching authors.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get distinct authors",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only return authors whose name starts with this prefix (case-insensitive)",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return {author, count} objects instead of plain names",
+                        "name": "counts",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of authors per page (default: DEFAULT_PAGE_SIZE, 10; lowered to MAX_PAGE_SIZE, 100, when larger)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default: 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/controllers.AuthorCount"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "URLs of the first, prev, next and last pages"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Number of distinct authors matching q"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/count": {
+            "get": {
+                "description": "Return the number of books, optionally filtered by author and year",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Count books",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only count books by this author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only count books from this publisher",
+                        "name": "publisher",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only count books published in this year",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only count books in this ISO 639-1 language (e.g. en)",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only count books matching this full-text search",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only count books that are (true) or aren't (false) available to check out",
+                        "name": "available",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/diff": {
+            "get": {
+                "description": "Compare books a and b field by field, e.g. to spot near-duplicates. Only the fields that differ are\nreturned, each with its value in both books; id and the timestamps are left out. Identical books\ngive an empty object.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Compare two books",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the first book",
+                        "name": "a",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the second book",
+                        "name": "b",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\\\"title\\\": {\\\"a\\\": \\\"...\\\", \\\"b\\\": \\\"...\\\"}}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or invalid book id",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Book not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error fetching books",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/events": {
+            "get": {
+                "description": "Keep the connection open and push a server-sent event for every book change on any instance, read from\nthe book_events Kafka topic. The event name is the event type (book.created, book.updated, book.deleted,\nbook.checked_out or book.returned) and the data the event payload. A comment line is sent every\nSSE_HEARTBEAT_INTERVAL while idle. Clients that fall too far behind are disconnected and should reconnect.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Stream live book changes",
+                "responses": {
+                    "200": {
+                        "description": "One event per change",
+                        "schema": {
+                            "$ref": "#/definitions/kafka.BookEvent"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/export.json": {
+            "get": {
+                "description": "Download every matching book as a single JSON array in DEFAULT_SORT order, for backups. Like\n/books/stream the rows are streamed as they are read and never held in memory.\nWith chunk_size the export is split into files of at most that many books, in id order: without chunk\nthe response is a manifest with the id range and URL of every chunk, and with chunk (starting at 1),\nfrom_id and to_id it is the JSON array of the books in that range. Ranges don't shift when books are\nadded or deleted after the manifest was made, and books added since aren't in any chunk.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Export books as JSON",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Full-text search over title and author",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only export books by this author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only export books from this publisher",
+                        "name": "publisher",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only export books published in this year",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only export books in this ISO 639-1 language (e.g. en)",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only export books that are (true) or aren't (false) available to check out",
+                        "name": "available",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Split the export into chunks of this many books",
+                        "name": "chunk_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Download this chunk (starting at 1); requires chunk_size, from_id and to_id",
+                        "name": "chunk",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "First id of the chunk, from the manifest",
+                        "name": "from_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Last id of the chunk, from the manifest",
+                        "name": "to_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The books, or an ExportManifest when chunk_size is set without chunk",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Book"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter or chunk, or more chunks than MAX_EXPORT_CHUNKS",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/isbn/{isbn}": {
+            "put": {
+                "description": "Store the book under the ISBN in the path: the book with that ISBN is updated if there is one,\notherwise a new book is created. Lets catalog integrations sync without tracking our ids.\nThe ISBN may contain hyphens and spaces; an ISBN in the body must be the same one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Create or update a book by ISBN",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ISBN-10 or ISBN-13",
+                        "name": "isbn",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Book object",
+                        "name": "book",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Reject the book when it has validation warnings instead of returning them",
+                        "name": "strict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Existing book updated",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.bookWithWarnings"
+                        },
+                        "headers": {
+                            "Content-Location": {
+                                "type": "string",
+                                "description": "URL of the updated book"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Book created",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.bookWithWarnings"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created book"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ISBN or request body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Unique field already in use",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type is not application/json",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Title or author too long, text with control characters, or an ISBN check digit that doesn't match (with ISBN_CHECKSUM=reject or strict)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/lookup": {
+            "post": {
+                "description": "Return the books matching a list of ISBNs, in request order, and the ISBNs that match no book, so a\npartner can reconcile their catalog against ours. ISBNs may contain hyphens and spaces. Cached books are\nserved from the cache and the rest are loaded in a single query. At most MAX_BATCH_SIZE ISBNs per request.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Look up books by ISBN",
+                "parameters": [
+                    {
+                        "description": "ISBNs to look up",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.LookupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.lookupResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "More ISBNs than MAX_BATCH_SIZE",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error fetching books",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/publishers": {
+            "get": {
+                "description": "Retrieve the distinct publisher names, sorted alphabetically, optionally with the number of books per publisher. Books without a publisher are left out.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get distinct publishers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only return publishers whose name starts with this prefix (case-insensitive)",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return {publisher, count} objects instead of plain names",
+                        "name": "counts",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/controllers.PublisherCount"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/random": {
+            "get": {
+                "description": "Return one book picked at random, for a \"surprise me\" feature. A random id between the lowest and\nhighest is drawn and the first book from there on returned, which costs two index lookups however\nlarge the table is, unlike ORDER BY RANDOM() which scans every row. Books that follow a gap in the\nids (left by deletes) are proportionally more likely to be picked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get a random book",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        }
+                    },
+                    "404": {
+                        "description": "There are no books",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error fetching book",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/recent": {
+            "get": {
+                "description": "Retrieve the most recently created books, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get recently added books",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of books to return (default: 5, max: 50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Book"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/search": {
+            "post": {
+                "description": "Same as GET /books, for searches too long or structured for a query string: the filters, sort,\nfields and pagination are sent as a JSON body. Results, caching and response formats match the\nequivalent GET request, except that no Link header is set since the next page needs the same body.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Search books",
+                "parameters": [
+                    {
+                        "description": "Filters, sort, fields and pagination",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.BookQuery"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Book"
+                            }
+                        },
+                        "headers": {
+                            "X-Limit-Clamped": {
+                                "type": "string",
+                                "description": "Maximum page size, set when the requested limit was lowered to it"
+                            },
+                            "X-Page-Limit": {
+                                "type": "integer",
+                                "description": "Page size used (offset pagination only)"
+                            },
+                            "X-Page-Offset": {
+                                "type": "integer",
+                                "description": "Offset used (offset pagination only)"
+                            },
+                            "X-Snapshot": {
+                                "type": "integer",
+                                "description": "Snapshot token to send on the following pages (offset pagination only)"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Number of books matching the filters (offset pagination only)"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON, unknown field, invalid filter, invalid sort or offset past MAX_OFFSET",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Page larger than MAX_RESPONSE_BYTES; request a smaller limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/slug/{slug}": {
+            "get": {
+                "description": "Retrieve details of a book by its URL slug. Slugs are case-insensitive.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get book by slug",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Book slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "null",
+                            "404"
+                        ],
+                        "type": "string",
+                        "description": "Answer a missing book with 200 and null instead of 404 (default: MISSING_BOOK_RESPONSE)",
+                        "name": "missing",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the book, for If-Match on updates and deletes"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Book not found, unless missing=null",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error fetching book",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/stats": {
+            "get": {
+                "description": "Return the number of books, the earliest and latest publication year, the number of books per decade\nand the authors (co-authors included) with the most books. Results are cached for CACHE_TTL_STATS (five minutes by default)\nand dropped after writes, once STATS_INVALIDATION_DEBOUNCE passes without another write.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get catalog statistics",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of authors to return (default: 10, max: 100)",
+                        "name": "top",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.BookStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/stream": {
+            "get": {
+                "description": "Stream every matching book as newline-delimited JSON, one book per line, in DEFAULT_SORT order.\nRows are read from the database one at a time and flushed every 100 books, so the whole\ncollection is never held in memory and consumers can start processing right away.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Stream books as NDJSON",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Full-text search over title and author",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only stream books by this author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only stream books from this publisher",
+                        "name": "publisher",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only stream books published in this year",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only stream books in this ISO 639-1 language (e.g. en)",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only stream books that are (true) or aren't (false) available to check out",
+                        "name": "available",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One book per line",
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/validate": {
+            "post": {
+                "description": "Run the checks of POST /books on a book without storing it or publishing an event, e.g. for\nlive form validation. The response reports the warnings create would return.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Validate a book without creating it",
+                "parameters": [
+                    {
+                        "description": "Book object",
+                        "name": "book",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Treat validation warnings as errors",
+                        "name": "strict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\\\"valid\\\": true, \\\"warnings\\\": [...]}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "{\\\"valid\\\": false, \\\"error\\\": \\\"...\\\"}",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}": {
+            "get": {
+                "description": "Retrieve details of a book by its ID. Last-Modified is set from updated_at, and a request\nwith an If-None-Match listing its ETag, or else an If-Modified-Since no older than it, gets 304 without a body.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get book by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated list of fields to return (e.g. id,title)",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the book if it changed after this HTTP date",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return the book if its ETag isn't listed; If-Modified-Since is then ignored",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "enum": [
+                            "null",
+                            "404"
+                        ],
+                        "type": "string",
+                        "description": "Answer a missing book with 200 and null instead of 404 (default: MISSING_BOOK_RESPONSE)",
+                        "name": "missing",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the book, for If-Match on updates and deletes (not set with fields)"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "When the book was last updated"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Book not modified (ETag listed in If-None-Match, or not changed since If-Modified-Since)"
+                    },
+                    "400": {
+                        "description": "Unknown field",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Book not found, unless missing=null",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error fetching book",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Modify the details of an existing book. The slug is preserved when the title changes so existing URLs keep working.\nAn update that changes nothing, such as a retry, isn't written and publishes no event; it is answered with the\nunchanged book and X-No-Op: true.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Update an existing book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated book object",
+                        "name": "book",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Reject the book when it has validation warnings instead of returning them",
+                        "name": "strict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only return the fields that changed, plus id, updated_at and any warnings",
+                        "name": "changes",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only update the book if its current ETag is one of these",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.bookWithWarnings"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated book"
+                            },
+                            "X-No-Op": {
+                                "type": "string",
+                                "description": "true when the update changed nothing and was skipped"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Book not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Unique field already in use",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Book has changed since it was fetched",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type is not application/json",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Title or author too long, text with control characters, or an ISBN check digit that doesn't match (with ISBN_CHECKSUM=reject or strict)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error fetching or updating the book",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a book from the collection",
+                "tags": [
+                    "books"
+                ],
+                "summary": "Delete a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only delete the book if its current ETag is one of these",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Book deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Book not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Book has changed since it was fetched",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error fetching or deleting the book",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}/checkout": {
+            "post": {
+                "description": "Mark an available book as checked out.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Check out a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        }
+                    },
+                    "404": {
+                        "description": "Book not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Book is already checked out",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}/return": {
+            "post": {
+                "description": "Mark a checked out book as available again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Return a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        }
+                    },
+                    "404": {
+                        "description": "Book not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Book is not checked out",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controllers.APIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "rate_limit": {
+                    "description": "RateLimit caps the key's requests per minute; 0, the default, means no\nlimit",
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.APIKeyWithUsage": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Prefix is the start of the key, to tell keys apart without storing them",
+                    "type": "string"
+                },
+                "rate_limit": {
+                    "description": "RateLimit is how many requests per minute the key may make; 0 means\nno limit",
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "requests_today": {
+                    "type": "integer"
+                },
+                "revoked_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.AdminOverview": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "$ref": "#/definitions/controllers.overviewBooks"
+                },
+                "cache": {
+                    "$ref": "#/definitions/controllers.overviewCache"
+                },
+                "database_pool": {
+                    "$ref": "#/definitions/controllers.overviewPool"
+                },
+                "generated_at": {
+                    "type": "string"
+                },
+                "health": {
+                    "type": "object"
+                },
+                "recent_events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.historyEvent"
+                    }
+                }
+            }
+        },
+        "controllers.AuthorCount": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.BookQuery": {
+            "type": "object",
+            "properties": {
+                "after_id": {
+                    "description": "AfterID switches to keyset pagination and takes precedence over Offset",
+                    "type": "integer"
+                },
+                "author": {
+                    "type": "string"
+                },
+                "available": {
+                    "type": "boolean"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "language": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "publisher": {
+                    "type": "string"
+                },
+                "q": {
+                    "type": "string"
+                },
+                "snapshot": {
+                    "description": "Snapshot is the token from the first page's meta. Passing it on later\npages leaves out books added since, so pages don't shift under inserts.",
+                    "type": "integer"
+                },
+                "sort": {
+                    "description": "Sort is relevance (only valid together with q), recent, or a sortable\nfield such as title or -year (descending). Left empty, searches use\nSEARCH_DEFAULT_SORT and other lists DEFAULT_SORT.",
+                    "type": "string"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.BookStats": {
+            "type": "object",
+            "properties": {
+                "by_decade": {
+                    "description": "ByDecade is ordered by decade, e.g. 1990 for 1990-1999 and -10 for\n10-1 BCE",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.DecadeCount"
+                    }
+                },
+                "max_year": {
+                    "type": "integer"
+                },
+                "min_year": {
+                    "type": "integer"
+                },
+                "top_authors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.AuthorCount"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.BulkUpdateFilter": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "description": "Author matches the primary author",
+                    "type": "string"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "language": {
+                    "type": "string"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.BulkUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "author"
+                },
+                "filter": {
+                    "$ref": "#/definitions/controllers.BulkUpdateFilter"
+                },
+                "value": {}
+            }
+        },
+        "controllers.CacheRefreshRequest": {
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "controllers.CacheWarmRequest": {
+            "type": "object",
+            "required": [
+                "queries"
+            ],
+            "properties": {
+                "queries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.BookQuery"
+                    }
+                }
+            }
+        },
+        "controllers.CreatedAPIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Prefix is the start of the key, to tell keys apart without storing them",
+                    "type": "string"
+                },
+                "rate_limit": {
+                    "description": "RateLimit is how many requests per minute the key may make; 0 means\nno limit",
+                    "type": "integer"
+                },
+                "revoked_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.CreatedWebhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "disabled_at": {
+                    "type": "string"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_event_id": {
+                    "type": "integer"
+                },
+                "retry_at": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.DecadeCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "decade": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.FeatureRequest": {
+            "type": "object",
+            "required": [
+                "enabled"
+            ],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "controllers.LookupRequest": {
+            "type": "object",
+            "required": [
+                "isbns"
+            ],
+            "properties": {
+                "isbns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "controllers.PublisherCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "publisher": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.ReadOnlyRequest": {
+            "type": "object",
+            "required": [
+                "enabled"
+            ],
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "controllers.ReindexStatus": {
+            "type": "object",
+            "properties": {
+                "blocks_done": {
+                    "type": "integer"
+                },
+                "blocks_total": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "tuples_done": {
+                    "type": "integer"
+                },
+                "tuples_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.WebhookRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.bookWithWarnings": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "authors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "available": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "isbn": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "language": {
+                    "type": "string"
+                },
+                "publisher": {
+                    "type": "string"
+                },
+                "slug": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "year": {
+                    "type": "integer",
+                    "x-numeric-string": true
+                }
+            }
+        },
+        "controllers.deadLetter": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "dead_lettered_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "object"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "topic": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.featureState": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "description": "Default is the state from FEATURE_\u003cNAME\u003e, used when no runtime toggle is set",
+                    "type": "boolean"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.historyEvent": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "object"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "sent_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.lookupResult": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Book"
+                    }
+                },
+                "not_found": {
+                    "description": "NotFound echoes the requested ISBNs, as sent, that match no book\nor aren't valid ISBNs",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "controllers.overviewBooks": {
+            "type": "object",
+            "properties": {
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.overviewCache": {
+            "type": "object",
+            "properties": {
+                "hit_ratio": {
+                    "type": "number"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.overviewPool": {
+            "type": "object",
+            "properties": {
+                "idle": {
+                    "type": "integer"
+                },
+                "in_use": {
+                    "type": "integer"
+                },
+                "max_open": {
+                    "type": "integer"
+                },
+                "open": {
+                    "type": "integer"
+                },
+                "wait_count": {
+                    "type": "integer"
+                },
+                "wait_duration_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "kafka.BookEvent": {
+            "type": "object",
+            "properties": {
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "description": "RequestID is the X-Request-ID of the HTTP request that caused the\nchange, so consumers can correlate the event with the API logs",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Prefix is the start of the key, to tell keys apart without storing them",
+                    "type": "string"
+                },
+                "rate_limit": {
+                    "description": "RateLimit is how many requests per minute the key may make; 0 means\nno limit",
+                    "type": "integer"
+                },
+                "revoked_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Book": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "authors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "available": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "isbn": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "language": {
+                    "type": "string"
+                },
+                "publisher": {
+                    "type": "string"
+                },
+                "slug": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "year": {
+                    "type": "integer",
+                    "x-numeric-string": true
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "disabled_at": {
+                    "type": "string"
+                },
+                "failures": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_event_id": {
+                    "type": "integer"
+                },
+                "retry_at": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "response_code": {
+                    "description": "ResponseCode is null when the receiver didn't answer",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Admin endpoints require \"Bearer \u003cADMIN_TOKEN\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -250,7 +3835,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "13.53.47.251:8000",
-	BasePath:         "/",
+	BasePath:         "/v1",
 	Schemes:          []string{},
 	Title:            "Books API",
 	Description:      "This is a simple API for managing books.",
@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// consumerPollTimeout bounds how long the consumer blocks waiting for a
+// message, and so how long it takes to notice cancellation
+const consumerPollTimeout = time.Second
+
+// Broadcaster fans book events out to in-process subscribers, such as the
+// connected server-sent event clients
+type Broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan BookEvent]struct{}
+	closed      bool
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subscribers: make(map[chan BookEvent]struct{})}
+}
+
+// BookEvents receives every event the consumer reads from the book events
+// topic
+var BookEvents = NewBroadcaster()
+
+// Subscribe returns a channel receiving every event broadcast from now on
+// and a function to stop receiving them. The channel is closed when the
+// subscriber falls more than buffer events behind or the broadcaster is
+// closed, so a stalled client can't hold events back for everyone else.
+func (b *Broadcaster) Subscribe(buffer int) (<-chan BookEvent, func()) {
+	events := make(chan BookEvent, buffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(events)
+		return events, func() {}
+	}
+	b.subscribers[events] = struct{}{}
+	return events, func() { b.remove(events) }
+}
+
+// Broadcast sends evt to every subscriber without blocking
+func (b *Broadcaster) Broadcast(evt BookEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for events := range b.subscribers {
+		select {
+		case events <- evt:
+		default:
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// Close ends every subscription and rejects new ones, so long-lived
+// subscribers let the server shut down
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for events := range b.subscribers {
+		delete(b.subscribers, events)
+		close(events)
+	}
+}
+
+func (b *Broadcaster) remove(events chan BookEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[events]; ok {
+		delete(b.subscribers, events)
+		close(events)
+	}
+}
+
+// StartConsumer reads topic and broadcasts every event to BookEvents until
+// ctx is cancelled. Every instance uses its own consumer group and starts at
+// the latest offset, so each one sees every new event but none of the
+// backlog.
+func StartConsumer(ctx context.Context, topic string) {
+	if !Enabled() {
+		return
+	}
+	if usesRedisStreams() {
+		startStreamConsumer(ctx, topic)
+		return
+	}
+	host, _ := os.Hostname()
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  os.Getenv("KAFKA_BROKER"),
+		"group.id":           fmt.Sprintf("books-backend-live-%s-%d", host, os.Getpid()),
+		"auto.offset.reset":  "latest",
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		fmt.Println("Failed to create Kafka consumer:", err)
+		return
+	}
+	if err := consumer.Subscribe(topic, nil); err != nil {
+		fmt.Println("Failed to subscribe to Kafka topic:", err)
+		consumer.Close()
+		return
+	}
+
+	go func() {
+		defer consumer.Close()
+		for ctx.Err() == nil {
+			message, err := consumer.ReadMessage(consumerPollTimeout)
+			if err != nil {
+				if kafkaErr, ok := err.(kafka.Error); !ok || kafkaErr.Code() != kafka.ErrTimedOut {
+					fmt.Println("Kafka consumer error:", err)
+				}
+				continue
+			}
+			var evt BookEvent
+			if err := json.Unmarshal(message.Value, &evt); err != nil {
+				fmt.Println("Skipping malformed book event:", err)
+				continue
+			}
+			BookEvents.Broadcast(evt)
+		}
+	}()
+}
@@ -0,0 +1,61 @@
+package kafka
+
+import "testing"
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster()
+	first, _ := b.Subscribe(4)
+	second, unsubscribe := b.Subscribe(4)
+
+	b.Broadcast(BookEvent{Event: "book.created", ID: 1})
+	for _, events := range []<-chan BookEvent{first, second} {
+		if evt := <-events; evt.ID != 1 {
+			t.Errorf("received %+v, want event 1", evt)
+		}
+	}
+
+	// An unsubscribed channel is closed and receives nothing more
+	unsubscribe()
+	b.Broadcast(BookEvent{Event: "book.updated", ID: 1})
+	if _, ok := <-second; ok {
+		t.Error("unsubscribed channel still receives events")
+	}
+	if evt := <-first; evt.Event != "book.updated" {
+		t.Errorf("received %+v, want the update", evt)
+	}
+}
+
+func TestBroadcasterDropsSlowSubscribers(t *testing.T) {
+	b := NewBroadcaster()
+	slow, _ := b.Subscribe(2)
+	fast, _ := b.Subscribe(10)
+
+	for id := uint(1); id <= 3; id++ {
+		b.Broadcast(BookEvent{Event: "book.created", ID: id})
+	}
+	// The slow subscriber's two buffered events are still delivered before
+	// its channel closes
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("slow subscriber received %d events, want the 2 it had room for", received)
+	}
+	if len(fast) != 3 {
+		t.Errorf("fast subscriber has %d events, want 3", len(fast))
+	}
+}
+
+func TestBroadcasterClose(t *testing.T) {
+	b := NewBroadcaster()
+	events, _ := b.Subscribe(1)
+	b.Close()
+	if _, ok := <-events; ok {
+		t.Error("subscription still open after Close")
+	}
+	late, _ := b.Subscribe(1)
+	if _, ok := <-late; ok {
+		t.Error("subscription after Close is open")
+	}
+}
@@ -0,0 +1,38 @@
+package kafka
+
+import "sync"
+
+// NoopPublisher discards every event
+type NoopPublisher struct{}
+
+func (NoopPublisher) Publish(topic string, evt BookEvent) error {
+	return nil
+}
+
+// PublishedEvent is an event recorded by MemoryPublisher
+type PublishedEvent struct {
+	Topic string
+	Event BookEvent
+}
+
+// MemoryPublisher keeps published events in memory so they can be inspected
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []PublishedEvent
+}
+
+func (p *MemoryPublisher) Publish(topic string, evt BookEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, PublishedEvent{Topic: topic, Event: evt})
+	return nil
+}
+
+// Events returns a copy of everything published so far
+func (p *MemoryPublisher) Events() []PublishedEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := make([]PublishedEvent, len(p.events))
+	copy(events, p.events)
+	return events
+}
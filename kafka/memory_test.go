@@ -0,0 +1,32 @@
+package kafka
+
+import "testing"
+
+func TestMemoryPublisherRecordsEvents(t *testing.T) {
+	var publisher Publisher = &MemoryPublisher{}
+	publisher.Publish("book_events", BookEvent{Event: "book.created", ID: 1, Title: "Dune"})
+	publisher.Publish("book_events", BookEvent{Event: "book.deleted", ID: 1})
+
+	events := publisher.(*MemoryPublisher).Events()
+	if len(events) != 2 {
+		t.Fatalf("%d events recorded, want 2", len(events))
+	}
+	if events[0].Topic != "book_events" || events[0].Event.Event != "book.created" || events[1].Event.Event != "book.deleted" {
+		t.Errorf("events = %+v", events)
+	}
+
+	// Events returns a copy the caller may modify
+	events[0].Event.Title = "changed"
+	if publisher.(*MemoryPublisher).Events()[0].Event.Title != "Dune" {
+		t.Error("modifying the returned events changed the recorded ones")
+	}
+}
+
+func TestNoopPublisherIsTheDefault(t *testing.T) {
+	if _, ok := EventPublisher.(NoopPublisher); !ok {
+		t.Fatalf("EventPublisher = %T, want NoopPublisher until a broker is configured", EventPublisher)
+	}
+	if err := EventPublisher.Publish("book_events", BookEvent{Event: "book.created"}); err != nil {
+		t.Errorf("Publish = %v, want nil", err)
+	}
+}
@@ -1,26 +1,246 @@
 package kafka
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/rohans540/books-backend/startup"
 )
 
-var Producer *kafka.Producer
+const (
+	healthCheckInterval   = 10 * time.Second
+	defaultReconnectAfter = 30 * time.Second
+	metadataTimeoutMs     = 5000
+)
+
+// BookEvent is the payload published for every change to a book
+type BookEvent struct {
+	Event string `json:"event"`
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	// RequestID is the X-Request-ID of the HTTP request that caused the
+	// change, so consumers can correlate the event with the API logs
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// requestIDHeader is the message header the request id is also sent in
+const requestIDHeader = "X-Request-ID"
+
+// Publisher sends book events to a message broker
+type Publisher interface {
+	Publish(topic string, evt BookEvent) error
+}
+
+// HealthReporter is implemented by publishers that can tell whether their
+// broker connection currently works
+type HealthReporter interface {
+	Health() error
+}
+
+// EventPublisher is the publisher used by the controllers. It stays a no-op
+// until InitProducer connects to a broker.
+var EventPublisher Publisher = NoopPublisher{}
+
+// Enabled reports whether a broker is configured: KAFKA_BROKER, or
+// REDIS_ADDR when BROKER=redis selects Redis Streams. Without one the
+// service runs without publishing or consuming events.
+func Enabled() bool {
+	if usesRedisStreams() {
+		return os.Getenv("REDIS_ADDR") != ""
+	}
+	return os.Getenv("KAFKA_BROKER") != ""
+}
 
 func InitProducer() {
-	p, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": os.Getenv("KAFKA_BROKER")})
+	if !Enabled() {
+		if usesRedisStreams() {
+			fmt.Println("REDIS_ADDR is not set, book events are disabled")
+		} else {
+			fmt.Println("KAFKA_BROKER is not set, book events are disabled")
+		}
+		return
+	}
+	if usesRedisStreams() {
+		initStreamProducer()
+		return
+	}
+	config := &kafka.ConfigMap{
+		"bootstrap.servers": os.Getenv("KAFKA_BROKER"),
+		// Bound how long Publish can wait for a delivery report
+		"message.timeout.ms": 30000,
+	}
+	p, err := kafka.NewProducer(config)
 	if err != nil {
 		fmt.Println("Failed to create Kafka producer:", err)
 		return
 	}
-	Producer = p
+
+	// The producer connects lazily, so ask for metadata to know the broker is up
+	err = startup.WaitFor("Kafka", func() error {
+		_, err := p.GetMetadata(nil, false, metadataTimeoutMs)
+		return err
+	})
+	if err != nil {
+		fmt.Println("Kafka broker not reachable, events will be queued:", err)
+	}
+
+	publisher := &ConfluentPublisher{producer: p, config: config}
+	go publisher.watchEvents(p)
+	go publisher.monitor()
+	EventPublisher = WithRetry(publisher)
+}
+
+// ConfluentPublisher publishes events through the confluent Kafka client.
+// It recreates the underlying producer when it hits a fatal error or can't
+// reach the broker for longer than KAFKA_RECONNECT_AFTER.
+type ConfluentPublisher struct {
+	// mu is held for reading by Publish and for writing while the producer
+	// is swapped, so in-flight publishes finish on the producer they started on
+	mu       sync.RWMutex
+	producer *kafka.Producer
+	config   *kafka.ConfigMap
+
+	healthMu     sync.Mutex
+	lastErr      error
+	failingSince time.Time
 }
 
-func PublishMessage(topic string, message string) {
-	Producer.Produce(&kafka.Message{
+// Publish blocks until the broker acknowledges the message, so a nil error
+// means the event was delivered
+func (p *ConfluentPublisher) Publish(topic string, evt BookEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	message := &kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
-		Value:          []byte(message),
-	}, nil)
+		Value:          data,
+	}
+	if evt.RequestID != "" {
+		message.Headers = []kafka.Header{{Key: requestIDHeader, Value: []byte(evt.RequestID)}}
+	}
+
+	delivery := make(chan kafka.Event, 1)
+	err = p.producer.Produce(message, delivery)
+	if err != nil {
+		p.recordFailure(err)
+		return err
+	}
+
+	report, ok := (<-delivery).(*kafka.Message)
+	if !ok {
+		return fmt.Errorf("unexpected delivery report for topic %s", topic)
+	}
+	if report.TopicPartition.Error != nil {
+		p.recordFailure(report.TopicPartition.Error)
+		return report.TopicPartition.Error
+	}
+	p.recordSuccess()
+	return nil
+}
+
+// Health returns the last broker error, or nil while the producer is healthy
+func (p *ConfluentPublisher) Health() error {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	return p.lastErr
+}
+
+func (p *ConfluentPublisher) recordFailure(err error) {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	if p.lastErr == nil {
+		p.failingSince = time.Now()
+	}
+	p.lastErr = err
+}
+
+func (p *ConfluentPublisher) recordSuccess() {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	p.lastErr = nil
+	p.failingSince = time.Time{}
+}
+
+// failingFor returns how long the producer has been failing, or 0
+func (p *ConfluentPublisher) failingFor() time.Duration {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	if p.lastErr == nil {
+		return 0
+	}
+	return time.Since(p.failingSince)
+}
+
+// watchEvents records client-level errors reported by producer and
+// reconnects immediately on fatal ones
+func (p *ConfluentPublisher) watchEvents(producer *kafka.Producer) {
+	for event := range producer.Events() {
+		kafkaErr, ok := event.(kafka.Error)
+		if !ok {
+			continue
+		}
+		p.recordFailure(kafkaErr)
+		if kafkaErr.IsFatal() {
+			fmt.Println("Fatal Kafka producer error, reconnecting:", kafkaErr)
+			p.reconnect()
+		}
+	}
+}
+
+// monitor checks the broker connection periodically and recreates the
+// producer once it has been failing for longer than KAFKA_RECONNECT_AFTER
+func (p *ConfluentPublisher) monitor() {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.mu.RLock()
+		_, err := p.producer.GetMetadata(nil, false, metadataTimeoutMs)
+		p.mu.RUnlock()
+
+		if err == nil {
+			p.recordSuccess()
+			continue
+		}
+		p.recordFailure(err)
+		if p.failingFor() > reconnectAfter() {
+			fmt.Println("Kafka producer unhealthy, reconnecting:", err)
+			p.reconnect()
+		}
+	}
+}
+
+func reconnectAfter() time.Duration {
+	after, err := time.ParseDuration(os.Getenv("KAFKA_RECONNECT_AFTER"))
+	if err != nil || after <= 0 {
+		return defaultReconnectAfter
+	}
+	return after
+}
+
+// reconnect swaps in a new producer and closes the old one
+func (p *ConfluentPublisher) reconnect() {
+	producer, err := kafka.NewProducer(p.config)
+	if err != nil {
+		fmt.Println("Failed to recreate Kafka producer:", err)
+		return
+	}
+
+	p.mu.Lock()
+	old := p.producer
+	p.producer = producer
+	p.mu.Unlock()
+
+	// Reset the failure window so the new producer gets a full grace period
+	p.recordSuccess()
+	go p.watchEvents(producer)
+	go old.Close()
 }
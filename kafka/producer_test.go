@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// newUnreachablePublisher returns a publisher for a broker nobody listens on,
+// which fails every delivery quickly
+func newUnreachablePublisher(t *testing.T) *ConfluentPublisher {
+	t.Helper()
+	config := &kafka.ConfigMap{"bootstrap.servers": "127.0.0.1:1", "message.timeout.ms": 200}
+	producer, err := kafka.NewProducer(config)
+	if err != nil {
+		t.Fatalf("create producer: %v", err)
+	}
+	p := &ConfluentPublisher{producer: producer, config: config}
+	t.Cleanup(func() {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		p.producer.Close()
+	})
+	return p
+}
+
+func TestProducerFailureAndRecovery(t *testing.T) {
+	p := newUnreachablePublisher(t)
+	if err := p.Health(); err != nil {
+		t.Fatalf("Health of a new producer = %v, want nil", err)
+	}
+
+	if err := p.Publish("book_events", BookEvent{Event: "book.created", ID: 1}); err == nil {
+		t.Fatal("Publish to an unreachable broker succeeded")
+	}
+	if p.Health() == nil || p.failingFor() <= 0 {
+		t.Fatalf("Health = %v, failingFor = %s, want the failure recorded", p.Health(), p.failingFor())
+	}
+
+	p.mu.RLock()
+	old := p.producer
+	p.mu.RUnlock()
+	p.reconnect()
+
+	p.mu.RLock()
+	swapped := p.producer != old
+	p.mu.RUnlock()
+	if !swapped {
+		t.Error("reconnect kept the failing producer")
+	}
+	if err := p.Health(); err != nil || p.failingFor() != 0 {
+		t.Errorf("Health after reconnect = %v, failingFor = %s, want a fresh start", err, p.failingFor())
+	}
+}
+
+func TestReconnectAfter(t *testing.T) {
+	t.Setenv("KAFKA_RECONNECT_AFTER", "5s")
+	if got := reconnectAfter(); got.String() != "5s" {
+		t.Errorf("reconnectAfter = %s, want 5s", got)
+	}
+	t.Setenv("KAFKA_RECONNECT_AFTER", "soon")
+	if got := reconnectAfter(); got != defaultReconnectAfter {
+		t.Errorf("reconnectAfter with an invalid value = %s, want the default", got)
+	}
+}
+
+func TestInitProducerWithoutBroker(t *testing.T) {
+	t.Setenv("KAFKA_BROKER", "")
+	t.Setenv("BROKER", "")
+	previous := EventPublisher
+	EventPublisher = NoopPublisher{}
+	t.Cleanup(func() { EventPublisher = previous })
+
+	if Enabled() {
+		t.Fatal("events enabled without a broker")
+	}
+	InitProducer()
+	if _, ok := EventPublisher.(NoopPublisher); !ok {
+		t.Fatalf("EventPublisher = %T, want the no-op publisher", EventPublisher)
+	}
+	if err := EventPublisher.Publish("book_events", BookEvent{Event: "book.created", ID: 1}); err != nil {
+		t.Errorf("Publish = %v, want a silent no-op", err)
+	}
+}
@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"errors"
+	"expvar"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+const (
+	defaultPublishAttempts = 3
+	defaultRetryBaseDelay  = 100 * time.Millisecond
+	maxRetryDelay          = 5 * time.Second
+)
+
+// Publish retry metrics, served with the other expvars on /debug/vars
+var (
+	publishRetries  = expvar.NewInt("kafka_publish_retries")
+	publishFailures = expvar.NewInt("kafka_publish_failures")
+)
+
+// RetryingPublisher retries failed publishes with exponential backoff and
+// full jitter, so instances recovering from the same broker outage don't
+// retry in lockstep
+type RetryingPublisher struct {
+	Publisher
+	attempts  int
+	baseDelay time.Duration
+}
+
+// WithRetry wraps p with the attempts and base delay configured through
+// KAFKA_PUBLISH_ATTEMPTS and KAFKA_RETRY_BASE_DELAY
+func WithRetry(p Publisher) *RetryingPublisher {
+	return &RetryingPublisher{Publisher: p, attempts: publishAttempts(), baseDelay: retryBaseDelay()}
+}
+
+func publishAttempts() int {
+	attempts, err := strconv.Atoi(os.Getenv("KAFKA_PUBLISH_ATTEMPTS"))
+	if err != nil || attempts <= 0 {
+		return defaultPublishAttempts
+	}
+	return attempts
+}
+
+func retryBaseDelay() time.Duration {
+	delay, err := time.ParseDuration(os.Getenv("KAFKA_RETRY_BASE_DELAY"))
+	if err != nil || delay <= 0 {
+		return defaultRetryBaseDelay
+	}
+	return delay
+}
+
+// Publish tries up to the configured number of attempts. Fatal client errors
+// are not retried since only a new producer can recover from them.
+func (p *RetryingPublisher) Publish(topic string, evt BookEvent) error {
+	delay := p.baseDelay
+
+	var err error
+	for attempt := 1; attempt <= p.attempts; attempt++ {
+		err = p.Publisher.Publish(topic, evt)
+		if err == nil || !isRetriable(err) {
+			break
+		}
+		if attempt < p.attempts {
+			log.Printf("Kafka publish failed (attempt %d/%d): %v", attempt, p.attempts, err)
+			publishRetries.Add(1)
+			time.Sleep(rand.N(delay))
+			delay = min(delay*2, maxRetryDelay)
+		}
+	}
+	if err != nil {
+		publishFailures.Add(1)
+	}
+	return err
+}
+
+// Health reports the wrapped publisher's health, if it tracks any
+func (p *RetryingPublisher) Health() error {
+	if reporter, ok := p.Publisher.(HealthReporter); ok {
+		return reporter.Health()
+	}
+	return nil
+}
+
+func isRetriable(err error) bool {
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		return !kafkaErr.IsFatal()
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// flakyPublisher fails its first failures publishes with err
+type flakyPublisher struct {
+	MemoryPublisher
+	failures int
+	err      error
+	calls    int
+}
+
+func (p *flakyPublisher) Publish(topic string, evt BookEvent) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return p.err
+	}
+	return p.MemoryPublisher.Publish(topic, evt)
+}
+
+func TestRetryingPublisherEventuallySucceeds(t *testing.T) {
+	flaky := &flakyPublisher{failures: 2, err: errors.New("broker unavailable")}
+	p := &RetryingPublisher{Publisher: flaky, attempts: 3, baseDelay: time.Millisecond}
+	retries, failures := publishRetries.Value(), publishFailures.Value()
+
+	if err := p.Publish("book_events", BookEvent{Event: "book.created", ID: 1}); err != nil {
+		t.Fatalf("Publish = %v, want success on the third attempt", err)
+	}
+	if flaky.calls != 3 || len(flaky.Events()) != 1 {
+		t.Errorf("%d attempts, %d events published, want 3 and 1", flaky.calls, len(flaky.Events()))
+	}
+	if got := publishRetries.Value() - retries; got != 2 {
+		t.Errorf("%d retries counted, want 2", got)
+	}
+	if publishFailures.Value() != failures {
+		t.Error("a publish that succeeded was counted as a failure")
+	}
+}
+
+func TestRetryingPublisherGivesUp(t *testing.T) {
+	flaky := &flakyPublisher{failures: 5, err: errors.New("broker unavailable")}
+	p := &RetryingPublisher{Publisher: flaky, attempts: 3, baseDelay: time.Millisecond}
+	failures := publishFailures.Value()
+
+	if err := p.Publish("book_events", BookEvent{Event: "book.created", ID: 1}); err == nil {
+		t.Fatal("Publish succeeded, want the last error")
+	}
+	if flaky.calls != 3 {
+		t.Errorf("%d attempts, want 3", flaky.calls)
+	}
+	if got := publishFailures.Value() - failures; got != 1 {
+		t.Errorf("%d failures counted, want 1", got)
+	}
+}
+
+func TestRetryingPublisherSkipsFatalErrors(t *testing.T) {
+	flaky := &flakyPublisher{failures: 1, err: kafka.NewError(kafka.ErrFatal, "fenced", true)}
+	p := &RetryingPublisher{Publisher: flaky, attempts: 3, baseDelay: time.Millisecond}
+
+	if err := p.Publish("book_events", BookEvent{Event: "book.created", ID: 1}); err == nil {
+		t.Fatal("Publish succeeded, want the fatal error")
+	}
+	if flaky.calls != 1 {
+		t.Errorf("%d attempts, want a fatal error not to be retried", flaky.calls)
+	}
+}
+
+func TestWithRetryConfiguration(t *testing.T) {
+	t.Setenv("KAFKA_PUBLISH_ATTEMPTS", "5")
+	t.Setenv("KAFKA_RETRY_BASE_DELAY", "250ms")
+	p := WithRetry(NoopPublisher{})
+	if p.attempts != 5 || p.baseDelay != 250*time.Millisecond {
+		t.Errorf("attempts = %d, base delay = %s, want 5 and 250ms", p.attempts, p.baseDelay)
+	}
+}
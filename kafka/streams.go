@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rohans540/books-backend/startup"
+)
+
+// brokerRedis selects Redis Streams instead of Kafka through BROKER
+const brokerRedis = "redis"
+
+// defaultStreamMaxLen caps each stream at roughly this many entries unless
+// REDIS_STREAM_MAXLEN is set
+const defaultStreamMaxLen = 100000
+
+// streamReadBlock bounds how long the stream consumer blocks waiting for an
+// entry, and so how long it takes to notice cancellation
+const streamReadBlock = time.Second
+
+// Stream entry fields. The event field holds the same JSON as a Kafka
+// message value, so consumers see an identical schema on either broker.
+const (
+	streamEventField     = "event"
+	streamRequestIDField = "request_id"
+)
+
+// usesRedisStreams reports whether BROKER=redis selects Redis Streams
+func usesRedisStreams() bool {
+	return os.Getenv("BROKER") == brokerRedis
+}
+
+func streamMaxLen() int64 {
+	maxLen, err := strconv.ParseInt(os.Getenv("REDIS_STREAM_MAXLEN"), 10, 64)
+	if err != nil || maxLen <= 0 {
+		return defaultStreamMaxLen
+	}
+	return maxLen
+}
+
+// newStreamClient connects to REDIS_ADDR, the same Redis the cache uses
+func newStreamClient() *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: os.Getenv("REDIS_ADDR")})
+}
+
+// initStreamProducer makes EventPublisher append events to Redis Streams
+func initStreamProducer() {
+	client := newStreamClient()
+	err := startup.WaitFor("Redis Streams", func() error {
+		return client.Ping(context.Background()).Err()
+	})
+	if err != nil {
+		fmt.Println("Redis not reachable, events will be queued:", err)
+	}
+	EventPublisher = WithRetry(NewStreamPublisher(client))
+}
+
+// StreamPublisher appends book events to a Redis stream named after the
+// topic. Streams are trimmed to about REDIS_STREAM_MAXLEN entries so they
+// can't grow without bound.
+type StreamPublisher struct {
+	client *redis.Client
+	maxLen int64
+}
+
+func NewStreamPublisher(client *redis.Client) *StreamPublisher {
+	return &StreamPublisher{client: client, maxLen: streamMaxLen()}
+}
+
+// Publish returns once Redis stored the entry, so a nil error means the
+// event was delivered
+func (p *StreamPublisher) Publish(topic string, evt BookEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	values := map[string]interface{}{streamEventField: data}
+	if evt.RequestID != "" {
+		values[streamRequestIDField] = evt.RequestID
+	}
+	return p.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: topic,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+}
+
+// Health pings Redis
+func (p *StreamPublisher) Health() error {
+	return p.client.Ping(context.Background()).Err()
+}
+
+// startStreamConsumer reads the topic's stream and broadcasts every event to
+// BookEvents until ctx is cancelled. Like the Kafka consumer it starts at the
+// newest entry, so each instance sees every new event but none of the backlog.
+func startStreamConsumer(ctx context.Context, topic string) {
+	client := newStreamClient()
+	go func() {
+		defer client.Close()
+		lastID := "$"
+		for ctx.Err() == nil {
+			streams, err := client.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{topic, lastID},
+				Block:   streamReadBlock,
+			}).Result()
+			if err != nil {
+				if err != redis.Nil && ctx.Err() == nil {
+					fmt.Println("Redis stream consumer error:", err)
+					time.Sleep(streamReadBlock)
+				}
+				continue
+			}
+			for _, stream := range streams {
+				for _, message := range stream.Messages {
+					lastID = message.ID
+					data, _ := message.Values[streamEventField].(string)
+					var evt BookEvent
+					if err := json.Unmarshal([]byte(data), &evt); err != nil {
+						fmt.Println("Skipping malformed book event:", err)
+						continue
+					}
+					BookEvents.Broadcast(evt)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// startMiniredis points REDIS_ADDR at a miniredis server for the test
+func startMiniredis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis: %v", err)
+	}
+	t.Cleanup(server.Close)
+	t.Setenv("REDIS_ADDR", server.Addr())
+	return server
+}
+
+func TestStreamPublisher(t *testing.T) {
+	server := startMiniredis(t)
+	client := newStreamClient()
+	defer client.Close()
+	publisher := NewStreamPublisher(client)
+
+	evt := BookEvent{Event: "book.created", ID: 7, Title: "Dune", RequestID: "req-123"}
+	if err := publisher.Publish("book_events", evt); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.Publish("book_events", BookEvent{Event: "book.deleted", ID: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := server.Stream("book_events")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("stream = %v, %v, want 2 entries", entries, err)
+	}
+	// The entry carries the same JSON as a Kafka message value
+	values := map[string]string{}
+	for i := 0; i+1 < len(entries[0].Values); i += 2 {
+		values[entries[0].Values[i]] = entries[0].Values[i+1]
+	}
+	var got BookEvent
+	if err := json.Unmarshal([]byte(values[streamEventField]), &got); err != nil || got != evt {
+		t.Errorf("event field = %s, want %+v", values[streamEventField], evt)
+	}
+	if values[streamRequestIDField] != "req-123" {
+		t.Errorf("request_id field = %q, want req-123", values[streamRequestIDField])
+	}
+
+	if err := publisher.Health(); err != nil {
+		t.Errorf("Health = %v", err)
+	}
+	server.Close()
+	if err := publisher.Health(); err == nil {
+		t.Error("Health = nil with Redis down")
+	}
+}
+
+func TestStreamPublisherTrims(t *testing.T) {
+	server := startMiniredis(t)
+	t.Setenv("REDIS_STREAM_MAXLEN", "3")
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	publisher := NewStreamPublisher(client)
+
+	for id := uint(1); id <= 10; id++ {
+		publisher.Publish("book_events", BookEvent{Event: "book.created", ID: id})
+	}
+	if entries, _ := server.Stream("book_events"); len(entries) > 3 {
+		t.Errorf("stream holds %d entries, want at most 3", len(entries))
+	}
+}
+
+func TestStreamConsumerBroadcasts(t *testing.T) {
+	startMiniredis(t)
+	client := newStreamClient()
+	defer client.Close()
+	publisher := NewStreamPublisher(client)
+
+	events, unsubscribe := BookEvents.Subscribe(16)
+	defer unsubscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startStreamConsumer(ctx, "book_events")
+
+	// The consumer only sees entries added after its first read, so keep
+	// publishing until one comes through
+	deadline := time.After(5 * time.Second)
+	for {
+		publisher.Publish("book_events", BookEvent{Event: "book.updated", ID: 3, Title: "Dune"})
+		select {
+		case evt := <-events:
+			if evt.Event != "book.updated" || evt.ID != 3 || evt.Title != "Dune" {
+				t.Errorf("received %+v, want the published event", evt)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no event received from the stream")
+		}
+	}
+}
+
+func TestEnabledWithRedisBroker(t *testing.T) {
+	t.Setenv("BROKER", brokerRedis)
+	t.Setenv("KAFKA_BROKER", "localhost:9092")
+	t.Setenv("REDIS_ADDR", "")
+	if Enabled() {
+		t.Error("Enabled without REDIS_ADDR")
+	}
+	t.Setenv("REDIS_ADDR", "localhost:6379")
+	if !Enabled() {
+		t.Error("not Enabled with REDIS_ADDR")
+	}
+}
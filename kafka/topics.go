@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+const topicCreateTimeout = 30 * time.Second
+
+// autoCreateTopics reports whether missing topics are created at startup,
+// enabled with KAFKA_AUTO_CREATE_TOPICS=true
+func autoCreateTopics() bool {
+	return os.Getenv("KAFKA_AUTO_CREATE_TOPICS") == "true"
+}
+
+// topicPartitions returns the partition count of created topics,
+// KAFKA_TOPIC_PARTITIONS or 1
+func topicPartitions() int {
+	partitions, err := strconv.Atoi(os.Getenv("KAFKA_TOPIC_PARTITIONS"))
+	if err != nil || partitions <= 0 {
+		return 1
+	}
+	return partitions
+}
+
+// topicReplication returns the replication factor of created topics,
+// KAFKA_TOPIC_REPLICATION or 1
+func topicReplication() int {
+	replication, err := strconv.Atoi(os.Getenv("KAFKA_TOPIC_REPLICATION"))
+	if err != nil || replication <= 0 {
+		return 1
+	}
+	return replication
+}
+
+// createsTopics reports whether EnsureTopics has anything to do: topic
+// creation is enabled and a Kafka broker, rather than Redis Streams, is
+// configured
+func createsTopics() bool {
+	return autoCreateTopics() && Enabled() && !usesRedisStreams()
+}
+
+// topicSpecs describes topics as EnsureTopics creates them
+func topicSpecs(topics []string) []kafka.TopicSpecification {
+	specs := make([]kafka.TopicSpecification, len(topics))
+	for i, topic := range topics {
+		specs[i] = kafka.TopicSpecification{
+			Topic:             topic,
+			NumPartitions:     topicPartitions(),
+			ReplicationFactor: topicReplication(),
+		}
+	}
+	return specs
+}
+
+// EnsureTopics creates the given topics on the Kafka broker when
+// KAFKA_AUTO_CREATE_TOPICS=true, so a fresh broker without automatic topic
+// creation doesn't reject every publish. Topics that already exist are left
+// as they are. Failures are logged and the service starts anyway, as with an
+// unreachable broker. Redis Streams need no setup and are skipped.
+func EnsureTopics(topics ...string) {
+	if !createsTopics() {
+		return
+	}
+	admin, err := kafka.NewAdminClient(&kafka.ConfigMap{"bootstrap.servers": os.Getenv("KAFKA_BROKER")})
+	if err != nil {
+		fmt.Println("Failed to create Kafka admin client, topics not created:", err)
+		return
+	}
+	defer admin.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), topicCreateTimeout)
+	defer cancel()
+	results, err := admin.CreateTopics(ctx, topicSpecs(topics), kafka.SetAdminOperationTimeout(topicCreateTimeout))
+	if err != nil {
+		fmt.Println("Failed to create Kafka topics:", err)
+		return
+	}
+	for _, result := range results {
+		switch result.Error.Code() {
+		case kafka.ErrNoError:
+			fmt.Printf("Created Kafka topic %s\n", result.Topic)
+		case kafka.ErrTopicAlreadyExists:
+			fmt.Printf("Kafka topic %s already exists\n", result.Topic)
+		default:
+			fmt.Printf("Failed to create Kafka topic %s: %v\n", result.Topic, result.Error)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package kafka
+
+import "testing"
+
+func TestCreatesTopics(t *testing.T) {
+	tests := []struct {
+		name, autoCreate, broker, kafkaBroker string
+		want                                  bool
+	}{
+		{"disabled by default", "", "", "localhost:9092", false},
+		{"enabled", "true", "", "localhost:9092", true},
+		{"no broker", "true", "", "", false},
+		{"redis streams", "true", brokerRedis, "localhost:9092", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KAFKA_AUTO_CREATE_TOPICS", tt.autoCreate)
+			t.Setenv("BROKER", tt.broker)
+			t.Setenv("KAFKA_BROKER", tt.kafkaBroker)
+			t.Setenv("REDIS_ADDR", "localhost:6379")
+			if got := createsTopics(); got != tt.want {
+				t.Errorf("createsTopics() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Disabled, EnsureTopics returns without contacting a broker
+	t.Setenv("KAFKA_AUTO_CREATE_TOPICS", "false")
+	t.Setenv("KAFKA_BROKER", "127.0.0.1:1")
+	EnsureTopics("book_events")
+}
+
+func TestTopicSpecs(t *testing.T) {
+	tests := []struct {
+		partitions, replication string
+		wantPartitions          int
+		wantReplication         int
+	}{
+		{"", "", 1, 1},
+		{"6", "3", 6, 3},
+		{"0", "-1", 1, 1},
+		{"many", "x", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Setenv("KAFKA_TOPIC_PARTITIONS", tt.partitions)
+		t.Setenv("KAFKA_TOPIC_REPLICATION", tt.replication)
+		specs := topicSpecs([]string{"book_events", "book_events.dlq"})
+		if len(specs) != 2 || specs[0].Topic != "book_events" || specs[1].Topic != "book_events.dlq" {
+			t.Fatalf("specs = %+v, want one per topic", specs)
+		}
+		for _, spec := range specs {
+			if spec.NumPartitions != tt.wantPartitions || spec.ReplicationFactor != tt.wantReplication {
+				t.Errorf("partitions %q, replication %q: spec = %+v, want %d partitions and replication %d",
+					tt.partitions, tt.replication, spec, tt.wantPartitions, tt.wantReplication)
+			}
+		}
+	}
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/controllers"
 	"github.com/rohans540/books-backend/database"
-	_ "github.com/rohans540/books-backend/docs"
+	"github.com/rohans540/books-backend/docs"
 	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/middleware"
+	"github.com/rohans540/books-backend/migrations"
+	"github.com/rohans540/books-backend/outbox"
 	"github.com/rohans540/books-backend/redis"
 	"github.com/rohans540/books-backend/routes"
+	"github.com/rohans540/books-backend/seed"
+	"github.com/rohans540/books-backend/webhook"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -19,26 +33,106 @@ import (
 // @version 1.0
 // @description This is a simple API for managing books.
 // @host 13.53.47.251:8000
-// @BasePath /
+// @BasePath /v1
+
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Admin endpoints require "Bearer <ADMIN_TOKEN>"
 
 func main() {
 	// Load environment variables
 	database.ConnectDB()
+	if err := controllers.ValidatePageSizes(); err != nil {
+		log.Fatalf("Invalid page size configuration: %v", err)
+	}
+	if err := controllers.ValidateTextLimits(); err != nil {
+		log.Fatalf("Invalid text length configuration: %v", err)
+	}
+	if err := controllers.ValidateMinYear(); err != nil {
+		log.Fatalf("Invalid year configuration: %v", err)
+	}
+	if err := controllers.ValidateISBNChecksumMode(); err != nil {
+		log.Fatalf("Invalid ISBN configuration: %v", err)
+	}
+	if err := controllers.ValidateBatchSize(); err != nil {
+		log.Fatalf("Invalid batch size configuration: %v", err)
+	}
+	if err := controllers.ValidateCacheTTLs(); err != nil {
+		log.Fatalf("Invalid cache TTL configuration: %v", err)
+	}
+	if err := controllers.ValidateCacheWriteMode(); err != nil {
+		log.Fatalf("Invalid cache configuration: %v", err)
+	}
+	if err := controllers.ValidateCacheValueSize(); err != nil {
+		log.Fatalf("Invalid cache configuration: %v", err)
+	}
+	if err := controllers.ValidateResponseSize(); err != nil {
+		log.Fatalf("Invalid response size configuration: %v", err)
+	}
+	if err := controllers.ValidateExportChunks(); err != nil {
+		log.Fatalf("Invalid export configuration: %v", err)
+	}
+	if err := routes.ValidateTrailingSlash(); err != nil {
+		log.Fatalf("Invalid routing configuration: %v", err)
+	}
+	if err := middleware.CheckFieldNames(); err != nil {
+		log.Fatalf("Invalid API field naming: %v", err)
+	}
+	if os.Getenv("RUN_MIGRATIONS") == "true" {
+		if err := migrations.Up(database.DB); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+	if err := migrations.Verify(database.DB); err != nil {
+		if os.Getenv("STRICT_SCHEMA_CHECK") == "true" {
+			log.Fatalf("Database schema check failed: %v", err)
+		}
+		log.Printf("Database schema check failed, run the migrations: %v", err)
+	}
+	seed.Run(database.DB)
 	kafka.InitProducer()
+	kafka.EnsureTopics(controllers.BookEventsTopic, outbox.DeadLetterTopic(controllers.BookEventsTopic))
 	redis.ConnectRedis()
 
-	router := gin.Default()
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	outbox.StartRelay(backgroundCtx)
+	webhook.StartRelay(backgroundCtx)
+	controllers.StartCacheReconciler(backgroundCtx)
+	controllers.StartLiveEvents(backgroundCtx)
+
+	// Mount the API, and its Swagger doc, under BASE_PATH
+	basePath := routes.BasePath()
+	docs.SwaggerInfo.BasePath = basePath + "/v1"
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	router.Use(middleware.RequestID(), middleware.Logger(), gin.Recovery())
+	// Probes must answer even when the service is saturated, and live event
+	// streams stay open without touching the database
+	router.Use(middleware.ConcurrencyLimit(middleware.MaxInFlight(),
+		basePath+"/livez", basePath+"/readyz", basePath+"/healthz", basePath+"/version", basePath+"/",
+		basePath+"/v1/books/events", basePath+"/books/events"))
+	// Streams legitimately outlast any request timeout
+	router.Use(middleware.Timeout(middleware.RequestTimeout(),
+		basePath+"/v1/books/stream", basePath+"/books/stream",
+		basePath+"/v1/books/export.json", basePath+"/books/export.json",
+		basePath+"/v1/admin/backup", basePath+"/admin/backup",
+		basePath+"/v1/books/events", basePath+"/books/events"))
+	router.Use(middleware.RetryBudget(database.RetryBudget()))
 
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
+	router.Use(cors.New(corsConfig()))
+	router.Use(middleware.BodyLimit(middleware.MaxBodyBytes()))
+	// List multipart upload routes here to exempt them
+	router.Use(middleware.RequireJSON())
+	router.Use(middleware.OpenAPIValidation())
+	router.Use(middleware.Gzip(middleware.GzipMinSize()))
 
 	// Swagger Documentation
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET(basePath+"/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Setup Routes
 	routes.SetupRoutes(router)
@@ -48,5 +142,131 @@ func main() {
 		port = "8000"
 	}
 
-	log.Fatal(router.Run(":" + port))
+	// Bound how long a client may take to send a request or read the response,
+	// so slow or idle connections can't pile up. Zero disables a timeout.
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           routes.Handler(router),
+		ReadHeaderTimeout: envDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       envDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      envDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       envDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+	}
+	certFile, keyFile, tlsConfig, err := serverTLS()
+	if err != nil {
+		log.Fatalf("Invalid TLS configuration: %v", err)
+	}
+	srv.TLSConfig = tlsConfig
+	// Live event streams never finish on their own, so end them when
+	// shutting down instead of waiting out SHUTDOWN_TIMEOUT
+	srv.RegisterOnShutdown(kafka.BookEvents.Close)
+
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			// HTTP/2 is negotiated automatically over TLS
+			err = srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// On SIGINT/SIGTERM fail readiness first, give the load balancer time to
+	// depool this instance, then let in-flight requests finish
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down server...")
+	controllers.MarkShuttingDown()
+	time.Sleep(envDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), envDuration("SHUTDOWN_TIMEOUT", 10*time.Second))
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Fatalf("Server forced to shut down: %v", err)
+	}
+	log.Println("Server stopped")
+}
+
+// envDuration reads a Go duration such as "5s" from key, or returns fallback
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// tlsVersions maps the accepted TLS_MIN_VERSION values to their constants
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// serverTLS reads TLS_CERT_FILE and TLS_KEY_FILE, which must be set together
+// to serve HTTPS directly, and TLS_MIN_VERSION (1.2 unless set to 1.3). It
+// returns a nil config for plain HTTP, e.g. behind a TLS-terminating proxy.
+// The certificate is loaded once at startup.
+func serverTLS() (string, string, *tls.Config, error) {
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if certFile == "" && keyFile == "" {
+		return "", "", nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return "", "", nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	minVersion := uint16(tls.VersionTLS12)
+	if raw := os.Getenv("TLS_MIN_VERSION"); raw != "" {
+		version, ok := tlsVersions[raw]
+		if !ok {
+			return "", "", nil, fmt.Errorf("TLS_MIN_VERSION must be 1.2 or 1.3, got %q", raw)
+		}
+		minVersion = version
+	}
+	return certFile, keyFile, &tls.Config{MinVersion: minVersion}, nil
+}
+
+// trustedProxies reads TRUSTED_PROXIES, a comma-separated list of IPs or CIDRs
+// (e.g. the load balancer's subnet) whose X-Forwarded-For is believed when
+// resolving the client IP. Without it no proxy is trusted and the client IP is
+// the address of the connection.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// corsConfig builds the CORS policy from CORS_ALLOWED_ORIGINS (comma-separated).
+// Credentials are only allowed for an explicit origin list; without one every
+// origin is allowed but credentials are not, as the spec forbids "*" with credentials.
+func corsConfig() cors.Config {
+	config := cors.Config{
+		AllowMethods:  []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Authorization", "If-Match", "If-Modified-Since", "If-None-Match", "Cache-Control", "X-Strict-Binding", middleware.APIKeyHeader, middleware.RequestIDHeader},
+		ExposeHeaders: []string{"Content-Length", "Link", "ETag", "X-Total-Count", "X-Page-Limit", "X-Page-Offset", "X-Snapshot", "X-No-Op", middleware.RequestIDHeader},
+	}
+
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	if len(origins) == 0 {
+		config.AllowAllOrigins = true
+		return config
+	}
+	config.AllowOrigins = origins
+	config.AllowCredentials = true
+	return config
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	tests := []struct {
+		name, proxies, remote, forwardedFor, want string
+	}{
+		{"trusted proxy", "10.0.0.0/8, 192.168.1.1", "10.1.2.3:4000", "203.0.113.7", "203.0.113.7"},
+		{"chain of trusted proxies", "10.0.0.0/8, 192.168.1.1", "10.1.2.3:4000", "203.0.113.7, 192.168.1.1", "203.0.113.7"},
+		{"spoofed header from untrusted client", "10.0.0.0/8", "198.51.100.1:4000", "203.0.113.7", "198.51.100.1"},
+		{"no trusted proxies", "", "10.1.2.3:4000", "203.0.113.7", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.proxies)
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+				t.Fatal(err)
+			}
+			router.GET("/ip", func(ctx *gin.Context) { ctx.String(http.StatusOK, ctx.ClientIP()) })
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Body.String() != tt.want {
+				t.Errorf("client IP = %q, want %q", w.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "not-an-ip")
+	if err := gin.New().SetTrustedProxies(trustedProxies()); err == nil {
+		t.Error("an invalid proxy was accepted")
+	}
+}
+
+func TestCORSExposesPaginationHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(cors.New(corsConfig()))
+	router.GET("/books", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/books", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	exposed := strings.ToLower(w.Header().Get("Access-Control-Expose-Headers"))
+	for _, header := range []string{"X-Total-Count", "X-Page-Limit", "X-Page-Offset"} {
+		if !strings.Contains(exposed, strings.ToLower(header)) {
+			t.Errorf("Access-Control-Expose-Headers = %q, missing %s", exposed, header)
+		}
+	}
+}
+
+func TestCORSAllowsConditionalHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(cors.New(corsConfig()))
+	router.GET("/books", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	// A browser only sends these on the real request when the preflight allows them
+	for _, header := range []string{"If-None-Match", "If-Modified-Since", "If-Match"} {
+		req := httptest.NewRequest(http.MethodOptions, "/books", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		req.Header.Set("Access-Control-Request-Headers", header)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		allowed := strings.ToLower(w.Header().Get("Access-Control-Allow-Headers"))
+		if w.Code >= http.StatusBadRequest || !strings.Contains(allowed, strings.ToLower(header)) {
+			t.Errorf("preflight for %s: status = %d, Access-Control-Allow-Headers = %q", header, w.Code, allowed)
+		}
+	}
+}
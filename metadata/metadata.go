@@ -0,0 +1,111 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound is returned by Lookup when the source doesn't know the ISBN
+var ErrNotFound = errors.New("isbn not found")
+
+// Book is the metadata a Source knows about an ISBN. Fields it doesn't know
+// are left empty.
+type Book struct {
+	Title   string
+	Authors []string
+	Year    int
+}
+
+// Source looks up book metadata by ISBN
+type Source interface {
+	Lookup(ctx context.Context, isbn string) (Book, error)
+}
+
+// BookSource is the source used to enrich books created with ?enrich=true
+var BookSource Source = NewOpenLibrary(&http.Client{Timeout: lookupTimeout()})
+
+const (
+	defaultOpenLibraryURL = "https://openlibrary.org"
+	defaultLookupTimeout  = 3 * time.Second
+)
+
+// lookupTimeout bounds a single metadata request, configurable through
+// METADATA_LOOKUP_TIMEOUT (e.g. "2s")
+func lookupTimeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv("METADATA_LOOKUP_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		return defaultLookupTimeout
+	}
+	return timeout
+}
+
+// OpenLibrary looks books up through the Open Library books API. The base URL
+// can be pointed at a mirror with OPENLIBRARY_URL.
+type OpenLibrary struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewOpenLibrary(client *http.Client) *OpenLibrary {
+	baseURL := os.Getenv("OPENLIBRARY_URL")
+	if baseURL == "" {
+		baseURL = defaultOpenLibraryURL
+	}
+	return &OpenLibrary{client: client, baseURL: baseURL}
+}
+
+// openLibraryBook is the part of a jscmd=data entry we use
+type openLibraryBook struct {
+	Title   string `json:"title"`
+	Authors []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	// PublishDate is free text such as "1988", "March 1988" or "Mar 22, 1988"
+	PublishDate string `json:"publish_date"`
+}
+
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+func (s *OpenLibrary) Lookup(ctx context.Context, isbn string) (Book, error) {
+	key := "ISBN:" + isbn
+	query := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"data"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/books?"+query.Encode(), nil)
+	if err != nil {
+		return Book{}, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Book{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Book{}, fmt.Errorf("open library answered %s", resp.Status)
+	}
+
+	// Unknown ISBNs are answered with an empty object
+	var entries map[string]openLibraryBook
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return Book{}, err
+	}
+	entry, ok := entries[key]
+	if !ok {
+		return Book{}, ErrNotFound
+	}
+
+	book := Book{Title: entry.Title}
+	for _, author := range entry.Authors {
+		book.Authors = append(book.Authors, author.Name)
+	}
+	if year := yearPattern.FindString(entry.PublishDate); year != "" {
+		book.Year, _ = strconv.Atoi(year)
+	}
+	return book, nil
+}
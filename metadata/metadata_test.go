@@ -0,0 +1,79 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubOpenLibrary serves handler in place of the Open Library API
+func stubOpenLibrary(t *testing.T, timeout time.Duration, handler http.HandlerFunc) *OpenLibrary {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	t.Setenv("OPENLIBRARY_URL", server.URL)
+	return NewOpenLibrary(&http.Client{Timeout: timeout})
+}
+
+func TestOpenLibraryLookup(t *testing.T) {
+	source := stubOpenLibrary(t, time.Second, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("bibkeys"); got != "ISBN:0131103628" {
+			t.Errorf("bibkeys = %q, want ISBN:0131103628", got)
+		}
+		w.Write([]byte(`{"ISBN:0131103628": {"title": "The C Programming Language",
+			"authors": [{"name": "Brian W. Kernighan"}, {"name": "Dennis M. Ritchie"}],
+			"publish_date": "March 22, 1988"}}`))
+	})
+
+	book, err := source.Lookup(context.Background(), "0131103628")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Book{Title: "The C Programming Language", Authors: []string{"Brian W. Kernighan", "Dennis M. Ritchie"}, Year: 1988}
+	if !reflect.DeepEqual(book, want) {
+		t.Errorf("Lookup = %+v, want %+v", book, want)
+	}
+}
+
+func TestOpenLibraryLookupFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		handler http.HandlerFunc
+	}{
+		{"unknown isbn", time.Second, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{}`))
+		}},
+		{"server error", time.Second, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}},
+		{"malformed body", time.Second, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`not json`))
+		}},
+		{"timeout", 10 * time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := stubOpenLibrary(t, tt.timeout, tt.handler)
+			if _, err := source.Lookup(context.Background(), "0131103628"); err == nil {
+				t.Error("Lookup succeeded")
+			}
+		})
+	}
+
+	source := stubOpenLibrary(t, time.Second, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	if _, err := source.Lookup(context.Background(), "0131103628"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown isbn: Lookup = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin only lets through requests carrying "Authorization: Bearer
+// <ADMIN_TOKEN>". When ADMIN_TOKEN is unset, admin endpoints are disabled.
+func RequireAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := os.Getenv("ADMIN_TOKEN")
+		if token == "" {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin endpoints are disabled"})
+			return
+		}
+
+		provided := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultMaxBodyBytes = 1 << 20 // 1 MiB
+
+// MaxBodyBytes returns the request body limit, configurable through MAX_BODY_BYTES
+func MaxBodyBytes() int64 {
+	limit, err := strconv.ParseInt(os.Getenv("MAX_BODY_BYTES"), 10, 64)
+	if err != nil || limit <= 0 {
+		return defaultMaxBodyBytes
+	}
+	return limit
+}
+
+// BodyLimit rejects POST, PUT and PATCH requests whose body exceeds limit
+// bytes with 413. Bodies without a Content-Length are capped with
+// http.MaxBytesReader so reading past the limit fails.
+func BodyLimit(limit int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		switch ctx.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			ctx.Next()
+			return
+		}
+
+		if ctx.Request.ContentLength > limit {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func bodyLimitRouter(limit int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(BodyLimit(limit))
+	echo := func(ctx *gin.Context) {
+		if _, err := io.ReadAll(ctx.Request.Body); err != nil {
+			ctx.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		ctx.Status(http.StatusOK)
+	}
+	router.POST("/books", echo)
+	router.GET("/books", echo)
+	return router
+}
+
+func TestBodyLimit(t *testing.T) {
+	router := bodyLimitRouter(16)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		// chunked sends the body without a Content-Length
+		chunked bool
+		want    int
+	}{
+		{"within the limit", http.MethodPost, `{"title":"Dune"}`, false, http.StatusOK},
+		{"over the limit", http.MethodPost, `{"title":"Dune Messiah"}`, false, http.StatusRequestEntityTooLarge},
+		{"over the limit without Content-Length", http.MethodPost, `{"title":"Dune Messiah"}`, true, http.StatusRequestEntityTooLarge},
+		{"GET is not limited", http.MethodGet, `{"title":"Dune Messiah"}`, false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/books", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	t.Setenv("MAX_BODY_BYTES", "2048")
+	if got := MaxBodyBytes(); got != 2048 {
+		t.Errorf("MaxBodyBytes = %d, want 2048", got)
+	}
+	t.Setenv("MAX_BODY_BYTES", "nope")
+	if got := MaxBodyBytes(); got != defaultMaxBodyBytes {
+		t.Errorf("MaxBodyBytes with an invalid value = %d, want the default", got)
+	}
+}
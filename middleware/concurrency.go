@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// busyRetryAfter is the Retry-After sent with 503 when every slot is taken,
+// in seconds. Spikes are short, so clients are told to come back soon.
+const busyRetryAfter = "1"
+
+// MaxInFlight returns how many requests are served at once, configurable
+// through MAX_IN_FLIGHT. Zero, the default, disables the limit.
+func MaxInFlight() int {
+	limit, err := strconv.Atoi(os.Getenv("MAX_IN_FLIGHT"))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// ConcurrencyLimit serves at most limit requests at once, so a sudden spike
+// can't pile up connections on the database and Redis. Requests beyond it
+// aren't queued: they get 503 with Retry-After straight away. Requests for
+// the exempt paths are always let through. A limit of zero disables it.
+func ConcurrencyLimit(limit int, exempt ...string) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(ctx *gin.Context) { ctx.Next() }
+	}
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+	slots := make(chan struct{}, limit)
+
+	return func(ctx *gin.Context) {
+		if skip[ctx.Request.URL.Path] {
+			ctx.Next()
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			ctx.Next()
+		default:
+			ctx.Header("Retry-After", busyRetryAfter)
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Server is busy, try again later"})
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router := testutil.NewRouter()
+	router.Use(ConcurrencyLimit(1, "/health"))
+	router.GET("/slow", func(ctx *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		ctx.Status(http.StatusOK)
+	})
+	router.GET("/fast", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	router.GET("/health", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	// Hold the only slot
+	done := make(chan int)
+	go func() { done <- testutil.Request(router, http.MethodGet, "/slow", "").Code }()
+	<-entered
+
+	w := testutil.Request(router, http.MethodGet, "/fast", "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("saturated: status = %d, want 503", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != busyRetryAfter {
+		t.Errorf("saturated: Retry-After = %q, want %q", got, busyRetryAfter)
+	}
+	if w := testutil.Request(router, http.MethodGet, "/health", ""); w.Code != http.StatusOK {
+		t.Errorf("saturated: exempt path status = %d, want 200", w.Code)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("slot holder status = %d, want 200", code)
+	}
+	// The slot is freed once the request finishes
+	if w := testutil.Request(router, http.MethodGet, "/fast", ""); w.Code != http.StatusOK {
+		t.Errorf("released: status = %d, want 200", w.Code)
+	}
+}
+
+func TestConcurrencyLimitDisabled(t *testing.T) {
+	router := testutil.NewRouter()
+	router.Use(ConcurrencyLimit(0))
+	router.GET("/", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	if w := testutil.Request(router, http.MethodGet, "/", ""); w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
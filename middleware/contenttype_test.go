@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestRequireJSON(t *testing.T) {
+	router := testutil.NewRouter()
+	router.Use(RequireJSON("/books/import"))
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	router.POST("/books", ok)
+	router.PUT("/books/:id", ok)
+	router.POST("/books/import", ok)
+
+	tests := []struct {
+		name, method, path, contentType, body string
+		want                                  int
+	}{
+		{"json", http.MethodPost, "/books", "application/json", "{}", http.StatusOK},
+		{"json with charset", http.MethodPut, "/books/1", "application/json; charset=utf-8", "{}", http.StatusOK},
+		{"json suffix", http.MethodPost, "/books", "application/merge-patch+json", "{}", http.StatusOK},
+		{"form data", http.MethodPost, "/books", "application/x-www-form-urlencoded", "title=Dune", http.StatusUnsupportedMediaType},
+		{"plain text", http.MethodPut, "/books/1", "text/plain", "{}", http.StatusUnsupportedMediaType},
+		{"no content type", http.MethodPost, "/books", "", "{}", http.StatusUnsupportedMediaType},
+		{"no body", http.MethodPost, "/books", "", "", http.StatusOK},
+		{"exempt upload", http.MethodPost, "/books/import", "multipart/form-data; boundary=x", "--x--", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/redis"
+)
+
+// featureKeyPrefix namespaces the runtime feature toggles in the cache, so
+// every instance sharing it picks them up without a restart
+const featureKeyPrefix = "settings:feature:"
+
+// Features lists the endpoints that can be switched off per environment.
+// All of them are enabled unless configured otherwise.
+var Features = []string{
+	"stream",        // GET /books/stream
+	"live_events",   // GET /books/events
+	"stats",         // GET /books/stats
+	"validate",      // POST /books/validate
+	"bulk_update",   // PATCH /books
+	"event_history", // GET /admin/events
+}
+
+// IsFeature reports whether name is a known feature
+func IsFeature(name string) bool {
+	for _, feature := range Features {
+		if feature == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Feature answers requests with notFound while the feature name is disabled,
+// so a disabled endpoint looks exactly like one that doesn't exist
+func Feature(name string, notFound gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !FeatureEnabled(ctx.Request.Context(), name) {
+			notFound(ctx)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// FeatureEnabled reports whether the feature name is on. A toggle stored in
+// the cache wins; without one FEATURE_<NAME> (e.g. FEATURE_LIVE_EVENTS=false)
+// decides, and features default to enabled.
+func FeatureEnabled(ctx context.Context, name string) bool {
+	if value, err := redis.BookCache.Get(ctx, featureKeyPrefix+name); err == nil {
+		return value == "true"
+	}
+	return FeatureDefault(name)
+}
+
+// FeatureDefault returns the configured state of name, ignoring runtime
+// toggles
+func FeatureDefault(name string) bool {
+	enabled, err := strconv.ParseBool(os.Getenv("FEATURE_" + strings.ToUpper(name)))
+	return err != nil || enabled
+}
+
+// SetFeature stores a runtime toggle for name, overriding FEATURE_<NAME>
+func SetFeature(ctx context.Context, name string, enabled bool) error {
+	return redis.BookCache.Set(ctx, featureKeyPrefix+name, strconv.FormatBool(enabled), 0)
+}
+
+// ResetFeature drops the runtime toggle for name, so FEATURE_<NAME> applies
+// again
+func ResetFeature(ctx context.Context, name string) error {
+	return redis.BookCache.Del(ctx, featureKeyPrefix+name)
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestFeature(t *testing.T) {
+	testutil.SetupCache(t)
+	router := testutil.NewRouter()
+	notFound := func(ctx *gin.Context) { ctx.Status(http.StatusNotFound) }
+	router.GET("/stats", Feature("stats", notFound), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	check := func(state string, want int) {
+		t.Helper()
+		if w := testutil.Request(router, http.MethodGet, "/stats", ""); w.Code != want {
+			t.Errorf("%s: status = %d, want %d", state, w.Code, want)
+		}
+	}
+
+	check("enabled by default", http.StatusOK)
+	t.Setenv("FEATURE_STATS", "false")
+	check("disabled by config", http.StatusNotFound)
+
+	// Runtime toggles override the configuration until they are reset
+	SetFeature(context.Background(), "stats", true)
+	check("toggled on", http.StatusOK)
+	SetFeature(context.Background(), "stats", false)
+	t.Setenv("FEATURE_STATS", "true")
+	check("toggled off", http.StatusNotFound)
+	ResetFeature(context.Background(), "stats")
+	check("reset", http.StatusOK)
+}
+
+func TestFeatureDefault(t *testing.T) {
+	for _, tt := range []struct {
+		env  string
+		want bool
+	}{{"", true}, {"true", true}, {"0", false}, {"false", false}, {"maybe", true}} {
+		t.Setenv("FEATURE_STREAM", tt.env)
+		if got := FeatureDefault("stream"); got != tt.want {
+			t.Errorf("FEATURE_STREAM=%q: enabled = %t, want %t", tt.env, got, tt.want)
+		}
+	}
+}
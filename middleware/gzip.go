@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultGzipMinSize = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(io.Discard) },
+}
+
+// GzipMinSize returns the smallest response body that gets compressed,
+// configurable through GZIP_MIN_SIZE
+func GzipMinSize() int {
+	size, err := strconv.Atoi(os.Getenv("GZIP_MIN_SIZE"))
+	if err != nil || size < 0 {
+		return defaultGzipMinSize
+	}
+	return size
+}
+
+// Gzip compresses responses for clients that accept gzip. The first minSize
+// bytes are buffered so smaller responses are sent as they are; a Flush
+// before that starts compressing right away so streamed responses aren't held
+// back.
+func Gzip(minSize int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodHead || !acceptsGzip(ctx.GetHeader("Accept-Encoding")) {
+			ctx.Next()
+			return
+		}
+
+		ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+		writer := &gzipWriter{ResponseWriter: ctx.Writer, minSize: minSize}
+		ctx.Writer = writer
+		defer writer.close()
+		ctx.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if coding = strings.TrimSpace(coding); coding != "gzip" && coding != "*" {
+			continue
+		}
+		// "gzip;q=0" explicitly refuses gzip
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			return err == nil && q > 0
+		}
+		return true
+	}
+	return false
+}
+
+type gzipWriter struct {
+	gin.ResponseWriter
+	minSize int
+	buf     []byte
+	// decided is set once the response is known to be compressed (gz != nil)
+	// or passed through
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minSize {
+			return len(data), nil
+		}
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to change
+// its write deadline
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		w.start()
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start decides whether to compress and writes out what was buffered so far.
+// Responses that are already encoded or can't have a body are passed through.
+func (w *gzipWriter) start() error {
+	w.decided = true
+	header := w.Header()
+	status := w.Status()
+	if header.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		// The compressed body differs byte for byte, so a strong ETag of the
+		// identity body must not be reused for it
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close sends a response that stayed below minSize uncompressed, or finishes
+// the gzip stream
+func (w *gzipWriter) close() {
+	if !w.decided {
+		w.decided = true
+		if len(w.buf) > 0 {
+			w.ResponseWriter.Write(w.buf)
+		}
+		return
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
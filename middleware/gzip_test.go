@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func gzipRouter(body string) *gin.Engine {
+	router := testutil.NewRouter()
+	router.Use(Gzip(64))
+	router.GET("/books", func(ctx *gin.Context) {
+		ctx.Header("ETag", `"v1"`)
+		ctx.String(http.StatusOK, body)
+	})
+	router.GET("/export", func(ctx *gin.Context) {
+		// A streamed response flushes before reaching the minimum size
+		ctx.Writer.WriteString("id,title\n")
+		ctx.Writer.Flush()
+		ctx.Writer.WriteString("1,Dune\n")
+	})
+	return router
+}
+
+func TestGzipLargeResponse(t *testing.T) {
+	body := strings.Repeat(`{"title":"Dune"},`, 100)
+	w := testutil.Request(gzipRouter(body), http.MethodGet, "/books", "", "Accept-Encoding", "gzip, deflate")
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", w.Header().Get("Content-Encoding"))
+	}
+	if etag := w.Header().Get("ETag"); etag != `W/"v1"` {
+		t.Errorf("ETag = %q, want the weak form of the identity tag", etag)
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := io.ReadAll(reader)
+	if string(decoded) != body {
+		t.Error("decompressed body differs from the original")
+	}
+}
+
+func TestGzipSkipped(t *testing.T) {
+	large := strings.Repeat("x", 200)
+	tests := []struct {
+		name, body, acceptEncoding string
+	}{
+		{"below the minimum size", "small", "gzip"},
+		{"gzip not accepted", large, "br"},
+		{"gzip refused", large, "gzip;q=0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testutil.Request(gzipRouter(tt.body), http.MethodGet, "/books", "", "Accept-Encoding", tt.acceptEncoding)
+			if encoding := w.Header().Get("Content-Encoding"); encoding != "" {
+				t.Errorf("Content-Encoding = %q, want none", encoding)
+			}
+			if w.Body.String() != tt.body || w.Header().Get("ETag") != `"v1"` {
+				t.Errorf("body or ETag changed: %q, %q", w.Body, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestGzipStreamedResponse(t *testing.T) {
+	w := testutil.Request(gzipRouter(""), http.MethodGet, "/export", "", "Accept-Encoding", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip once the stream flushed", w.Header().Get("Content-Encoding"))
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := io.ReadAll(reader)
+	if string(decoded) != "id,title\n1,Dune\n" {
+		t.Errorf("decompressed body = %q", decoded)
+	}
+}
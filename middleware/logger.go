@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger returns the access log middleware selected by LOG_FORMAT: "json"
+// writes one structured line per request, anything else keeps gin's text log
+func Logger() gin.HandlerFunc {
+	if os.Getenv("LOG_FORMAT") == "json" {
+		return JSONLogger(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+	}
+	return gin.Logger()
+}
+
+// JSONLogger writes an access log entry for every request to logger
+func JSONLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		path := ctx.Request.URL.Path
+		if ctx.Request.URL.RawQuery != "" {
+			path += "?" + ctx.Request.URL.RawQuery
+		}
+
+		ctx.Next()
+
+		attrs := []any{
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", path),
+			slog.Int("status", ctx.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+			slog.String("request_id", GetRequestID(ctx)),
+			slog.Int("bytes", ctx.Writer.Size()),
+		}
+		if id, ok := GetAPIKeyID(ctx); ok {
+			attrs = append(attrs, slog.Uint64("api_key_id", uint64(id)))
+		}
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", ctx.Errors.String()))
+		}
+		logger.Info("request", attrs...)
+	}
+}
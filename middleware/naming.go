@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/rohans540/books-backend/docs"
+)
+
+// snakeCase is the naming convention for every JSON field and query
+// parameter of the API
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// CheckFieldNames reports the schema properties and query parameters of the
+// generated Swagger spec that aren't snake_case, so a json tag or @Param
+// breaking the convention fails at startup instead of reaching clients
+func CheckFieldNames() error {
+	var doc openapi2.T
+	if err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &doc); err != nil {
+		return err
+	}
+
+	var invalid []string
+	for name, schema := range doc.Definitions {
+		if schema.Value == nil {
+			continue
+		}
+		for property := range schema.Value.Properties {
+			if !snakeCase.MatchString(property) {
+				invalid = append(invalid, name+"."+property)
+			}
+		}
+	}
+	for path, item := range doc.Paths {
+		for method, operation := range item.Operations() {
+			for _, param := range operation.Parameters {
+				if param.In == "query" && !snakeCase.MatchString(param.Name) {
+					invalid = append(invalid, method+" "+path+" ?"+param.Name)
+				}
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("field names must be snake_case: %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
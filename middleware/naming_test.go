@@ -0,0 +1,29 @@
+package middleware
+
+import "testing"
+
+func TestCheckFieldNames(t *testing.T) {
+	if err := CheckFieldNames(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]bool{
+		"title":      true,
+		"updated_at": true,
+		"after_id":   true,
+		"isbn13":     true,
+		"updatedAt":  false,
+		"UpdatedAt":  false,
+		"updated-at": false,
+		"_private":   false,
+		"trailing_":  false,
+		"double__":   false,
+		"":           false,
+	} {
+		if got := snakeCase.MatchString(name); got != want {
+			t.Errorf("%q: snake_case = %t, want %t", name, got, want)
+		}
+	}
+}
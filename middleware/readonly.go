@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/redis"
+)
+
+// readOnlyKey holds the runtime read-only toggle, so every instance sharing
+// the cache picks it up without a restart
+const readOnlyKey = "settings:read_only"
+
+// ReadOnly rejects writes with 503 while the service is in read-only mode.
+// GET, HEAD and OPTIONS requests are always let through.
+func ReadOnly() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			ctx.Next()
+			return
+		}
+		if IsReadOnly(ctx.Request.Context()) {
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "service in read-only mode"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// IsReadOnly reports whether writes are currently rejected. READ_ONLY=true
+// forces read-only mode; otherwise the toggle stored in the cache decides.
+func IsReadOnly(ctx context.Context) bool {
+	if os.Getenv("READ_ONLY") == "true" {
+		return true
+	}
+	value, err := redis.BookCache.Get(ctx, readOnlyKey)
+	return err == nil && value == "true"
+}
+
+// SetReadOnly stores the runtime read-only toggle. It has no effect while
+// READ_ONLY=true is set.
+func SetReadOnly(ctx context.Context, enabled bool) error {
+	if !enabled {
+		return redis.BookCache.Del(ctx, readOnlyKey)
+	}
+	return redis.BookCache.Set(ctx, readOnlyKey, "true", 0)
+}
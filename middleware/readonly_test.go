@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func readOnlyRouter() *gin.Engine {
+	router := testutil.NewRouter()
+	router.Use(ReadOnly())
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	router.GET("/books", ok)
+	router.POST("/books", ok)
+	router.DELETE("/books/1", ok)
+	return router
+}
+
+func TestReadOnly(t *testing.T) {
+	testutil.SetupCache(t)
+	router := readOnlyRouter()
+
+	check := func(state string, wantWrite int) {
+		t.Helper()
+		if w := testutil.Request(router, http.MethodGet, "/books", ""); w.Code != http.StatusOK {
+			t.Errorf("%s: GET status = %d, want 200", state, w.Code)
+		}
+		for _, method := range []string{http.MethodPost, http.MethodDelete} {
+			path := "/books"
+			if method == http.MethodDelete {
+				path = "/books/1"
+			}
+			if w := testutil.Request(router, method, path, ""); w.Code != wantWrite {
+				t.Errorf("%s: %s status = %d, want %d", state, method, w.Code, wantWrite)
+			}
+		}
+	}
+
+	check("writable", http.StatusOK)
+
+	// The runtime toggle takes effect on the next request
+	if err := SetReadOnly(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+	check("toggled on", http.StatusServiceUnavailable)
+	SetReadOnly(context.Background(), false)
+	check("toggled off", http.StatusOK)
+
+	t.Setenv("READ_ONLY", "true")
+	check("READ_ONLY=true", http.StatusServiceUnavailable)
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/database"
+)
+
+// RetryBudget gives every request a budget of budget for all its retries
+// together, so a run of transient failures can't keep it retrying long after
+// the client gave up. Zero leaves only the request's deadline as a limit.
+func RetryBudget(budget time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(database.WithRetryBudget(ctx.Request.Context(), budget))
+		ctx.Next()
+	}
+}
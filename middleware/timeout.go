@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultRequestTimeout stays below the server's default write timeout, so
+// the 503 still reaches the client
+const defaultRequestTimeout = 20 * time.Second
+
+// RequestTimeout returns how long a request may take before it is answered
+// with 503, configurable through REQUEST_TIMEOUT. Zero disables the timeout.
+func RequestTimeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv("REQUEST_TIMEOUT"))
+	if err != nil || timeout < 0 {
+		return defaultRequestTimeout
+	}
+	return timeout
+}
+
+// Timeout cancels the request context after timeout and, unless the handler
+// has started its response by then, answers 503 right away. Whatever the
+// handler writes afterwards is discarded. Queries and calls bound to the
+// request context stop at the deadline; the handler still runs until it
+// returns. Requests for the exempt paths, such as streams meant to stay
+// open, are never timed out. A timeout of zero disables it.
+func Timeout(timeout time.Duration, exempt ...string) gin.HandlerFunc {
+	if timeout <= 0 {
+		return func(ctx *gin.Context) { ctx.Next() }
+	}
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+
+	return func(ctx *gin.Context) {
+		if skip[ctx.Request.URL.Path] {
+			ctx.Next()
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
+		writer := &timeoutWriter{ResponseWriter: ctx.Writer, ctx: reqCtx, header: make(http.Header)}
+		ctx.Writer = writer
+		timer := time.AfterFunc(timeout, writer.timeout)
+		defer func() {
+			timer.Stop()
+			writer.finish()
+		}()
+		ctx.Next()
+	}
+}
+
+// timeoutWriter holds the handler's headers back until it writes, so a 503
+// sent on timeout doesn't carry headers meant for the real response. mu
+// serializes the handler's writes with the timeout response.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	// ctx is the request context, whose deadline may pass just before the
+	// timer fires
+	ctx    context.Context
+	mu     sync.Mutex
+	header http.Header
+	// committed is set once the handler started its response, timedOut once
+	// the 503 was sent instead
+	committed bool
+	timedOut  bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.commit() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.commit() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.commit() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.commit() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// Status, Size and Written read the underlying writer, which the 503 may be
+// written to concurrently, so they take mu as well
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Status()
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Size()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Written()
+}
+
+// commit copies the handler's headers on its first write and reports whether
+// it may still write. A handler woken by the deadline can get here before the
+// timer fires, so a first write after the deadline sends the 503 itself.
+// Callers hold mu.
+func (w *timeoutWriter) commit() bool {
+	if !w.committed && !w.timedOut && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.respondTimeout()
+	}
+	if w.timedOut {
+		return false
+	}
+	if !w.committed {
+		w.committed = true
+		header := w.ResponseWriter.Header()
+		for key, values := range w.header {
+			header[key] = values
+		}
+	}
+	return true
+}
+
+// timeout sends the 503 unless the handler already started its response
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.committed || w.timedOut {
+		return
+	}
+	w.respondTimeout()
+}
+
+// respondTimeout writes the 503. Callers hold mu.
+func (w *timeoutWriter) respondTimeout() {
+	w.timedOut = true
+	body, _ := json.Marshal(gin.H{"error": "Request timed out"})
+	w.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+	w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+}
+
+// finish hands the headers of a response without a body, such as a 204, to
+// the underlying writer once the handler returned in time
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.commit()
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestTimeout(t *testing.T) {
+	cancelled := make(chan error, 1)
+	router := testutil.NewRouter()
+	router.Use(Timeout(20*time.Millisecond, "/stream"))
+	slow := func(ctx *gin.Context) {
+		// Stands in for a query bound to the request context
+		<-ctx.Request.Context().Done()
+		cancelled <- ctx.Request.Context().Err()
+		ctx.Header("X-Late", "true")
+		ctx.JSON(http.StatusOK, gin.H{"late": true})
+	}
+	router.GET("/slow", slow)
+	router.GET("/fast", func(ctx *gin.Context) {
+		ctx.Header("X-Fast", "true")
+		ctx.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	router.GET("/stream", func(ctx *gin.Context) {
+		time.Sleep(40 * time.Millisecond)
+		ctx.Status(http.StatusOK)
+	})
+
+	w := testutil.Request(router, http.MethodGet, "/slow", "")
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "Request timed out") {
+		t.Errorf("slow: status = %d, body %s, want the 503", w.Code, w.Body)
+	}
+	if w.Header().Get("X-Late") != "" || strings.Contains(w.Body.String(), "late") {
+		t.Errorf("slow: the late response leaked into the 503: %v %s", w.Header(), w.Body)
+	}
+	if err := <-cancelled; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow: handler context error = %v, want the deadline", err)
+	}
+
+	w = testutil.Request(router, http.MethodGet, "/fast", "")
+	if w.Code != http.StatusOK || w.Header().Get("X-Fast") != "true" {
+		t.Errorf("fast: status = %d, headers %v, want the handler's response", w.Code, w.Header())
+	}
+	if w := testutil.Request(router, http.MethodGet, "/stream", ""); w.Code != http.StatusOK {
+		t.Errorf("exempt: status = %d, want 200", w.Code)
+	}
+}
+
+func TestTimeoutWriterStateIsSafeToRead(t *testing.T) {
+	router := testutil.NewRouter()
+	router.Use(Timeout(10 * time.Millisecond))
+	written := make(chan bool, 1)
+	router.GET("/poll", func(ctx *gin.Context) {
+		// Handlers and middleware check the writer while the 503 may be
+		// written concurrently; run with -race
+		for ctx.Request.Context().Err() == nil {
+			_ = ctx.Writer.Written()
+			_ = ctx.Writer.Status()
+			_ = ctx.Writer.Size()
+		}
+		time.Sleep(5 * time.Millisecond)
+		written <- ctx.Writer.Written()
+	})
+
+	if w := testutil.Request(router, http.MethodGet, "/poll", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+	if !<-written {
+		t.Error("Written() = false after the 503 was sent")
+	}
+}
+
+func TestTimeoutWriterAfterDeadlineBeforeTimer(t *testing.T) {
+	// The handler woke up on the deadline and writes before the timer fired
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	router := testutil.NewRouter()
+	router.GET("/late", func(c *gin.Context) {
+		writer := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx, header: make(http.Header)}
+		c.Writer = writer
+		c.Header("X-Late", "true")
+		c.JSON(http.StatusOK, gin.H{"late": true})
+		writer.finish()
+	})
+
+	w := testutil.Request(router, http.MethodGet, "/late", "")
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "Request timed out") {
+		t.Errorf("status = %d, body %s, want the 503", w.Code, w.Body)
+	}
+	if w.Header().Get("X-Late") != "" || strings.Contains(w.Body.String(), "late") {
+		t.Errorf("the late response leaked into the 503: %v %s", w.Header(), w.Body)
+	}
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var createBooks = &gormigrate.Migration{
+	ID: "202502230001_create_books",
+	Migrate: func(tx *gorm.DB) error {
+		// Snapshot of the table at this version, independent of models.Book
+		type book struct {
+			ID     uint   `gorm:"primaryKey"`
+			Title  string `gorm:"not null"`
+			Author string `gorm:"not null"`
+			Year   int
+		}
+		// AutoMigrate keeps this a no-op on databases created before migrations
+		return tx.Table("books").AutoMigrate(&book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("books")
+	},
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addBookTimestamps = &gormigrate.Migration{
+	ID: "202502230002_add_book_timestamps",
+	Migrate: func(tx *gorm.DB) error {
+		// Existing rows are backfilled with the migration time
+		return tx.Exec(`ALTER TABLE books
+			ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now(),
+			ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now()`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE books DROP COLUMN created_at, DROP COLUMN updated_at`).Error
+	},
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addBookLanguage = &gormigrate.Migration{
+	ID: "202502230003_add_book_language",
+	Migrate: func(tx *gorm.DB) error {
+		// Existing books predate the field and are assumed to be English
+		return tx.Exec(`ALTER TABLE books ADD COLUMN IF NOT EXISTS language varchar(2) NOT NULL DEFAULT 'en'`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE books DROP COLUMN language`).Error
+	},
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addBookSearchIndex = &gormigrate.Migration{
+	ID: "202502230004_add_book_search_index",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_books_search
+			ON books USING GIN (to_tsvector('english', title || ' ' || author))`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`DROP INDEX IF EXISTS idx_books_search`).Error
+	},
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/rohans540/books-backend/models"
+	"gorm.io/gorm"
+)
+
+var slugIndex = uniqueIndex{Name: "idx_books_slug", Table: "books", Columns: []string{"slug"}}
+
+var addBookSlug = &gormigrate.Migration{
+	ID: "202502230005_add_book_slug",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE books ADD COLUMN IF NOT EXISTS slug text`).Error; err != nil {
+			return err
+		}
+
+		// Backfill existing books in id order so the oldest keeps the bare slug
+		var books []struct {
+			ID    uint
+			Title string
+		}
+		if err := tx.Table("books").Select("id, title").Where("slug IS NULL").Order("id").Find(&books).Error; err != nil {
+			return err
+		}
+		taken := make(map[string]bool)
+		for _, book := range books {
+			base := models.Slugify(book.Title)
+			slug := base
+			for n := 2; taken[slug]; n++ {
+				slug = fmt.Sprintf("%s-%d", base, n)
+			}
+			taken[slug] = true
+			if err := tx.Table("books").Where("id = ?", book.ID).Update("slug", slug).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec(`ALTER TABLE books ALTER COLUMN slug SET NOT NULL`).Error; err != nil {
+			return err
+		}
+		return createUniqueIndex(tx, slugIndex)
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE books DROP COLUMN slug`).Error
+	},
+}
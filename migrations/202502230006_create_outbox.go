@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var createOutbox = &gormigrate.Migration{
+	ID: "202502230006_create_outbox",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(`CREATE TABLE IF NOT EXISTS outbox (
+			id bigserial PRIMARY KEY,
+			topic text NOT NULL,
+			payload text NOT NULL,
+			created_at timestamptz NOT NULL DEFAULT now(),
+			sent_at timestamptz
+		)`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_outbox_unsent ON outbox (id) WHERE sent_at IS NULL`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`DROP TABLE outbox`).Error
+	},
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addBookAvailable = &gormigrate.Migration{
+	ID: "202502230007_add_book_available",
+	Migrate: func(tx *gorm.DB) error {
+		// Existing books are on the shelf until someone checks them out
+		return tx.Exec(`ALTER TABLE books ADD COLUMN IF NOT EXISTS available boolean NOT NULL DEFAULT true`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE books DROP COLUMN available`).Error
+	},
+}
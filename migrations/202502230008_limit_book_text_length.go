@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var limitBookTextLength = &gormigrate.Migration{
+	ID: "202502230008_limit_book_text_length",
+	Migrate: func(tx *gorm.DB) error {
+		// Fails rather than truncates if an existing value is too long
+		if err := tx.Exec(`ALTER TABLE books ALTER COLUMN title TYPE varchar(255)`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE books ALTER COLUMN author TYPE varchar(255)`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE books ALTER COLUMN title TYPE text`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE books ALTER COLUMN author TYPE text`).Error
+	},
+}
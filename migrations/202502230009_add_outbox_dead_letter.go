@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addOutboxDeadLetter = &gormigrate.Migration{
+	ID: "202502230009_add_outbox_dead_letter",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS dead_lettered_at timestamptz`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS last_error text`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE outbox DROP COLUMN dead_lettered_at`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE outbox DROP COLUMN last_error`).Error
+	},
+}
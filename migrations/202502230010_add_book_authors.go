@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addBookAuthors = &gormigrate.Migration{
+	ID: "202502230010_add_book_authors",
+	Migrate: func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE books ADD COLUMN IF NOT EXISTS authors jsonb NOT NULL DEFAULT '[]'`,
+			// Every existing book has exactly its single author
+			`UPDATE books SET authors = jsonb_build_array(author) WHERE authors = '[]'`,
+			`CREATE INDEX IF NOT EXISTS idx_books_authors ON books USING GIN (authors)`,
+			// Search over every author, not only the primary one
+			`DROP INDEX IF EXISTS idx_books_search`,
+			`CREATE INDEX idx_books_search ON books USING GIN (to_tsvector('english', title || ' ' || authors::text))`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		// Dropping the column drops both indexes on it
+		if err := tx.Exec(`ALTER TABLE books DROP COLUMN authors`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_books_search
+			ON books USING GIN (to_tsvector('english', title || ' ' || author))`).Error
+	},
+}
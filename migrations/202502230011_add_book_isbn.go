@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var isbnIndex = uniqueIndex{Name: "idx_books_isbn", Table: "books", Columns: []string{"isbn"}}
+
+var addBookISBN = &gormigrate.Migration{
+	ID: "202502230011_add_book_isbn",
+	Migrate: func(tx *gorm.DB) error {
+		// Nullable: books without an ISBN don't conflict with each other
+		if err := tx.Exec(`ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn varchar(13)`).Error; err != nil {
+			return err
+		}
+		return createUniqueIndex(tx, isbnIndex)
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE books DROP COLUMN isbn`).Error
+	},
+}
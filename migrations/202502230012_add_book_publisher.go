@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addBookPublisher = &gormigrate.Migration{
+	ID: "202502230012_add_book_publisher",
+	Migrate: func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE books ADD COLUMN IF NOT EXISTS publisher varchar(255) NOT NULL DEFAULT ''`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_books_publisher ON books (publisher)`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE books DROP COLUMN publisher`).Error
+	},
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addOutboxEventType = &gormigrate.Migration{
+	ID: "202502230013_add_outbox_event_type",
+	Migrate: func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS event_type text NOT NULL DEFAULT ''`,
+			`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS book_id bigint`,
+			// Existing events only have the type and book id in their payload
+			`UPDATE outbox SET event_type = payload::jsonb->>'event', book_id = (payload::jsonb->>'id')::bigint WHERE event_type = ''`,
+			`CREATE INDEX IF NOT EXISTS idx_outbox_event_type ON outbox (event_type, created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_outbox_created_at ON outbox (created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_outbox_book_id ON outbox (book_id)`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		// Dropping the columns drops their indexes
+		if err := tx.Exec(`DROP INDEX IF EXISTS idx_outbox_created_at`).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`ALTER TABLE outbox DROP COLUMN event_type`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE outbox DROP COLUMN book_id`).Error
+	},
+}
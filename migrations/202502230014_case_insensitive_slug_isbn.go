@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// Slugs are stored in lower case and ISBNs in upper case; these indexes keep
+// two books from differing only in case even if a row is written outside the
+// application
+var (
+	slugLowerIndex = uniqueIndex{Name: "idx_books_slug_lower", Table: "books", Columns: []string{"lower(slug)"}}
+	isbnUpperIndex = uniqueIndex{Name: "idx_books_isbn_upper", Table: "books", Columns: []string{"upper(isbn)"}}
+)
+
+var caseInsensitiveSlugISBN = &gormigrate.Migration{
+	ID: "202502230014_case_insensitive_slug_isbn",
+	Migrate: func(tx *gorm.DB) error {
+		if err := createUniqueIndex(tx, slugLowerIndex); err != nil {
+			return err
+		}
+		if err := createUniqueIndex(tx, isbnUpperIndex); err != nil {
+			return err
+		}
+		// The indexes above guarantee the canonical forms are free
+		if err := tx.Exec(`UPDATE books SET slug = lower(slug) WHERE slug <> lower(slug)`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE books SET isbn = upper(isbn) WHERE isbn <> upper(isbn)`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Exec(`DROP INDEX IF EXISTS idx_books_isbn_upper`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DROP INDEX IF EXISTS idx_books_slug_lower`).Error
+	},
+}
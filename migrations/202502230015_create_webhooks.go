@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var createWebhooks = &gormigrate.Migration{
+	ID: "202502230015_create_webhooks",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.Exec(`CREATE TABLE IF NOT EXISTS webhooks (
+			id bigserial PRIMARY KEY,
+			url text NOT NULL,
+			secret text NOT NULL,
+			last_event_id bigint NOT NULL DEFAULT 0,
+			failures integer NOT NULL DEFAULT 0,
+			last_error text,
+			retry_at timestamptz,
+			disabled_at timestamptz,
+			created_at timestamptz NOT NULL DEFAULT now(),
+			updated_at timestamptz NOT NULL DEFAULT now()
+		)`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`DROP TABLE webhooks`).Error
+	},
+}
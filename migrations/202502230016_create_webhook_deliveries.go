@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var createWebhookDeliveries = &gormigrate.Migration{
+	ID: "202502230016_create_webhook_deliveries",
+	Migrate: func(tx *gorm.DB) error {
+		statements := []string{
+			`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id bigserial PRIMARY KEY,
+				webhook_id bigint NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+				event_id bigint NOT NULL,
+				attempt integer NOT NULL,
+				status text NOT NULL,
+				response_code integer,
+				error text,
+				created_at timestamptz NOT NULL DEFAULT now()
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at)`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`DROP TABLE webhook_deliveries`).Error
+	},
+}
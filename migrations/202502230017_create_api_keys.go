@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var createAPIKeys = &gormigrate.Migration{
+	ID: "202502230017_create_api_keys",
+	Migrate: func(tx *gorm.DB) error {
+		statements := []string{
+			`CREATE TABLE IF NOT EXISTS api_keys (
+				id bigserial PRIMARY KEY,
+				name text NOT NULL,
+				prefix text NOT NULL,
+				key_hash text NOT NULL,
+				rate_limit integer NOT NULL DEFAULT 0,
+				revoked_at timestamptz,
+				created_at timestamptz NOT NULL DEFAULT now()
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash)`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`DROP TABLE api_keys`).Error
+	},
+}
@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addWebhookLease = &gormigrate.Migration{
+	ID: "202502230018_add_webhook_lease",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS claimed_until timestamptz`).Error
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Exec(`ALTER TABLE webhooks DROP COLUMN claimed_until`).Error
+	},
+}
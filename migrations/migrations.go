@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// all lists every schema migration in the order it must be applied.
+// Append new migrations at the end; never edit or reorder applied ones.
+var all = []*gormigrate.Migration{
+	createBooks,
+	addBookTimestamps,
+	addBookLanguage,
+	addBookSearchIndex,
+	addBookSlug,
+	createOutbox,
+	addBookAvailable,
+	limitBookTextLength,
+	addOutboxDeadLetter,
+	addBookAuthors,
+	addBookISBN,
+	addBookPublisher,
+	addOutboxEventType,
+	caseInsensitiveSlugISBN,
+	createWebhooks,
+	createWebhookDeliveries,
+	createAPIKeys,
+	addWebhookLease,
+}
+
+func newMigrator(db *gorm.DB) *gormigrate.Gormigrate {
+	return gormigrate.New(db, gormigrate.DefaultOptions, all)
+}
+
+// Up applies every pending migration. Applied versions are recorded in the
+// migrations table.
+func Up(db *gorm.DB) error {
+	return newMigrator(db).Migrate()
+}
+
+// Down reverts the most recently applied migration
+func Down(db *gorm.DB) error {
+	return newMigrator(db).RollbackLast()
+}
@@ -0,0 +1,113 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// uniqueIndex is a unique index whose creation can fail on existing data.
+// Columns may also be expressions such as lower(slug).
+type uniqueIndex struct {
+	Name    string
+	Table   string
+	Columns []string
+}
+
+// uniqueIndexes lists every unique index created through createUniqueIndex,
+// so CheckDuplicates can report conflicts before the migration runs
+var uniqueIndexes = []uniqueIndex{
+	slugIndex,
+	isbnIndex,
+	slugLowerIndex,
+	isbnUpperIndex,
+}
+
+// Duplicate is a group of rows that share the values of a unique index. IDs
+// are in ascending order, so the first one is the oldest row.
+type Duplicate struct {
+	Index  string
+	Values string
+	IDs    []uint
+}
+
+// CheckDuplicates reports the rows that would make one of the unique indexes
+// fail to build, without changing anything
+func CheckDuplicates(db *gorm.DB) ([]Duplicate, error) {
+	var all []Duplicate
+	for _, index := range uniqueIndexes {
+		if !db.Migrator().HasTable(index.Table) {
+			continue
+		}
+		duplicates, err := findDuplicates(db, index)
+		if err != nil {
+			return all, fmt.Errorf("%s: %w", index.Name, err)
+		}
+		all = append(all, duplicates...)
+	}
+	return all, nil
+}
+
+func findDuplicates(tx *gorm.DB, index uniqueIndex) ([]Duplicate, error) {
+	columns := strings.Join(index.Columns, ", ")
+	var rows []struct {
+		SharedValues string
+		IDs          string
+	}
+	// Unique indexes treat NULLs as distinct, so rows with a NULL never conflict
+	err := tx.Raw(`SELECT concat_ws(', ', ` + columns + `) AS shared_values, string_agg(id::text, ',' ORDER BY id) AS ids
+		FROM ` + index.Table + `
+		WHERE ` + strings.Join(index.Columns, " IS NOT NULL AND ") + ` IS NOT NULL
+		GROUP BY ` + columns + `
+		HAVING count(*) > 1
+		ORDER BY min(id)`).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := make([]Duplicate, len(rows))
+	for i, row := range rows {
+		duplicates[i] = Duplicate{Index: index.Name, Values: row.SharedValues}
+		for _, raw := range strings.Split(row.IDs, ",") {
+			id, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			duplicates[i].IDs = append(duplicates[i].IDs, uint(id))
+		}
+	}
+	return duplicates, nil
+}
+
+// createUniqueIndex builds index after checking the table for rows that
+// violate it. Conflicts fail the migration with a report of the offending
+// ids, unless MIGRATION_DEDUPE=true, in which case every duplicate but the
+// oldest row is deleted first.
+func createUniqueIndex(tx *gorm.DB, index uniqueIndex) error {
+	duplicates, err := findDuplicates(tx, index)
+	if err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		if os.Getenv("MIGRATION_DEDUPE") != "true" {
+			return fmt.Errorf("cannot create %s, %d groups of rows share a value (%s); run `migrate check` for the full list or set MIGRATION_DEDUPE=true to keep only the oldest row of each",
+				index.Name, len(duplicates), describeDuplicate(duplicates[0]))
+		}
+		for _, duplicate := range duplicates {
+			log.Printf("Deduplicating %s: keeping %s id %d, deleting %v", index.Name, index.Table, duplicate.IDs[0], duplicate.IDs[1:])
+			if err := tx.Exec(`DELETE FROM `+index.Table+` WHERE id IN ?`, duplicate.IDs[1:]).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS ` + index.Name + ` ON ` + index.Table + ` (` + strings.Join(index.Columns, ", ") + `)`).Error
+}
+
+func describeDuplicate(duplicate Duplicate) string {
+	return fmt.Sprintf("%q in ids %v", duplicate.Values, duplicate.IDs)
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rohans540/books-backend/models"
+	"gorm.io/gorm"
+)
+
+// verifiedModels are the tables Verify checks, with the indexes the queries
+// rely on. Update the index list when a migration adds or renames one.
+var verifiedModels = []struct {
+	model   interface{}
+	indexes []string
+}{
+	{&models.Book{}, []string{"idx_books_slug", "idx_books_search", "idx_books_authors", "idx_books_isbn", "idx_books_publisher", "idx_books_slug_lower", "idx_books_isbn_upper"}},
+	{&models.OutboxEvent{}, []string{"idx_outbox_unsent", "idx_outbox_event_type", "idx_outbox_created_at", "idx_outbox_book_id"}},
+	{&models.Webhook{}, nil},
+	{&models.WebhookDelivery{}, []string{"idx_webhook_deliveries_webhook"}},
+	{&models.APIKey{}, []string{"idx_api_keys_key_hash"}},
+}
+
+// Verify checks that every model's table exists with a column for each of
+// its fields and with the expected indexes. It catches a schema left behind
+// by a failed migration or changed by hand before it shows up as failing
+// queries.
+func Verify(db *gorm.DB) error {
+	migrator := db.Migrator()
+
+	var problems []string
+	for _, verified := range verifiedModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(verified.model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+
+		if !migrator.HasTable(verified.model) {
+			problems = append(problems, "missing table "+table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(verified.model, field.DBName) {
+				problems = append(problems, "missing column "+table+"."+field.DBName)
+			}
+		}
+		for _, index := range verified.indexes {
+			if !migrator.HasIndex(verified.model, index) {
+				problems = append(problems, "missing index "+index+" on "+table)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("unexpected database schema: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
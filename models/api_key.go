@@ -0,0 +1,30 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// APIKey lets a machine client call the API with an X-API-Key header. Only a
+// hash of the key is stored; the key itself is returned once, when it is
+// created. A revoked key is kept so its usage stays on record.
+type APIKey struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"not null" json:"name"`
+	// Prefix is the start of the key, to tell keys apart without storing them
+	Prefix  string `gorm:"not null" json:"prefix"`
+	KeyHash string `gorm:"not null;uniqueIndex:idx_api_keys_key_hash" json:"-"`
+	// RateLimit is how many requests per minute the key may make; 0 means
+	// no limit
+	RateLimit int        `gorm:"not null;default:0" json:"rate_limit"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// HashAPIKey returns the hex SHA-256 of key, which is what APIKey stores.
+// Keys are long random strings, so a fast unsalted hash is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// StringList is a list of strings stored as a JSON array in a jsonb column
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(l))
+	return string(data), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+}
+
+// SyncAuthors keeps Author, the primary author, equal to the first entry of
+// Authors. A book given only Author gets it as its single author.
+func (b *Book) SyncAuthors() {
+	if len(b.Authors) == 0 {
+		if b.Author != "" {
+			b.Authors = StringList{b.Author}
+		}
+		return
+	}
+	b.Author = b.Authors[0]
+}
+
+// BeforeSave keeps Author and Authors in sync for every writer, including
+// the seed
+func (b *Book) BeforeSave(tx *gorm.DB) error {
+	b.SyncAuthors()
+	return nil
+}
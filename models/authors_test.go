@@ -0,0 +1,45 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSyncAuthors(t *testing.T) {
+	tests := []struct {
+		name        string
+		book        Book
+		wantAuthor  string
+		wantAuthors StringList
+	}{
+		{"single author", Book{Author: "Frank Herbert"}, "Frank Herbert", StringList{"Frank Herbert"}},
+		{"authors list", Book{Authors: StringList{"Terry Pratchett", "Neil Gaiman"}}, "Terry Pratchett", StringList{"Terry Pratchett", "Neil Gaiman"}},
+		{"list wins over author", Book{Author: "Someone", Authors: StringList{"Neil Gaiman"}}, "Neil Gaiman", StringList{"Neil Gaiman"}},
+		{"neither", Book{}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.book.SyncAuthors()
+			if tt.book.Author != tt.wantAuthor || !reflect.DeepEqual(tt.book.Authors, tt.wantAuthors) {
+				t.Errorf("author %q, authors %q, want %q, %q", tt.book.Author, tt.book.Authors, tt.wantAuthor, tt.wantAuthors)
+			}
+		})
+	}
+}
+
+func TestStringListRoundTrip(t *testing.T) {
+	value, err := StringList{"Terry Pratchett", "Neil Gaiman"}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scanned StringList
+	if err := scanned.Scan(value); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(scanned, StringList{"Terry Pratchett", "Neil Gaiman"}) {
+		t.Errorf("scanned %q", scanned)
+	}
+	if err := scanned.Scan(42); err == nil {
+		t.Error("scanning an int succeeded")
+	}
+}
@@ -1,14 +1,25 @@
 package models
 
-import "gorm.io/gorm"
+import "time"
 
-type Book struct {
-	ID     uint   `gorm:"primaryKey" json:"id"`
-	Title  string `gorm:"not null" json:"title"`
-	Author string `gorm:"not null" json:"author"`
-	Year   int    `json:"year"`
-}
+// MaxTextLength is the size of the title, author and publisher columns, in
+// characters. It also caps every entry of Authors.
+const MaxTextLength = 255
 
-func MigrateBooks(db *gorm.DB) {
-	db.AutoMigrate(&Book{})
+// Book is a book in the collection. Author is the primary author and always
+// equals the first entry of Authors, which lists co-authors too. The xml tags
+// are used when a client asks for XML instead of JSON.
+type Book struct {
+	ID        uint       `gorm:"primaryKey" json:"id" xml:"id"`
+	Title     string     `gorm:"size:255;not null" json:"title" xml:"title"`
+	Slug      string     `gorm:"uniqueIndex;not null" json:"slug" xml:"slug"`
+	ISBN      *string    `gorm:"size:13;uniqueIndex" json:"isbn" xml:"isbn,omitempty" extensions:"x-nullable"`
+	Author    string     `gorm:"size:255;not null" json:"author" xml:"author"`
+	Authors   StringList `gorm:"type:jsonb;not null;default:'[]'" json:"authors" xml:"authors>author" swaggertype:"array,string"`
+	Year      int        `json:"year" xml:"year" extensions:"x-numeric-string"`
+	Publisher string     `gorm:"size:255;not null;default:''" json:"publisher" xml:"publisher"`
+	Language  string     `gorm:"size:2;not null;default:en" json:"language" xml:"language"`
+	Available bool       `gorm:"not null;default:true" json:"available" xml:"available"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" xml:"updated_at"`
 }
@@ -0,0 +1,67 @@
+package models
+
+import "strings"
+
+// NormalizeISBN strips the hyphens and spaces ISBNs are usually printed with
+// and upper-cases the ISBN-10 check character, so "0-13-110362-8" and
+// "0131103628" are stored and looked up the same way
+func NormalizeISBN(isbn string) string {
+	isbn = strings.NewReplacer("-", "", " ", "").Replace(isbn)
+	return strings.ToUpper(isbn)
+}
+
+// IsValidISBN reports whether a normalized isbn has the shape of an ISBN-10
+// (nine digits and a digit or X) or an ISBN-13 (thirteen digits)
+func IsValidISBN(isbn string) bool {
+	switch len(isbn) {
+	case 10:
+		return allDigits(isbn[:9]) && (isDigit(isbn[9]) || isbn[9] == 'X')
+	case 13:
+		return allDigits(isbn)
+	default:
+		return false
+	}
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// HasValidISBNChecksum reports whether the check digit of an isbn that passes
+// IsValidISBN matches the other digits. ISBN-10 digits are weighted 10 down
+// to 1 and must sum to a multiple of 11, with X standing for 10; ISBN-13
+// digits are weighted 1 and 3 alternately and must sum to a multiple of 10.
+func HasValidISBNChecksum(isbn string) bool {
+	sum := 0
+	switch len(isbn) {
+	case 10:
+		for i := 0; i < 10; i++ {
+			digit := int(isbn[i] - '0')
+			if isbn[i] == 'X' {
+				digit = 10
+			}
+			sum += (10 - i) * digit
+		}
+		return sum%11 == 0
+	case 13:
+		for i := 0; i < 13; i++ {
+			weight := 1
+			if i%2 == 1 {
+				weight = 3
+			}
+			sum += weight * int(isbn[i]-'0')
+		}
+		return sum%10 == 0
+	default:
+		return false
+	}
+}
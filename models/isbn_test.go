@@ -0,0 +1,23 @@
+package models
+
+import "testing"
+
+func TestHasValidISBNChecksum(t *testing.T) {
+	tests := []struct {
+		isbn string
+		want bool
+	}{
+		{"0306406152", true},
+		{"0306406153", false},
+		{"097522980X", true},
+		{"0975229801", false},
+		{"9780306406157", true},
+		{"9780306406158", false},
+		{"030640615", false},
+	}
+	for _, tt := range tests {
+		if got := HasValidISBNChecksum(tt.isbn); got != tt.want {
+			t.Errorf("HasValidISBNChecksum(%q) = %v, want %v", tt.isbn, got, tt.want)
+		}
+	}
+}
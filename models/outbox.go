@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// OutboxEvent is an event written in the same transaction as the change it
+// describes. The outbox relay publishes it and then sets SentAt, or, when it
+// can't be published, forwards it to the dead-letter topic and sets
+// DeadLetteredAt and LastError. Events are kept after publishing, so the
+// table doubles as the change history of every book; EventType and BookID
+// repeat the payload's event and id so the history can be filtered.
+type OutboxEvent struct {
+	ID             uint   `gorm:"primaryKey"`
+	Topic          string `gorm:"not null"`
+	EventType      string `gorm:"not null;default:''"`
+	BookID         *uint
+	Payload        string `gorm:"type:text;not null"`
+	CreatedAt      time.Time
+	SentAt         *time.Time
+	DeadLetteredAt *time.Time
+	LastError      *string `gorm:"type:text"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Slugify turns a title into a lowercase, hyphen-separated URL slug
+func Slugify(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			hyphen = false
+		} else if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "book"
+	}
+	return slug
+}
+
+// NormalizeSlug returns the canonical, lower-case form slugs are stored in,
+// so a lookup for "The-Hobbit" finds "the-hobbit"
+func NormalizeSlug(slug string) string {
+	return strings.ToLower(strings.TrimSpace(slug))
+}
+
+// BeforeCreate assigns a unique slug derived from the title. When the slug
+// is taken, -2, -3, ... is appended until a free one is found.
+func (b *Book) BeforeCreate(tx *gorm.DB) error {
+	if b.Slug != "" {
+		return nil
+	}
+	base := Slugify(b.Title)
+
+	var taken []string
+	err := tx.Session(&gorm.Session{NewDB: true}).Model(&Book{}).
+		Where("slug = ? OR slug LIKE ?", base, base+"-%").
+		Pluck("slug", &taken).Error
+	if err != nil {
+		return err
+	}
+	b.Slug = nextFreeSlug(base, taken)
+	return nil
+}
+
+func nextFreeSlug(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug
+}
@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Dune":                      "dune",
+		"The Left Hand of Darkness": "the-left-hand-of-darkness",
+		"  Hello,   World!  ":       "hello-world",
+		"L'Étranger":                "l-étranger",
+		"2001: A Space Odyssey":     "2001-a-space-odyssey",
+		"!!!":                       "book",
+	}
+	for title, want := range tests {
+		if got := Slugify(title); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestNextFreeSlug(t *testing.T) {
+	tests := []struct {
+		taken []string
+		want  string
+	}{
+		{nil, "dune"},
+		{[]string{"dune"}, "dune-2"},
+		{[]string{"dune", "dune-2"}, "dune-3"},
+		{[]string{"dune", "dune-3"}, "dune-2"},
+		{[]string{"dune-2"}, "dune"},
+	}
+	for _, tt := range tests {
+		if got := nextFreeSlug("dune", tt.taken); got != tt.want {
+			t.Errorf("nextFreeSlug(dune, %v) = %q, want %q", tt.taken, got, tt.want)
+		}
+	}
+}
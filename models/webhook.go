@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Webhook is a URL that receives every book event as a signed POST. Events
+// are read from the outbox in id order; LastEventID is the last one the URL
+// accepted. A failed delivery is retried after RetryAt, and after too many
+// failures in a row the webhook is disabled until it is updated. While an
+// instance delivers a batch, ClaimedUntil keeps the others away from it.
+type Webhook struct {
+	ID  uint   `gorm:"primaryKey" json:"id"`
+	URL string `gorm:"not null" json:"url"`
+	// Secret signs every delivery; it is only returned when the webhook is
+	// created
+	Secret      string     `gorm:"not null" json:"-"`
+	LastEventID uint       `gorm:"not null;default:0" json:"last_event_id"`
+	Failures    int        `gorm:"not null;default:0" json:"failures"`
+	LastError   *string    `gorm:"type:text" json:"last_error"`
+	RetryAt     *time.Time `json:"retry_at"`
+	DisabledAt  *time.Time `json:"disabled_at"`
+	// ClaimedUntil is when the claim of the instance delivering to the
+	// webhook runs out, so a crashed instance doesn't hold it for good
+	ClaimedUntil *time.Time `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Delivery outcomes
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one attempt to deliver an event to a webhook. Attempt
+// counts the tries of the same event, so a failing receiver shows a growing
+// attempt for one event until it accepts it or the webhook is disabled.
+type WebhookDelivery struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	WebhookID uint   `gorm:"not null" json:"webhook_id"`
+	EventID   uint   `gorm:"not null" json:"event_id"`
+	Attempt   int    `gorm:"not null" json:"attempt"`
+	Status    string `gorm:"not null" json:"status"`
+	// ResponseCode is null when the receiver didn't answer
+	ResponseCode *int      `json:"response_code"`
+	Error        *string   `gorm:"type:text" json:"error"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidYear is returned when a book's year is neither a JSON number nor
+// a numeric string
+var ErrInvalidYear = errors.New("Year must be a whole number")
+
+// UnmarshalJSON accepts year both as a JSON number and as a numeric string
+// ("1997"), which form-based frontends tend to send
+func (b *Book) UnmarshalJSON(data []byte) error {
+	// plainBook has Book's fields but not this method, so decoding into it
+	// doesn't recurse
+	type plainBook Book
+	aux := struct {
+		*plainBook
+		Year json.RawMessage `json:"year"`
+	}{plainBook: (*plainBook)(b)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if len(aux.Year) == 0 || string(aux.Year) == "null" {
+		return nil
+	}
+
+	raw := string(aux.Year)
+	var s string
+	if json.Unmarshal(aux.Year, &s) == nil {
+		raw = strings.TrimSpace(s)
+	}
+	year, err := strconv.Atoi(raw)
+	if err != nil {
+		return ErrInvalidYear
+	}
+	b.Year = year
+	return nil
+}
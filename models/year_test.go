@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestBookYearUnmarshal(t *testing.T) {
+	tests := []struct {
+		body string
+		year int
+		err  error
+	}{
+		{`{"year": 1965}`, 1965, nil},
+		{`{"year": "1965"}`, 1965, nil},
+		{`{"year": " 1965 "}`, 1965, nil},
+		{`{"year": "-44"}`, -44, nil},
+		{`{"year": null}`, 0, nil},
+		{`{}`, 0, nil},
+		{`{"year": "sixties"}`, 0, ErrInvalidYear},
+		{`{"year": 1965.5}`, 0, ErrInvalidYear},
+		{`{"year": ""}`, 0, ErrInvalidYear},
+		{`{"year": true}`, 0, ErrInvalidYear},
+	}
+	for _, tt := range tests {
+		var book Book
+		err := json.Unmarshal([]byte(tt.body), &book)
+		if !errors.Is(err, tt.err) || book.Year != tt.year {
+			t.Errorf("%s: year %d, err %v, want %d, %v", tt.body, book.Year, err, tt.year, tt.err)
+		}
+	}
+}
+
+func TestBookUnmarshalKeepsOtherFields(t *testing.T) {
+	var book Book
+	if err := json.Unmarshal([]byte(`{"title": "Dune", "year": "1965", "authors": ["Frank Herbert"]}`), &book); err != nil {
+		t.Fatal(err)
+	}
+	if book.Title != "Dune" || len(book.Authors) != 1 {
+		t.Errorf("book = %+v, want the other fields decoded too", book)
+	}
+}
@@ -0,0 +1,245 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log"
+	"os"
+	"time"
+
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPollInterval = time.Second
+	relayBatchSize      = 100
+)
+
+// eventsCoalesced counts events dropped because a newer one superseded them,
+// served with the other expvars on /debug/vars
+var eventsCoalesced = expvar.NewInt("outbox_events_coalesced")
+
+// Enqueue stores evt for publishing on topic. Call it with the transaction
+// that writes the change so the event is only recorded if the change commits.
+func Enqueue(tx *gorm.DB, topic string, evt kafka.BookEvent) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	bookID := evt.ID
+	return tx.Create(&models.OutboxEvent{Topic: topic, EventType: evt.Event, BookID: &bookID, Payload: string(payload)}).Error
+}
+
+// HistoryFilter selects events from the history. Zero fields don't filter.
+type HistoryFilter struct {
+	EventType string
+	BookID    uint
+	Since     time.Time
+	Until     time.Time
+}
+
+// History returns up to limit events matching filter, newest first,
+// skipping the first offset
+func History(db *gorm.DB, filter HistoryFilter, limit, offset int) ([]models.OutboxEvent, error) {
+	query := db.Model(&models.OutboxEvent{})
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if filter.BookID != 0 {
+		query = query.Where("book_id = ?", filter.BookID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var events []models.OutboxEvent
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events).Error
+	return events, err
+}
+
+// ErrNotDeadLettered is returned by Replay for events that aren't dead-lettered
+var ErrNotDeadLettered = errors.New("event is not dead-lettered")
+
+// DeadLetterTopic returns the topic events that can't be published to topic
+// are forwarded to: KAFKA_DLQ_TOPIC, or topic with a ".dlq" suffix
+func DeadLetterTopic(topic string) string {
+	if dlq := os.Getenv("KAFKA_DLQ_TOPIC"); dlq != "" {
+		return dlq
+	}
+	return topic + ".dlq"
+}
+
+// DeadLetters returns up to limit dead-lettered events, oldest first
+func DeadLetters(db *gorm.DB, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := db.Where("dead_lettered_at IS NOT NULL").Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// Replay puts a dead-lettered event back in the queue so the relay tries to
+// publish it to its original topic again
+func Replay(db *gorm.DB, id uint) error {
+	result := db.Model(&models.OutboxEvent{}).
+		Where("id = ? AND dead_lettered_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"dead_lettered_at": nil, "last_error": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotDeadLettered
+	}
+	return nil
+}
+
+// pollInterval returns how often the relay looks for unsent events,
+// configurable through OUTBOX_POLL_INTERVAL (e.g. "500ms")
+func pollInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("OUTBOX_POLL_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return defaultPollInterval
+	}
+	return interval
+}
+
+// CoalesceWindow returns how close together two events of the same type for
+// the same book must be for only the later one to be published, configurable
+// through EVENT_COALESCE_WINDOW (e.g. "2s"). Zero, the default, publishes
+// every event.
+func CoalesceWindow() time.Duration {
+	window, err := time.ParseDuration(os.Getenv("EVENT_COALESCE_WINDOW"))
+	if err != nil || window < 0 {
+		return 0
+	}
+	return window
+}
+
+// Superseded reports whether events[i] can be dropped in favour of a later
+// event in the batch: the book's next event, of the same type and topic and
+// no more than window after it. The later event carries the newer state, and
+// as only back-to-back events are merged, consumers still see every change of
+// type in order.
+func Superseded(events []models.OutboxEvent, i int, window time.Duration) bool {
+	event := events[i]
+	if window == 0 || event.BookID == nil {
+		return false
+	}
+	for _, next := range events[i+1:] {
+		if next.BookID == nil || *next.BookID != *event.BookID {
+			continue
+		}
+		return next.Topic == event.Topic && next.EventType == event.EventType &&
+			next.CreatedAt.Sub(event.CreatedAt) <= window
+	}
+	return false
+}
+
+// StartRelay publishes unsent outbox events in the background until ctx is
+// cancelled. Events are published in insertion order and marked sent only
+// after the publisher accepts them, so delivery is at-least-once. An event the
+// publisher rejects even after its retries is forwarded to the dead-letter
+// topic and kept in the outbox for inspection and replay; one whose payload
+// can't be decoded is only kept in the outbox. Without a broker the relay
+// doesn't run and events stay queued until one is configured.
+//
+// With EVENT_COALESCE_WINDOW set, events are held back until they are older
+// than the window, and an event followed within it by another of the same type
+// for the same book is marked sent without being published, so bulk edits
+// don't flood consumers with intermediate states.
+func StartRelay(ctx context.Context) {
+	if !kafka.Enabled() {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(pollInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := relayBatch(); err != nil {
+					log.Println("Outbox relay:", err)
+				}
+			}
+		}
+	}()
+}
+
+// relayBatch publishes up to relayBatchSize events. Rows are locked with
+// SKIP LOCKED so several instances can relay concurrently without sending
+// the same event twice.
+func relayBatch() error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var events []models.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND dead_lettered_at IS NULL").
+			Order("id").
+			Limit(relayBatchSize).
+			Find(&events).Error
+		if err != nil {
+			return err
+		}
+
+		window := CoalesceWindow()
+		cutoff := time.Now().Add(-window)
+		for i, event := range events {
+			if window > 0 && event.CreatedAt.After(cutoff) {
+				// Too young to know whether it will be superseded; this and
+				// every later event wait for the next poll to keep the order
+				return nil
+			}
+			if Superseded(events, i, window) {
+				eventsCoalesced.Add(1)
+				if err := tx.Model(&event).Update("sent_at", time.Now()).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			var evt kafka.BookEvent
+			if err := json.Unmarshal([]byte(event.Payload), &evt); err != nil {
+				// Retrying can't fix the payload, and there is no event to
+				// forward to the dead-letter topic: keep the row for
+				// inspection and carry on with the rest of the batch
+				log.Printf("Outbox relay: event %d dead-lettered: decode: %v", event.ID, err)
+				if err := deadLetter(tx, &event, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := kafka.EventPublisher.Publish(event.Topic, evt); err != nil {
+				// When even the dead-letter topic is unreachable the broker is
+				// most likely down: stop so later events aren't sent out of
+				// order; the events already sent are still marked on commit
+				if dlqErr := kafka.EventPublisher.Publish(DeadLetterTopic(event.Topic), evt); dlqErr != nil {
+					log.Printf("Outbox relay: publish event %d: %v (dead-letter: %v)", event.ID, err, dlqErr)
+					return nil
+				}
+				log.Printf("Outbox relay: event %d dead-lettered: %v", event.ID, err)
+				if err := deadLetter(tx, &event, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Model(&event).Update("sent_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deadLetter takes event out of the queue, recording why it couldn't be sent
+func deadLetter(tx *gorm.DB, event *models.OutboxEvent, cause error) error {
+	now, lastError := time.Now(), cause.Error()
+	return tx.Model(event).Updates(models.OutboxEvent{DeadLetteredAt: &now, LastError: &lastError}).Error
+}
@@ -0,0 +1,214 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/kafka"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+	"gorm.io/gorm"
+)
+
+// failingPublisher rejects events for one topic and records the others
+type failingPublisher struct {
+	kafka.MemoryPublisher
+	topic string
+}
+
+func (p *failingPublisher) Publish(topic string, evt kafka.BookEvent) error {
+	if topic == p.topic {
+		return errors.New("broker unavailable")
+	}
+	return p.MemoryPublisher.Publish(topic, evt)
+}
+
+func TestEnqueueOnlyRecordsCommittedChanges(t *testing.T) {
+	db := testutil.SetupDB(t)
+
+	rollback := errors.New("rollback")
+	db.Transaction(func(tx *gorm.DB) error {
+		Enqueue(tx, "book_events", kafka.BookEvent{Event: "book.created", ID: 1})
+		return rollback
+	})
+	db.Transaction(func(tx *gorm.DB) error {
+		return Enqueue(tx, "book_events", kafka.BookEvent{Event: "book.created", ID: 2})
+	})
+
+	var events []models.OutboxEvent
+	db.Find(&events)
+	if len(events) != 1 || *events[0].BookID != 2 || events[0].EventType != "book.created" {
+		t.Errorf("events = %+v, want only the committed one", events)
+	}
+}
+
+func TestRelayPublishesInOrder(t *testing.T) {
+	db := testutil.SetupDB(t)
+	publisher := testutil.SetupPublisher(t)
+	for id := uint(1); id <= 3; id++ {
+		if err := Enqueue(db, "book_events", kafka.BookEvent{Event: "book.updated", ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := relayBatch(); err != nil {
+		t.Fatalf("relayBatch: %v", err)
+	}
+	published := publisher.Events()
+	if len(published) != 3 {
+		t.Fatalf("%d events published, want 3", len(published))
+	}
+	for i, event := range published {
+		if event.Event.ID != uint(i+1) || event.Topic != "book_events" {
+			t.Errorf("event %d = %+v, want book %d on book_events", i, event, i+1)
+		}
+	}
+
+	var unsent int64
+	db.Model(&models.OutboxEvent{}).Where("sent_at IS NULL").Count(&unsent)
+	if unsent != 0 {
+		t.Errorf("%d events left unsent", unsent)
+	}
+
+	// Sent events are not published again
+	relayBatch()
+	if n := len(publisher.Events()); n != 3 {
+		t.Errorf("%d events published after a second poll, want 3", n)
+	}
+}
+
+func TestRelayDeadLettersRejectedEvents(t *testing.T) {
+	db := testutil.SetupDB(t)
+	publisher := &failingPublisher{topic: "book_events"}
+	previous := kafka.EventPublisher
+	kafka.EventPublisher = publisher
+	defer func() { kafka.EventPublisher = previous }()
+	Enqueue(db, "book_events", kafka.BookEvent{Event: "book.created", ID: 1})
+
+	if err := relayBatch(); err != nil {
+		t.Fatalf("relayBatch: %v", err)
+	}
+	published := publisher.Events()
+	if len(published) != 1 || published[0].Topic != "book_events.dlq" {
+		t.Fatalf("published = %+v, want the event on the dead-letter topic", published)
+	}
+
+	dead, err := DeadLetters(db, 10)
+	if err != nil || len(dead) != 1 || dead[0].LastError == nil {
+		t.Fatalf("dead letters = %+v, %v, want the event with its error", dead, err)
+	}
+	if err := Replay(db, dead[0].ID); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if err := Replay(db, dead[0].ID); !errors.Is(err, ErrNotDeadLettered) {
+		t.Errorf("second Replay = %v, want ErrNotDeadLettered", err)
+	}
+}
+
+func TestRelayDeadLettersUndecodableEvents(t *testing.T) {
+	db := testutil.SetupDB(t)
+	publisher := testutil.SetupPublisher(t)
+	corrupt := models.OutboxEvent{Topic: "book_events", EventType: "book.created", Payload: "{not json"}
+	db.Create(&corrupt)
+	Enqueue(db, "book_events", kafka.BookEvent{Event: "book.created", ID: 2})
+
+	if err := relayBatch(); err != nil {
+		t.Fatalf("relayBatch: %v", err)
+	}
+	published := publisher.Events()
+	if len(published) != 1 || published[0].Event.ID != 2 || published[0].Topic != "book_events" {
+		t.Fatalf("published = %+v, want the valid event behind the corrupt one", published)
+	}
+
+	dead, err := DeadLetters(db, 10)
+	if err != nil || len(dead) != 1 || dead[0].ID != corrupt.ID || dead[0].LastError == nil {
+		t.Fatalf("dead letters = %+v, %v, want the corrupt event with its error", dead, err)
+	}
+
+	// The corrupt row is out of the queue, so the next poll sends nothing
+	relayBatch()
+	if n := len(publisher.Events()); n != 1 {
+		t.Errorf("%d events published after a second poll, want 1", n)
+	}
+}
+
+func TestRelayStopsWhenBrokerIsDown(t *testing.T) {
+	db := testutil.SetupDB(t)
+	publisher := &failingPublisher{}
+	previous := kafka.EventPublisher
+	kafka.EventPublisher = publisher
+	defer func() { kafka.EventPublisher = previous }()
+	Enqueue(db, "book_events", kafka.BookEvent{Event: "book.created", ID: 1})
+	Enqueue(db, "book_events", kafka.BookEvent{Event: "book.created", ID: 2})
+
+	// Neither the topic nor its dead-letter topic accept events
+	publisher.topic = "book_events"
+	t.Setenv("KAFKA_DLQ_TOPIC", "book_events")
+	relayBatch()
+
+	var unsent int64
+	db.Model(&models.OutboxEvent{}).Where("sent_at IS NULL AND dead_lettered_at IS NULL").Count(&unsent)
+	if unsent != 2 {
+		t.Errorf("%d events still queued, want both kept for the next poll", unsent)
+	}
+}
+
+func TestRelayCoalescesRapidUpdates(t *testing.T) {
+	t.Setenv("EVENT_COALESCE_WINDOW", "1s")
+	db := testutil.SetupDB(t)
+	publisher := testutil.SetupPublisher(t)
+
+	// A burst of edits to book 1, interleaved with another book's event
+	start := time.Now().Add(-time.Minute)
+	events := []struct {
+		evt kafka.BookEvent
+		at  time.Duration
+	}{
+		{kafka.BookEvent{Event: "book.updated", ID: 1, Title: "Draft 1"}, 0},
+		{kafka.BookEvent{Event: "book.updated", ID: 1, Title: "Draft 2"}, 100 * time.Millisecond},
+		{kafka.BookEvent{Event: "book.created", ID: 2, Title: "Emma"}, 150 * time.Millisecond},
+		{kafka.BookEvent{Event: "book.updated", ID: 1, Title: "Dune"}, 200 * time.Millisecond},
+		// Another type of event is never merged into the updates
+		{kafka.BookEvent{Event: "book.deleted", ID: 1, Title: "Dune"}, 300 * time.Millisecond},
+	}
+	for i, e := range events {
+		if err := Enqueue(db, "book_events", e.evt); err != nil {
+			t.Fatal(err)
+		}
+		db.Model(&models.OutboxEvent{}).Where("id = ?", i+1).Update("created_at", start.Add(e.at))
+	}
+
+	if err := relayBatch(); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, published := range publisher.Events() {
+		got = append(got, fmt.Sprintf("%s %d %s", published.Event.Event, published.Event.ID, published.Event.Title))
+	}
+	want := []string{"book.created 2 Emma", "book.updated 1 Dune", "book.deleted 1 Dune"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("published %q, want %q", got, want)
+	}
+
+	// The superseded events are settled too, not left for the next poll
+	var unsent int64
+	db.Model(&models.OutboxEvent{}).Where("sent_at IS NULL").Count(&unsent)
+	if unsent != 0 {
+		t.Errorf("%d events left unsent", unsent)
+	}
+}
+
+func TestRelayHoldsBackEventsInsideTheWindow(t *testing.T) {
+	t.Setenv("EVENT_COALESCE_WINDOW", "1m")
+	db := testutil.SetupDB(t)
+	publisher := testutil.SetupPublisher(t)
+	Enqueue(db, "book_events", kafka.BookEvent{Event: "book.updated", ID: 1})
+
+	// A later update could still supersede it
+	relayBatch()
+	if n := len(publisher.Events()); n != 0 {
+		t.Errorf("%d events published inside the window, want 0", n)
+	}
+}
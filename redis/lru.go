@@ -0,0 +1,240 @@
+package redis
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lruKeyPrefix limits the LRU to single-book keys (by id, slug and sparse
+// variants); lists are large and invalidated on every write
+const lruKeyPrefix = "book:"
+
+const defaultLRUMaxAge = 5 * time.Second
+
+// LRUSize returns how many single-book entries are kept in process,
+// configurable through CACHE_LRU_SIZE. Zero, the default, disables the LRU.
+func LRUSize() int {
+	size, err := strconv.Atoi(os.Getenv("CACHE_LRU_SIZE"))
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
+
+// LRUMaxAge returns how long an LRU entry is served before it is read from
+// the wrapped cache again, configurable through CACHE_LRU_TTL. It bounds how
+// long another instance's invalidation can go unnoticed.
+func LRUMaxAge() time.Duration {
+	age, err := time.ParseDuration(os.Getenv("CACHE_LRU_TTL"))
+	if err != nil || age <= 0 {
+		return defaultLRUMaxAge
+	}
+	return age
+}
+
+type lruEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// LRUCache keeps the most recently used single-book values of the wrapped
+// Cache in process, so the hottest books are served without a Redis round
+// trip. Every write and invalidation goes through to the wrapped cache and
+// drops the local copy.
+type LRUCache struct {
+	Cache
+	size   int
+	maxAge time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	tags    map[string]map[string]bool
+}
+
+// WithLRU wraps cache with an in-process LRU of size entries. A size of zero
+// returns cache unchanged.
+func WithLRU(cache Cache, size int, maxAge time.Duration) Cache {
+	if size <= 0 {
+		return cache
+	}
+	return &LRUCache{
+		Cache:   cache,
+		size:    size,
+		maxAge:  maxAge,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		tags:    make(map[string]map[string]bool),
+	}
+}
+
+func (c *LRUCache) Get(ctx context.Context, key string) (string, error) {
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+	value, err := c.Cache.Get(ctx, key)
+	if err == nil {
+		c.store(key, value, 0)
+	}
+	return value, err
+}
+
+// MGet serves what it can from the LRU and fetches the rest in one call
+func (c *LRUCache) MGet(ctx context.Context, keys ...string) ([]string, error) {
+	values := make([]string, len(keys))
+	var missing []string
+	var positions []int
+	for i, key := range keys {
+		if value, ok := c.lookup(key); ok {
+			values[i] = value
+			continue
+		}
+		missing = append(missing, key)
+		positions = append(positions, i)
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	fetched, err := c.Cache.MGet(ctx, missing...)
+	for i, value := range fetched {
+		values[positions[i]] = value
+		if value != "" {
+			c.store(missing[i], value, 0)
+		}
+	}
+	return values, err
+}
+
+func (c *LRUCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := c.Cache.Set(ctx, key, value, ttl); err != nil {
+		c.remove(key)
+		return err
+	}
+	c.store(key, toString(value), ttl)
+	return nil
+}
+
+func (c *LRUCache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	if err := c.Cache.MSet(ctx, values, ttl); err != nil {
+		for key := range values {
+			c.remove(key)
+		}
+		return err
+	}
+	for key, value := range values {
+		c.store(key, toString(value), ttl)
+	}
+	return nil
+}
+
+func (c *LRUCache) Del(ctx context.Context, keys ...string) error {
+	c.remove(keys...)
+	return c.Cache.Del(ctx, keys...)
+}
+
+func (c *LRUCache) DeletePattern(ctx context.Context, pattern string) error {
+	c.mu.Lock()
+	for key, element := range c.entries {
+		if ok, _ := path.Match(pattern, key); ok {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+	return c.Cache.DeletePattern(ctx, pattern)
+}
+
+// Tag also remembers tagged single-book keys locally, so InvalidateTag can
+// drop their LRU entries without asking the wrapped cache for the members
+func (c *LRUCache) Tag(ctx context.Context, tag string, keys ...string) error {
+	c.mu.Lock()
+	for _, key := range keys {
+		if !strings.HasPrefix(key, lruKeyPrefix) {
+			continue
+		}
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]bool)
+		}
+		c.tags[tag][key] = true
+	}
+	c.mu.Unlock()
+	return c.Cache.Tag(ctx, tag, keys...)
+}
+
+func (c *LRUCache) InvalidateTag(ctx context.Context, tag string) error {
+	c.mu.Lock()
+	keys := c.tags[tag]
+	delete(c.tags, tag)
+	c.mu.Unlock()
+	for key := range keys {
+		c.remove(key)
+	}
+	return c.Cache.InvalidateTag(ctx, tag)
+}
+
+func (c *LRUCache) lookup(key string) (string, bool) {
+	if !strings.HasPrefix(key, lruKeyPrefix) {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// store keeps value for at most maxAge, or ttl when that is shorter, and
+// evicts the least recently used entry once the LRU is full
+func (c *LRUCache) store(key, value string, ttl time.Duration) {
+	if !strings.HasPrefix(key, lruKeyPrefix) {
+		return
+	}
+	age := c.maxAge
+	if ttl > 0 && ttl < age {
+		age = ttl
+	}
+	entry := &lruEntry{key: key, value: value, expiresAt: time.Now().Add(age)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (c *LRUCache) remove(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingCache counts the reads that reach the wrapped cache
+type countingCache struct {
+	Cache
+	gets int
+}
+
+func (c *countingCache) Get(ctx context.Context, key string) (string, error) {
+	c.gets++
+	return c.Cache.Get(ctx, key)
+}
+
+func newTestLRU(size int, maxAge time.Duration) (Cache, *MemoryCache, *countingCache) {
+	backend := NewMemoryCache()
+	counting := &countingCache{Cache: backend}
+	return WithLRU(counting, size, maxAge), backend, counting
+}
+
+func TestLRUServesHotBooksLocally(t *testing.T) {
+	cache, backend, counting := newTestLRU(10, time.Minute)
+	cache.Set(ctx, "book:1", "dune", 0)
+
+	// Changed behind the LRU's back, so only a local hit still sees "dune"
+	backend.Set(ctx, "book:1", "changed", 0)
+	if value, _ := cache.Get(ctx, "book:1"); value != "dune" || counting.gets != 0 {
+		t.Errorf("Get = %q after %d backend reads, want the local copy", value, counting.gets)
+	}
+
+	// Lists are not kept locally
+	cache.Set(ctx, "books", "[]", 0)
+	cache.Get(ctx, "books")
+	if counting.gets != 1 {
+		t.Errorf("%d backend reads for a list, want 1", counting.gets)
+	}
+}
+
+func TestLRUFallsBackToWrappedCache(t *testing.T) {
+	cache, backend, counting := newTestLRU(10, 10*time.Millisecond)
+	backend.Set(ctx, "book:1", "dune", 0)
+
+	if value, _ := cache.Get(ctx, "book:1"); value != "dune" || counting.gets != 1 {
+		t.Fatalf("first Get = %q after %d backend reads, want a backend read", value, counting.gets)
+	}
+	cache.Get(ctx, "book:1")
+	if counting.gets != 1 {
+		t.Errorf("second Get read the backend again")
+	}
+
+	// Entries older than the max age are read again
+	time.Sleep(20 * time.Millisecond)
+	backend.Set(ctx, "book:1", "dune messiah", 0)
+	if value, _ := cache.Get(ctx, "book:1"); value != "dune messiah" {
+		t.Errorf("Get after the max age = %q, want the backend value", value)
+	}
+}
+
+func TestLRUInvalidation(t *testing.T) {
+	cache, _, _ := newTestLRU(10, time.Minute)
+	for _, key := range []string{"book:1", "book:2", "book:3:fields=title", "book:slug:dune"} {
+		cache.Set(ctx, key, "v", 0)
+	}
+	cache.Tag(ctx, "tag:books", "book:slug:dune")
+
+	cache.Del(ctx, "book:1")
+	cache.DeletePattern(ctx, "book:3:fields=*")
+	cache.InvalidateTag(ctx, "tag:books")
+	for _, key := range []string{"book:1", "book:3:fields=title", "book:slug:dune"} {
+		if _, err := cache.Get(ctx, key); !errors.Is(err, ErrCacheMiss) {
+			t.Errorf("%s survived its invalidation: %v", key, err)
+		}
+	}
+	if _, err := cache.Get(ctx, "book:2"); err != nil {
+		t.Errorf("book:2 was invalidated too: %v", err)
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, backend, _ := newTestLRU(2, time.Minute)
+	cache.Set(ctx, "book:1", "one", 0)
+	cache.Set(ctx, "book:2", "two", 0)
+	cache.Get(ctx, "book:1")
+	cache.Set(ctx, "book:3", "three", 0)
+
+	// book:2 was evicted, so the backend's changed value shows through
+	backend.Set(ctx, "book:1", "changed", 0)
+	backend.Set(ctx, "book:2", "changed", 0)
+	if value, _ := cache.Get(ctx, "book:1"); value != "one" {
+		t.Errorf("book:1 = %q, want it kept as recently used", value)
+	}
+	if value, _ := cache.Get(ctx, "book:2"); value != "changed" {
+		t.Errorf("book:2 = %q, want it evicted", value)
+	}
+}
+
+func TestWithLRUDisabled(t *testing.T) {
+	backend := NewMemoryCache()
+	if WithLRU(backend, 0, time.Minute) != Cache(backend) {
+		t.Error("a size of zero wrapped the cache")
+	}
+}
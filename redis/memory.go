@@ -0,0 +1,223 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// NoopCache never stores anything, so every Get is a miss
+type NoopCache struct{}
+
+func (NoopCache) Get(ctx context.Context, key string) (string, error) {
+	return "", ErrCacheMiss
+}
+
+func (NoopCache) MGet(ctx context.Context, keys ...string) ([]string, error) {
+	return make([]string, len(keys)), nil
+}
+
+func (NoopCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return nil
+}
+
+func (NoopCache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	return nil
+}
+
+func (NoopCache) Del(ctx context.Context, keys ...string) error {
+	return nil
+}
+
+func (NoopCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (NoopCache) DeletePattern(ctx context.Context, pattern string) error {
+	return nil
+}
+
+func (NoopCache) Tag(ctx context.Context, tag string, keys ...string) error {
+	return nil
+}
+
+func (NoopCache) InvalidateTag(ctx context.Context, tag string) error {
+	return nil
+}
+
+func (NoopCache) Keys(ctx context.Context, pattern string, limit int) ([]KeyInfo, error) {
+	return nil, nil
+}
+
+func (NoopCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// MemoryCache is a process-local Cache for running without Redis
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	tags    map[string]map[string]bool
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]memoryEntry),
+		tags:    make(map[string]map[string]bool),
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", ErrCacheMiss
+	}
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return "", ErrCacheMiss
+	}
+	return entry.value, nil
+}
+
+func (c *MemoryCache) MGet(ctx context.Context, keys ...string) ([]string, error) {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i], _ = c.Get(ctx, key)
+	}
+	return values, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	entry := memoryEntry{value: toString(value)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	return nil
+}
+
+func (c *MemoryCache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	for key, value := range values {
+		c.Set(ctx, key, value, ttl)
+	}
+	return nil
+}
+
+func (c *MemoryCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		ok = false
+	}
+	var count int64
+	if ok {
+		count, _ = strconv.ParseInt(entry.value, 10, 64)
+	} else {
+		entry = memoryEntry{}
+		if ttl > 0 {
+			entry.expiresAt = time.Now().Add(ttl)
+		}
+	}
+	count++
+	entry.value = strconv.FormatInt(count, 10)
+	c.entries[key] = entry
+	return count, nil
+}
+
+func (c *MemoryCache) Del(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+// DeletePattern removes every key matching a Redis-style glob pattern
+func (c *MemoryCache) DeletePattern(ctx context.Context, pattern string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if ok, _ := path.Match(pattern, key); ok {
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) Tag(ctx context.Context, tag string, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tags[tag] == nil {
+		c.tags[tag] = make(map[string]bool)
+	}
+	for _, key := range keys {
+		c.tags[tag][key] = true
+	}
+	return nil
+}
+
+func (c *MemoryCache) InvalidateTag(ctx context.Context, tag string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.tags[tag] {
+		delete(c.entries, key)
+	}
+	delete(c.tags, tag)
+	return nil
+}
+
+func (c *MemoryCache) Keys(ctx context.Context, pattern string, limit int) ([]KeyInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var infos []KeyInfo
+	now := time.Now()
+	for key, entry := range c.entries {
+		if len(infos) >= limit {
+			break
+		}
+		if ok, _ := path.Match(pattern, key); !ok {
+			continue
+		}
+		ttl := time.Duration(-1)
+		if !entry.expiresAt.IsZero() {
+			if ttl = entry.expiresAt.Sub(now); ttl <= 0 {
+				continue
+			}
+		}
+		infos = append(infos, KeyInfo{Key: key, TTL: ttl})
+	}
+	return infos, nil
+}
+
+func (c *MemoryCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
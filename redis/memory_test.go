@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache()
+
+	if _, err := cache.Get(ctx, "book:1"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Get on empty cache = %v, want ErrCacheMiss", err)
+	}
+	cache.Set(ctx, "book:1", []byte(`{"id":1}`), 0)
+	if value, err := cache.Get(ctx, "book:1"); err != nil || value != `{"id":1}` {
+		t.Errorf("Get = %q, %v, want the stored value", value, err)
+	}
+
+	cache.Del(ctx, "book:1")
+	if _, err := cache.Get(ctx, "book:1"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get after Del = %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	cache := NewMemoryCache()
+	cache.Set(ctx, "books", "[]", 10*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	if _, err := cache.Get(ctx, "books"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get after the TTL = %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestNoopCacheAlwaysMisses(t *testing.T) {
+	var cache Cache = NoopCache{}
+	if err := cache.Set(context.Background(), "books", "[]", 0); err != nil {
+		t.Fatalf("Set = %v", err)
+	}
+	if _, err := cache.Get(context.Background(), "books"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get = %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestConnectRedisWithoutAddrUsesMemoryCache(t *testing.T) {
+	t.Setenv("REDIS_ADDR", "")
+	t.Setenv("REDIS_CLUSTER_ADDRS", "")
+	os.Unsetenv("REDIS_ADDR")
+	previous := BookCache
+	defer func() { BookCache = previous }()
+
+	ConnectRedis()
+	if err := BookCache.Set(ctx, "books", "[]", 0); err != nil {
+		t.Fatalf("Set = %v", err)
+	}
+	if value, err := BookCache.Get(ctx, "books"); err != nil || value != "[]" {
+		t.Errorf("Get = %q, %v, want the in-memory cache to keep the value", value, err)
+	}
+}
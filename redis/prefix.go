@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// PrefixedCache namespaces every key, tag and pattern of the wrapped Cache
+// with prefix, so environments sharing one Redis don't read each other's keys
+type PrefixedCache struct {
+	Cache
+	prefix string
+}
+
+// WithPrefix wraps cache so its keys become "<prefix>:<key>". An empty prefix
+// returns cache unchanged.
+func WithPrefix(cache Cache, prefix string) Cache {
+	if prefix == "" {
+		return cache
+	}
+	return &PrefixedCache{Cache: cache, prefix: prefix + ":"}
+}
+
+func (c *PrefixedCache) key(key string) string {
+	return c.prefix + key
+}
+
+func (c *PrefixedCache) keys(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.key(key)
+	}
+	return prefixed
+}
+
+func (c *PrefixedCache) Get(ctx context.Context, key string) (string, error) {
+	return c.Cache.Get(ctx, c.key(key))
+}
+
+func (c *PrefixedCache) MGet(ctx context.Context, keys ...string) ([]string, error) {
+	return c.Cache.MGet(ctx, c.keys(keys)...)
+}
+
+func (c *PrefixedCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.Cache.Set(ctx, c.key(key), value, ttl)
+}
+
+func (c *PrefixedCache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	prefixed := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		prefixed[c.key(key)] = value
+	}
+	return c.Cache.MSet(ctx, prefixed, ttl)
+}
+
+func (c *PrefixedCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.Cache.Incr(ctx, c.key(key), ttl)
+}
+
+func (c *PrefixedCache) Del(ctx context.Context, keys ...string) error {
+	return c.Cache.Del(ctx, c.keys(keys)...)
+}
+
+func (c *PrefixedCache) DeletePattern(ctx context.Context, pattern string) error {
+	return c.Cache.DeletePattern(ctx, c.key(pattern))
+}
+
+// Tag stores the prefixed keys in the prefixed tag, so InvalidateTag on the
+// wrapped cache deletes them as they are
+func (c *PrefixedCache) Tag(ctx context.Context, tag string, keys ...string) error {
+	return c.Cache.Tag(ctx, c.key(tag), c.keys(keys)...)
+}
+
+func (c *PrefixedCache) InvalidateTag(ctx context.Context, tag string) error {
+	return c.Cache.InvalidateTag(ctx, c.key(tag))
+}
+
+// Keys lists keys in this namespace and returns them without the prefix
+func (c *PrefixedCache) Keys(ctx context.Context, pattern string, limit int) ([]KeyInfo, error) {
+	infos, err := c.Cache.Keys(ctx, c.key(pattern), limit)
+	for i := range infos {
+		infos[i].Key = strings.TrimPrefix(infos[i].Key, c.prefix)
+	}
+	return infos, err
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPrefixedCacheNamespacesEveryKey(t *testing.T) {
+	shared := NewMemoryCache()
+	staging := WithPrefix(shared, "staging")
+	prod := WithPrefix(shared, "prod")
+
+	staging.Set(ctx, "book:1", "staging book", 0)
+	staging.MSet(ctx, map[string]interface{}{"book:2": "b", "books": "[]"}, 0)
+	staging.Incr(ctx, "books:version", 0)
+	staging.Tag(ctx, "tag:books", "books")
+	prod.Set(ctx, "book:1", "prod book", 0)
+
+	infos, _ := shared.Keys(ctx, "*", 100)
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Key, "staging:") && !strings.HasPrefix(info.Key, "prod:") {
+			t.Errorf("key %q is not namespaced", info.Key)
+		}
+	}
+	if value, _ := staging.Get(ctx, "book:1"); value != "staging book" {
+		t.Errorf("staging book:1 = %q, want its own value", value)
+	}
+	if value, _ := prod.Get(ctx, "book:1"); value != "prod book" {
+		t.Errorf("prod book:1 = %q, want its own value", value)
+	}
+
+	// Keys come back without the prefix
+	own, _ := staging.Keys(ctx, "book:*", 100)
+	if len(own) != 2 || strings.HasPrefix(own[0].Key, "staging:") {
+		t.Errorf("staging keys = %+v, want book:1 and book:2 unprefixed", own)
+	}
+
+	staging.DeletePattern(ctx, "book:*")
+	staging.InvalidateTag(ctx, "tag:books")
+	if _, err := staging.Get(ctx, "books"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("tagged key survived InvalidateTag: %v", err)
+	}
+	if _, err := prod.Get(ctx, "book:1"); err != nil {
+		t.Errorf("DeletePattern in staging deleted a prod key: %v", err)
+	}
+}
+
+func TestWithEmptyPrefix(t *testing.T) {
+	cache := NewMemoryCache()
+	if WithPrefix(cache, "") != Cache(cache) {
+		t.Error("an empty prefix wrapped the cache")
+	}
+}
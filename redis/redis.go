@@ -2,24 +2,313 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/rohans540/books-backend/startup"
 )
 
-var RedisClient *redis.Client
+// ErrCacheMiss is returned by Get when the key is not cached
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache is the key/value store the controllers cache responses in
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	// MGet returns the values of keys in order, with "" for every miss
+	MGet(ctx context.Context, keys ...string) ([]string, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// MSet stores every value of values under its key with the same ttl
+	MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	// Incr adds one to the counter at key and returns the new count. A new
+	// counter expires after ttl, or never when ttl is zero.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	DeletePattern(ctx context.Context, pattern string) error
+	// Tag records keys under tag so InvalidateTag can delete them together
+	Tag(ctx context.Context, tag string, keys ...string) error
+	// InvalidateTag deletes every key recorded under tag, and the tag itself
+	InvalidateTag(ctx context.Context, tag string) error
+	// Keys returns up to limit keys matching pattern with their remaining TTL
+	Keys(ctx context.Context, pattern string, limit int) ([]KeyInfo, error)
+	Ping(ctx context.Context) error
+}
+
+// KeyInfo describes a cached key. TTL is negative for keys that don't expire.
+type KeyInfo struct {
+	Key string
+	TTL time.Duration
+}
+
+// BookCache is the cache used by the controllers. It stays a no-op until
+// ConnectRedis configures a backend.
+var BookCache Cache = NoopCache{}
+
 var ctx = context.Background()
 
+// scanBatchSize is the COUNT hint passed to SCAN
+const scanBatchSize = 100
+
+// ConnectRedis connects to the Redis Cluster seed nodes in
+// REDIS_CLUSTER_ADDRS, or to the single node at REDIS_ADDR, and falls back to
+// an in-memory cache when neither is set. Keys are namespaced with
+// CACHE_PREFIX when it is set, and hot single-book keys are kept in process
+// when CACHE_LRU_SIZE is set.
 func ConnectRedis() {
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr: os.Getenv("REDIS_ADDR"),
-	})
+	prefix := os.Getenv("CACHE_PREFIX")
+	addr := os.Getenv("REDIS_ADDR")
+	cluster := clusterAddrs()
+	if addr == "" && len(cluster) == 0 {
+		fmt.Println("REDIS_ADDR not set, using in-memory cache")
+		BookCache = WithLRU(WithPrefix(NewMemoryCache(), prefix), LRUSize(), LRUMaxAge())
+		return
+	}
 
-	_, err := RedisClient.Ping(ctx).Result()
+	var client redis.UniversalClient
+	if len(cluster) > 0 {
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs: cluster,
+		})
+	} else {
+		client = redis.NewClient(&redis.Options{
+			Addr: addr,
+		})
+	}
+
+	err := startup.WaitFor("Redis", func() error {
+		return client.Ping(ctx).Err()
+	})
 	if err != nil {
 		fmt.Println("Failed to connect to Redis:", err)
 	} else {
 		fmt.Println("Connected to Redis")
 	}
+	BookCache = WithLRU(WithPrefix(&RedisCache{client: client}, prefix), LRUSize(), LRUMaxAge())
+}
+
+// clusterAddrs reads the comma-separated seed nodes of REDIS_CLUSTER_ADDRS
+func clusterAddrs() []string {
+	var addrs []string
+	for _, addr := range strings.Split(os.Getenv("REDIS_CLUSTER_ADDRS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// RedisCache implements Cache on top of a single-node or cluster Redis
+// client. With a cluster, keys on a failing node return errors while keys on
+// the healthy nodes keep working; callers treat those errors as misses and
+// fall back to the database.
+type RedisCache struct {
+	client redis.UniversalClient
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
+	val, err := c.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", ErrCacheMiss
+	}
+	return val, err
+}
+
+// MGet reads keys in a single pipelined round trip. Each key gets its own GET
+// so keys may hash to different cluster slots, and a key on a failing node
+// is returned as a miss without losing the others. The error lists the keys
+// that failed.
+func (c *RedisCache) MGet(ctx context.Context, keys ...string) ([]string, error) {
+	values := make([]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	pipe.Exec(ctx)
+
+	var errs []error
+	for i, cmd := range cmds {
+		value, err := cmd.Result()
+		switch {
+		case err == nil:
+			values[i] = value
+		case err != redis.Nil:
+			errs = append(errs, fmt.Errorf("get %s: %w", keys[i], err))
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *RedisCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	count, err := c.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 && ttl > 0 {
+		err = c.client.Expire(ctx, key, ttl).Err()
+	}
+	return count, err
+}
+
+// MSet writes values in a single pipelined round trip. Each key gets its own
+// SET, since MSET can't set an expiry.
+func (c *RedisCache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	pipe := c.client.Pipeline()
+	for key, value := range values {
+		pipe.Set(ctx, key, value, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Del removes keys in a single pipelined round trip. Each key gets its own
+// DEL so this also works when keys hash to different cluster slots.
+func (c *RedisCache) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Del(ctx, key)
+	}
+	pipe.Exec(ctx)
+
+	var errs []error
+	for i, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("del %s: %w", keys[i], err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		log.Printf("Failed to delete %d of %d cache keys: %v", len(errs), len(keys), err)
+		return err
+	}
+	return nil
+}
+
+// DeletePattern removes every key matching pattern, using SCAN so large
+// keyspaces don't block the server. Matches are deleted one pipeline per
+// SCAN page, so invalidating N keys costs about N/scanBatchSize round trips
+// instead of N.
+func (c *RedisCache) DeletePattern(ctx context.Context, pattern string) error {
+	return c.scan(ctx, pattern, func(keys []string) error {
+		return c.Del(ctx, keys...)
+	})
+}
+
+// errScanDone stops a scan early without reporting an error
+var errScanDone = errors.New("scan done")
+
+// scan calls fn with each page of keys matching pattern until fn returns an
+// error. SCAN only walks the node it is sent to, so a cluster is scanned
+// master by master; a failing master doesn't stop the others.
+func (c *RedisCache) scan(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	cluster, ok := c.client.(*redis.ClusterClient)
+	if !ok {
+		return scanNode(ctx, c.client, pattern, fn)
+	}
+
+	var mu sync.Mutex
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return scanNode(ctx, node, pattern, func(keys []string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return fn(keys)
+		})
+	})
+}
+
+func scanNode(ctx context.Context, client redis.Cmdable, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+		if err := fn(keys); err != nil {
+			return err
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Tag adds keys to the Redis set named tag
+func (c *RedisCache) Tag(ctx context.Context, tag string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	members := make([]interface{}, len(keys))
+	for i, key := range keys {
+		members[i] = key
+	}
+	return c.client.SAdd(ctx, tag, members...).Err()
+}
+
+// InvalidateTag reads the tag set and deletes its members and the set in one
+// pipelined pass, without scanning the keyspace
+func (c *RedisCache) InvalidateTag(ctx context.Context, tag string) error {
+	keys, err := c.client.SMembers(ctx, tag).Result()
+	if err != nil {
+		return err
+	}
+	return c.Del(ctx, append(keys, tag)...)
+}
+
+// Keys SCANs for pattern and fetches the TTLs of each page in one pipeline
+func (c *RedisCache) Keys(ctx context.Context, pattern string, limit int) ([]KeyInfo, error) {
+	var infos []KeyInfo
+	err := c.scan(ctx, pattern, func(keys []string) error {
+		if len(keys) > limit-len(infos) {
+			keys = keys[:limit-len(infos)]
+		}
+
+		pipe := c.client.Pipeline()
+		cmds := make([]*redis.DurationCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.TTL(ctx, key)
+		}
+		if len(keys) > 0 {
+			if _, err := pipe.Exec(ctx); err != nil {
+				return err
+			}
+		}
+		for i, key := range keys {
+			infos = append(infos, KeyInfo{Key: key, TTL: cmds[i].Val()})
+		}
+
+		if len(infos) >= limit {
+			return errScanDone
+		}
+		return nil
+	})
+	if errors.Is(err, errScanDone) {
+		err = nil
+	}
+	return infos, err
+}
+
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
 }
@@ -1,17 +1,133 @@
 package routes
 
 import (
+	"expvar"
+	"os"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/rohans540/books-backend/controllers"
+	"github.com/rohans540/books-backend/middleware"
 )
 
+// BasePath reads BASE_PATH, the prefix the whole API is mounted under when
+// a gateway forwards it from a sub-path (e.g. "/api/books-service"). It is
+// empty by default, mounting the API at the root.
+func BasePath() string {
+	base := strings.Trim(strings.TrimSpace(os.Getenv("BASE_PATH")), "/")
+	if base == "" {
+		return ""
+	}
+	return "/" + base
+}
+
+// SetupRoutes mounts every API version on the router, under BasePath.
+//
+// To introduce a new version, add a registerV2 function next to registerV1
+// and mount it on its own "/v2" group below. Older versions stay mounted
+// until their deprecation period is over.
 func SetupRoutes(router *gin.Engine) {
-	api := router.Group("/books")
+	// Unknown paths and methods get the same JSON errors as everything else
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(controllers.RouteNotFound)
+	router.NoMethod(controllers.MethodNotAllowed)
+	configureSlashes(router)
+
+	root := router.Group(BasePath())
+	root.GET("/", controllers.RootInfo(BasePath()))
+
+	// Kubernetes probes are unversioned
+	root.GET("/livez", controllers.Livez)
+	root.GET("/readyz", controllers.Readyz)
+	root.GET("/healthz", controllers.Readyz)
+	root.GET("/version", controllers.GetVersion)
+
+	// Runtime and publish metrics (expvar), for operators only
+	root.GET("/debug/vars", middleware.RequireAdmin(), gin.WrapH(expvar.Handler()))
+
+	registerV1(root.Group("/v1"))
+
+	// Unversioned aliases kept for existing clients during the deprecation period
+	legacy := root.Group("", deprecated)
+	registerV1(legacy)
+}
+
+// feature gates a route behind a feature flag; disabled routes answer like
+// unknown ones
+func feature(name string) gin.HandlerFunc {
+	return middleware.Feature(name, controllers.RouteNotFound)
+}
+
+func registerV1(group *gin.RouterGroup) {
+	// Book routes accept API keys; admin routes use the admin token instead
+	clients := group.Group("", middleware.APIKeyAuth())
+
+	// Validation, lookups and searches don't write anything, so they stay
+	// available in read-only mode
+	clients.POST("/books/validate", feature("validate"), controllers.ValidateBookPayload)
+	clients.POST("/books/lookup", controllers.LookupBooksByISBN)
+	clients.POST("/books/search", controllers.SearchBooks)
+
+	// Writes are rejected with 503 while the service is in read-only mode
+	api := clients.Group("/books", middleware.ReadOnly())
 	{
 		api.GET("", controllers.GetBooks)
+		api.GET("/count", controllers.CountBooks)
+		api.GET("/recent", controllers.GetRecentBooks)
+		api.GET("/random", controllers.GetRandomBook)
+		api.GET("/stream", feature("stream"), controllers.StreamBooks)
+		api.GET("/export.json", controllers.ExportBooks)
+		api.GET("/events", feature("live_events"), controllers.StreamBookEvents)
+		api.GET("/authors", controllers.GetAuthors)
+		api.GET("/publishers", controllers.GetPublishers)
+		api.GET("/stats", feature("stats"), controllers.GetBookStats)
+		api.GET("/diff", controllers.GetBookDiff)
+		api.GET("/slug/:slug", controllers.GetBookBySlug)
 		api.GET("/:id", controllers.GetBookByID)
 		api.POST("", controllers.CreateBook)
+		api.PATCH("", feature("bulk_update"), middleware.RequireAdmin(), controllers.BulkUpdateBooks)
 		api.PUT("/:id", controllers.UpdateBook)
+		api.PUT("/isbn/:isbn", controllers.UpsertBookByISBN)
 		api.DELETE("/:id", controllers.DeleteBook)
+		api.POST("/:id/checkout", controllers.CheckoutBook)
+		api.POST("/:id/return", controllers.ReturnBook)
 	}
+
+	admin := group.Group("/admin", middleware.RequireAdmin())
+	{
+		admin.GET("/overview", controllers.GetOverview)
+		admin.GET("/read-only", controllers.GetReadOnly)
+		admin.PUT("/read-only", controllers.SetReadOnly)
+		admin.GET("/cache/keys", controllers.ListCacheKeys)
+		admin.DELETE("/cache", controllers.FlushCache)
+		admin.POST("/cache/warm", controllers.WarmCache)
+		admin.POST("/cache/refresh", controllers.RefreshCache)
+		admin.POST("/reindex", controllers.StartReindex)
+		admin.GET("/reindex", controllers.GetReindexStatus)
+		admin.POST("/backup", controllers.ExportBackup)
+		admin.GET("/dead-letters", controllers.ListDeadLetters)
+		admin.POST("/dead-letters/:id/replay", controllers.ReplayDeadLetter)
+		admin.GET("/webhooks", controllers.ListWebhooks)
+		admin.POST("/webhooks", controllers.CreateWebhook)
+		admin.GET("/webhooks/:id", controllers.GetWebhook)
+		admin.PUT("/webhooks/:id", controllers.UpdateWebhook)
+		admin.DELETE("/webhooks/:id", controllers.DeleteWebhook)
+		admin.GET("/webhooks/:id/deliveries", controllers.ListWebhookDeliveries)
+		admin.GET("/events", feature("event_history"), controllers.ListEvents)
+		admin.GET("/api-keys", controllers.ListAPIKeys)
+		admin.POST("/api-keys", controllers.CreateAPIKey)
+		admin.DELETE("/api-keys/:id", controllers.RevokeAPIKey)
+		admin.GET("/features", controllers.ListFeatures)
+		admin.PUT("/features/:name", controllers.SetFeature)
+		admin.DELETE("/features/:name", controllers.ResetFeature)
+	}
+}
+
+// deprecated flags responses served from the unversioned paths
+func deprecated(ctx *gin.Context) {
+	ctx.Header("Deprecation", "true")
+	base := BasePath()
+	successor := base + "/v1" + strings.TrimPrefix(ctx.Request.URL.Path, base)
+	ctx.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+	ctx.Next()
 }
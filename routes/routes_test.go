@@ -0,0 +1,130 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/routes"
+	"github.com/rohans540/books-backend/testutil"
+)
+
+func TestBasePath(t *testing.T) {
+	for env, want := range map[string]string{"": "", "/": "", "api/books-service": "/api/books-service", " /api/books-service/ ": "/api/books-service"} {
+		t.Setenv("BASE_PATH", env)
+		if got := routes.BasePath(); got != want {
+			t.Errorf("BASE_PATH=%q: BasePath = %q, want %q", env, got, want)
+		}
+	}
+}
+
+func TestRoutesMountedUnderBasePath(t *testing.T) {
+	t.Setenv("BASE_PATH", "/api/books-service")
+	testutil.SetupDB(t)
+	testutil.SetupCache(t)
+	testutil.SetupPublisher(t)
+	router := testutil.NewRouter()
+	routes.SetupRoutes(router)
+
+	for _, path := range []string{"/api/books-service/v1/books", "/api/books-service/books", "/api/books-service/healthz", "/api/books-service/version"} {
+		if w := testutil.Request(router, http.MethodGet, path, ""); w.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want 200", path, w.Code)
+		}
+	}
+	for _, path := range []string{"/v1/books", "/books", "/healthz"} {
+		if w := testutil.Request(router, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status = %d, want 404 outside the base path", path, w.Code)
+		}
+	}
+
+	// Links the API hands out stay under the prefix
+	w := testutil.Request(router, http.MethodPost, "/api/books-service/v1/books", `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`)
+	if w.Code != http.StatusCreated || !strings.HasPrefix(w.Header().Get("Location"), "/api/books-service/v1/books/") {
+		t.Errorf("create: status = %d, Location = %q, want it under the base path", w.Code, w.Header().Get("Location"))
+	}
+	var root struct {
+		Links map[string]string `json:"links"`
+	}
+	json.Unmarshal(testutil.Request(router, http.MethodGet, "/api/books-service/", "").Body.Bytes(), &root)
+	if root.Links["health"] != "/api/books-service/healthz" {
+		t.Errorf("root health link = %q, want it under the base path", root.Links["health"])
+	}
+}
+
+func TestTrailingSlash(t *testing.T) {
+	type request struct {
+		method, path, body string
+	}
+	create := `{"title": "Dune", "author": "Frank Herbert", "year": 1965}`
+	update := `{"title": "Dune Messiah", "author": "Frank Herbert", "year": 1969}`
+	requests := []request{
+		{http.MethodGet, "/v1/books", ""},
+		{http.MethodPost, "/v1/books", create},
+		{http.MethodPut, "/v1/books/1", update},
+		{http.MethodDelete, "/v1/books/1", ""},
+	}
+	exact := map[string]int{http.MethodGet: http.StatusOK, http.MethodPost: http.StatusCreated, http.MethodPut: http.StatusOK, http.MethodDelete: http.StatusOK}
+
+	tests := []struct {
+		mode string
+		// slashed returns the status expected for req with a trailing slash
+		slashed func(req request) int
+	}{
+		{"redirect", func(req request) int {
+			if req.method == http.MethodGet {
+				return http.StatusMovedPermanently
+			}
+			return http.StatusTemporaryRedirect
+		}},
+		{"ignore", func(req request) int { return exact[req.method] }},
+		{"strict", func(request) int { return http.StatusNotFound }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			t.Setenv("TRAILING_SLASH", tt.mode)
+			for _, slash := range []bool{false, true} {
+				db := testutil.SetupDB(t)
+				testutil.SetupCache(t)
+				testutil.SetupPublisher(t)
+				router := testutil.NewRouter()
+				routes.SetupRoutes(router)
+				handler := routes.Handler(router)
+				testutil.SeedBooks(t, db, models.Book{Title: "Neuromancer", Author: "William Gibson", Year: 1984})
+
+				for _, req := range requests {
+					path, want := req.path, exact[req.method]
+					if slash {
+						path, want = path+"/", tt.slashed(req)
+					}
+					w := testutil.Request(handler, req.method, path, req.body)
+					if w.Code != want {
+						t.Errorf("%s %s: status = %d, want %d: %s", req.method, path, w.Code, want, w.Body)
+						continue
+					}
+					if w.Code == http.StatusMovedPermanently || w.Code == http.StatusTemporaryRedirect {
+						if location := w.Header().Get("Location"); location != req.path {
+							t.Errorf("%s %s: Location = %q, want %q", req.method, path, location, req.path)
+						}
+					}
+				}
+
+				// A POST served without a redirect kept its body
+				var count, want int64
+				if !slash || tt.mode == "ignore" {
+					want = 1
+				}
+				db.Model(&models.Book{}).Where("title = ?", "Dune").Count(&count)
+				if count != want {
+					t.Errorf("slash %v: %d books created from the POST body, want %d", slash, count, want)
+				}
+			}
+		})
+	}
+
+	t.Setenv("TRAILING_SLASH", "sometimes")
+	if err := routes.ValidateTrailingSlash(); err == nil {
+		t.Error("ValidateTrailingSlash accepted an unknown mode")
+	}
+}
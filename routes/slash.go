@@ -0,0 +1,80 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trailing slash modes, chosen with TRAILING_SLASH. With "redirect" a path
+// with a stray trailing slash (or missing one) is redirected to the route:
+// 301 for GET, 307 for every other method so the body is sent again. With
+// "ignore" both forms are served directly without a redirect, for clients that
+// don't follow redirects on writes. With "strict" only the exact path matches.
+const (
+	slashRedirect = "redirect"
+	slashIgnore   = "ignore"
+	slashStrict   = "strict"
+)
+
+// ValidateTrailingSlash checks TRAILING_SLASH so a typo fails at startup
+// instead of silently selecting the default
+func ValidateTrailingSlash() error {
+	_, err := trailingSlashSetting()
+	return err
+}
+
+func trailingSlashMode() string {
+	mode, err := trailingSlashSetting()
+	if err != nil {
+		return slashRedirect
+	}
+	return mode
+}
+
+func trailingSlashSetting() (string, error) {
+	switch mode := os.Getenv("TRAILING_SLASH"); mode {
+	case "":
+		return slashRedirect, nil
+	case slashRedirect, slashIgnore, slashStrict:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("TRAILING_SLASH must be %s, %s or %s, got %q", slashRedirect, slashIgnore, slashStrict, mode)
+	}
+}
+
+// configureSlashes sets the router's redirect behavior for TRAILING_SLASH.
+// Paths that only differ in case or have extra segments like ".." are never
+// redirected to a route.
+func configureSlashes(router *gin.Engine) {
+	router.RedirectTrailingSlash = trailingSlashMode() == slashRedirect
+	router.RedirectFixedPath = false
+}
+
+// Handler wraps the router for serving. With TRAILING_SLASH=ignore it removes
+// a trailing slash before routing, so "/v1/books/" is served by "/v1/books"
+// with its body intact. The API root is always served with its slash.
+func Handler(router *gin.Engine) http.Handler {
+	if trailingSlashMode() != slashIgnore {
+		return router
+	}
+	base := BasePath()
+	root := base + "/"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path := r.URL.Path; base != "" && path == base {
+			r.URL.Path = root
+		} else if path != root && strings.HasSuffix(path, "/") {
+			r.URL.Path = strings.TrimRight(path, "/")
+			if r.URL.RawPath != "" {
+				r.URL.RawPath = strings.TrimRight(r.URL.RawPath, "/")
+			}
+			if r.URL.Path == "" {
+				r.URL.Path = "/"
+			}
+		}
+		router.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,50 @@
+package seed
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rohans540/books-backend/models"
+	"gorm.io/gorm"
+)
+
+var sampleBooks = []models.Book{
+	{Title: "The Go Programming Language", Author: "Alan A. A. Donovan", Year: 2015},
+	{Title: "Clean Code", Author: "Robert C. Martin", Year: 2008},
+	{Title: "The Pragmatic Programmer", Author: "Andrew Hunt", Year: 1999},
+	{Title: "Designing Data-Intensive Applications", Author: "Martin Kleppmann", Year: 2017},
+	{Title: "Refactoring", Author: "Martin Fowler", Year: 1999},
+	{Title: "Structure and Interpretation of Computer Programs", Author: "Harold Abelson", Year: 1985},
+	{Title: "The Mythical Man-Month", Author: "Frederick P. Brooks Jr.", Year: 1975},
+	{Title: "Introduction to Algorithms", Author: "Thomas H. Cormen", Year: 1990},
+}
+
+// Run inserts the sample books when SEED=true and the books table is empty.
+// It never runs when APP_ENV is set to production.
+func Run(db *gorm.DB) {
+	if os.Getenv("SEED") != "true" {
+		return
+	}
+	if os.Getenv("APP_ENV") == "production" {
+		fmt.Println("Skipping seed: APP_ENV is production")
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.Book{}).Count(&count).Error; err != nil {
+		fmt.Println("Failed to count books for seeding:", err)
+		return
+	}
+	if count > 0 {
+		fmt.Println("Skipping seed: books table is not empty")
+		return
+	}
+
+	books := make([]models.Book, len(sampleBooks))
+	copy(books, sampleBooks)
+	if err := db.Create(&books).Error; err != nil {
+		fmt.Println("Failed to seed books:", err)
+		return
+	}
+	fmt.Printf("Seeded %d sample books\n", len(books))
+}
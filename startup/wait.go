@@ -0,0 +1,47 @@
+package startup
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	defaultTimeout = 60 * time.Second
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
+
+// Timeout returns how long to wait for each dependency, configurable through
+// STARTUP_TIMEOUT (a Go duration such as "90s")
+func Timeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv("STARTUP_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		return defaultTimeout
+	}
+	return timeout
+}
+
+// WaitFor calls check with exponential backoff until it succeeds or the
+// startup timeout elapses, logging every failed attempt
+func WaitFor(name string, check func() error) error {
+	deadline := time.Now().Add(Timeout())
+	backoff := initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := check()
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("%s not ready after %d attempts: %w", name, attempt, err)
+		}
+
+		fmt.Printf("Waiting for %s (attempt %d): %v\n", name, attempt, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rohans540/books-backend/database"
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/outbox"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Delivery headers. The signature is "sha256=" followed by the hex HMAC-SHA256
+// of the body, keyed with the webhook's secret.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultTimeout      = 5 * time.Second
+	defaultMaxFailures  = 10
+	deliveryBatchSize   = 100
+	maxRetryDelay       = 5 * time.Minute
+)
+
+// commitGrace holds back events younger than this. Outbox ids are assigned
+// on insert, so a slow transaction can commit an event after one with a
+// higher id; waiting a moment keeps the cursor from skipping past it.
+const commitGrace = 2 * time.Second
+
+// Sign returns the signature header value of body for secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// pollInterval returns how often webhooks are checked for new events,
+// configurable through WEBHOOK_POLL_INTERVAL
+func pollInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("WEBHOOK_POLL_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return defaultPollInterval
+	}
+	return interval
+}
+
+// timeout returns how long a receiver may take to answer, configurable
+// through WEBHOOK_TIMEOUT
+func timeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv("WEBHOOK_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		return defaultTimeout
+	}
+	return timeout
+}
+
+// maxFailures returns after how many failed deliveries in a row a webhook
+// is disabled, configurable through WEBHOOK_MAX_FAILURES
+func maxFailures() int {
+	failures, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_FAILURES"))
+	if err != nil || failures <= 0 {
+		return defaultMaxFailures
+	}
+	return failures
+}
+
+// retryDelay doubles with every failure in a row, starting at one second
+func retryDelay(failures int) time.Duration {
+	delay := time.Second
+	for i := 1; i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// StartRelay delivers new outbox events to every enabled webhook in the
+// background until ctx is cancelled. Each webhook gets its events in order
+// and at least once; a failed delivery stops that webhook's batch and is
+// retried with backoff. It reads the outbox directly, so webhooks work with
+// or without a message broker, and coalesces events the way the broker relay
+// does.
+func StartRelay(ctx context.Context) {
+	client := &http.Client{Timeout: timeout()}
+	go func() {
+		ticker := time.NewTicker(pollInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := deliverAll(ctx, client); err != nil {
+					log.Println("Webhook relay:", err)
+				}
+			}
+		}
+	}()
+}
+
+// deliverAll runs a batch for every webhook that is due
+func deliverAll(ctx context.Context, client *http.Client) error {
+	var ids []uint
+	err := database.DB.Model(&models.Webhook{}).
+		Where("disabled_at IS NULL AND (retry_at IS NULL OR retry_at <= ?)", time.Now()).
+		Order("id").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := deliverBatch(ctx, client, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimDuration returns how long a claim on a webhook lasts: long enough for
+// a whole batch of deliveries that each run into the timeout
+func claimDuration() time.Duration {
+	return deliveryBatchSize*timeout() + time.Minute
+}
+
+// claim takes hook id for one batch with a conditional update, so of several
+// instances only one gets it, without holding a lock while delivering. A
+// claim left behind by a crashed instance runs out after claimDuration.
+func claim(id uint) (models.Webhook, bool, error) {
+	var hook models.Webhook
+	now := time.Now()
+	result := database.DB.Model(&models.Webhook{}).
+		Where("id = ? AND disabled_at IS NULL AND (claimed_until IS NULL OR claimed_until < ?)", id, now).
+		UpdateColumn("claimed_until", now.Add(claimDuration()))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return hook, false, result.Error // gone, disabled or claimed by another instance
+	}
+	err := database.DB.Clauses(dbresolver.Write).First(&hook, id).Error
+	return hook, err == nil, err
+}
+
+// deliverBatch sends up to deliveryBatchSize events to one webhook. The
+// webhook is claimed, the events are delivered outside any transaction, and
+// the attempts and the webhook's new state are then recorded in one short
+// transaction that also releases the claim.
+//
+// Events the broker relay coalesces under EVENT_COALESCE_WINDOW are skipped
+// here as well, so webhooks see the same events as broker consumers; events
+// are held back until they are older than the window to know.
+func deliverBatch(ctx context.Context, client *http.Client, id uint) error {
+	hook, ok, err := claim(id)
+	if !ok {
+		return err
+	}
+
+	window := outbox.CoalesceWindow()
+	var events []models.OutboxEvent
+	err = database.DB.Clauses(dbresolver.Write).
+		Where("id > ? AND created_at < ?", hook.LastEventID, time.Now().Add(-max(commitGrace, window))).
+		Order("id").
+		Limit(deliveryBatchSize).
+		Find(&events).Error
+	if err != nil {
+		release(hook.ID)
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	disabled := false
+	for i, event := range events {
+		if outbox.Superseded(events, i, window) {
+			hook.LastEventID = event.ID
+			continue
+		}
+		code, err := deliver(ctx, client, hook, event)
+		if err != nil && ctx.Err() != nil {
+			// Shutting down isn't the receiver's fault
+			break
+		}
+		deliveries = append(deliveries, newDelivery(hook, event, code, err))
+		if err != nil {
+			disabled = recordFailure(&hook, err)
+			break
+		}
+		hook.LastEventID = event.ID
+		hook.Failures, hook.LastError, hook.RetryAt = 0, nil, nil
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(deliveries) > 0 {
+			if err := tx.Create(&deliveries).Error; err != nil {
+				return err
+			}
+		}
+		// disabled_at is only written when this batch disabled the webhook,
+		// so an update made while delivering isn't undone
+		columns := []interface{}{"failures", "last_error", "retry_at", "claimed_until"}
+		if disabled {
+			columns = append(columns, "disabled_at")
+		}
+		hook.ClaimedUntil = nil
+		return tx.Model(&hook).Select("last_event_id", columns...).Updates(&hook).Error
+	})
+}
+
+// release gives up the claim on a webhook without recording anything
+func release(id uint) {
+	err := database.DB.Model(&models.Webhook{}).Where("id = ?", id).UpdateColumn("claimed_until", nil).Error
+	if err != nil {
+		log.Printf("Webhook %d: release claim: %v", id, err)
+	}
+}
+
+// recordFailure schedules the retry of a failed delivery, or disables the
+// webhook once it has failed too often in a row and reports true
+func recordFailure(hook *models.Webhook, err error) bool {
+	now, lastError := time.Now(), err.Error()
+	hook.Failures++
+	hook.LastError = &lastError
+	if hook.Failures >= maxFailures() {
+		hook.DisabledAt = &now
+		log.Printf("Webhook %d disabled after %d failed deliveries: %v", hook.ID, hook.Failures, err)
+		return true
+	}
+	retryAt := now.Add(retryDelay(hook.Failures))
+	hook.RetryAt = &retryAt
+	log.Printf("Webhook %d delivery failed (%d in a row): %v", hook.ID, hook.Failures, err)
+	return false
+}
+
+// newDelivery records an attempt to deliver event to hook, made before
+// hook's failures are updated for it
+func newDelivery(hook models.Webhook, event models.OutboxEvent, code int, err error) models.WebhookDelivery {
+	delivery := models.WebhookDelivery{
+		WebhookID: hook.ID,
+		EventID:   event.ID,
+		Attempt:   hook.Failures + 1,
+		Status:    models.DeliveryDelivered,
+	}
+	if code != 0 {
+		delivery.ResponseCode = &code
+	}
+	if err != nil {
+		lastError := err.Error()
+		delivery.Status, delivery.Error = models.DeliveryFailed, &lastError
+	}
+	return delivery
+}
+
+// deliver POSTs one event, the same BookEvent JSON that is published to
+// the broker, and returns the receiver's status code, or zero when it
+// didn't answer. Any 2xx answer counts as delivered.
+func deliver(ctx context.Context, client *http.Client, hook models.Webhook, event models.OutboxEvent) (int, error) {
+	body := []byte(event.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(event.ID), 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
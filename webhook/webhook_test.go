@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rohans540/books-backend/models"
+	"github.com/rohans540/books-backend/testutil"
+	"gorm.io/gorm"
+)
+
+// received is a delivery as the stub receiver saw it
+type received struct {
+	body                      string
+	event, delivery, verified string
+}
+
+// stubReceiver answers every delivery with status and records it, checking
+// the signature against secret the way a receiver would
+func stubReceiver(t *testing.T, secret string, status *int) (*httptest.Server, func() []received) {
+	t.Helper()
+	var mu sync.Mutex
+	var deliveries []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		verified := "bad signature"
+		if hmac.Equal([]byte(r.Header.Get(SignatureHeader)), []byte("sha256="+hex.EncodeToString(mac.Sum(nil)))) {
+			verified = "ok"
+		}
+		mu.Lock()
+		deliveries = append(deliveries, received{string(body), r.Header.Get(EventHeader), r.Header.Get(DeliveryHeader), verified})
+		mu.Unlock()
+		w.WriteHeader(*status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), deliveries...)
+	}
+}
+
+// queueEvent adds an outbox event old enough to be past commitGrace
+func queueEvent(t *testing.T, db *gorm.DB, eventType, payload string) models.OutboxEvent {
+	t.Helper()
+	event := models.OutboxEvent{Topic: "book_events", EventType: eventType, Payload: payload, CreatedAt: time.Now().Add(-time.Minute)}
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestDeliverySignatureAndPayload(t *testing.T) {
+	db := testutil.SetupDB(t)
+	status := http.StatusOK
+	server, deliveries := stubReceiver(t, "s3cret", &status)
+	hook := models.Webhook{URL: server.URL, Secret: "s3cret"}
+	db.Create(&hook)
+
+	created := queueEvent(t, db, "book.created", `{"event":"book.created","id":1,"title":"Dune"}`)
+	deleted := queueEvent(t, db, "book.deleted", `{"event":"book.deleted","id":1,"title":"Dune"}`)
+	if err := deliverAll(context.Background(), http.DefaultClient); err != nil {
+		t.Fatal(err)
+	}
+
+	got := deliveries()
+	want := []received{
+		{created.Payload, "book.created", "1", "ok"},
+		{deleted.Payload, "book.deleted", "2", "ok"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("received %d deliveries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("delivery %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Delivered events aren't sent again
+	db.First(&hook, hook.ID)
+	if hook.LastEventID != deleted.ID || hook.Failures != 0 {
+		t.Errorf("webhook = %+v, want it past event %d", hook, deleted.ID)
+	}
+	deliverAll(context.Background(), http.DefaultClient)
+	if n := len(deliveries()); n != 2 {
+		t.Errorf("%d deliveries after a second run, want still 2", n)
+	}
+	var logged int64
+	db.Model(&models.WebhookDelivery{}).Where("webhook_id = ? AND status = ?", hook.ID, models.DeliveryDelivered).Count(&logged)
+	if logged != 2 {
+		t.Errorf("%d delivered attempts logged, want 2", logged)
+	}
+}
+
+func TestFailingWebhookIsRetriedThenDisabled(t *testing.T) {
+	t.Setenv("WEBHOOK_MAX_FAILURES", "3")
+	db := testutil.SetupDB(t)
+	status := http.StatusInternalServerError
+	server, deliveries := stubReceiver(t, "s3cret", &status)
+	hook := models.Webhook{URL: server.URL, Secret: "s3cret"}
+	db.Create(&hook)
+	event := queueEvent(t, db, "book.created", `{"event":"book.created","id":1}`)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		deliverAll(context.Background(), http.DefaultClient)
+		db.First(&hook, hook.ID)
+		if hook.Failures != attempt || hook.LastEventID != 0 || hook.LastError == nil {
+			t.Fatalf("attempt %d: webhook = %+v, want %d failures and the event still pending", attempt, hook, attempt)
+		}
+		if attempt < 3 && (hook.RetryAt == nil || !hook.RetryAt.After(time.Now())) {
+			t.Errorf("attempt %d: retry_at = %v, want a later retry", attempt, hook.RetryAt)
+		}
+		// Not due again until retry_at, so a run in between sends nothing
+		deliverAll(context.Background(), http.DefaultClient)
+		if n := len(deliveries()); n != attempt {
+			t.Fatalf("attempt %d: %d deliveries, want %d", attempt, n, attempt)
+		}
+		db.Model(&hook).Update("retry_at", nil)
+	}
+	if hook.DisabledAt == nil {
+		t.Fatalf("webhook = %+v, want it disabled after 3 failures", hook)
+	}
+
+	// A disabled webhook gets nothing, even once the receiver recovers
+	status = http.StatusOK
+	deliverAll(context.Background(), http.DefaultClient)
+	if n := len(deliveries()); n != 3 {
+		t.Errorf("%d deliveries to a disabled webhook, want none after the 3 failures", n-3)
+	}
+	db.First(&hook, hook.ID)
+	if hook.LastEventID == event.ID {
+		t.Error("event marked delivered to a disabled webhook")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for failures, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 20: maxRetryDelay} {
+		if got := retryDelay(failures); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestDeliveryHoldsNoDatabaseLock(t *testing.T) {
+	db := testutil.SetupDB(t)
+	// The test database has a single connection, so a receiver that reads
+	// the webhooks while a delivery transaction holds it times out
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+		defer cancel()
+		var count int64
+		if err := db.WithContext(ctx).Model(&models.Webhook{}).Count(&count).Error; err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	hook := models.Webhook{URL: server.URL, Secret: "s3cret"}
+	db.Create(&hook)
+	event := queueEvent(t, db, "book.created", `{"event":"book.created","id":1}`)
+
+	if err := deliverAll(context.Background(), http.DefaultClient); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&hook, hook.ID)
+	if hook.LastEventID != event.ID || hook.Failures != 0 || hook.ClaimedUntil != nil {
+		t.Errorf("webhook = %+v, want it past event %d and unclaimed", hook, event.ID)
+	}
+}
+
+func TestClaimedWebhookIsSkipped(t *testing.T) {
+	db := testutil.SetupDB(t)
+	status := http.StatusOK
+	server, deliveries := stubReceiver(t, "s3cret", &status)
+	claimed := time.Now().Add(time.Minute)
+	hook := models.Webhook{URL: server.URL, Secret: "s3cret", ClaimedUntil: &claimed}
+	db.Create(&hook)
+	queueEvent(t, db, "book.created", `{"event":"book.created","id":1}`)
+
+	deliverAll(context.Background(), http.DefaultClient)
+	if n := len(deliveries()); n != 0 {
+		t.Fatalf("%d deliveries to a webhook claimed by another instance, want none", n)
+	}
+
+	// A claim that ran out, e.g. of a crashed instance, is taken over
+	db.Model(&hook).Update("claimed_until", time.Now().Add(-time.Second))
+	deliverAll(context.Background(), http.DefaultClient)
+	if n := len(deliveries()); n != 1 {
+		t.Errorf("%d deliveries after the claim ran out, want 1", n)
+	}
+}
+
+func TestDeliveriesAreCoalescedLikeTheRelay(t *testing.T) {
+	t.Setenv("EVENT_COALESCE_WINDOW", "1s")
+	db := testutil.SetupDB(t)
+	status := http.StatusOK
+	server, deliveries := stubReceiver(t, "s3cret", &status)
+	hook := models.Webhook{URL: server.URL, Secret: "s3cret"}
+	db.Create(&hook)
+
+	bookID := uint(1)
+	created := time.Now().Add(-time.Minute)
+	var events []models.OutboxEvent
+	for i, payload := range []string{`{"event":"book.updated","id":1,"year":1965}`, `{"event":"book.updated","id":1,"year":1966}`} {
+		event := models.OutboxEvent{Topic: "book_events", EventType: "book.updated", BookID: &bookID, Payload: payload, CreatedAt: created.Add(time.Duration(i) * 100 * time.Millisecond)}
+		db.Create(&event)
+		events = append(events, event)
+	}
+
+	deliverAll(context.Background(), http.DefaultClient)
+	got := deliveries()
+	if len(got) != 1 || got[0].body != events[1].Payload {
+		t.Fatalf("deliveries = %+v, want only the later update", got)
+	}
+	db.First(&hook, hook.ID)
+	if hook.LastEventID != events[1].ID {
+		t.Errorf("last event = %d, want %d", hook.LastEventID, events[1].ID)
+	}
+}